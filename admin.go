@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"answerflow/modules/currency"
+)
+
+// requireAdmin guards admin handlers with the ADMIN_TOKEN bearer token.
+func requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !isAdminRequest(r) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
+
+// handleQuarantine lists rates held back by anomaly screening (GET) or
+// resolves one of them (POST ?id=<provider:key>&action=approve|discard).
+func handleQuarantine(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, globalAPICache.QuarantinedRates())
+
+	case http.MethodPost:
+		id := r.URL.Query().Get("id")
+		var err error
+		switch r.URL.Query().Get("action") {
+		case "approve":
+			err = globalAPICache.ApproveQuarantined(id)
+		case "discard":
+			err = globalAPICache.DiscardQuarantined(id)
+		default:
+			http.Error(w, "action must be approve or discard", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleWireLog lists recent provider HTTP exchanges, newest first
+// (GET ?provider=mastercard to filter). Empty unless WIRE_LOG is enabled.
+func handleWireLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, globalAPICache.WireLog(r.URL.Query().Get("provider")))
+}
+
+// handleParseStats reports parse cache hit rates and which query patterns
+// match how often.
+func handleParseStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, currencyModule.ParseStats())
+}
+
+// handleAliasPacks reports the loaded alias packs on GET and reads
+// ALIAS_PACKS_DIR again on POST.
+func handleAliasPacks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, currencyModule.AliasPackStats())
+	case http.MethodPost:
+		stats, err := currencyModule.ReloadAliasPacks()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		writeJSON(w, stats)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleFeeCalibration reports the correction factor for modeled Whitebird
+// rates and the calibration run that set it.
+func handleFeeCalibration(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, globalAPICache.FeeCalibration())
+}
+
+// handleDNSStats reports provider hostname resolution times and cache state.
+func handleDNSStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, currency.DNSStats())
+}
+
+// handleConnectionStats reports per-provider connection reuse, to verify
+// keep-alive and HTTP/2 settings.
+func handleConnectionStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, globalAPICache.ConnectionStats())
+}
+
+// handleFaults lists injected provider faults (GET), injects one (POST
+// ?provider=&fault=circuit|latency|corrupt|error|stale&duration=5m[&latency=2s])
+// or clears a provider's faults (DELETE ?provider=<name>|*). Registered only
+// with FAULT_INJECTION=true.
+func handleFaults(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, currency.Faults())
+
+	case http.MethodPost:
+		duration, err := time.ParseDuration(q.Get("duration"))
+		if err != nil {
+			http.Error(w, "invalid duration", http.StatusBadRequest)
+			return
+		}
+		var latency time.Duration
+		if v := q.Get("latency"); v != "" {
+			if latency, err = time.ParseDuration(v); err != nil {
+				http.Error(w, "invalid latency", http.StatusBadRequest)
+				return
+			}
+		}
+		if err := currency.InjectFault(q.Get("provider"), q.Get("fault"), duration, latency); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case http.MethodDelete:
+		if q.Get("provider") == "" {
+			http.Error(w, "missing provider", http.StatusBadRequest)
+			return
+		}
+		currency.ClearFaults(q.Get("provider"))
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// In-flight ceiling for query endpoints. Requests beyond MAX_IN_FLIGHT wait up
+// to QUEUE_TIMEOUT for a slot, then get 503 with Retry-After, so a burst from
+// several launcher clients can't exhaust provider rate limits or memory.
+// MAX_IN_FLIGHT=0 disables the limit.
+var (
+	maxInFlight  = getEnvInt("MAX_IN_FLIGHT", 64)
+	queueTimeout = getEnvDuration("QUEUE_TIMEOUT", 250*time.Millisecond)
+	retryAfter   = getEnvDuration("RETRY_AFTER", time.Second)
+)
+
+// inFlightLimiter is one in-flight budget, shared by every handler it wraps
+// and by frontends that acquire slots directly, like the Telegram bot.
+type inFlightLimiter struct {
+	slots chan struct{} // nil when the limit is disabled
+}
+
+func newInFlightLimiter() *inFlightLimiter {
+	if maxInFlight <= 0 {
+		return &inFlightLimiter{}
+	}
+	return &inFlightLimiter{slots: make(chan struct{}, maxInFlight)}
+}
+
+// acquire takes a slot, waiting up to queueTimeout for one. It reports false
+// if none freed up in time or ctx was done; otherwise the caller must release.
+func (l *inFlightLimiter) acquire(ctx context.Context) bool {
+	if l.slots == nil {
+		return true
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+	}
+	timer := time.NewTimer(queueTimeout)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (l *inFlightLimiter) release() {
+	if l.slots != nil {
+		<-l.slots
+	}
+}
+
+// wrap returns next behind the limiter.
+func (l *inFlightLimiter) wrap(next http.HandlerFunc) http.HandlerFunc {
+	retryAfterSeconds := strconv.Itoa(int((retryAfter + time.Second - 1) / time.Second))
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !l.acquire(r.Context()) {
+			if r.Context().Err() != nil {
+				return
+			}
+			w.Header().Set("Retry-After", retryAfterSeconds)
+			http.Error(w, "too many requests in flight", http.StatusServiceUnavailable)
+			return
+		}
+		defer l.release()
+		next(w, r)
+	}
+}
@@ -0,0 +1,67 @@
+// Package buildinfo identifies the running binary. Release builds stamp it
+// with
+//
+//	go build -ldflags "-X answerflow/buildinfo.Version=v1.4.0 -X answerflow/buildinfo.Commit=$(git rev-parse HEAD) -X answerflow/buildinfo.Date=$(date -u +%FT%TZ)"
+//
+// and anything left unstamped is taken from the VCS metadata the Go
+// toolchain embeds when building inside a git checkout.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Set with -ldflags -X; see the package comment.
+var (
+	Version string
+	Commit  string
+	Date    string
+)
+
+// Info describes the running build.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"build_date"`
+	Modified  bool   `json:"modified,omitempty"` // built from a checkout with uncommitted changes
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build's identity; fields nothing recorded are "unknown".
+func Get() Info {
+	info := Info{Version: Version, Commit: Commit, Date: Date, GoVersion: runtime.Version()}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = s.Value
+				}
+			case "vcs.time":
+				if info.Date == "" {
+					info.Date = s.Value
+				}
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			}
+		}
+	}
+	for _, field := range []*string{&info.Version, &info.Commit, &info.Date} {
+		if *field == "" {
+			*field = "unknown"
+		}
+	}
+	return info
+}
+
+// ShortCommit is the commit abbreviated the way git shows it.
+func (i Info) ShortCommit() string {
+	if len(i.Commit) > 12 {
+		return i.Commit[:12]
+	}
+	return i.Commit
+}
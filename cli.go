@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"answerflow/commontypes"
+	"answerflow/modules/currency"
+)
+
+// runQueryCommand implements `answerflow query [flags] <query>`. The query runs
+// through the local module pipeline, or against a running server with -server.
+func runQueryCommand(args []string) int {
+	fs := flag.NewFlagSet("query", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print results as JSON (same as -format flow)")
+	format := fs.String("format", "table", "output format: table, flow, raycast, alfred, csv")
+	server := fs.String("server", "", "query a running server (e.g. http://localhost:8080) instead of computing locally")
+	selection := fs.String("modules", "", "run only these modules, comma-separated (e.g. currency,calculator)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: answerflow query [flags] <query>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	query := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if query == "" {
+		fs.Usage()
+		return 2
+	}
+
+	if *asJSON {
+		*format = "flow"
+	}
+	var serialize func([]commontypes.FlowResult) interface{}
+	if *format != "table" {
+		var ok bool
+		if serialize, ok = resultSerializers[*format]; !ok {
+			fmt.Fprintf(os.Stderr, "Error: unknown format %q\n", *format)
+			return 2
+		}
+	}
+
+	var results []commontypes.FlowResult
+	var err error
+	if *server != "" {
+		results, err = queryRemote(*server, query, *selection)
+	} else {
+		results, err = queryLocal(query, *selection)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if serialize != nil {
+		if table, ok := serialize(results).(csvOutput); ok {
+			if err := table.write(os.Stdout); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			return 0
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(serialize(results)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	if len(results) == 0 {
+		fmt.Println("No results found")
+		return 1
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, res := range results {
+		fmt.Fprintf(tw, "%s\t%s\n", res.Title, res.SubTitle)
+	}
+	tw.Flush()
+	return 0
+}
+
+func queryLocal(query, selection string) ([]commontypes.FlowResult, error) {
+	globalAPICache = currency.NewAPICache()
+	if err := globalAPICache.LoadFromFile(); err != nil {
+		log.Printf("Warning: Could not load cached data: %v", err)
+	}
+	if !globalAPICache.HasCachedRates() {
+		log.Println("No usable cached rates, performing initial fetch...")
+		if err := globalAPICache.InitialFetch(); err != nil {
+			return nil, err
+		}
+	}
+	globalAPICache.InitializeTradeablePairs()
+	registerModules()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	if selection != "" {
+		selected, err := parseModuleSelection(selection)
+		if err != nil {
+			return nil, err
+		}
+		ctx = withModuleSelection(ctx, selected)
+	}
+	return runModules(ctx, query), nil
+}
+
+func queryRemote(server, query, selection string) ([]commontypes.FlowResult, error) {
+	params := url.Values{"q": {query}}
+	if selection != "" {
+		params.Set("modules", selection)
+	}
+	resp, err := http.Get(strings.TrimRight(server, "/") + "/?" + params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %s", resp.Status)
+	}
+
+	var results []commontypes.FlowResult
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return results, nil
+}
@@ -0,0 +1,219 @@
+// Command smoketest runs a canonical set of queries against a running
+// answerflow instance and checks the response structure and that values fall
+// in sane ranges. It exits 1 when any check fails, for use in deploy
+// pipelines:
+//
+//	smoketest -url https://rates.example.com
+//
+// With -admin-token on an instance started with FAULT_INJECTION=true it also
+// checks that stale Mastercard data is flagged rather than served as fresh.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"answerflow/commontypes"
+)
+
+// check is one canonical query and the amount its best matching result
+// must copy: a number in [min, max], in currency when set.
+type check struct {
+	name     string
+	query    string
+	currency string
+	min, max float64
+	crypto   bool // skipped with -crypto=false, e.g. on fiat-only deployments
+}
+
+var checks = []check{
+	{name: "forward fiat", query: "100 usd to eur", currency: "EUR", min: 50, max: 150},
+	{name: "inverse fiat", query: "how much eur for 100 usd", currency: "EUR", min: 50, max: 150},
+	{name: "forward crypto", query: "1 btc to usd", currency: "USD", min: 1000, max: 10000000, crypto: true},
+	{name: "calculator", query: "2+2*3", min: 8, max: 8},
+}
+
+type smokeClient struct {
+	base       string
+	adminToken string
+	http       *http.Client
+}
+
+func main() {
+	base := flag.String("url", envOr("SMOKE_URL", "http://localhost:8080"), "base URL of the instance")
+	adminToken := flag.String("admin-token", os.Getenv("ADMIN_TOKEN"), "admin token; enables the stale-data check")
+	crypto := flag.Bool("crypto", true, "run the crypto checks")
+	timeout := flag.Duration("timeout", 30*time.Second, "per-request timeout")
+	flag.Parse()
+
+	c := &smokeClient{
+		base:       strings.TrimRight(*base, "/"),
+		adminToken: *adminToken,
+		http:       &http.Client{Timeout: *timeout},
+	}
+
+	failed := 0
+	report := func(name string, err error) {
+		if err != nil {
+			failed++
+			fmt.Printf("FAIL %-16s %v\n", name, err)
+			return
+		}
+		fmt.Printf("ok   %s\n", name)
+	}
+
+	report("health", c.checkHealth())
+	for _, ch := range checks {
+		if ch.crypto && !*crypto {
+			fmt.Printf("-    %s (skipped)\n", ch.name)
+			continue
+		}
+		report(ch.name, c.checkQuery(ch))
+	}
+	if c.adminToken != "" {
+		report("stale data", c.checkStale())
+	} else {
+		fmt.Println("-    stale data (skipped, no -admin-token)")
+	}
+
+	if failed > 0 {
+		fmt.Printf("%d check(s) failed\n", failed)
+		os.Exit(1)
+	}
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func (c *smokeClient) do(method, path string, admin bool) (*http.Response, []byte, error) {
+	req, err := http.NewRequest(method, c.base+path, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if admin {
+		req.Header.Set("Authorization", "Bearer "+c.adminToken)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	return resp, body, err
+}
+
+func (c *smokeClient) checkHealth() error {
+	resp, body, err := c.do("GET", "/health", false)
+	if err != nil {
+		return err
+	}
+	var health struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(body, &health); err != nil {
+		return fmt.Errorf("invalid health JSON: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || (health.Status != "ok" && health.Status != "degraded") {
+		return fmt.Errorf("status %d, health %q", resp.StatusCode, health.Status)
+	}
+	return nil
+}
+
+// query runs q and validates the structure every result must have.
+func (c *smokeClient) query(q string) ([]commontypes.FlowResult, error) {
+	resp, body, err := c.do("GET", "/?q="+url.QueryEscape(q), false)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	var results []commontypes.FlowResult
+	if err := json.Unmarshal(body, &results); err != nil {
+		return nil, fmt.Errorf("invalid results JSON: %w", err)
+	}
+	for i, res := range results {
+		if strings.TrimSpace(res.Title) == "" {
+			return nil, fmt.Errorf("result %d has no title", i)
+		}
+		if res.JsonRPCAction.Method == "" {
+			return nil, fmt.Errorf("result %d (%q) has no action", i, res.Title)
+		}
+	}
+	return results, nil
+}
+
+func (c *smokeClient) checkQuery(ch check) error {
+	results, err := c.query(ch.query)
+	if err != nil {
+		return err
+	}
+	var seen []string
+	for _, res := range results {
+		amount, currency, ok := copiedAmount(res)
+		if !ok || currency != ch.currency {
+			continue
+		}
+		if amount >= ch.min && amount <= ch.max {
+			return nil
+		}
+		seen = append(seen, strconv.FormatFloat(amount, 'g', -1, 64))
+	}
+	if len(seen) > 0 {
+		return fmt.Errorf("%q: %s %s outside [%g, %g]", ch.query, strings.Join(seen, ", "), ch.currency, ch.min, ch.max)
+	}
+	return fmt.Errorf("%q: no result copying an amount in %q among %d results", ch.query, ch.currency, len(results))
+}
+
+// checkStale marks Mastercard data stale through the fault injection API and
+// expects a fiat conversion to be refused or badged, never served as fresh.
+func (c *smokeClient) checkStale() error {
+	resp, body, err := c.do("POST", "/admin/faults?provider=mastercard&fault=stale&duration=30s", true)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("injecting stale fault: status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	defer c.do("DELETE", "/admin/faults?provider=mastercard", true)
+
+	const q = "100 eur to gbp"
+	results, err := c.query(q)
+	if err != nil {
+		return err
+	}
+	for _, res := range results {
+		_, currency, ok := copiedAmount(res)
+		if ok && currency == "GBP" && !strings.HasPrefix(res.Title, string(commontypes.BadgeStale)) {
+			return fmt.Errorf("%q: stale rate served without a stale badge: %q", q, res.Title)
+		}
+	}
+	return nil
+}
+
+// copiedAmount parses a result's clipboard text: "123.45 EUR" or a bare
+// calculator number.
+func copiedAmount(res commontypes.FlowResult) (float64, string, bool) {
+	if res.JsonRPCAction.Method != "copy_to_clipboard" || len(res.JsonRPCAction.Parameters) == 0 {
+		return 0, "", false
+	}
+	text, _ := res.JsonRPCAction.Parameters[0].(string)
+	number, currency, _ := strings.Cut(strings.TrimSpace(text), " ")
+	amount, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return amount, currency, true
+}
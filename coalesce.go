@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"answerflow/commontypes"
+
+	"golang.org/x/sync/singleflight"
+)
+
+var queryGroup singleflight.Group
+
+// runModulesCoalesced is runModules with identical concurrent queries (e.g.
+// launcher retries of the same keystroke) sharing a single execution. The
+// shared run is detached from the first caller's cancellation so one client
+// going away doesn't fail the others; each caller still stops waiting when
+// its own context ends.
+func runModulesCoalesced(ctx context.Context, query string) []commontypes.FlowResult {
+	// Diagnostics are per request and can't be shared
+	if commontypes.DiagnosticsFromContext(ctx) != nil {
+		return runModules(ctx, query)
+	}
+
+	// Results depend on the home currency, language, reference preference,
+	// fast path, personal fee, USDT network, favorites, watchlist and module
+	// selection as well as the query
+	reference, set := commontypes.ReferenceRatesFromContext(ctx)
+	fee, _ := commontypes.PersonalFeeFromContext(ctx)
+	key := fmt.Sprintf("%s\x00%s\x00%t%t%t\x00%g\x00%s\x00%s\x00%s\x00%s\x00%s", commontypes.HomeCurrencyFromContext(ctx), commontypes.LanguageFromContext(ctx),
+		set, reference, commontypes.FastPathFromContext(ctx), fee, commontypes.USDTNetworkFromContext(ctx),
+		strings.Join(commontypes.FavoriteCurrenciesFromContext(ctx), ","), strings.Join(commontypes.WatchlistFromContext(ctx), ","),
+		moduleSelectionString(ctx), strings.Join(strings.Fields(query), " "))
+	ch := queryGroup.DoChan(key, func() (interface{}, error) {
+		sharedCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), requestTimeout)
+		defer cancel()
+		return runModules(sharedCtx, query), nil
+	})
+
+	select {
+	case res := <-ch:
+		shared := res.Val.([]commontypes.FlowResult)
+		results := make([]commontypes.FlowResult, len(shared))
+		copy(results, shared)
+		return results
+	case <-ctx.Done():
+		return nil
+	}
+}
@@ -0,0 +1,83 @@
+package commontypes
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// Launcher action methods, as Flow Launcher names them. Modules build
+// actions with the constructors below instead of spelling methods out; the
+// server drops actions whose method isn't allowed or whose parameters don't
+// fit it (see ValidateAction).
+const (
+	ActionCopyToClipboard = "copy_to_clipboard"
+	ActionChangeQuery     = "Flow.Launcher.ChangeQuery"
+	ActionOpenURL         = "Flow.Launcher.OpenUrl"
+	ActionRunShell        = "Flow.Launcher.ShellRun"
+)
+
+// DefaultActionMethods are the methods allowed unless configured otherwise.
+// Running shell commands is left out: a result should never be able to run
+// something on the user's machine unless the deployment opts in.
+var DefaultActionMethods = []string{ActionCopyToClipboard, ActionChangeQuery, ActionOpenURL}
+
+// CopyToClipboard copies text.
+func CopyToClipboard(text string) JsonRPCAction {
+	return JsonRPCAction{Method: ActionCopyToClipboard, Parameters: []interface{}{text}}
+}
+
+// ChangeQuery replaces the launcher query with query, running it again if
+// requery is set.
+func ChangeQuery(query string, requery bool) JsonRPCAction {
+	return JsonRPCAction{Method: ActionChangeQuery, Parameters: []interface{}{query, requery}}
+}
+
+// OpenURL opens an http or https URL in the browser.
+func OpenURL(rawURL string) (JsonRPCAction, error) {
+	action := JsonRPCAction{Method: ActionOpenURL, Parameters: []interface{}{rawURL}}
+	return action, ValidateAction(action)
+}
+
+// RunShell runs command in the user's shell. It only takes effect where
+// ActionRunShell is explicitly allowed.
+func RunShell(command string) JsonRPCAction {
+	return JsonRPCAction{Method: ActionRunShell, Parameters: []interface{}{command}}
+}
+
+// ValidateAction checks that a has a known method with the parameters it
+// takes. The empty action, which does nothing, is valid.
+func ValidateAction(a JsonRPCAction) error {
+	text := func(i int) (string, bool) {
+		if i >= len(a.Parameters) {
+			return "", false
+		}
+		s, ok := a.Parameters[i].(string)
+		return s, ok
+	}
+	switch a.Method {
+	case "":
+		return nil
+	case ActionCopyToClipboard, ActionRunShell:
+		if s, ok := text(0); !ok || s == "" || len(a.Parameters) != 1 {
+			return fmt.Errorf("%s takes one non-empty string", a.Method)
+		}
+	case ActionChangeQuery:
+		if _, ok := text(0); !ok || len(a.Parameters) != 2 {
+			return fmt.Errorf("%s takes a query and a requery flag", a.Method)
+		}
+		if _, ok := a.Parameters[1].(bool); !ok {
+			return fmt.Errorf("%s takes a query and a requery flag", a.Method)
+		}
+	case ActionOpenURL:
+		s, ok := text(0)
+		if !ok || len(a.Parameters) != 1 {
+			return fmt.Errorf("%s takes one URL", a.Method)
+		}
+		if u, err := url.Parse(s); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%s needs an absolute http(s) URL, not %q", a.Method, s)
+		}
+	default:
+		return fmt.Errorf("unknown action method %q", a.Method)
+	}
+	return nil
+}
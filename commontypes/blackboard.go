@@ -0,0 +1,81 @@
+package commontypes
+
+import (
+	"context"
+	"sync"
+)
+
+// Blackboard holds what modules parsed from one query, so a module can reuse
+// another's parse instead of repeating it. Publishers are unaware of their
+// readers; a reader declares the modules it reads from (see
+// modules.DependencyAware) and runs after them. Methods are safe for
+// concurrent use and no-ops on a nil receiver.
+type Blackboard struct {
+	mu      sync.RWMutex
+	entries map[string]interface{}
+}
+
+// Well-known blackboard entries.
+const (
+	// EntryConversion is a ParsedConversion, published by the currency
+	// module for queries it reads as a conversion.
+	EntryConversion = "conversion"
+)
+
+// ParsedConversion is an amount and currency pair as the currency module
+// read them; To is empty when the query names no target.
+type ParsedConversion struct {
+	Amount  float64
+	From    string
+	To      string
+	Inverse bool // Amount of From is wanted, paid in To
+}
+
+type blackboardContextKey struct{}
+
+func NewBlackboard() *Blackboard {
+	return &Blackboard{entries: make(map[string]interface{})}
+}
+
+// WithBlackboard attaches b to ctx.
+func WithBlackboard(ctx context.Context, b *Blackboard) context.Context {
+	return context.WithValue(ctx, blackboardContextKey{}, b)
+}
+
+// BlackboardFromContext returns the blackboard attached to ctx, or nil.
+func BlackboardFromContext(ctx context.Context) *Blackboard {
+	b, _ := ctx.Value(blackboardContextKey{}).(*Blackboard)
+	return b
+}
+
+// Publish stores value under key, replacing an earlier value.
+func (b *Blackboard) Publish(key string, value interface{}) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entries[key] = value
+}
+
+// Lookup returns the value published under key.
+func (b *Blackboard) Lookup(key string) (interface{}, bool) {
+	if b == nil {
+		return nil, false
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	value, ok := b.entries[key]
+	return value, ok
+}
+
+// ConversionFromContext returns the conversion the currency module parsed
+// from the query, if any.
+func ConversionFromContext(ctx context.Context) (ParsedConversion, bool) {
+	value, ok := BlackboardFromContext(ctx).Lookup(EntryConversion)
+	if !ok {
+		return ParsedConversion{}, false
+	}
+	conversion, ok := value.(ParsedConversion)
+	return conversion, ok
+}
@@ -0,0 +1,67 @@
+package commontypes
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// CopyVariants returns context menu items that copy a numeric result in the
+// formats different paste targets want: with thousand separators, as the
+// raw float at full precision, and as a spreadsheet formula when formula is
+// set. plain is the number the result's own action copies; variants that
+// would copy the same text are left out.
+func CopyVariants(plain string, value float64, formula string) []ContextMenuItem {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil
+	}
+
+	var items []ContextMenuItem
+	seen := map[string]bool{plain: true}
+	add := func(text, subTitle string) {
+		if seen[text] {
+			return
+		}
+		seen[text] = true
+		items = append(items, ContextMenuItem{
+			Title:         text,
+			SubTitle:      subTitle,
+			JsonRPCAction: CopyToClipboard(text),
+		})
+	}
+
+	add(GroupThousands(plain), "Copy with thousand separators")
+	add(strconv.FormatFloat(value, 'f', -1, 64), "Copy raw full-precision value")
+	if formula != "" {
+		add("="+formula, "Copy as spreadsheet formula")
+	}
+	return items
+}
+
+// GroupThousands inserts commas between thousands in the integer part of a
+// plain decimal number such as "-1234567.89". Anything else is returned
+// unchanged.
+func GroupThousands(plain string) string {
+	sign, digits := "", plain
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	intPart, frac, hasFrac := strings.Cut(digits, ".")
+	if intPart == "" || strings.Trim(intPart, "0123456789") != "" {
+		return plain
+	}
+
+	var b strings.Builder
+	b.WriteString(sign)
+	for i, r := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	if hasFrac {
+		b.WriteByte('.')
+		b.WriteString(frac)
+	}
+	return b.String()
+}
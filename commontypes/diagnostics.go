@@ -0,0 +1,111 @@
+package commontypes
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// Diagnostics collects per-request debugging details for ?debug=1 responses.
+// Methods are safe for concurrent use and no-ops on a nil receiver, so
+// instrumented code can call them unconditionally.
+type Diagnostics struct {
+	mu          sync.Mutex
+	Modules     []ModuleTiming
+	CacheHits   int
+	CacheMisses int
+	Providers   []string
+	Routes      []interface{}
+	Errors      []string
+}
+
+// ModuleTiming is how long one module took to answer and how it ended.
+type ModuleTiming struct {
+	Module   string  `json:"module"`
+	Millis   float64 `json:"ms"`
+	Results  int     `json:"results"`
+	Error    string  `json:"error,omitempty"`
+	Disabled bool    `json:"disabled,omitempty"`
+}
+
+type diagnosticsContextKey struct{}
+
+// MarshalJSON locks d so modules still running after a timeout can't race
+// the encoder.
+func (d *Diagnostics) MarshalJSON() ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return json.Marshal(struct {
+		Modules     []ModuleTiming `json:"modules"`
+		CacheHits   int            `json:"cache_hits"`
+		CacheMisses int            `json:"cache_misses"`
+		Providers   []string       `json:"providers_contacted"`
+		Routes      []interface{}  `json:"routes"`
+		Errors      []string       `json:"suppressed_errors"`
+	}{d.Modules, d.CacheHits, d.CacheMisses, d.Providers, d.Routes, d.Errors})
+}
+
+// WithDiagnostics attaches d to ctx.
+func WithDiagnostics(ctx context.Context, d *Diagnostics) context.Context {
+	return context.WithValue(ctx, diagnosticsContextKey{}, d)
+}
+
+// DiagnosticsFromContext returns the collector attached to ctx, or nil.
+func DiagnosticsFromContext(ctx context.Context) *Diagnostics {
+	d, _ := ctx.Value(diagnosticsContextKey{}).(*Diagnostics)
+	return d
+}
+
+func (d *Diagnostics) AddModule(t ModuleTiming) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Modules = append(d.Modules, t)
+}
+
+func (d *Diagnostics) AddCacheLookup(hit bool) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if hit {
+		d.CacheHits++
+	} else {
+		d.CacheMisses++
+	}
+}
+
+// AddRoute records the route a conversion took and the providers it used.
+func (d *Diagnostics) AddRoute(route interface{}, providers []string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Routes = append(d.Routes, route)
+	for _, p := range providers {
+		known := false
+		for _, existing := range d.Providers {
+			if existing == p {
+				known = true
+				break
+			}
+		}
+		if !known {
+			d.Providers = append(d.Providers, p)
+		}
+	}
+}
+
+// AddError records an error that was swallowed instead of shown to the user.
+func (d *Diagnostics) AddError(err error) {
+	if d == nil || err == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Errors = append(d.Errors, err.Error())
+}
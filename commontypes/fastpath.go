@@ -0,0 +1,31 @@
+package commontypes
+
+import "context"
+
+type fastPathContextKey struct{}
+
+// WithFastPath asks modules to answer from cached data only, without
+// provider calls, accepting approximate results for constant latency.
+// Results that would differ from a full answer carry BadgeApproximate.
+func WithFastPath(ctx context.Context) context.Context {
+	return context.WithValue(ctx, fastPathContextKey{}, true)
+}
+
+// FastPathFromContext reports whether ctx asks for a fast-path answer.
+func FastPathFromContext(ctx context.Context) bool {
+	fast, _ := ctx.Value(fastPathContextKey{}).(bool)
+	return fast
+}
+
+// HasApproximate reports whether any of results is approximate, i.e.
+// worth refining with a full query.
+func HasApproximate(results []FlowResult) bool {
+	for _, res := range results {
+		for _, b := range res.Badges {
+			if b == BadgeApproximate {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,18 @@
+package commontypes
+
+import "context"
+
+type favoriteCurrenciesContextKey struct{}
+
+// WithFavoriteCurrencies attaches the requester's favorite currencies (e.g.
+// "THB", "GEL"); modules offer conversions into them alongside their
+// configured targets.
+func WithFavoriteCurrencies(ctx context.Context, codes []string) context.Context {
+	return context.WithValue(ctx, favoriteCurrenciesContextKey{}, codes)
+}
+
+// FavoriteCurrenciesFromContext returns the favorites attached to ctx, or nil.
+func FavoriteCurrenciesFromContext(ctx context.Context) []string {
+	codes, _ := ctx.Value(favoriteCurrenciesContextKey{}).([]string)
+	return codes
+}
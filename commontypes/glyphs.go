@@ -0,0 +1,11 @@
+package commontypes
+
+// Glyphs shared by every module and output path. Keep emoji and tags here
+// rather than in string literals scattered across formatters: one place to
+// get right, and nothing to garble when a file is re-saved in a legacy
+// encoding.
+const (
+	GlyphBuy     = "🛍️"
+	GlyphSell    = "🏷️"
+	GlyphWarning = "⚠️"
+)
@@ -0,0 +1,17 @@
+package commontypes
+
+import "context"
+
+type homeCurrencyContextKey struct{}
+
+// WithHomeCurrency attaches the requester's home currency (e.g. "GBP") to
+// ctx; modules use it instead of their configured default.
+func WithHomeCurrency(ctx context.Context, code string) context.Context {
+	return context.WithValue(ctx, homeCurrencyContextKey{}, code)
+}
+
+// HomeCurrencyFromContext returns the home currency attached to ctx, or "".
+func HomeCurrencyFromContext(ctx context.Context) string {
+	code, _ := ctx.Value(homeCurrencyContextKey{}).(string)
+	return code
+}
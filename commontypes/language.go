@@ -0,0 +1,18 @@
+package commontypes
+
+import "context"
+
+type languageContextKey struct{}
+
+// WithLanguage attaches the requester's language (e.g. "ru") to ctx;
+// modules with text in several languages use it instead of their configured
+// default.
+func WithLanguage(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, languageContextKey{}, lang)
+}
+
+// LanguageFromContext returns the language attached to ctx, or "".
+func LanguageFromContext(ctx context.Context) string {
+	lang, _ := ctx.Value(languageContextKey{}).(string)
+	return lang
+}
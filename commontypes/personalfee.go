@@ -0,0 +1,18 @@
+package commontypes
+
+import "context"
+
+type personalFeeContextKey struct{}
+
+// WithPersonalFee attaches the user's own bank markup, in percent, to be
+// deducted on top of the modeled route. A fee typed in the query wins.
+func WithPersonalFee(ctx context.Context, percent float64) context.Context {
+	return context.WithValue(ctx, personalFeeContextKey{}, percent)
+}
+
+// PersonalFeeFromContext returns the fee attached to ctx and whether there
+// was one.
+func PersonalFeeFromContext(ctx context.Context) (percent float64, ok bool) {
+	percent, ok = ctx.Value(personalFeeContextKey{}).(float64)
+	return percent, ok
+}
@@ -0,0 +1,18 @@
+package commontypes
+
+import "context"
+
+type referenceRatesContextKey struct{}
+
+// WithReferenceRates asks modules to add (or, with false, omit) mid-market
+// reference results next to the realistic ones, overriding their default.
+func WithReferenceRates(ctx context.Context, enabled bool) context.Context {
+	return context.WithValue(ctx, referenceRatesContextKey{}, enabled)
+}
+
+// ReferenceRatesFromContext returns the preference attached to ctx and
+// whether there was one.
+func ReferenceRatesFromContext(ctx context.Context) (enabled, ok bool) {
+	enabled, ok = ctx.Value(referenceRatesContextKey{}).(bool)
+	return enabled, ok
+}
@@ -0,0 +1,30 @@
+package commontypes
+
+// ScoringStrategy decides the final score of a module result. Modules keep
+// assigning their own base scores per result type; a strategy adjusts them,
+// so deployments can tune ranking without touching formatter code.
+type ScoringStrategy interface {
+	Score(module string, res *FlowResult) int
+}
+
+// Scoring is embedded by modules that accept a ScoringStrategy. Its zero
+// value leaves base scores alone.
+type Scoring struct {
+	strategy ScoringStrategy
+}
+
+// SetScoringStrategy installs strategy. It is called once, when the module
+// is registered, before any query runs.
+func (s *Scoring) SetScoringStrategy(strategy ScoringStrategy) {
+	s.strategy = strategy
+}
+
+// ApplyScoring rescores results in place with the installed strategy.
+func (s *Scoring) ApplyScoring(module string, results []FlowResult) {
+	if s.strategy == nil {
+		return
+	}
+	for i := range results {
+		results[i].Score = s.strategy.Score(module, &results[i])
+	}
+}
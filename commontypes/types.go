@@ -1,5 +1,7 @@
 package commontypes
 
+import "time"
+
 // FlowResult represents a single item in the list of results for Flow Launcher.
 type FlowResult struct {
 	Title            string            `json:"Title"`
@@ -8,8 +10,24 @@ type FlowResult struct {
 	Score            int               `json:"Score"`
 	JsonRPCAction    JsonRPCAction     `json:"JsonRPCAction"`
 	ContextMenuItems []ContextMenuItem `json:"ContextMenuItems,omitempty"`
+	ContextData      interface{}       `json:"ContextData,omitempty"`
+	CacheTTL         int               `json:"CacheTTL,omitempty"`  // Seconds a client may reuse the result for the same query; 0 means don't cache
+	ValidAsOf        *time.Time        `json:"ValidAsOf,omitempty"` // Timestamp of the oldest provider data the result is built on
+	Badges           []Badge           `json:"-"`                   // Rendered by the decorator pipeline in main
+	Group            string            `json:"-"`                   // Section label, mapped by each output format that has sections
+	Error            bool              `json:"-"`                   // Explains why the query couldn't be answered rather than answering it
 }
 
+// Badge is a state glyph shown alongside a result.
+type Badge string
+
+const (
+	BadgeStale       Badge = "⚠"
+	BadgeOffline     Badge = "🔌"
+	BadgeFavorite    Badge = "⭐"
+	BadgeApproximate Badge = "≈"
+)
+
 // JsonRPCAction defines an action to be performed by Flow Launcher.
 type JsonRPCAction struct {
 	Method     string        `json:"method"`
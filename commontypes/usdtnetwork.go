@@ -0,0 +1,21 @@
+package commontypes
+
+import (
+	"context"
+	"strings"
+)
+
+type usdtNetworkContextKey struct{}
+
+// WithUSDTNetwork attaches the network the user usually holds USDT on
+// ("trc20", "erc20", ...), so conversions can account for moving it to or
+// from the exchange.
+func WithUSDTNetwork(ctx context.Context, network string) context.Context {
+	return context.WithValue(ctx, usdtNetworkContextKey{}, strings.ToLower(network))
+}
+
+// USDTNetworkFromContext returns the USDT network attached to ctx, or "".
+func USDTNetworkFromContext(ctx context.Context) string {
+	network, _ := ctx.Value(usdtNetworkContextKey{}).(string)
+	return network
+}
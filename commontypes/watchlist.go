@@ -0,0 +1,17 @@
+package commontypes
+
+import "context"
+
+type watchlistContextKey struct{}
+
+// WithWatchlist attaches the requester's watchlist, pairs such as "USD/RUB"
+// shown when the query is empty.
+func WithWatchlist(ctx context.Context, pairs []string) context.Context {
+	return context.WithValue(ctx, watchlistContextKey{}, pairs)
+}
+
+// WatchlistFromContext returns the watchlist attached to ctx, or nil.
+func WatchlistFromContext(ctx context.Context) []string {
+	pairs, _ := ctx.Value(watchlistContextKey{}).([]string)
+	return pairs
+}
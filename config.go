@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"answerflow/cron"
+	"answerflow/modules/currency"
+	"answerflow/preset"
+)
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		currency.InvalidSetting(key, value, "not an integer", defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		currency.InvalidSetting(key, value, "not a boolean", defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		currency.InvalidSetting(key, value, "not a duration such as 30s or 5m", defaultValue)
+		return defaultValue
+	}
+	if parsed < 0 {
+		currency.InvalidSetting(key, value, "must not be negative", defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// configError reports every invalid setting at once, so a misconfigured
+// deployment is fixed in one round instead of one restart per mistake.
+type configError []currency.ConfigIssue
+
+func (e configError) Error() string {
+	lines := make([]string, 0, len(e)+1)
+	lines = append(lines, fmt.Sprintf("%d invalid settings:", len(e)))
+	for _, issue := range e {
+		lines = append(lines, "  "+issue.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// validateConfig checks the settings of all packages and returns a
+// configError naming each invalid one, or nil.
+func validateConfig() error {
+	issues := currency.ConfigIssues()
+	if name := os.Getenv("PRESET"); name != "" && preset.Active == "" {
+		issues = append(issues, currency.ConfigIssue{Key: "PRESET", Value: name, Problem: "not one of " + strings.Join(preset.Names(), ", ")})
+	}
+	for _, code := range quickTargets {
+		if !currency.KnownCurrency(code) {
+			issues = append(issues, currency.ConfigIssue{Key: "QUICK_TARGETS", Value: code, Problem: "not a supported currency"})
+		}
+	}
+	for _, code := range rubRatesCurrencies {
+		if !currency.KnownCurrency(code) {
+			issues = append(issues, currency.ConfigIssue{Key: "RUB_RATES_CURRENCIES", Value: code, Problem: "not a supported currency"})
+		}
+	}
+	if _, err := cron.Parse(summaryCron); err != nil {
+		issues = append(issues, currency.ConfigIssue{Key: "SUMMARY_CRON", Value: summaryCron, Problem: err.Error()})
+	}
+	if path := getEnv("RESULT_RULES_FILE", ""); path != "" {
+		if _, err := loadResultRules(path); err != nil {
+			issues = append(issues, currency.ConfigIssue{Key: "RESULT_RULES_FILE", Value: path, Problem: err.Error()})
+		}
+	}
+	// Zero turns the in-flight limit and query history off
+	for _, s := range []struct {
+		key        string
+		value, min int
+	}{
+		{"MAX_IN_FLIGHT", maxInFlight, 0},
+		{"SUGGEST_HISTORY_SIZE", suggestHistorySize, 0},
+		{"PROFILES_MAX", profilesMax, 1},
+		{"SHARE_MAX_ENTRIES", shareMaxEntries, 1},
+		{"RESULT_MAX_TITLE_LENGTH", maxTitleLength, 1},
+		{"RESULT_MAX_SUBTITLE_LENGTH", maxSubTitleLength, 1},
+		{"RUB_RATES_AMOUNT", rubRatesAmount, 1},
+	} {
+		if s.value < s.min {
+			issues = append(issues, currency.ConfigIssue{Key: s.key, Value: strconv.Itoa(s.value), Problem: fmt.Sprintf("must be at least %d", s.min)})
+		}
+	}
+	if len(issues) == 0 {
+		return nil
+	}
+	return configError(issues)
+}
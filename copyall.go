@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"answerflow/commontypes"
+)
+
+// COPY_ALL adds a last item copying every copyable result of the query as
+// one block, a line per result's clipboard value, for pasting a comparison
+// into a chat. Results explaining an error are left out, and the item needs
+// at least two lines. Off by default.
+var copyAllEnabled = getEnvBool("COPY_ALL", false)
+
+// withCopyAll returns results followed by the copy-all item, leaving
+// results itself alone: coalesced queries share it.
+func withCopyAll(results []commontypes.FlowResult) []commontypes.FlowResult {
+	if !copyAllEnabled {
+		return results
+	}
+	var lines []string
+	lowest := 0
+	for i, res := range results {
+		if i == 0 || res.Score < lowest {
+			lowest = res.Score
+		}
+		if text, ok := clipboardText(res); ok && !res.Error {
+			lines = append(lines, text)
+		}
+	}
+	if len(lines) < 2 {
+		return results
+	}
+	block := strings.Join(lines, "\n")
+	item := commontypes.FlowResult{
+		Title:         fmt.Sprintf("Copy all %d results", len(lines)),
+		SubTitle:      strings.Join(lines, " | "),
+		IcoPath:       defaultModuleIcon,
+		Score:         lowest - 1,
+		JsonRPCAction: commontypes.CopyToClipboard(block),
+	}
+	return append(results[:len(results):len(results)], item)
+}
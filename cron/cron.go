@@ -0,0 +1,116 @@
+// Package cron parses standard five-field cron expressions for the service's
+// scheduled work: the daily summary and provider refreshes.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a standard five-field cron expression
+// (minute hour day-of-month month day-of-week) supporting *, lists, ranges
+// and steps. Like cron, a restricted day-of-month and day-of-week match if
+// either does. A leading CRON_TZ=<zone> evaluates it in that time zone
+// rather than the server's ("CRON_TZ=Europe/Moscow 0 9 * * 1-5").
+type Schedule struct {
+	minute, hour, dom, month, dow []bool
+	domStar, dowStar              bool
+	loc                           *time.Location
+}
+
+func Parse(spec string) (*Schedule, error) {
+	fields := strings.Fields(spec)
+	s := Schedule{loc: time.Local}
+	if len(fields) > 0 && strings.HasPrefix(fields[0], "CRON_TZ=") {
+		loc, err := time.LoadLocation(strings.TrimPrefix(fields[0], "CRON_TZ="))
+		if err != nil {
+			return nil, fmt.Errorf("cron spec %q: %w", spec, err)
+		}
+		s.loc, fields = loc, fields[1:]
+	}
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron spec %q: expected 5 fields", spec)
+	}
+
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+	s.dow[0] = s.dow[0] || s.dow[7] // 7 is Sunday too
+	s.domStar = fields[2] == "*"
+	s.dowStar = fields[4] == "*"
+	return &s, nil
+}
+
+func parseCronField(field string, min, max int) ([]bool, error) {
+	set := make([]bool, max+1)
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("cron field %q: bad step", field)
+			}
+			step = n
+			part = part[:i]
+		}
+
+		lo, hi := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("cron field %q: %w", field, err)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("cron field %q: %w", field, err)
+				}
+			} else if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("cron field %q: out of range %d-%d", field, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+// Next returns the first matching minute strictly after t, or the zero time
+// if nothing matches within a year (e.g. "0 0 30 2 *").
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.In(s.loc).Truncate(time.Minute).Add(time.Minute)
+	for limit := t.AddDate(1, 0, 0); t.Before(limit); t = t.Add(time.Minute) {
+		if !s.month[t.Month()] || !s.hour[t.Hour()] || !s.minute[t.Minute()] {
+			continue
+		}
+		domMatch, dowMatch := s.dom[t.Day()], s.dow[t.Weekday()]
+		switch {
+		case s.domStar && s.dowStar,
+			s.domStar && dowMatch,
+			s.dowStar && domMatch,
+			!s.domStar && !s.dowStar && (domMatch || dowMatch):
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"answerflow/commontypes"
+)
+
+// ResultContext tells a decorator where a result came from.
+type ResultContext struct {
+	Query  string
+	Module string
+}
+
+// ResultDecorator adjusts a module result before it is serialized, so
+// presentation concerns shared by all modules live in one place.
+type ResultDecorator func(rc ResultContext, res *commontypes.FlowResult)
+
+// resultDecorators run in registration order on every result returned by
+// runModules; decorateBadges always runs last so badges stay in front.
+var resultDecorators []ResultDecorator
+
+func registerResultDecorator(d ResultDecorator) {
+	resultDecorators = append(resultDecorators, d)
+}
+
+func init() {
+	path := getEnv("RESULT_RULES_FILE", "")
+	if path == "" {
+		return
+	}
+	rules, err := loadResultRules(path)
+	if err != nil {
+		log.Printf("Warning: result rules not loaded: %v", err)
+		return
+	}
+	registerResultDecorator(rules.apply)
+	log.Printf("Loaded %d result rules from %s", len(rules), path)
+}
+
+func decorateResult(rc ResultContext, res *commontypes.FlowResult) {
+	for _, decorate := range resultDecorators {
+		decorate(rc, res)
+	}
+	decorateBadges(res)
+}
+
+// decorateBadges prefixes the title with the result's badge glyphs. Flow
+// Launcher has no icon overlays, and a title prefix survives every other
+// output format unchanged.
+func decorateBadges(res *commontypes.FlowResult) {
+	if len(res.Badges) == 0 {
+		return
+	}
+	glyphs := make([]string, len(res.Badges))
+	for i, b := range res.Badges {
+		glyphs[i] = string(b)
+	}
+	res.Title = strings.Join(glyphs, "") + " " + res.Title
+}
+
+// resultRule is one entry of RESULT_RULES_FILE, a JSON array such as
+//
+//	[{"module": "CurrencyConverter", "match": "RUB", "replace": {"продать": "sell"}, "note": "cash-out via TON"}]
+//
+// A rule applies when the module (if set) matches and the regexp (if set)
+// matches the title or subtitle. Replacements are applied to the subtitle,
+// then the note is appended to it.
+type resultRule struct {
+	Module  string            `json:"module"`
+	Match   string            `json:"match"`
+	Replace map[string]string `json:"replace"`
+	Note    string            `json:"note"`
+
+	re *regexp.Regexp
+}
+
+type resultRules []resultRule
+
+func loadResultRules(path string) (resultRules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules resultRules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for i := range rules {
+		if rules[i].Match == "" {
+			continue
+		}
+		if rules[i].re, err = regexp.Compile(rules[i].Match); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+	}
+	return rules, nil
+}
+
+func (rules resultRules) apply(rc ResultContext, res *commontypes.FlowResult) {
+	for _, rule := range rules {
+		if rule.Module != "" && rule.Module != rc.Module {
+			continue
+		}
+		if rule.re != nil && !rule.re.MatchString(res.Title) && !rule.re.MatchString(res.SubTitle) {
+			continue
+		}
+		for from, to := range rule.Replace {
+			res.SubTitle = strings.ReplaceAll(res.SubTitle, from, to)
+		}
+		switch {
+		case rule.Note == "":
+		case res.SubTitle == "":
+			res.SubTitle = rule.Note
+		default:
+			res.SubTitle += " | " + rule.Note
+		}
+	}
+}
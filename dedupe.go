@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"answerflow/commontypes"
+)
+
+// resultKey normalizes what a result would give the user: its clipboard
+// payload if it copies something, otherwise its title. Digit grouping,
+// whitespace and case are ignored so "7,800" and "7800" compare equal.
+func resultKey(res commontypes.FlowResult) string {
+	text := res.Title
+	if clip, ok := clipboardText(res); ok {
+		text = clip
+	}
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == ',' {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, text)
+}
+
+// dedupeResults returns a new slice with results that duplicate another
+// module's answer removed, keeping the highest-scored copy (the first on a
+// tie). Results from the same module are never merged with each other;
+// origins[i] names the module that produced results[i]. The origins of the
+// kept results are returned alongside.
+func dedupeResults(results []commontypes.FlowResult, origins []string) ([]commontypes.FlowResult, []string) {
+	out := make([]commontypes.FlowResult, 0, len(results))
+	outOrigins := make([]string, 0, len(results))
+	seen := make(map[string]int) // key -> index in out
+	seenOrigin := make(map[string]string)
+
+	for i, res := range results {
+		key := resultKey(res)
+		if j, ok := seen[key]; ok && key != "" && seenOrigin[key] != origins[i] {
+			if res.Score > out[j].Score {
+				out[j] = res
+				outOrigins[j] = origins[i]
+				seenOrigin[key] = origins[i]
+			}
+			continue
+		}
+		seen[key] = len(out)
+		seenOrigin[key] = origins[i]
+		out = append(out, res)
+		outOrigins = append(outOrigins, origins[i])
+	}
+	return out, outOrigins
+}
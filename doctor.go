@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"answerflow/modules/currency"
+	"answerflow/notify"
+	"answerflow/preset"
+)
+
+const (
+	doctorOK   = "ok"
+	doctorWarn = "warn"
+	doctorFail = "FAIL"
+	doctorSkip = "-"
+)
+
+type doctorCheck struct {
+	name, status, latency, detail string
+}
+
+// runDoctorCommand implements `answerflow doctor`: it validates the
+// configuration, checks that the rate cache is writable and probes every
+// enabled provider, then prints a readiness report. The exit code is 1 when
+// a setting is invalid (the server refuses to start then) or a critical
+// provider is unreachable.
+func runDoctorCommand(args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	timeout := fs.Duration("timeout", 30*time.Second, "overall time limit for the provider probes")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: answerflow doctor [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	var checks []doctorCheck
+	add := func(name, status, latency, detail string) {
+		checks = append(checks, doctorCheck{name, status, latency, detail})
+	}
+
+	// Configuration
+	ready := true
+	if err := validateConfig(); err != nil {
+		for _, issue := range err.(configError) {
+			add("config", doctorFail, "", issue.String())
+		}
+		ready = false
+	} else {
+		add("config", doctorOK, "", "no invalid settings")
+	}
+	if preset.Active != "" {
+		add("preset", doctorOK, "", preset.Active)
+	} else {
+		add("preset", doctorSkip, "", "none (PRESET not set)")
+	}
+	if adminToken == "" {
+		add("admin API", doctorSkip, "", "disabled (ADMIN_TOKEN not set)")
+	} else {
+		add("admin API", doctorOK, "", "enabled")
+	}
+	if channels := notify.FromEnv(); len(channels) > 0 {
+		add("notifications", doctorOK, "", channels.Name())
+	} else {
+		add("notifications", doctorSkip, "", "no channel configured")
+	}
+
+	// Persistence
+	if path, err := currency.CheckPersistence(); err != nil {
+		add("rate cache", doctorWarn, "", fmt.Sprintf("%s not writable, rates won't survive restarts: %v", path, err))
+	} else {
+		add("rate cache", doctorOK, "", path+" writable")
+	}
+
+	// Providers
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	for _, probe := range currency.NewAPICache().ProbeProviders(ctx) {
+		name := "provider " + probe.Provider
+		switch {
+		case probe.Criticality == "disabled":
+			add(name, doctorSkip, "", "disabled")
+		case probe.Err != nil:
+			status := doctorWarn
+			if probe.Criticality == "critical" {
+				status, ready = doctorFail, false
+			}
+			add(name, status, probe.Latency.Round(time.Millisecond).String(),
+				fmt.Sprintf("%s: %v", probe.Criticality, probe.Err))
+		default:
+			add(name, doctorOK, probe.Latency.Round(time.Millisecond).String(),
+				fmt.Sprintf("%s: %s", probe.Criticality, probe.Detail))
+		}
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHECK\tSTATUS\tLATENCY\tDETAIL")
+	for _, c := range checks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", c.name, c.status, c.latency, c.detail)
+	}
+	tw.Flush()
+
+	if !ready {
+		fmt.Println("\nNot ready: invalid settings or a critical provider unreachable")
+		return 1
+	}
+	fmt.Println("\nReady")
+	return 0
+}
@@ -0,0 +1,102 @@
+// Package events is an in-process publish/subscribe bus for things that
+// happen across subsystems, so a fetcher announces a rate update once and
+// persistence, alerts, metrics and streams each react to it without the
+// fetcher knowing about them:
+//
+//	rate-updated        a provider's rates were refreshed
+//	provider-down       a provider failed too many updates in a row
+//	provider-recovered  a provider that was down updates again
+//	query-served        a query was answered
+//	result-selected     a client reported the result the user picked
+//
+// Handlers run synchronously on the publisher's goroutine, so they must be
+// quick and hand slow work to a goroutine of their own. A panicking handler
+// is logged and doesn't affect the others.
+package events
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+type Kind string
+
+const (
+	RateUpdated       Kind = "rate-updated"
+	ProviderDown      Kind = "provider-down"
+	ProviderRecovered Kind = "provider-recovered"
+	QueryServed       Kind = "query-served"
+	ResultSelected    Kind = "result-selected"
+)
+
+// Event is one occurrence; fields not meaningful for its Kind are zero.
+type Event struct {
+	Kind Kind
+	Time time.Time
+
+	Provider string // rate-updated, provider-down, provider-recovered
+	Err      error  // provider-down: the last failure
+	Fails    int    // provider-down: consecutive failures
+
+	Query    string        // query-served, result-selected
+	Client   string        // query-served: the requester's client ID, if it gave one
+	Results  int           // query-served
+	Duration time.Duration // query-served
+	Title    string        // result-selected
+}
+
+type subscription struct {
+	id int
+	fn func(Event)
+}
+
+var (
+	mu     sync.RWMutex
+	nextID int
+	subs   = make(map[Kind][]subscription)
+)
+
+// Subscribe calls fn for every later event of kind, until the returned
+// function is called.
+func Subscribe(kind Kind, fn func(Event)) (unsubscribe func()) {
+	mu.Lock()
+	defer mu.Unlock()
+	nextID++
+	id := nextID
+	subs[kind] = append(subs[kind], subscription{id, fn})
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		list := subs[kind]
+		for i, s := range list {
+			if s.id == id {
+				subs[kind] = append(list[:i:i], list[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish delivers e to the subscribers of its kind, stamping its time if
+// unset.
+func Publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	mu.RLock()
+	list := subs[e.Kind]
+	mu.RUnlock()
+	for _, s := range list {
+		deliver(s.fn, e)
+	}
+}
+
+func deliver(fn func(Event), e Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Error: %s event handler panicked: %v", e.Kind, r)
+		}
+	}()
+	fn(e)
+}
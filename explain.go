@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"answerflow/modules/currency"
+	"answerflow/numparse"
+)
+
+// handleExplain serves GET /explain?amount=&from=&to= with a step-by-step
+// breakdown of the conversion route. Add format=text for plain text.
+func handleExplain(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	amount, err := strconv.ParseFloat(numparse.Normalize(q.Get("amount")), 64)
+	if err != nil {
+		http.Error(w, "invalid amount", http.StatusBadRequest)
+		return
+	}
+
+	explanation, err := currencyModule.Explain(amount, q.Get("from"), q.Get("to"), globalAPICache)
+	if err != nil {
+		http.Error(w, currency.TranslateError(err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	if q.Get("format") == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(explanation.String()))
+		return
+	}
+	writeJSON(w, explanation)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"regexp"
+
+	"answerflow/commontypes"
+	"answerflow/modules/currency"
+)
+
+// CURRENCY_FLAGS=true prefixes fiat codes in currency results with their
+// flag: "🇺🇸 USD = 🇪🇺 EUR".
+var currencyFlagsEnabled = getEnvBool("CURRENCY_FLAGS", false)
+
+// Currency codes in results follow their amount: "100 USD", "1 USD = 92.5 RUB".
+// Anchoring on the amount keeps words such as ALL or TOP, which are also
+// currency codes, from gaining a flag.
+var regexCurrencyCode = regexp.MustCompile(`\d [A-Z]{3}\b`)
+
+func init() {
+	if currencyFlagsEnabled {
+		registerResultDecorator(decorateFlags)
+	}
+}
+
+func decorateFlags(rc ResultContext, res *commontypes.FlowResult) {
+	if currencyModule == nil || rc.Module != currencyModule.Name() {
+		return
+	}
+	res.Title = addFlags(res.Title)
+	res.SubTitle = addFlags(res.SubTitle)
+}
+
+func addFlags(s string) string {
+	return regexCurrencyCode.ReplaceAllStringFunc(s, func(match string) string {
+		amount, code := match[:len(match)-3], match[len(match)-3:]
+		if flag := currency.FlagEmoji(code); flag != "" {
+			return amount + flag + " " + code
+		}
+		return match
+	})
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"answerflow/commontypes"
+	"answerflow/modules/currency"
+)
+
+// resultSerializers render module results for clients other than Flow
+// Launcher. Selected with ?format= on the query endpoint and -format in the CLI.
+// Each decides what to do with result groups: Flow Launcher and Alfred ignore
+// them, Raycast lists each group's results together.
+var resultSerializers = map[string]func([]commontypes.FlowResult) interface{}{
+	"flow":    func(results []commontypes.FlowResult) interface{} { return results },
+	"raycast": toRaycastOutput,
+	"alfred":  toAlfredOutput,
+	"csv":     toCSVOutput,
+}
+
+// clipboardText extracts the text a result would copy, if its primary action
+// is a clipboard copy.
+func clipboardText(res commontypes.FlowResult) (string, bool) {
+	if res.JsonRPCAction.Method != commontypes.ActionCopyToClipboard || len(res.JsonRPCAction.Parameters) == 0 {
+		return "", false
+	}
+	text, ok := res.JsonRPCAction.Parameters[0].(string)
+	return text, ok && text != ""
+}
+
+// openURL extracts the URL a result would open, if its primary action opens
+// one.
+func openURL(res commontypes.FlowResult) (string, bool) {
+	if res.JsonRPCAction.Method != commontypes.ActionOpenURL || len(res.JsonRPCAction.Parameters) == 0 {
+		return "", false
+	}
+	u, ok := res.JsonRPCAction.Parameters[0].(string)
+	return u, ok && u != ""
+}
+
+type raycastAction struct {
+	Type    string `json:"type"`
+	Title   string `json:"title"`
+	Content string `json:"content,omitempty"`
+	Query   string `json:"query,omitempty"`
+	URL     string `json:"url,omitempty"`
+}
+
+type raycastItem struct {
+	Title    string          `json:"title"`
+	Subtitle string          `json:"subtitle,omitempty"`
+	Icon     string          `json:"icon,omitempty"`
+	Actions  []raycastAction `json:"actions,omitempty"`
+}
+
+type raycastOutput struct {
+	Items []raycastItem `json:"items"`
+}
+
+// toRaycastOutput maps results to the item list consumed by Raycast script
+// commands. Clipboard copies become "copy" actions; Flow's ChangeQuery becomes
+// a "search" action carrying the suggested query, and OpenUrl an "open"
+// action. Other actions, such as shell commands, are not passed on. Only
+// fields Raycast reads are emitted; groups are kept together, ordered by their
+// best result.
+func toRaycastOutput(results []commontypes.FlowResult) interface{} {
+	out := raycastOutput{Items: make([]raycastItem, 0, len(results))}
+	for _, res := range groupResults(results) {
+		item := raycastItem{Title: res.Title, Subtitle: res.SubTitle, Icon: res.IcoPath}
+		if text, ok := clipboardText(res); ok {
+			item.Actions = append(item.Actions, raycastAction{Type: "copy", Title: "Copy to Clipboard", Content: text})
+		} else if res.JsonRPCAction.Method == commontypes.ActionChangeQuery && len(res.JsonRPCAction.Parameters) > 0 {
+			if query, ok := res.JsonRPCAction.Parameters[0].(string); ok {
+				item.Actions = append(item.Actions, raycastAction{Type: "search", Title: "Search", Query: query})
+			}
+		} else if u, ok := openURL(res); ok {
+			item.Actions = append(item.Actions, raycastAction{Type: "open", Title: "Open in Browser", URL: u})
+		}
+		out.Items = append(out.Items, item)
+	}
+	return out
+}
+
+// groupResults reorders results so each group is contiguous, keeping groups
+// in the order of their first result and results in order within a group.
+func groupResults(results []commontypes.FlowResult) []commontypes.FlowResult {
+	var order []string
+	byGroup := make(map[string][]commontypes.FlowResult)
+	for _, res := range results {
+		if _, seen := byGroup[res.Group]; !seen {
+			order = append(order, res.Group)
+		}
+		byGroup[res.Group] = append(byGroup[res.Group], res)
+	}
+	grouped := make([]commontypes.FlowResult, 0, len(results))
+	for _, group := range order {
+		grouped = append(grouped, byGroup[group]...)
+	}
+	return grouped
+}
+
+type alfredIcon struct {
+	Path string `json:"path"`
+}
+
+type alfredMod struct {
+	Arg      string `json:"arg"`
+	Subtitle string `json:"subtitle,omitempty"`
+	Valid    bool   `json:"valid"`
+}
+
+type alfredText struct {
+	Copy      string `json:"copy,omitempty"`
+	LargeType string `json:"largetype,omitempty"`
+}
+
+type alfredItem struct {
+	Title        string               `json:"title"`
+	Subtitle     string               `json:"subtitle,omitempty"`
+	Arg          string               `json:"arg,omitempty"`
+	Autocomplete string               `json:"autocomplete,omitempty"`
+	Valid        bool                 `json:"valid"`
+	Icon         *alfredIcon          `json:"icon,omitempty"`
+	Text         *alfredText          `json:"text,omitempty"`
+	Mods         map[string]alfredMod `json:"mods,omitempty"`
+	QuickLookURL string               `json:"quicklookurl,omitempty"`
+}
+
+type alfredOutput struct {
+	Cache *alfredCache `json:"cache,omitempty"`
+	Items []alfredItem `json:"items"`
+}
+
+// alfredCache asks Alfred to reuse the output for the same query.
+type alfredCache struct {
+	Seconds     int  `json:"seconds"`
+	LooseReload bool `json:"loosereload"`
+}
+
+// toAlfredOutput maps results to Alfred's Script Filter JSON. The clipboard
+// text becomes the item's arg (wire it to a Copy to Clipboard output), the
+// first copyable context menu item becomes the ⌘ modifier, and ChangeQuery
+// suggestions become non-actionable autocomplete items; a URL to open becomes
+// the Quick Look URL. Shell commands are not passed on. Alfred only renders
+// local icon files, so remote icon URLs are dropped.
+func toAlfredOutput(results []commontypes.FlowResult) interface{} {
+	out := alfredOutput{Items: make([]alfredItem, 0, len(results))}
+	for _, res := range results {
+		item := alfredItem{Title: res.Title, Subtitle: res.SubTitle}
+		if res.IcoPath != "" && !strings.HasPrefix(res.IcoPath, "http://") && !strings.HasPrefix(res.IcoPath, "https://") {
+			item.Icon = &alfredIcon{Path: res.IcoPath}
+		}
+
+		if text, ok := clipboardText(res); ok {
+			item.Arg = text
+			item.Valid = true
+			item.Text = &alfredText{Copy: text, LargeType: text}
+		} else if res.JsonRPCAction.Method == commontypes.ActionChangeQuery && len(res.JsonRPCAction.Parameters) > 0 {
+			if query, ok := res.JsonRPCAction.Parameters[0].(string); ok {
+				item.Autocomplete = query
+			}
+		} else if u, ok := openURL(res); ok {
+			item.QuickLookURL = u
+		}
+
+		for _, cm := range res.ContextMenuItems {
+			cmResult := commontypes.FlowResult{JsonRPCAction: cm.JsonRPCAction}
+			if text, ok := clipboardText(cmResult); ok {
+				item.Mods = map[string]alfredMod{"cmd": {Arg: text, Subtitle: cm.Title, Valid: true}}
+				break
+			}
+		}
+
+		out.Items = append(out.Items, item)
+	}
+	if ttl := minCacheTTL(results); ttl > 0 {
+		out.Cache = &alfredCache{Seconds: ttl, LooseReload: true}
+	}
+	return out
+}
+
+// minCacheTTL is the cache hint for a whole result list: the shortest of
+// its results' hints, or 0 when any result must not be cached.
+func minCacheTTL(results []commontypes.FlowResult) int {
+	ttl := 0
+	for i, res := range results {
+		if res.CacheTTL <= 0 {
+			return 0
+		}
+		if i == 0 || res.CacheTTL < ttl {
+			ttl = res.CacheTTL
+		}
+	}
+	return ttl
+}
+
+// csvOutput is a header row and one row per conversion, written as CSV
+// rather than JSON by writeResults and the CLI.
+type csvOutput [][]string
+
+// toCSVOutput lists the conversions among results for spreadsheets, which
+// can pull it with IMPORTDATA: pair, amount, result, effective rate and when
+// the oldest rate used was quoted. Results that aren't conversions (the
+// calculator, hints) are left out.
+func toCSVOutput(results []commontypes.FlowResult) interface{} {
+	out := csvOutput{{"pair", "amount", "result", "rate", "timestamp"}}
+	for _, res := range results {
+		route, ok := res.ContextData.(*currency.Route)
+		if !ok || route == nil {
+			continue
+		}
+		out = append(out, []string{
+			route.From + "/" + route.To,
+			strconv.FormatFloat(route.Amount, 'f', -1, 64),
+			strconv.FormatFloat(route.Result, 'f', -1, 64),
+			strconv.FormatFloat(route.EffectiveRate, 'g', 10, 64),
+			route.QuotedAt.UTC().Format(time.RFC3339),
+		})
+	}
+	return out
+}
+
+func (c csvOutput) write(w io.Writer) error {
+	out := csv.NewWriter(w)
+	out.WriteAll(c)
+	return out.Error()
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"answerflow/modules/currency"
+	"answerflow/numparse"
+)
+
+// /ha/sensor/{pair} answers in the shape Home Assistant's REST sensor reads:
+// the effective rate as state, the route behind it as attributes. A sensor
+// for the USD/RUB rate a card holder actually gets:
+//
+//	sensor:
+//	  - platform: rest
+//	    resource: http://answerflow:8080/ha/sensor/usd-rub
+//	    value_template: "{{ value_json.state }}"
+//	    json_attributes: [attributes]
+//
+// The rate is priced for HA_SENSOR_AMOUNT units of the first currency, or
+// ?amount=, so fixed fees don't dominate it.
+var haSensorAmount = getEnvInt("HA_SENSOR_AMOUNT", 1000)
+
+var haPairPattern = regexp.MustCompile(`^([A-Za-z0-9]{2,10})[-_/]([A-Za-z0-9]{2,10})$`)
+
+func handleHASensor(w http.ResponseWriter, r *http.Request) {
+	matches := haPairPattern.FindStringSubmatch(strings.TrimPrefix(r.URL.Path, "/ha/sensor/"))
+	if matches == nil {
+		http.Error(w, "pair must look like usd-rub", http.StatusNotFound)
+		return
+	}
+	amount := float64(haSensorAmount)
+	if value := r.URL.Query().Get("amount"); value != "" {
+		var err error
+		if amount, err = strconv.ParseFloat(numparse.Normalize(value), 64); err != nil {
+			http.Error(w, "invalid amount", http.StatusBadRequest)
+			return
+		}
+	}
+
+	route, err := currencyModule.Explain(amount, matches[1], matches[2], globalAPICache)
+	if err != nil {
+		http.Error(w, currency.TranslateError(err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	legs := make([]string, 0, len(route.Legs))
+	fees := make([]string, 0, len(route.Legs))
+	for _, leg := range route.Legs {
+		legs = append(legs, leg.Description)
+		fees = append(fees, fmt.Sprintf("%s→%s: %s", leg.From, leg.To, leg.Fee))
+	}
+	writeJSON(w, map[string]interface{}{
+		// Home Assistant keeps states as strings of at most 255 characters
+		"state": strconv.FormatFloat(roundSignificant(route.EffectiveRate, 6), 'f', -1, 64),
+		"attributes": map[string]interface{}{
+			"friendly_name":       fmt.Sprintf("%s/%s effective rate", route.From, route.To),
+			"unit_of_measurement": route.To,
+			"from":                route.From,
+			"to":                  route.To,
+			"amount":              route.Amount,
+			"result":              route.Result,
+			"providers":           route.Providers,
+			"route":               legs,
+			"fees":                fees,
+			"staleness_seconds":   math.Round(route.StalenessSeconds),
+			"quoted_at":           route.QuotedAt,
+			"valid_until":         route.ValidUntil,
+		},
+	})
+}
+
+// roundSignificant rounds v to digits significant figures.
+func roundSignificant(v float64, digits int) float64 {
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(v, 'g', digits, 64), 64)
+	return rounded
+}
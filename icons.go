@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Module icons. ICONS overrides a module's default icon, as comma-separated
+// Module=source entries keyed by module name:
+//
+//	ICONS=CurrencyConverter=embedded:currency.png,Help=/opt/icons/help.png
+//
+// A source is one of
+//
+//	embedded:NAME     an icon shipped in the binary (icons/), extracted to
+//	                  ICON_DIR so the launcher can read it
+//	http(s)://...     a remote icon; with ICON_CACHE it is downloaded once
+//	                  into ICON_DIR and served from there, so it works offline
+//	anything else     a local file, "file://" optional, made absolute
+//
+// An icon that can't be resolved is logged and the module keeps its
+// built-in default.
+var (
+	iconOverrides = loadIconOverrides(getEnv("ICONS", ""))
+	iconDir       = getEnv("ICON_DIR", "data/icons")
+	iconCache     = getEnvBool("ICON_CACHE", false)
+)
+
+//go:embed icons
+var embeddedIcons embed.FS
+
+const iconDownloadTimeout = 10 * time.Second
+
+// maxIconSize bounds downloads; launcher icons are a few kilobytes.
+const maxIconSize = 1 << 20
+
+// loadIconOverrides reads ICONS into sources by module name.
+func loadIconOverrides(value string) map[string]string {
+	overrides := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, source, ok := strings.Cut(entry, "=")
+		name, source = strings.TrimSpace(name), strings.TrimSpace(source)
+		if !ok || name == "" || source == "" {
+			log.Printf("Warning: ICONS entry %q is not Module=source; ignored", entry)
+			continue
+		}
+		overrides[name] = source
+	}
+	return overrides
+}
+
+// moduleIcon returns the icon for the named module: its ICONS entry when one
+// is set and resolves, fallback otherwise. fallback itself is resolved too,
+// so built-in defaults and external modules' icons get the same treatment.
+func moduleIcon(name, fallback string) string {
+	if source, ok := iconOverrides[name]; ok {
+		icon, err := resolveIcon(source)
+		if err == nil {
+			return icon
+		}
+		log.Printf("Warning: icon for %s: %v; using the default", name, err)
+	}
+	if fallback == "" {
+		return ""
+	}
+	icon, err := resolveIcon(fallback)
+	if err != nil {
+		log.Printf("Warning: icon for %s: %v", name, err)
+		return fallback
+	}
+	return icon
+}
+
+// resolveIcon turns an icon source into what the launcher is given: a URL
+// or an absolute local path.
+func resolveIcon(source string) (string, error) {
+	switch {
+	case strings.HasPrefix(source, "embedded:"):
+		return extractEmbeddedIcon(strings.TrimPrefix(source, "embedded:"))
+	case strings.HasPrefix(source, "http://"), strings.HasPrefix(source, "https://"):
+		if !iconCache {
+			return source, nil
+		}
+		local, err := cachedRemoteIcon(source)
+		if err != nil {
+			// Still usable while online
+			log.Printf("Warning: icon %s not cached: %v", source, err)
+			return source, nil
+		}
+		return local, nil
+	default:
+		local, err := filepath.Abs(strings.TrimPrefix(source, "file://"))
+		if err != nil {
+			return "", err
+		}
+		if _, err := os.Stat(local); err != nil {
+			return "", err
+		}
+		return local, nil
+	}
+}
+
+// extractEmbeddedIcon writes the embedded icon name to ICON_DIR, unless an
+// identical copy is already there, and returns its absolute path.
+func extractEmbeddedIcon(name string) (string, error) {
+	data, err := embeddedIcons.ReadFile(path.Join("icons", path.Clean("/" + name)[1:]))
+	if err != nil {
+		return "", fmt.Errorf("no embedded icon %q", name)
+	}
+	target := filepath.Join(iconDir, filepath.Base(name))
+	if existing, err := os.ReadFile(target); err != nil || !bytes.Equal(existing, data) {
+		if err := writeIcon(target, data); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Abs(target)
+}
+
+// cachedRemoteIcon returns the local copy of the icon at url, downloading it
+// the first time. Copies are named by a hash of the URL, so changing the URL
+// fetches the new icon.
+func cachedRemoteIcon(url string) (string, error) {
+	sum := sha256.Sum256([]byte(url))
+	ext := path.Ext(path.Base(strings.SplitN(url, "?", 2)[0]))
+	if len(ext) > 5 {
+		ext = ""
+	}
+	target := filepath.Join(iconDir, "remote-"+hex.EncodeToString(sum[:8])+ext)
+	if _, err := os.Stat(target); err == nil {
+		return filepath.Abs(target)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), iconDownloadTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download returned %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxIconSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxIconSize {
+		return "", fmt.Errorf("icon larger than %d bytes", maxIconSize)
+	}
+	if err := writeIcon(target, data); err != nil {
+		return "", err
+	}
+	return filepath.Abs(target)
+}
+
+// writeIcon writes data to target through a temporary file, so a launcher
+// reading the icon never sees half of it.
+func writeIcon(target string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	tmp := target + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// IDLE_EXIT_AFTER stops the service after this long without queries, saving
+// the rate cache first. Meant for systemd socket activation, where the next
+// query starts it again. Zero keeps it running.
+var idleExitAfter = getEnvDuration("IDLE_EXIT_AFTER", 0)
+
+// lastActivity is the unix nano time of the most recent query.
+var lastActivity atomic.Int64
+
+func init() {
+	lastActivity.Store(time.Now().UnixNano())
+}
+
+// trackActivity records every request except health checks, which a
+// monitor sends regardless of anyone using the launcher.
+func trackActivity(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			lastActivity.Store(time.Now().UnixNano())
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func idleFor() time.Duration {
+	return time.Since(time.Unix(0, lastActivity.Load()))
+}
+
+// exitWhenIdle shuts server down once no query arrived for after, then runs
+// cleanup. The returned channel is closed when both are done.
+func exitWhenIdle(server *http.Server, after time.Duration, cleanup func()) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		check := min(max(after/4, time.Second), time.Minute)
+		for {
+			time.Sleep(check)
+			if idleFor() >= after {
+				break
+			}
+		}
+		log.Printf("No queries for %v, shutting down", after)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			log.Printf("Warning: graceful shutdown failed: %v", err)
+		}
+		cleanup()
+	}()
+	return done
+}
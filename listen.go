@@ -0,0 +1,152 @@
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// listenConfig is where the HTTP receiver accepts connections. Flags override
+// the LISTEN_ADDR, PORT, LISTEN_SOCKET, SOCKET_MODE, TLS_CERT_FILE and
+// TLS_KEY_FILE environment variables.
+type listenConfig struct {
+	host       string // empty listens on all interfaces
+	port       int
+	socket     string // Unix socket path; replaces the TCP listener when set
+	socketMode os.FileMode
+	certFile   string
+	keyFile    string
+}
+
+func parseListenConfig(args []string) (listenConfig, error) {
+	var cfg listenConfig
+	fs := flag.NewFlagSet("answerflow", flag.ContinueOnError)
+	fs.StringVar(&cfg.host, "listen", getEnv("LISTEN_ADDR", ""), "bind address, e.g. 127.0.0.1 (default all interfaces)")
+	fs.IntVar(&cfg.port, "port", getEnvInt("PORT", 8080), "TCP port")
+	fs.StringVar(&cfg.socket, "socket", getEnv("LISTEN_SOCKET", ""), "listen on this Unix socket instead of TCP")
+	socketMode := fs.String("socket-mode", getEnv("SOCKET_MODE", "0660"), "permissions of the Unix socket (octal)")
+	fs.StringVar(&cfg.certFile, "tls-cert", getEnv("TLS_CERT_FILE", ""), "TLS certificate (PEM); serves HTTPS together with -tls-key")
+	fs.StringVar(&cfg.keyFile, "tls-key", getEnv("TLS_KEY_FILE", ""), "TLS private key (PEM)")
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+	if fs.NArg() > 0 {
+		return cfg, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	mode, err := strconv.ParseUint(*socketMode, 8, 32)
+	if err != nil || mode > 0777 {
+		return cfg, fmt.Errorf("invalid socket mode %q", *socketMode)
+	}
+	cfg.socketMode = os.FileMode(mode)
+	if cfg.port < 0 || cfg.port > 65535 {
+		return cfg, fmt.Errorf("invalid port %d", cfg.port)
+	}
+	if (cfg.certFile == "") != (cfg.keyFile == "") {
+		return cfg, fmt.Errorf("TLS needs both a certificate and a key")
+	}
+	return cfg, nil
+}
+
+func (c listenConfig) addr() string {
+	if c.socket != "" {
+		return "unix:" + c.socket
+	}
+	return net.JoinHostPort(c.host, strconv.Itoa(c.port))
+}
+
+func (c listenConfig) tls() bool {
+	return c.certFile != ""
+}
+
+// serve runs server on the configured address until it is shut down. A
+// socket passed by systemd takes precedence over the configured address.
+func (c listenConfig) serve(server *http.Server) error {
+	listener, err := systemdListener()
+	if err != nil {
+		return err
+	}
+	if listener != nil {
+		return c.serveOn(server, listener, "systemd socket "+listener.Addr().String())
+	}
+	if c.socket != "" {
+		return c.serveSocket(server)
+	}
+	server.Addr = c.addr()
+	if !c.tls() {
+		if ip := net.ParseIP(c.host); ip == nil || !ip.IsLoopback() {
+			log.Printf("Warning: serving plain HTTP on %s; set TLS_CERT_FILE and TLS_KEY_FILE before exposing it beyond localhost", server.Addr)
+		}
+		log.Printf("Flow HTTP Receiver listening on %s at path /", server.Addr)
+		return server.ListenAndServe()
+	}
+	server.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	log.Printf("Flow HTTPS Receiver listening on %s at path /", server.Addr)
+	return server.ListenAndServeTLS(c.certFile, c.keyFile)
+}
+
+// serveSocket serves on a Unix socket, so local launchers can reach the
+// receiver without any network port. Access is controlled by the socket's
+// file permissions. A socket left behind by a previous run is replaced.
+func (c listenConfig) serveSocket(server *http.Server) error {
+	if info, err := os.Lstat(c.socket); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return fmt.Errorf("%s exists and is not a socket", c.socket)
+		}
+		if err := os.Remove(c.socket); err != nil {
+			return fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+	listener, err := net.Listen("unix", c.socket)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	if err := os.Chmod(c.socket, c.socketMode); err != nil {
+		return fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+	return c.serveOn(server, listener, c.addr())
+}
+
+func (c listenConfig) serveOn(server *http.Server, listener net.Listener, name string) error {
+	if c.tls() {
+		server.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		log.Printf("Flow HTTPS Receiver listening on %s at path /", name)
+		return server.ServeTLS(listener, c.certFile, c.keyFile)
+	}
+	log.Printf("Flow HTTP Receiver listening on %s at path /", name)
+	return server.Serve(listener)
+}
+
+// systemdListener returns the socket handed over by systemd socket
+// activation (LISTEN_PID/LISTEN_FDS), or nil when started without one. The
+// unit's .socket file then owns the address, port and permissions.
+func systemdListener() (net.Listener, error) {
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("invalid LISTEN_FDS=%q", os.Getenv("LISTEN_FDS"))
+	}
+	if n > 1 {
+		log.Printf("Warning: systemd passed %d sockets, serving only the first", n)
+	}
+	// The sockets are ours; child processes must not pick them up
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	const listenFdsStart = 3 // SD_LISTEN_FDS_START
+	f := os.NewFile(listenFdsStart, "systemd-socket")
+	defer f.Close()
+	listener, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("systemd socket: %w", err)
+	}
+	return listener, nil
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"answerflow/commontypes"
+)
+
+// localeCurrencies maps a locale (language-REGION, or a bare language when
+// it implies one currency) to its home currency.
+var localeCurrencies = map[string]string{
+	"en-gb": "GBP", "en-us": "USD", "en-au": "AUD", "en-ca": "CAD", "en-nz": "NZD", "en-in": "INR",
+	"ja": "JPY", "zh": "CNY", "zh-cn": "CNY", "zh-tw": "TWD", "zh-hk": "HKD", "ko": "KRW",
+	"ru": "RUB", "uk": "UAH", "kk": "KZT", "be": "BYN", "pl": "PLN", "cs": "CZK", "tr": "TRY",
+	"de": "EUR", "fr": "EUR", "it": "EUR", "es": "EUR", "nl": "EUR", "fi": "EUR", "pt": "EUR",
+	"pt-br": "BRL", "es-mx": "MXN", "de-ch": "CHF", "fr-ch": "CHF", "sv": "SEK", "nb": "NOK", "da": "DKK",
+}
+
+// numberFormat is how a locale writes 1,234.5: its thousands separator and
+// decimal mark.
+type numberFormat struct {
+	thousand, decimal string
+}
+
+// localeNumberFormats maps a locale, keyed like localeCurrencies, to its
+// number format. Locales not listed write numbers the way results already
+// do, 1,234.5.
+var localeNumberFormats = map[string]numberFormat{
+	"de": {".", ","}, "it": {".", ","}, "es": {".", ","}, "nl": {".", ","}, "pt": {".", ","},
+	"tr": {".", ","}, "da": {".", ","}, "id": {".", ","},
+	"fr": {" ", ","}, "ru": {" ", ","}, "uk": {" ", ","}, "kk": {" ", ","},
+	"be": {" ", ","}, "pl": {" ", ","}, "cs": {" ", ","}, "sv": {" ", ","},
+	"nb": {" ", ","}, "fi": {" ", ","},
+	"de-ch": {"’", "."}, "fr-ch": {"’", "."}, "es-mx": {",", "."},
+}
+
+// normalizeLocale turns "en_GB" into "en-gb".
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// lookupLocale returns the entry of table for locale, trying language-REGION
+// before the bare language.
+func lookupLocale[V any](table map[string]V, locale string) (V, bool) {
+	locale = normalizeLocale(locale)
+	if v, ok := table[locale]; ok {
+		return v, true
+	}
+	language, _, _ := strings.Cut(locale, "-")
+	v, ok := table[language]
+	return v, ok
+}
+
+// acceptedLocales returns the locales of an Accept-Language header, most
+// preferred first, leaving out "*" and anything refused with q=0.
+func acceptedLocales(header string) []string {
+	type weighted struct {
+		locale string
+		q      float64
+	}
+	var locales []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = normalizeLocale(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > 0 {
+			locales = append(locales, weighted{tag, q})
+		}
+	}
+	sort.SliceStable(locales, func(i, j int) bool { return locales[i].q > locales[j].q })
+	out := make([]string, len(locales))
+	for i, l := range locales {
+		out[i] = l.locale
+	}
+	return out
+}
+
+// requestLocales are the locales to take defaults from, most specific
+// first: ?locale=, then the client's profile. Only requests without a
+// client ID fall back to Accept-Language: a client with a profile has said
+// what it wants there.
+func requestLocales(r *http.Request) []string {
+	if locale := r.URL.Query().Get("locale"); locale != "" {
+		return []string{locale}
+	}
+	if clientID(r) != "" {
+		if locale := requestProfile(r).Locale; locale != "" {
+			return []string{locale}
+		}
+		return nil
+	}
+	return acceptedLocales(r.Header.Get("Accept-Language"))
+}
+
+// homeCurrencyFor reads the requester's home currency from ?home=GBP or
+// ?locale=en-GB, then from the client's profile, then from Accept-Language.
+// It returns "" when none is given, leaving the module's configured default
+// in place.
+func homeCurrencyFor(r *http.Request) string {
+	if home := strings.TrimSpace(r.URL.Query().Get("home")); home != "" {
+		return strings.ToUpper(home)
+	}
+	if r.URL.Query().Get("locale") == "" {
+		if home := requestProfile(r).Home; home != "" {
+			return home
+		}
+	}
+	for _, locale := range requestLocales(r) {
+		if home := localeHomeCurrency(locale); home != "" {
+			return home
+		}
+	}
+	return ""
+}
+
+// languageFor returns the language results should be written in: ?lang=ru,
+// else the language of the first request locale, or "".
+func languageFor(r *http.Request) string {
+	if lang := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("lang"))); lang != "" {
+		return lang
+	}
+	if locales := requestLocales(r); len(locales) > 0 {
+		language, _, _ := strings.Cut(normalizeLocale(locales[0]), "-")
+		return language
+	}
+	return ""
+}
+
+// numberFormatFor returns the number format of the first request locale.
+// A first locale with the default format keeps it rather than falling
+// through to a less preferred one.
+func numberFormatFor(r *http.Request) (numberFormat, bool) {
+	locales := requestLocales(r)
+	if len(locales) == 0 {
+		return numberFormat{}, false
+	}
+	return lookupLocale(localeNumberFormats, locales[0])
+}
+
+// localeHomeCurrency maps a locale such as "en_GB" to its currency, or "".
+func localeHomeCurrency(locale string) string {
+	code, _ := lookupLocale(localeCurrencies, locale)
+	return code
+}
+
+type numberFormatContextKey struct{}
+
+func withNumberFormat(ctx context.Context, f numberFormat) context.Context {
+	return context.WithValue(ctx, numberFormatContextKey{}, f)
+}
+
+func numberFormatFromContext(ctx context.Context) (numberFormat, bool) {
+	f, ok := ctx.Value(numberFormatContextKey{}).(numberFormat)
+	return f, ok
+}
+
+// displayNumberPattern matches the numbers results display: grouped
+// thousands with an optional fraction, or a plain decimal fraction.
+var displayNumberPattern = regexp.MustCompile(`\d{1,3}(?:,\d{3})+(?:\.\d+)?|\d+\.\d+`)
+
+// localizeNumbers rewrites the numbers in result titles and subtitles to
+// format f. Clipboard values stay machine-readable. results is copied, not
+// modified: coalesced queries share it.
+func localizeNumbers(results []commontypes.FlowResult, f numberFormat) []commontypes.FlowResult {
+	out := make([]commontypes.FlowResult, len(results))
+	for i, res := range results {
+		res.Title = localizeText(res.Title, f)
+		res.SubTitle = localizeText(res.SubTitle, f)
+		out[i] = res
+	}
+	return out
+}
+
+func localizeText(s string, f numberFormat) string {
+	matches := displayNumberPattern.FindAllStringIndex(s, -1)
+	if len(matches) == 0 {
+		return s
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		// Skip versions, dates and identifiers such as "v1.2" or "1.2.3"
+		if (start > 0 && isNumberNeighbour(s[start-1])) || (end < len(s) && isNumberNeighbour(s[end]) && !sentenceEnd(s, end)) {
+			continue
+		}
+		b.WriteString(s[last:start])
+		for _, c := range s[start:end] {
+			switch c {
+			case ',':
+				b.WriteString(f.thousand)
+			case '.':
+				b.WriteString(f.decimal)
+			default:
+				b.WriteRune(c)
+			}
+		}
+		last = end
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+func isNumberNeighbour(c byte) bool {
+	return c == '.' || c == '_' || (c >= 'a' && c <= 'z' && c != 'e') || (c >= 'A' && c <= 'Z' && c != 'E')
+}
+
+// sentenceEnd reports whether the '.' at s[i] ends a sentence rather than
+// continuing a number.
+func sentenceEnd(s string, i int) bool {
+	return s[i] == '.' && (i+1 == len(s) || s[i+1] < '0' || s[i+1] > '9')
+}
@@ -2,36 +2,187 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/subtle"
+	"flag"
 	"log"
 	"net/http"
-	"sort"
+	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"answerflow/commontypes"
+	"answerflow/events"
 	"answerflow/modules"
 	"answerflow/modules/calculator"
 	"answerflow/modules/currency"
+	"answerflow/modules/external"
+	"answerflow/modules/help"
+	"answerflow/modules/loan"
+	"answerflow/modules/payment"
+	"answerflow/modules/transfer"
+	"answerflow/modules/vat"
+	"answerflow/notify"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
-	httpPort             = ":8080"
 	requestTimeout       = 20 * time.Second // Increased from 5s to accommodate API calls
 	defaultModuleIcon    = "https://img.icons8.com/badges/100/decision.png"
 	currencyModuleIcon   = "https://img.icons8.com/badges/100/euro-exchange.png"
 	calculatorModuleIcon = "https://img.icons8.com/badges/100/calculator.png"
+
+	// Upper bound on concurrent ProcessQuery calls per module, so a burst of
+	// keystrokes can't pile up unbounded goroutines and provider calls.
+	defaultModuleConcurrency = 8
 )
 
 var (
+	adminToken        = os.Getenv("ADMIN_TOKEN")
+	quickTargets      = loadQuickTargets(getEnv("QUICK_TARGETS", "EUR"))
 	registeredModules []modules.Module
-	globalAPICache    *currency.APICache
+	moduleSemaphores  = make(map[string]chan struct{})
+	// Modules each module waits for, from modules.DependencyAware
+	moduleDependencies = make(map[string][]string)
+	globalAPICache     *currency.APICache
+	currencyModule     *currency.CurrencyConverterModule
 )
 
+// loadQuickTargets reads QUICK_TARGETS, the currencies every conversion is
+// also shown in besides RUB and USD, which the currency module adds itself.
+func loadQuickTargets(value string) []string {
+	var codes []string
+	for _, code := range strings.Split(value, ",") {
+		if code = strings.ToUpper(strings.TrimSpace(code)); code != "" {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+func registerModule(m modules.Module) {
+	limit := defaultModuleConcurrency
+	if l, ok := m.(modules.ConcurrencyLimiter); ok && l.MaxConcurrency() > 0 {
+		limit = l.MaxConcurrency()
+	}
+	if s, ok := m.(modules.ScoringAware); ok {
+		s.SetScoringStrategy(scoringStrategy)
+	}
+	if d, ok := m.(modules.DependencyAware); ok {
+		for _, dep := range d.DependsOn() {
+			if _, registered := moduleSemaphores[dep]; !registered {
+				log.Printf("Warning: module %s depends on %s, which is not registered before it; not waiting for it", m.Name(), dep)
+				continue
+			}
+			moduleDependencies[m.Name()] = append(moduleDependencies[m.Name()], dep)
+		}
+	}
+	moduleSemaphores[m.Name()] = make(chan struct{}, limit)
+	moduleHealthByName[m.Name()] = &moduleHealth{}
+	registeredModules = append(registeredModules, m)
+}
+
+// enabledModules returns the registered modules not switched off.
+func enabledModules() []modules.Module {
+	var enabled []modules.Module
+	for _, m := range registeredModules {
+		if switches.Enabled(m.Name()) {
+			enabled = append(enabled, m)
+		}
+	}
+	return enabled
+}
+
+// registerModules sets up the module pipeline shared by all frontends.
+func registerModules() {
+	currencyModuleInstance := currency.NewCurrencyConverterModule(
+		quickTargets,
+		"USD", // Base conversion currency
+		moduleIcon("CurrencyConverter", currencyModuleIcon),
+		true, // ShortDisplayFormat
+	)
+	registerModule(currencyModuleInstance)
+	currencyModule = currencyModuleInstance
+
+	calculatorModuleInstance := calculator.NewCalculatorModule(moduleIcon("Calculator", calculatorModuleIcon))
+	registerModule(calculatorModuleInstance)
+
+	registerModule(payment.NewValidatorModule(moduleIcon("PaymentValidator", defaultModuleIcon)))
+	registerModule(vat.NewVATModule(currencyModuleInstance, moduleIcon("VAT", defaultModuleIcon)))
+	registerModule(loan.NewLoanModule(moduleIcon("Loan", calculatorModuleIcon)))
+	registerModule(help.NewHelpModule(enabledModules, moduleIcon("Help", defaultModuleIcon)))
+
+	// Comparison rows against transfer services, off by default: they cost
+	// an outbound request per distinct fiat conversion
+	if getEnvBool("TRANSFER_COMPARISON", false) {
+		if providers := transfer.ProvidersFromEnv(); len(providers) > 0 {
+			registerModule(transfer.NewComparisonModule(currencyModuleInstance, providers, moduleIcon("TransferComparison", currencyModuleIcon)))
+		}
+	}
+
+	if path := getEnv("EXTERNAL_MODULES_FILE", ""); path != "" {
+		registerExternalModules(path)
+	}
+}
+
+// registerExternalModules adds the modules declared in path. A bad file
+// only costs the external modules, never the built-in ones.
+func registerExternalModules(path string) {
+	configs, err := external.LoadConfig(path)
+	if err != nil {
+		log.Printf("Warning: external modules not loaded: %v", err)
+		return
+	}
+	for _, cfg := range configs {
+		if _, taken := moduleSemaphores[cfg.Name]; taken {
+			log.Printf("Warning: external module %s skipped: name already in use", cfg.Name)
+			continue
+		}
+		cfg.Icon = moduleIcon(cfg.Name, cfg.Icon)
+		m, err := external.New(cfg)
+		if err != nil {
+			log.Printf("Warning: external module %s skipped: %v", cfg.Name, err)
+			continue
+		}
+		registerModule(m)
+	}
+	log.Printf("Loaded %d external modules from %s", len(configs), path)
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "query":
+			os.Exit(runQueryCommand(os.Args[2:]))
+		case "doctor":
+			os.Exit(runDoctorCommand(os.Args[2:]))
+		case "soak":
+			os.Exit(runSoakCommand(os.Args[2:]))
+		}
+	}
+
+	if err := validateConfig(); err != nil {
+		log.Fatalf("Invalid configuration (run `answerflow doctor` for details): %v", err)
+	}
+
+	listen, err := parseListenConfig(os.Args[1:])
+	if err != nil {
+		if err == flag.ErrHelp {
+			os.Exit(0)
+		}
+		log.Fatalf("Invalid listen configuration: %v", err)
+	}
+
+	shutdownTracing := initTracing()
+	defer shutdownTracing(context.Background())
+
 	globalAPICache = currency.NewAPICache()
 	log.Println("Performing initial fetch of currency data...")
-	if err := globalAPICache.InitialFetch(); err != nil {
+	if err := globalAPICache.WarmUp(); err != nil {
 		log.Fatalf("Failed to perform initial data fetch: %v", err)
 	}
 	log.Println("Initial data fetch complete.")
@@ -39,33 +190,74 @@ func main() {
 	// Initialize tradeable pairs immediately after initial fetch
 	globalAPICache.InitializeTradeablePairs()
 
+	notifier := notify.FromEnv()
+	currency.SetNotifier(notifier)
 	globalAPICache.StartBackgroundUpdaters()
+	globalAPICache.AddJanitorTask("shares", shares.compact)
 
-	currencyModuleInstance := currency.NewCurrencyConverterModule(
-		[]string{"EUR"}, // Quick conversion targets (EUR only, RUB/USD handled specially)
-		"USD",           // Base conversion currency
-		currencyModuleIcon,
-		true, // ShortDisplayFormat
-	)
-	registeredModules = append(registeredModules, currencyModuleInstance)
+	registerModules()
+	loadModuleSwitches()
+	loadProfiles()
 
-	calculatorModuleInstance := calculator.NewCalculatorModule(calculatorModuleIcon)
-	registeredModules = append(registeredModules, calculatorModuleInstance)
+	// Query endpoints and the Telegram bot share one in-flight budget
+	limit := newInFlightLimiter()
+	if token := os.Getenv("TELEGRAM_BOT_TOKEN"); token != "" {
+		go newTelegramBot(token, limit).Run()
+	}
+	startSummaryScheduler(notifier)
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/", handleQuery)
+	mux.HandleFunc("/", limit.wrap(handleQuery))
+	mux.HandleFunc("/alfred", limit.wrap(handleAlfredQuery))
+	mux.HandleFunc("/explain", limit.wrap(handleExplain))
+	mux.HandleFunc("/parse", limit.wrap(handleParse))
+	mux.HandleFunc("/share", limit.wrap(handleShare))
+	mux.HandleFunc("/rates/rub", limit.wrap(handleRUBRates))
+	mux.HandleFunc("/ha/sensor/", limit.wrap(handleHASensor))
+	mux.HandleFunc("/r/", handleSharedQuote)
+	mux.HandleFunc("/health", handleHealth)
+	mux.HandleFunc("/version", handleVersion)
+	mux.HandleFunc("/suggest", handleSuggest)
+	mux.HandleFunc("/selected", handleSelected)
+	mux.HandleFunc("/profile", handleProfile)
+	mux.Handle("/ui/", uiHandler())
+	if adminToken != "" {
+		mux.HandleFunc("/admin/quarantine", requireAdmin(handleQuarantine))
+		mux.HandleFunc("/admin/wirelog", requireAdmin(handleWireLog))
+		mux.HandleFunc("/admin/modules", requireAdmin(handleModules))
+		mux.HandleFunc("/admin/parser", requireAdmin(handleParseStats))
+		mux.HandleFunc("/admin/aliases", requireAdmin(handleAliasPacks))
+		mux.HandleFunc("/admin/calibration", requireAdmin(handleFeeCalibration))
+		mux.HandleFunc("/admin/dns", requireAdmin(handleDNSStats))
+		mux.HandleFunc("/admin/connections", requireAdmin(handleConnectionStats))
+		mux.HandleFunc("/admin/profiles", requireAdmin(handleProfiles))
+		mux.HandleFunc("/admin/userdata", requireAdmin(handleUserData))
+		mux.HandleFunc("/metrics", requireAdmin(handleMetrics))
+		if currency.FaultInjectionEnabled() {
+			mux.HandleFunc("/admin/faults", requireAdmin(handleFaults))
+			log.Printf("Warning: fault injection is enabled (/admin/faults)")
+		}
+	} else {
+		log.Println("ADMIN_TOKEN not set, admin API disabled")
+	}
 
 	server := &http.Server{
-		Addr:         httpPort,
-		Handler:      mux,
+		Handler:      trackActivity(mux),
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  120 * time.Second,
 	}
 
-	log.Printf("Flow HTTP Receiver listening on port %s at path /", httpPort)
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("Could not listen on %s: %v\n", httpPort, err)
+	var idleDone <-chan struct{}
+	if idleExitAfter > 0 {
+		idleDone = exitWhenIdle(server, idleExitAfter, globalAPICache.Shutdown)
+	}
+
+	if err := listen.serve(server); err != nil && err != http.ErrServerClosed {
+		log.Fatalf("Could not listen on %s: %v\n", listen.addr(), err)
+	}
+	if idleDone != nil {
+		<-idleDone
 	}
 }
 
@@ -75,39 +267,265 @@ func handleQuery(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "flow"
+	}
+	serialize, ok := resultSerializers[format]
+	if !ok {
+		http.Error(w, "unknown format", http.StatusBadRequest)
+		return
+	}
+	serveResults(w, r, serialize)
+}
+
+// isAdminRequest reports whether r carries the admin bearer token.
+func isAdminRequest(r *http.Request) bool {
+	return adminToken != "" &&
+		subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+adminToken)) == 1
+}
+
+// handleAlfredQuery serves /alfred?q= in Alfred Script Filter format.
+func handleAlfredQuery(w http.ResponseWriter, r *http.Request) {
+	serveResults(w, r, toAlfredOutput)
+}
+
+func serveResults(w http.ResponseWriter, r *http.Request, serialize func([]commontypes.FlowResult) interface{}) {
 	query := r.URL.Query().Get("q")
 
-	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	// ?debug=1 adds a diagnostics object; it exposes internals, so admins only
+	var diag *commontypes.Diagnostics
+	if r.URL.Query().Get("debug") == "1" {
+		if !isAdminRequest(r) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		diag = &commontypes.Diagnostics{}
+	}
+
+	ctx, span := tracer.Start(r.Context(), "query", trace.WithAttributes(attribute.String("query", query)))
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
 	defer cancel()
+	if diag != nil {
+		ctx = commontypes.WithDiagnostics(ctx, diag)
+	}
+	if id := clientID(r); id != "" {
+		ctx = withClientID(ctx, id)
+	}
+	// Without explicit parameters or a profile, Accept-Language picks the
+	// home currency, result language and number format
+	w.Header().Add("Vary", "Accept-Language")
+	if home := homeCurrencyFor(r); home != "" {
+		ctx = commontypes.WithHomeCurrency(ctx, home)
+	}
+	if lang := languageFor(r); lang != "" {
+		ctx = commontypes.WithLanguage(ctx, lang)
+	}
+	if format, ok := numberFormatFor(r); ok {
+		ctx = withNumberFormat(ctx, format)
+	}
+	// ?reference=1 adds mid-market reference results, ?reference=0 hides them
+	if reference, err := strconv.ParseBool(r.URL.Query().Get("reference")); err == nil {
+		ctx = commontypes.WithReferenceRates(ctx, reference)
+	}
+	// ?fee=1.5 deducts the user's own bank markup (percent) from conversions;
+	// ?fee=0 drops the one from the client's profile
+	profile := requestProfile(r)
+	if fee, err := strconv.ParseFloat(r.URL.Query().Get("fee"), 64); err == nil {
+		if fee > 0 {
+			ctx = commontypes.WithPersonalFee(ctx, fee)
+		}
+	} else if profile.Fee > 0 {
+		ctx = commontypes.WithPersonalFee(ctx, profile.Fee)
+	}
+	if len(profile.Favorites) > 0 {
+		ctx = commontypes.WithFavoriteCurrencies(ctx, profile.Favorites)
+	}
+	if len(profile.Watchlist) > 0 {
+		ctx = commontypes.WithWatchlist(ctx, profile.Watchlist)
+	}
+	// ?usdt_network=trc20 prices moving USDT between that network and the exchange
+	if network := r.URL.Query().Get("usdt_network"); network != "" {
+		ctx = commontypes.WithUSDTNetwork(ctx, network)
+	}
+	// ?modules=currency,calculator runs only those modules
+	if value := r.URL.Query().Get("modules"); value != "" {
+		selected, err := parseModuleSelection(value)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ctx = withModuleSelection(ctx, selected)
+	}
 
-	var allResults []commontypes.FlowResult
+	// ?stream=1 answers twice over server-sent events: from cache first, then refined
+	if r.URL.Query().Get("stream") == "1" && diag == nil {
+		streamResults(w, ctx, query, serialize)
+		return
+	}
+	// ?fast=1 answers from cache only; approximate results are badged for a follow-up query
+	if fast, _ := strconv.ParseBool(r.URL.Query().Get("fast")); fast {
+		ctx = commontypes.WithFastPath(ctx)
+	}
+
+	allResults := queryResults(ctx, query)
+
+	if diag != nil {
+		writeJSON(w, map[string]interface{}{
+			"results":     serialize(allResults),
+			"diagnostics": diag,
+		})
+		return
+	}
+	writeResults(w, serialize(allResults))
+}
+
+// writeResults writes serialized results as JSON, or as CSV for ?format=csv.
+func writeResults(w http.ResponseWriter, v interface{}) {
+	table, ok := v.(csvOutput)
+	if !ok {
+		writeJSON(w, v)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	if err := table.write(w); err != nil {
+		log.Printf("Error writing CSV response: %v", err)
+	}
+}
+
+// queryResults runs query through the module pipeline and always returns a
+// non-nil list, with a hint item when nothing matched.
+func queryResults(ctx context.Context, query string) []commontypes.FlowResult {
+	allResults, served := runQuery(ctx, query)
+	events.Publish(served)
+	return allResults
+}
+
+// runQuery is queryResults without publishing the QueryServed event, which
+// it returns instead, for callers that answer a query in several passes to
+// publish once, for the final one.
+func runQuery(ctx context.Context, query string) ([]commontypes.FlowResult, events.Event) {
+	start := time.Now()
+	allResults := runModulesCoalesced(ctx, query)
+	served := events.Event{Kind: events.QueryServed, Query: query, Client: clientIDFromContext(ctx), Results: len(allResults), Duration: time.Since(start)}
+	// Copy-all takes the clipboard values, which stay machine-readable;
+	// only its displayed summary is localized with the rest
+	allResults = withCopyAll(allResults)
+	if format, ok := numberFormatFromContext(ctx); ok {
+		allResults = localizeNumbers(allResults, format)
+	}
+
+	if len(allResults) == 0 && query != "" {
+		if item, ok := noResultsItem(query); ok {
+			allResults = append(allResults, item)
+		}
+	}
+	if allResults == nil {
+		allResults = []commontypes.FlowResult{}
+	}
+	return allResults, served
+}
+
+// runModules fans query out to all registered modules and returns their
+// combined results sorted by score. Serving frontends go through
+// queryResults instead, which adds coalescing and query events on top.
+func runModules(ctx context.Context, query string) []commontypes.FlowResult {
+	// Results per module in registration order, so the outcome doesn't
+	// depend on which module finished first
+	perModule := make([][]commontypes.FlowResult, len(registeredModules))
 	var mu sync.Mutex
 	var wg sync.WaitGroup
 
-	for _, mod := range registeredModules {
+	ctx = commontypes.WithBlackboard(ctx, commontypes.NewBlackboard())
+	// Closed when a module is done, for the modules depending on it
+	finished := make(map[string]chan struct{}, len(registeredModules))
+	for _, m := range registeredModules {
+		finished[m.Name()] = make(chan struct{})
+	}
+
+	for i, mod := range registeredModules {
 		wg.Add(1)
-		go func(m modules.Module) {
+		go func(i int, m modules.Module) {
 			defer wg.Done()
-			moduleCtx := ctx
+			defer close(finished[m.Name()])
+			moduleCtx, span := tracer.Start(ctx, "module", trace.WithAttributes(attribute.String("module", m.Name())))
+			defer span.End()
+			diag := commontypes.DiagnosticsFromContext(ctx)
+			if !moduleSelected(ctx, m.Name()) {
+				return
+			}
+			health := moduleHealthByName[m.Name()]
+			if !health.Enabled() || !switches.Enabled(m.Name()) {
+				diag.AddModule(commontypes.ModuleTiming{Module: m.Name(), Disabled: true})
+				return
+			}
+
+			for _, dep := range moduleDependencies[m.Name()] {
+				select {
+				case <-finished[dep]:
+				case <-moduleCtx.Done():
+					log.Printf("Module '%s' skipped for query '%s': timed out waiting for %s", m.Name(), query, dep)
+					return
+				}
+			}
+
+			select {
+			case moduleSemaphores[m.Name()] <- struct{}{}:
+				defer func() { <-moduleSemaphores[m.Name()] }()
+			case <-moduleCtx.Done():
+				log.Printf("Module '%s' skipped for query '%s': timed out waiting for a free slot", m.Name(), query)
+				return
+			}
 
+			start := time.Now()
 			results, err := m.ProcessQuery(moduleCtx, query, globalAPICache)
+			timing := commontypes.ModuleTiming{
+				Module:  m.Name(),
+				Millis:  float64(time.Since(start).Microseconds()) / 1000,
+				Results: len(results),
+			}
+			if err != nil {
+				timing.Error = err.Error()
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			diag.AddModule(timing)
+			// A client that went away (next keystroke) says nothing about the module
+			switch timedOut := moduleCtx.Err() == context.DeadlineExceeded; {
+			case moduleCtx.Err() == context.Canceled:
+			case err != nil || timedOut:
+				health.RecordFailure(m.Name(), timedOut)
+			default:
+				health.RecordSuccess()
+			}
 			if err != nil {
 				log.Printf("Module '%s' failed for query '%s': %v", m.Name(), query, err)
 				return
 			}
 
+			group := ""
+			if g, ok := m.(modules.ResultGrouper); ok {
+				group = g.ResultGroup()
+			}
 			mu.Lock()
 			for _, res := range results {
+				if res.Group == "" {
+					res.Group = group
+				}
 				if res.IcoPath == "" {
 					res.IcoPath = m.DefaultIconPath()
 				}
 				if res.IcoPath == "" {
 					res.IcoPath = defaultModuleIcon
 				}
-				allResults = append(allResults, res)
+				decorateResult(ResultContext{Query: query, Module: m.Name()}, &res)
+				sanitizeResult(m.Name(), &res)
+				perModule[i] = append(perModule[i], res)
 			}
 			mu.Unlock()
-		}(mod)
+		}(i, mod)
 	}
 
 	waitChan := make(chan struct{})
@@ -122,28 +540,19 @@ func handleQuery(w http.ResponseWriter, r *http.Request) {
 		log.Printf("Request processing timed out or was canceled for query: '%s', error: %v", query, ctx.Err())
 	}
 
-	sort.SliceStable(allResults, func(i, j int) bool {
-		return allResults[i].Score > allResults[j].Score
-	})
-
-	if len(allResults) == 0 && query != "" {
-		noResultsItem := commontypes.FlowResult{
-			Title:    "No results found",
-			SubTitle: "Please try a different query.",
-			IcoPath:  defaultModuleIcon,
-			Score:    0,
-			JsonRPCAction: commontypes.JsonRPCAction{
-				Method:     "Flow.Launcher.ChangeQuery",
-				Parameters: []interface{}{query, false},
-			},
+	// Modules still running after a timeout keep appending; hand back a snapshot
+	var allResults []commontypes.FlowResult
+	var origins []string // module name per result, for cross-module dedupe
+	mu.Lock()
+	for i, results := range perModule {
+		for _, res := range results {
+			allResults = append(allResults, res)
+			origins = append(origins, registeredModules[i].Name())
 		}
-		allResults = append(allResults, noResultsItem)
-	} else if len(allResults) == 0 && query == "" {
-		allResults = []commontypes.FlowResult{}
 	}
+	mu.Unlock()
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(allResults); err != nil {
-		log.Printf("Error encoding JSON response: %v", err)
-	}
+	snapshot, origins := dedupeResults(allResults, origins)
+	sortResults(ctx, snapshot, origins)
+	return snapshot
 }
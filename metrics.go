@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"answerflow/events"
+	"answerflow/modules/currency"
+)
+
+// eventCounts tallies bus events for the metrics: provider updates and
+// outages by provider, queries served and results selected.
+var eventCounts = struct {
+	sync.Mutex
+	updates, downs    map[string]int
+	queries, selected int
+	querySeconds      float64
+}{updates: make(map[string]int), downs: make(map[string]int)}
+
+func init() {
+	events.Subscribe(events.RateUpdated, func(e events.Event) {
+		eventCounts.Lock()
+		eventCounts.updates[e.Provider]++
+		eventCounts.Unlock()
+	})
+	events.Subscribe(events.ProviderDown, func(e events.Event) {
+		eventCounts.Lock()
+		eventCounts.downs[e.Provider]++
+		eventCounts.Unlock()
+	})
+	events.Subscribe(events.QueryServed, func(e events.Event) {
+		eventCounts.Lock()
+		eventCounts.queries++
+		eventCounts.querySeconds += e.Duration.Seconds()
+		eventCounts.Unlock()
+	})
+	events.Subscribe(events.ResultSelected, func(e events.Event) {
+		eventCounts.Lock()
+		eventCounts.selected++
+		eventCounts.Unlock()
+	})
+}
+
+// handleMetrics serves provider quota usage, cache hit/miss counts, parser
+// statistics and event counts in the Prometheus text format.
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	quota := currency.QuotaReport()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	gauge := func(name, help string, value func(currency.QuotaUsage) float64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		for _, q := range quota {
+			fmt.Fprintf(w, "%s{provider=%q} %g\n", name, q.Provider, value(q))
+		}
+	}
+	fmt.Fprintf(w, "# HELP answerflow_provider_requests_total Requests sent to the provider.\n# TYPE answerflow_provider_requests_total counter\n")
+	for _, q := range quota {
+		fmt.Fprintf(w, "answerflow_provider_requests_total{provider=%q} %d\n", q.Provider, q.Total)
+	}
+	gauge("answerflow_provider_requests_last_minute", "Requests sent to the provider in the last minute.",
+		func(q currency.QuotaUsage) float64 { return q.LastMinute })
+	gauge("answerflow_provider_requests_last_hour", "Requests sent to the provider in the last hour.",
+		func(q currency.QuotaUsage) float64 { return float64(q.LastHour) })
+	gauge("answerflow_provider_rate_limit_per_minute", "Configured provider rate limit per minute.",
+		func(q currency.QuotaUsage) float64 { return q.LimitPerMinute })
+	gauge("answerflow_provider_rate_limit_burst", "Configured provider rate limit burst.",
+		func(q currency.QuotaUsage) float64 { return float64(q.Burst) })
+	gauge("answerflow_provider_quota_utilization", "Last minute's requests over the per-minute limit.",
+		func(q currency.QuotaUsage) float64 { return q.Utilization })
+
+	access := currency.CacheAccessReport()
+	fmt.Fprintf(w, "# HELP answerflow_cache_hits_total Lookups answered from the in-memory cache.\n# TYPE answerflow_cache_hits_total counter\n")
+	for _, a := range access {
+		fmt.Fprintf(w, "answerflow_cache_hits_total{accessor=%q} %d\n", a.Accessor, a.Hits)
+	}
+	fmt.Fprintf(w, "# HELP answerflow_cache_misses_total Lookups the in-memory cache could not answer.\n# TYPE answerflow_cache_misses_total counter\n")
+	for _, a := range access {
+		fmt.Fprintf(w, "answerflow_cache_misses_total{accessor=%q} %d\n", a.Accessor, a.Misses)
+	}
+
+	parse := currencyModule.ParseStats()
+	fmt.Fprintf(w, "# HELP answerflow_parse_cache_hits_total Queries parsed from the parse cache.\n# TYPE answerflow_parse_cache_hits_total counter\nanswerflow_parse_cache_hits_total %d\n", parse.CacheHits)
+	fmt.Fprintf(w, "# HELP answerflow_parse_cache_misses_total Queries the parser had to parse.\n# TYPE answerflow_parse_cache_misses_total counter\nanswerflow_parse_cache_misses_total %d\n", parse.CacheMisses)
+	fmt.Fprintf(w, "# HELP answerflow_parse_cache_entries Queries held in the parse cache.\n# TYPE answerflow_parse_cache_entries gauge\nanswerflow_parse_cache_entries %d\n", parse.CacheEntries)
+	patterns := make([]string, 0, len(parse.Patterns))
+	for p := range parse.Patterns {
+		patterns = append(patterns, p)
+	}
+	sort.Strings(patterns)
+	fmt.Fprintf(w, "# HELP answerflow_parse_pattern_matches_total Queries parsed by each query pattern.\n# TYPE answerflow_parse_pattern_matches_total counter\n")
+	for _, p := range patterns {
+		fmt.Fprintf(w, "answerflow_parse_pattern_matches_total{pattern=%q} %d\n", p, parse.Patterns[p])
+	}
+
+	eventCounts.Lock()
+	defer eventCounts.Unlock()
+	perProvider := func(name, help string, counts map[string]int) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+		providers := make([]string, 0, len(counts))
+		for p := range counts {
+			providers = append(providers, p)
+		}
+		sort.Strings(providers)
+		for _, p := range providers {
+			fmt.Fprintf(w, "%s{provider=%q} %d\n", name, p, counts[p])
+		}
+	}
+	perProvider("answerflow_provider_updates_total", "Successful background rate updates.", eventCounts.updates)
+	perProvider("answerflow_provider_outages_total", "Times the provider was declared down after repeated failures.", eventCounts.downs)
+	fmt.Fprintf(w, "# HELP answerflow_queries_served_total Queries answered.\n# TYPE answerflow_queries_served_total counter\nanswerflow_queries_served_total %d\n", eventCounts.queries)
+	fmt.Fprintf(w, "# HELP answerflow_query_seconds_total Time spent answering queries.\n# TYPE answerflow_query_seconds_total counter\nanswerflow_query_seconds_total %g\n", eventCounts.querySeconds)
+	fmt.Fprintf(w, "# HELP answerflow_results_selected_total Results clients reported as picked.\n# TYPE answerflow_results_selected_total counter\nanswerflow_results_selected_total %d\n", eventCounts.selected)
+}
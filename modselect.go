@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"answerflow/modules"
+)
+
+// ?modules=currency,calculator runs only the named modules for one request,
+// so an integrator that wants conversions doesn't wait on the others. A
+// module that depends on an unselected one runs without its entries.
+
+type moduleSelectionContextKey struct{}
+
+// parseModuleSelection resolves a comma-separated list of selectors to
+// module names. A selector matches a module's name or result group, case
+// insensitively, or the start of its name ("currency" for
+// CurrencyConverter). Every selector must match a registered module.
+func parseModuleSelection(value string) (map[string]bool, error) {
+	selected := make(map[string]bool)
+	for _, selector := range strings.Split(value, ",") {
+		selector = strings.ToLower(strings.TrimSpace(selector))
+		if selector == "" {
+			continue
+		}
+		matched := false
+		for _, m := range registeredModules {
+			if moduleMatches(m, selector) {
+				selected[m.Name()] = true
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("no module matches %q (modules: %s)", selector, strings.Join(moduleNames(), ", "))
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("empty module selection")
+	}
+	return selected, nil
+}
+
+func moduleMatches(m modules.Module, selector string) bool {
+	name := strings.ToLower(m.Name())
+	if strings.HasPrefix(name, selector) {
+		return true
+	}
+	g, ok := m.(modules.ResultGrouper)
+	return ok && strings.ToLower(g.ResultGroup()) == selector
+}
+
+func moduleNames() []string {
+	names := make([]string, 0, len(registeredModules))
+	for _, m := range registeredModules {
+		names = append(names, m.Name())
+	}
+	return names
+}
+
+func withModuleSelection(ctx context.Context, selected map[string]bool) context.Context {
+	return context.WithValue(ctx, moduleSelectionContextKey{}, selected)
+}
+
+// moduleSelected reports whether the request in ctx runs the named module;
+// all modules run when it made no selection.
+func moduleSelected(ctx context.Context, name string) bool {
+	selected, ok := ctx.Value(moduleSelectionContextKey{}).(map[string]bool)
+	return !ok || selected[name]
+}
+
+// moduleSelectionString is the selection in ctx in a canonical form, empty
+// when all modules run.
+func moduleSelectionString(ctx context.Context) string {
+	selected, _ := ctx.Value(moduleSelectionContextKey{}).(map[string]bool)
+	names := make([]string, 0, len(selected))
+	for name := range selected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"answerflow/modules/currency"
+)
+
+const (
+	moduleFailureThreshold = 5                // consecutive failures before disabling
+	moduleCooldown         = 30 * time.Second // how long a disabled module is skipped
+)
+
+// moduleHealth tracks a module's error and timeout history and disables it
+// after moduleFailureThreshold consecutive failures, so a broken provider
+// can't add its timeout to every keystroke. It re-enables after moduleCooldown.
+type moduleHealth struct {
+	mu                  sync.Mutex
+	requests            int64
+	errors              int64
+	timeouts            int64
+	consecutiveFailures int
+	disabledUntil       time.Time
+}
+
+type moduleHealthSnapshot struct {
+	State               string    `json:"state"`
+	Requests            int64     `json:"requests"`
+	Errors              int64     `json:"errors"`
+	Timeouts            int64     `json:"timeouts"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	DisabledUntil       time.Time `json:"disabled_until,omitempty"`
+}
+
+var moduleHealthByName = make(map[string]*moduleHealth)
+
+func (h *moduleHealth) Enabled() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return time.Now().After(h.disabledUntil)
+}
+
+func (h *moduleHealth) RecordSuccess() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.requests++
+	h.consecutiveFailures = 0
+}
+
+// RecordFailure counts a failed request; timeout distinguishes requests that
+// ran past the deadline from ones that returned an error.
+func (h *moduleHealth) RecordFailure(name string, timeout bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.requests++
+	if timeout {
+		h.timeouts++
+	} else {
+		h.errors++
+	}
+	h.consecutiveFailures++
+	if h.consecutiveFailures >= moduleFailureThreshold && time.Now().After(h.disabledUntil) {
+		h.disabledUntil = time.Now().Add(moduleCooldown)
+		log.Printf("Warning: module '%s' disabled for %v after %d consecutive failures", name, moduleCooldown, h.consecutiveFailures)
+	}
+}
+
+func (h *moduleHealth) Snapshot() moduleHealthSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := moduleHealthSnapshot{
+		State:               "enabled",
+		Requests:            h.requests,
+		Errors:              h.errors,
+		Timeouts:            h.timeouts,
+		ConsecutiveFailures: h.consecutiveFailures,
+	}
+	if time.Now().Before(h.disabledUntil) {
+		s.State = "disabled"
+		s.DisabledUntil = h.disabledUntil
+	}
+	return s
+}
+
+// handleHealth reports per-module health and conversion SLOs. It returns
+// 503 when every module is disabled or switched off, and status "degraded"
+// while a conversion SLO is breached or Whitebird reports its RUB operations
+// disabled.
+func handleHealth(w http.ResponseWriter, r *http.Request) {
+	status := "ok"
+	modulesHealth := make(map[string]moduleHealthSnapshot, len(registeredModules))
+	enabled := 0
+	for _, m := range registeredModules {
+		snap := moduleHealthByName[m.Name()].Snapshot()
+		if !switches.Enabled(m.Name()) {
+			snap.State = "off"
+		}
+		if snap.State == "enabled" {
+			enabled++
+		}
+		modulesHealth[m.Name()] = snap
+	}
+	slo, breached := currency.SLOReport()
+	if breached {
+		status = "degraded"
+	}
+	whitebird, probed := globalAPICache.WhitebirdOperation()
+	if probed && !whitebird.Enabled {
+		// The RUB bridge is down, the rest still converts
+		status = "degraded"
+	}
+	if enabled == 0 {
+		status = "unavailable"
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	body := map[string]interface{}{
+		"status":  status,
+		"modules": modulesHealth,
+		"slo":     slo,
+		"quota":   currency.QuotaReport(),
+	}
+	if probed {
+		body["whitebird"] = whitebird
+	}
+	writeJSON(w, body)
+}
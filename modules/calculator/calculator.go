@@ -11,7 +11,8 @@ import (
 	"answerflow/commontypes"
 	"answerflow/modules/currency"
 
-	"github.com/expr-lang/expr"
+	"answerflow/numparse"
+	"answerflow/safeexpr"
 )
 
 const calculatorScore = 75
@@ -19,6 +20,7 @@ const calculatorScore = 75
 type CalculatorModule struct {
 	iconPath string
 	mathEnv  map[string]interface{}
+	commontypes.Scoring
 }
 
 func NewCalculatorModule(iconPath string) *CalculatorModule {
@@ -87,42 +89,49 @@ func (m *CalculatorModule) DefaultIconPath() string {
 	return m.iconPath
 }
 
+func (m *CalculatorModule) ResultGroup() string {
+	return "Calculator"
+}
+
+func (m *CalculatorModule) QueryHint() string {
+	return "(2 + 3) * 4"
+}
+
 var numberRegex = regexp.MustCompile(`[0-9]+(?:[0-9\s ,.]*[0-9])?`)
 
 func preprocessQuery(query string) string {
 	processed := strings.ReplaceAll(query, "%", "/100.0")
-	processed = numberRegex.ReplaceAllStringFunc(processed, currency.NormalizeNumberString)
+	processed = numberRegex.ReplaceAllStringFunc(processed, numparse.Normalize)
 	return processed
 }
 
 func (m *CalculatorModule) ProcessQuery(ctx context.Context, query string, apiCache *currency.APICache) ([]commontypes.FlowResult, error) {
 	trimmed := strings.TrimSpace(query)
-	if trimmed == "" {
+	if trimmed == "" || len(trimmed) > safeexpr.MaxLength {
 		return nil, nil
 	}
 
 	processed := preprocessQuery(trimmed)
 
-	program, err := expr.Compile(processed, expr.Env(m.mathEnv))
-	if err != nil {
-		return nil, nil
-	}
-
-	output, err := expr.Run(program, m.mathEnv)
+	output, err := safeexpr.Eval(processed, m.mathEnv)
 	if err != nil {
 		return nil, nil
 	}
 
 	var resultStr string
+	var copyVariants []commontypes.ContextMenuItem
 	switch v := output.(type) {
 	case float64:
 		resultStr = strconv.FormatFloat(v, 'f', 8, 64)
 		resultStr = strings.TrimRight(resultStr, "0")
 		resultStr = strings.TrimRight(resultStr, ".")
+		copyVariants = commontypes.CopyVariants(resultStr, v, processed)
 	case int:
 		resultStr = strconv.Itoa(v)
+		copyVariants = commontypes.CopyVariants(resultStr, float64(v), processed)
 	case int64:
 		resultStr = strconv.FormatInt(v, 10)
+		copyVariants = commontypes.CopyVariants(resultStr, float64(v), processed)
 	case bool:
 		resultStr = strconv.FormatBool(v)
 	default:
@@ -130,15 +139,15 @@ func (m *CalculatorModule) ProcessQuery(ctx context.Context, query string, apiCa
 	}
 
 	flowResult := commontypes.FlowResult{
-		Title:    resultStr,
-		SubTitle: fmt.Sprintf("Result for: %s", trimmed),
-		IcoPath:  m.DefaultIconPath(),
-		Score:    calculatorScore,
-		JsonRPCAction: commontypes.JsonRPCAction{
-			Method:     "copy_to_clipboard",
-			Parameters: []interface{}{resultStr},
-		},
+		Title:            resultStr,
+		SubTitle:         fmt.Sprintf("Result for: %s", trimmed),
+		IcoPath:          m.DefaultIconPath(),
+		Score:            calculatorScore,
+		JsonRPCAction:    commontypes.CopyToClipboard(resultStr),
+		ContextMenuItems: copyVariants,
 	}
 
-	return []commontypes.FlowResult{flowResult}, nil
+	results := []commontypes.FlowResult{flowResult}
+	m.ApplyScoring(m.Name(), results)
+	return results, nil
 }
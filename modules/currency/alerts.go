@@ -0,0 +1,61 @@
+package currency
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"answerflow/events"
+	"answerflow/notify"
+)
+
+// Repeats of the same alert within this window are dropped, so a provider
+// that stays down or stale notifies once an hour rather than per query.
+const alertCooldown = time.Hour
+
+var (
+	alertNotifier notify.Notifier
+	alertMu       sync.Mutex
+	alertLastSent = make(map[string]time.Time)
+)
+
+// SetNotifier routes provider alerts (repeated update failures, critically
+// stale data, recoveries) to n. Alerts are only logged until it is set.
+func SetNotifier(n notify.Notifier) {
+	alertMu.Lock()
+	defer alertMu.Unlock()
+	alertNotifier = n
+}
+
+// Provider outages and recoveries come from the update loops as events.
+func init() {
+	events.Subscribe(events.ProviderDown, func(e events.Event) {
+		clearAlert("recovered:" + e.Provider)
+		alert("down:"+e.Provider, notify.LevelCritical, fmt.Sprintf("%s updates failing", e.Provider),
+			fmt.Sprintf("%s update failed %d consecutive times: %v", e.Provider, e.Fails, e.Err))
+	})
+	events.Subscribe(events.ProviderRecovered, func(e events.Event) {
+		clearAlert("down:" + e.Provider)
+		alert("recovered:"+e.Provider, notify.LevelInfo, fmt.Sprintf("%s recovered", e.Provider),
+			fmt.Sprintf("%s updates succeed again", e.Provider))
+	})
+}
+
+// alert sends a notification unless one with the same key went out within
+// alertCooldown.
+func alert(key string, level notify.Level, subject, text string) {
+	alertMu.Lock()
+	defer alertMu.Unlock()
+	if alertNotifier == nil || time.Since(alertLastSent[key]) < alertCooldown {
+		return
+	}
+	alertLastSent[key] = time.Now()
+	notify.Send(alertNotifier, notify.Message{Subject: subject, Text: text, Level: level})
+}
+
+// clearAlert lets the next alert with key through immediately.
+func clearAlert(key string) {
+	alertMu.Lock()
+	defer alertMu.Unlock()
+	delete(alertLastSent, key)
+}
@@ -0,0 +1,173 @@
+package currency
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ALIAS_PACKS_DIR names a directory of extra currency names and symbols,
+// for languages the embedded tables don't cover. Every *.json file in it is
+// a pack in the embedded tables' format, both parts optional:
+//
+//	{"aliases": {"türkische lira": "TRY"}, "symbols": {"₺": "TRY"}}
+//
+// The embedded tables take precedence, so a pack can add names but not
+// redirect "$" or "euro"; between packs, files later in name order win
+// ("10-de.json" before "20-tr.json"). Packs are read at startup and again
+// by ReloadAliasPacks.
+var aliasPacksDir = getEnvOrDefault("ALIAS_PACKS_DIR", "")
+
+type aliasPack struct {
+	Aliases map[string]string `json:"aliases"`
+	Symbols map[string]string `json:"symbols"`
+}
+
+// AliasPackStats describes the packs last loaded.
+type AliasPackStats struct {
+	Dir     string   `json:"dir"`
+	Files   []string `json:"files"`
+	Aliases int      `json:"aliases"` // entries added, shadowed ones not counted
+	Symbols int      `json:"symbols"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// packEntries are the table entries the loaded packs added, so a reload can
+// take them out again.
+type packEntries struct {
+	symbols    map[string]string
+	aliases    map[string]string
+	validCodes map[string]string
+	stats      AliasPackStats
+}
+
+// readAliasPacks reads the packs in dir, later files overriding earlier
+// ones. A file that can't be read or parsed is skipped and reported.
+func readAliasPacks(dir string) (aliasPack, AliasPackStats) {
+	merged := aliasPack{Aliases: make(map[string]string), Symbols: make(map[string]string)}
+	stats := AliasPackStats{Dir: dir}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		stats.Errors = append(stats.Errors, err.Error())
+		return merged, stats
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			stats.Errors = append(stats.Errors, err.Error())
+			continue
+		}
+		var pack aliasPack
+		if err := json.Unmarshal(data, &pack); err != nil {
+			stats.Errors = append(stats.Errors, fmt.Sprintf("%s: %v", filepath.Base(path), err))
+			continue
+		}
+		for alias, code := range pack.Aliases {
+			merged.Aliases[strings.ToLower(strings.TrimSpace(alias))] = strings.ToUpper(strings.TrimSpace(code))
+		}
+		for symbol, code := range pack.Symbols {
+			merged.Symbols[strings.TrimSpace(symbol)] = strings.ToUpper(strings.TrimSpace(code))
+		}
+		stats.Files = append(stats.Files, filepath.Base(path))
+	}
+	return merged, stats
+}
+
+// applyAliasPacksLocked replaces the entries of the previously loaded packs
+// with those of pack and rebuilds the query patterns around the new
+// symbols. Entries the embedded tables or the currency list already define
+// are left alone. cd.mu must be held.
+func (cd *CurrencyData) applyAliasPacksLocked(pack aliasPack, stats AliasPackStats) {
+	// Only entries still holding the pack's value are the pack's to remove
+	for symbol, code := range cd.packs.symbols {
+		if cd.symbols[symbol] == code {
+			delete(cd.symbols, symbol)
+		}
+	}
+	for alias, code := range cd.packs.aliases {
+		if cd.nameAliases[alias] == code {
+			delete(cd.nameAliases, alias)
+		}
+	}
+	for key, code := range cd.packs.validCodes {
+		if cd.validCodes[key] == code {
+			delete(cd.validCodes, key)
+		}
+	}
+
+	added := packEntries{
+		symbols:    make(map[string]string),
+		aliases:    make(map[string]string),
+		validCodes: make(map[string]string),
+	}
+	addCode := func(code string) {
+		key := strings.ToLower(code)
+		if _, ok := cd.validCodes[key]; !ok {
+			cd.validCodes[key] = code
+			added.validCodes[key] = code
+		}
+	}
+	for symbol, code := range pack.Symbols {
+		if symbol == "" || (fiatOnlyMode && !isFiatCode(code)) {
+			continue
+		}
+		if _, ok := cd.symbols[symbol]; ok {
+			continue
+		}
+		cd.symbols[symbol] = code
+		added.symbols[symbol] = code
+		addCode(code)
+	}
+	for alias, code := range pack.Aliases {
+		if isAliasTooShort(alias) || (fiatOnlyMode && !isFiatCode(code)) {
+			continue
+		}
+		if _, ok := cd.nameAliases[alias]; ok {
+			continue
+		}
+		cd.nameAliases[alias] = code
+		added.aliases[alias] = code
+		addCode(code)
+	}
+	stats.Symbols, stats.Aliases = len(added.symbols), len(added.aliases)
+	added.stats = stats
+	cd.packs = added
+
+	cd.rebuildPatternsLocked()
+	cd.parseCache.clear()
+	cd.rebuildCompletionsLocked()
+}
+
+// loadAliasPacks reads ALIAS_PACKS_DIR into the tables.
+func (cd *CurrencyData) loadAliasPacks() AliasPackStats {
+	pack, stats := readAliasPacks(aliasPacksDir)
+	cd.mu.Lock()
+	cd.applyAliasPacksLocked(pack, stats)
+	cd.mu.Unlock()
+	for _, e := range stats.Errors {
+		log.Printf("Warning: alias pack: %s", e)
+	}
+	log.Printf("Loaded %d alias packs from %s: %d names, %d symbols", len(stats.Files), stats.Dir, stats.Aliases, stats.Symbols)
+	return stats
+}
+
+// ReloadAliasPacks reads ALIAS_PACKS_DIR again, replacing the names and
+// symbols the packs added before. It fails if no directory is configured.
+func (m *CurrencyConverterModule) ReloadAliasPacks() (AliasPackStats, error) {
+	if aliasPacksDir == "" {
+		return AliasPackStats{}, fmt.Errorf("ALIAS_PACKS_DIR is not set")
+	}
+	return m.currencyData.loadAliasPacks(), nil
+}
+
+// AliasPackStats reports the packs currently loaded.
+func (m *CurrencyConverterModule) AliasPackStats() AliasPackStats {
+	m.currencyData.mu.RLock()
+	defer m.currencyData.mu.RUnlock()
+	return m.currencyData.packs.stats
+}
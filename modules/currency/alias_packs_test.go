@@ -0,0 +1,65 @@
+package currency
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Reloading packs takes the write lock on the tables while queries read
+// them; a reader that locks twice would deadlock against the waiting writer.
+func TestReloadAliasPacksWhileParsing(t *testing.T) {
+	dir := t.TempDir()
+	pack := `{"aliases": {"türkische lira": "TRY"}, "symbols": {"₺": "TRY"}}`
+	if err := os.WriteFile(filepath.Join(dir, "20-tr.json"), []byte(pack), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	defer func(dir string) { aliasPacksDir = dir }(aliasPacksDir)
+	aliasPacksDir = dir
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	cd := NewCurrencyData()
+	readers := []func(){
+		func() { cd.ExtractSymbol("türkische lira", "100") },
+		func() { ParseQueryWithOptions("100 ₺ to usd", cd, ParseOptions{NoCache: true}) },
+		func() { cd.CompleteCurrency("tür", 5) },
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				cd.loadAliasPacks()
+			}
+		}()
+		for _, read := range readers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range 500 {
+					read()
+				}
+			}()
+		}
+		wg.Wait()
+	}()
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("reloading alias packs while parsing deadlocked")
+	}
+
+	req, err := ParseQuery("100 ₺ to usd", cd)
+	if err != nil || req.FromCurrency != "TRY" || req.ToCurrency != "USD" {
+		t.Errorf("ParseQuery after reloads = %+v, %v, want TRY to USD", req, err)
+	}
+}
@@ -3,15 +3,25 @@ package currency
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"strconv"
-	"sync"
+	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/singleflight"
 )
 
+var depthGroup singleflight.Group
+
+// fetchBybitRates refreshes best bid/ask and 24h volume of every supported
+// USDT spot pair from a single tickers request. Full order books are only
+// fetched when a conversion needs depth (see ensureBybitDepth).
 func (ac *APICache) fetchBybitRates() error {
 	if !bybitCircuit.CanAttempt() {
 		return fmt.Errorf("circuit breaker open")
@@ -21,101 +31,48 @@ func (ac *APICache) fetchBybitRates() error {
 	ctx, cancel := context.WithTimeout(context.Background(), bybitAPITimeout*3)
 	defer cancel()
 
-	// Fetch top 50 most popular pairs for immediate availability
-	// Remaining symbols are loaded lazily via EnsureBybitSymbol
-	// FIXED: Removed duplicate MATICUSDT, OPUSDT; replaced MATICUSDT with POLUSDT; removed invalid MKRUSDT
-	keyPairs := []string{
-		"TONUSDT", "BTCUSDT", "ETHUSDT", "SOLUSDT", "ADAUSDT", "DOGEUSDT",
-		"XRPUSDT", "DOTUSDT", "LINKUSDT", "UNIUSDT", "ATOMUSDT", "AVAXUSDT",
-		"NEARUSDT", "APTUSDT", "ARBUSDT", "OPUSDT", "POLUSDT", "LTCUSDT",
-		"BCHUSDT", "ETCUSDT", "FILUSDT", "TRXUSDT", "XLMUSDT", "SHIBUSDT",
-		"PEPEUSDT", "WIFUSDT", "BONKUSDT", "FLOKIUSDT", "INJUSDT", "SUIUSDT",
-		"RENDERUSDT", "ICPUSDT", "AAVEUSDT", "LDOUSDT",
-		"BNBUSDT", "ALGOUSDT", "SANDUSDT", "MANAUSDT", "AXSUSDT",
-		"GALAUSDT", "ENJUSDT", "CHZUSDT", "FLOWUSDT", "GRTUSDT", "BATUSDT",
-		"ZRXUSDT", "COMPUSDT",
-	}
-
-	fetchedRates := make(map[string]*BybitRate)
-	var mu sync.Mutex
-	var anySuccess bool
-	var failCount int
-
-	sem := make(chan struct{}, 5)
-	var wg sync.WaitGroup
-
-loop:
-	for _, symbol := range keyPairs {
-		select {
-		case <-ctx.Done():
-			log.Printf("Bybit fetch context cancelled")
-			if !anySuccess {
-				return ctx.Err()
-			}
-			break loop
-		default:
-		}
-
-		wg.Add(1)
-		go func(sym string) {
-			defer wg.Done()
-
-			select {
-			case sem <- struct{}{}:
-				defer func() { <-sem }()
-			case <-ctx.Done():
-				return
-			}
-
-			rate, err := ac.fetchBybitOrderbook(ctx, sym)
-			if err != nil {
-				mu.Lock()
-				failCount++
-				mu.Unlock()
-				log.Printf("Failed to fetch Bybit rate for %s: %v", sym, err)
-				return
-			}
-			mu.Lock()
-			fetchedRates[sym] = rate
-			anySuccess = true
-			mu.Unlock()
-		}(symbol)
-	}
-
-	wg.Wait()
-
-	log.Printf("Bybit fetch complete: %d successes, %d failures", len(fetchedRates), failCount)
-
-	if !anySuccess {
+	tickers, err := ac.fetchBybitTickers(ctx)
+	if err != nil {
 		bybitCircuit.RecordFailure()
-		return fmt.Errorf("no rates fetched (all %d attempts failed)", failCount)
+		return err
 	}
-
 	bybitCircuit.RecordSuccess()
 
+	volumes := make(map[string]float64, len(tickers))
+	stored := 0
+
 	ac.mu.Lock()
-	for key, rate := range fetchedRates {
+	for key, ticker := range tickers {
+		volumes[key] = ticker.Volume24h
+		cryptoCode := strings.TrimSuffix(key, "USDT")
+		if !ac.validCryptos[cryptoCode] {
+			continue
+		}
+		rate := ticker.rate
+		if previous, ok := ac.bybitRates[key]; ok && previous != nil {
+			candidate := QuarantinedRate{Provider: "bybit", Key: key, Previous: bybitMidPrice(previous), Proposed: bybitMidPrice(rate), bybitRate: rate}
+			if !ac.screenRateLocked(candidate, previous.LastUpdate, anomalyThresholdCrypto, refreshPolicies[assetCrypto].RefreshInterval*2) {
+				continue
+			}
+		}
 		ac.bybitRates[key] = rate
-		ac.lastBybitRates[key] = rate
 		ac.tradeablePairs[key] = true
-
-		if len(key) > 4 && key[len(key)-4:] == "USDT" {
-			cryptoCode := key[:len(key)-4]
-			ac.currencyMetadata[cryptoCode] = &CurrencyMetadata{
-				DecimalPlaces:      GetCurrencyDecimalPlaces(cryptoCode),
-				MinTradingAmount:   0.000001,
-				MaxTradingAmount:   1000000,
-				IsTradeableOnBybit: true,
-				LastVerified:       time.Now(),
-			}
+		ac.currencyMetadata[cryptoCode] = &CurrencyMetadata{
+			DecimalPlaces:      GetCurrencyDecimalPlaces(cryptoCode),
+			MinTradingAmount:   0.000001,
+			MaxTradingAmount:   1000000,
+			IsTradeableOnBybit: true,
+			LastVerified:       time.Now(),
 		}
+		stored++
 	}
+	ac.bybitVolumes = volumes
+	ac.bybitVolumesUpdate = time.Now()
 	ac.bybitLastUpdate = time.Now()
 	ac.pairsLastCheck = time.Now()
 	ac.mu.Unlock()
 
-	log.Printf("Bybit rates updated: %d pairs (remaining %d symbols available via lazy loading)",
-		len(fetchedRates), len(supportedCryptos)-len(fetchedRates))
+	log.Printf("Bybit rates updated: %d pairs from %d tickers in one request", stored, len(tickers))
 
 	// Save to file after successful fetch
 	ac.SaveToFileAsync()
@@ -123,8 +80,12 @@ loop:
 	return nil
 }
 
-func (ac *APICache) fetchBybitOrderbook(ctx context.Context, symbol string) (*BybitRate, error) {
-	if err := bybitLimiter.Wait(ctx); err != nil {
+// fetchBybitOrderbook fetches depth levels per side of symbol's order book.
+func (ac *APICache) fetchBybitOrderbook(ctx context.Context, symbol string, depth int) (_ *BybitRate, err error) {
+	ctx, span := tracer.Start(ctx, "bybit.orderbook", trace.WithAttributes(attribute.String("symbol", symbol), attribute.Int("depth", depth)))
+	defer func() { endSpan(span, err) }()
+
+	if err := bybitScheduler.Wait(ctx); err != nil {
 		return nil, err
 	}
 
@@ -134,14 +95,8 @@ func (ac *APICache) fetchBybitOrderbook(ctx context.Context, symbol string) (*By
 	default:
 	}
 
-	// Use limit=200 for spot, as required by spec to get deeper liquidity and realistic pricing
-	url := fmt.Sprintf("%s?category=spot&symbol=%s&limit=200", bybitOrderbookURL, symbol)
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := ac.client.Do(req)
+	url := fmt.Sprintf("%s?category=spot&symbol=%s&limit=%d", bybitOrderbookURL, symbol, depth)
+	resp, err := bybitEndpoints.do(ctx, ac.clientFor(providerBybit), url, newBybitRequest)
 	if err != nil {
 		return nil, err
 	}
@@ -215,88 +170,245 @@ func (ac *APICache) fetchBybitOrderbook(ctx context.Context, symbol string) (*By
 		OrderBookBids: orderBookBids,
 		OrderBookAsks: orderBookAsks,
 		LastUpdate:    time.Now(),
+		DepthUpdate:   time.Now(),
+		DepthLimit:    depth,
 	}, nil
 }
 
+// newBybitRequest builds a signed GET request for a Bybit market endpoint.
+func newBybitRequest(ctx context.Context, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	setProviderHeaders(req, providerBybit)
+	signBybitRequest(req)
+	return req, nil
+}
+
+type bybitTicker struct {
+	rate      *BybitRate // top of book only
+	Volume24h float64    // USDT turnover
+}
+
+// fetchBybitTickers loads top of book and 24h turnover of every USDT spot
+// pair; omitting the symbol makes Bybit return the whole batch.
+func (ac *APICache) fetchBybitTickers(ctx context.Context) (_ map[string]bybitTicker, err error) {
+	ctx, span := tracer.Start(ctx, "bybit.tickers")
+	defer func() { endSpan(span, err) }()
+
+	if err := bybitScheduler.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	resp, err := bybitEndpoints.do(ctx, ac.clientFor(providerBybit), bybitTickersURL+"?category=spot", newBybitRequest)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %s", resp.Status)
+	}
+
+	var result struct {
+		RetCode int `json:"retCode"`
+		Result  struct {
+			List []struct {
+				Symbol      string `json:"symbol"`
+				Bid1Price   string `json:"bid1Price"`
+				Bid1Size    string `json:"bid1Size"`
+				Ask1Price   string `json:"ask1Price"`
+				Ask1Size    string `json:"ask1Size"`
+				Turnover24h string `json:"turnover24h"`
+			} `json:"list"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxHTTPResponseSize)).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if result.RetCode != 0 {
+		return nil, fmt.Errorf("API returned error code: %d", result.RetCode)
+	}
+
+	now := time.Now()
+	tickers := make(map[string]bybitTicker, len(result.Result.List))
+	for _, t := range result.Result.List {
+		if !strings.HasSuffix(t.Symbol, "USDT") {
+			continue
+		}
+		var values [5]float64
+		valid := true
+		for i, s := range []string{t.Bid1Price, t.Bid1Size, t.Ask1Price, t.Ask1Size, t.Turnover24h} {
+			v, err := strconv.ParseFloat(s, 64)
+			if err != nil || !isValidFloat(v) || v < 0 {
+				valid = false
+				break
+			}
+			values[i] = v
+		}
+		// Pairs without a two-sided market (halted, pre-listing) are skipped
+		if !valid || values[0] <= 0 || values[2] <= 0 {
+			continue
+		}
+		tickers[t.Symbol] = bybitTicker{
+			rate: &BybitRate{
+				BestBid:       values[0],
+				BestAsk:       values[2],
+				OrderBookBids: [][]float64{{values[0], values[1]}},
+				OrderBookAsks: [][]float64{{values[2], values[3]}},
+				LastUpdate:    now,
+			},
+			Volume24h: values[4],
+		}
+	}
+	if len(tickers) == 0 {
+		return nil, fmt.Errorf("no USDT tickers in response")
+	}
+	return tickers, nil
+}
+
+// ensureBybitDepth replaces the cached book of symbol with one deep enough
+// to price a conversion worth usdValue (see orderbookDepthForUSD). Depth is
+// kept until the next tickers refresh replaces the rate, so each symbol costs
+// at most one request per depth and refresh interval. It reports false when
+// the depth could not be loaded; the conversion in ctx then prices at the
+// best level and is marked approximate.
+func (ac *APICache) ensureBybitDepth(ctx context.Context, symbol string, usdValue float64) bool {
+	depth := orderbookDepthForUSD(usdValue)
+	ac.mu.RLock()
+	rate, ok := ac.bybitRates[symbol]
+	ac.mu.RUnlock()
+	if !ok || rate == nil || (rate.DepthLimit >= depth && time.Since(rate.DepthUpdate) < refreshPolicies[assetCrypto].RefreshInterval) {
+		return true
+	}
+
+	err := callProvider(ctx, func() error {
+		// Concurrent conversions on the same symbol and depth share one request
+		_, err, _ := depthGroup.Do(fmt.Sprintf("%s/%d", symbol, depth), func() (interface{}, error) {
+			ctx, cancel := context.WithTimeout(withPriority(context.WithoutCancel(ctx), priorityInteractive), bybitAPITimeout)
+			defer cancel()
+			book, err := ac.fetchBybitOrderbook(ctx, symbol, depth)
+			if err != nil {
+				return nil, err
+			}
+			ac.mu.Lock()
+			ac.bybitRates[symbol] = book
+			ac.enforceOrderbookBudgetLocked(symbol)
+			ac.mu.Unlock()
+			return nil, nil
+		})
+		return err
+	})
+	if err != nil {
+		log.Printf("Warning: failed to fetch Bybit order book for %s, using top of book: %v", symbol, err)
+		markApproximate(ctx)
+		return false
+	}
+	return true
+}
+
 // EnsureBybitSymbol lazily fetches and caches a symbol's orderbook if it's not already known.
 // This allows supporting a large list of symbols (515+) without pre-fetching all of them.
 // Uses retry logic for resilience against transient network errors.
-func (ac *APICache) EnsureBybitSymbol(symbol string) error {
+func (ac *APICache) EnsureBybitSymbol(ctx context.Context, symbol string) error {
+	if !providerEnabled(providerBybit) {
+		return fmt.Errorf("bybit provider disabled")
+	}
+
 	// Fast path: check with read lock first
 	ac.mu.RLock()
-	if _, ok := ac.bybitRates[symbol]; ok {
-		ac.mu.RUnlock()
+	_, ok := ac.bybitRates[symbol]
+	ac.mu.RUnlock()
+	if ok {
 		return nil
 	}
-	// Check if already being fetched
-	if _, fetching := ac.symbolsFetching[symbol]; fetching {
-		ac.mu.RUnlock()
-		// Wait briefly and retry
-		time.Sleep(100 * time.Millisecond)
-		return ac.EnsureBybitSymbol(symbol)
-	}
-	ac.mu.RUnlock()
 
-	// Symbol not found, acquire write lock for fetching
 	ac.mu.Lock()
 	// Double-check inside write lock (another goroutine might have fetched it)
 	if _, ok := ac.bybitRates[symbol]; ok {
 		ac.mu.Unlock()
 		return nil
 	}
-	// Check again if being fetched
-	if _, fetching := ac.symbolsFetching[symbol]; fetching {
+	// Another request is already fetching it: wait for that fetch, but no
+	// longer than our own request allows
+	if fetch, fetching := ac.symbolsFetching[symbol]; fetching {
+		ac.mu.Unlock()
+		select {
+		case <-fetch.done:
+			return fetch.err
+		case <-ctx.Done():
+			return fmt.Errorf("symbol %s not loaded: %w", symbol, ctx.Err())
+		}
+	}
+	if err := ac.checkLazySymbolLocked(symbol); err != nil {
 		ac.mu.Unlock()
-		time.Sleep(100 * time.Millisecond)
-		return ac.EnsureBybitSymbol(symbol)
+		return err
 	}
-
-	// Check circuit breaker while holding lock
 	if !bybitCircuit.CanAttempt() {
 		ac.mu.Unlock()
 		return fmt.Errorf("bybit circuit breaker open")
 	}
-
-	// Mark symbol as being fetched
-	ac.symbolsFetching[symbol] = true
+	fetch := &symbolFetch{done: make(chan struct{})}
+	ac.symbolsFetching[symbol] = fetch
 	ac.mu.Unlock()
 
-	// Fetch without holding lock (use retry logic for resilience)
-	var rate *BybitRate
-	err := retryWithBackoff(context.Background(), func() error {
-		ctx, cancel := context.WithTimeout(context.Background(), bybitAPITimeout*2)
-		defer cancel()
+	fetch.err = ac.loadBybitSymbol(ctx, symbol)
 
-		r, e := ac.fetchBybitOrderbook(ctx, symbol)
-		if e != nil {
-			return e
-		}
-		rate = r
-		return nil
-	})
-
-	// Clean up fetching status and store result
 	ac.mu.Lock()
 	delete(ac.symbolsFetching, symbol)
+	ac.mu.Unlock()
+	close(fetch.done)
+
+	if fetch.err == nil {
+		log.Printf("Lazily loaded Bybit symbol: %s", symbol)
+		// Save to file after lazy loading new symbol
+		ac.SaveToFileAsync()
+	}
+	return fetch.err
+}
+
+// symbolFetch is an in-flight lazy symbol load; done is closed once err is
+// set and the rate, if any, is cached.
+type symbolFetch struct {
+	done chan struct{}
+	err  error
+}
+
+// loadBybitSymbol fetches symbol's order book (use retry logic for
+// resilience) and caches it.
+func (ac *APICache) loadBybitSymbol(ctx context.Context, symbol string) error {
+	var rate *BybitRate
+	err := callProvider(ctx, func() error {
+		return retryWithBackoff(context.Background(), func() error {
+			// Lazy loads are driven by a user's query, so they go ahead of background refreshes
+			ctx, cancel := context.WithTimeout(withPriority(context.WithoutCancel(ctx), priorityInteractive), bybitAPITimeout*2)
+			defer cancel()
+
+			r, e := ac.fetchBybitOrderbook(ctx, symbol, bybitShallowDepth)
+			if e != nil {
+				return e
+			}
+			rate = r
+			return nil
+		})
+	})
 
+	if errors.Is(err, errBudgetExhausted) {
+		return fmt.Errorf("symbol %s not loaded: %w", symbol, err)
+	}
 	if err != nil {
-		ac.mu.Unlock()
 		bybitCircuit.RecordFailure()
 		return fmt.Errorf("failed to fetch symbol %s: %w", symbol, err)
 	}
-
 	bybitCircuit.RecordSuccess()
+
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
 	ac.bybitRates[symbol] = rate
-	ac.lastBybitRates[symbol] = rate
+	ac.enforceOrderbookBudgetLocked(symbol)
 	ac.tradeablePairs[symbol] = true
 	ac.bybitLastUpdate = time.Now()
 	ac.pairsLastCheck = time.Now()
-	ac.mu.Unlock()
-
-	log.Printf("Lazily loaded Bybit symbol: %s", symbol)
-
-	// Save to file after lazy loading new symbol
-	ac.SaveToFileAsync()
-
 	return nil
 }
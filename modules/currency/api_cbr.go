@@ -0,0 +1,129 @@
+package currency
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The Bank of Russia sets an official RUB rate for ~40 currencies every
+// working day. It is never used to convert, only shown next to RUB results
+// (CBR_COMPARISON) to tell how far the route's effective rate is from it.
+
+type cbrValCurs struct {
+	Date    string `xml:"Date,attr"`
+	Valutes []struct {
+		CharCode string `xml:"CharCode"`
+		Nominal  string `xml:"Nominal"`
+		Value    string `xml:"Value"`
+	} `xml:"Valute"`
+}
+
+// asciiOnly stands in for a windows-1251 decoder: only the ASCII fields of
+// the daily file (codes and numbers) are read, so other bytes are blanked
+// rather than decoded.
+type asciiOnly struct{ r io.Reader }
+
+func (a asciiOnly) Read(p []byte) (int, error) {
+	n, err := a.r.Read(p)
+	for i := range p[:n] {
+		if p[i] >= 0x80 {
+			p[i] = '?'
+		}
+	}
+	return n, err
+}
+
+func (ac *APICache) fetchCBRRates() error {
+	ctx, cancel := context.WithTimeout(context.Background(), cbrAPITimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", cbrRatesURL, nil)
+	if err != nil {
+		return err
+	}
+	setProviderHeaders(req, providerCBR)
+
+	resp, err := ac.clientFor(providerCBR).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %s", resp.Status)
+	}
+
+	var curs cbrValCurs
+	decoder := xml.NewDecoder(io.LimitReader(resp.Body, maxHTTPResponseSize))
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return asciiOnly{input}, nil
+	}
+	if err := decoder.Decode(&curs); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	// Values are RUB per Nominal units, with a decimal comma
+	rates := make(map[string]float64, len(curs.Valutes))
+	for _, v := range curs.Valutes {
+		value, err := strconv.ParseFloat(strings.Replace(strings.TrimSpace(v.Value), ",", ".", 1), 64)
+		if err != nil || !isValidFloat(value) || value <= 0 {
+			continue
+		}
+		nominal, err := strconv.ParseFloat(strings.TrimSpace(v.Nominal), 64)
+		if err != nil || nominal <= 0 {
+			continue
+		}
+		rates[strings.ToUpper(strings.TrimSpace(v.CharCode))] = value / nominal
+	}
+	if _, ok := rates[CurrencyUSD]; !ok {
+		return fmt.Errorf("USD rate missing from CBR rates")
+	}
+
+	ac.mu.Lock()
+	ac.cbrRates = rates
+	ac.cbrDate = curs.Date
+	ac.cbrLastUpdate = time.Now()
+	ac.mu.Unlock()
+
+	log.Printf("CBR official rates updated: %d rates (date %s)", len(rates), curs.Date)
+	return nil
+}
+
+// cbrRate returns the official RUB price of one unit of code, if a recent
+// enough one is known.
+func (ac *APICache) cbrRate(code string) (float64, bool) {
+	ac.mu.RLock()
+	defer ac.mu.RUnlock()
+	if time.Since(ac.cbrLastUpdate) > cbrMaxAge {
+		return 0, false
+	}
+	rate, ok := ac.cbrRates[code]
+	return rate, ok
+}
+
+// cbrComparisonInfo compares a RUB conversion's effective rate (to per from)
+// with the official rate of its foreign side, for the result subtitle:
+// " | ЦБ: 92.5, наш маршрут: 97.1, +5.0%". It is empty when the comparison is
+// off or the pair has no official rate.
+func (ac *APICache) cbrComparisonInfo(from, to string, displayRate float64) string {
+	if !cbrComparison || displayRate <= 0 || (from == CurrencyRUB) == (to == CurrencyRUB) {
+		return ""
+	}
+	foreign, routeRate := from, displayRate
+	if from == CurrencyRUB {
+		foreign, routeRate = to, 1/displayRate
+	}
+	official, ok := ac.cbrRate(foreign)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf(" | ЦБ: %s, наш маршрут: %s, %+.1f%%",
+		formatRate(official), formatRate(routeRate), (routeRate/official-1)*100)
+}
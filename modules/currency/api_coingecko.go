@@ -0,0 +1,126 @@
+package currency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// CoinGecko index prices are the fallback for assets without a usable Bybit
+// USDT order book. They are aggregated spot prices, not something you can
+// execute against, so results built on them are labelled as such.
+
+const coingeckoProvider = "CoinGecko index"
+
+type indexPrice struct {
+	USD        float64
+	LastUpdate time.Time
+}
+
+func coingeckoBaseURL() string {
+	if credentials.coingeckoAPIKey != "" {
+		return coingeckoProAPIURL
+	}
+	return coingeckoAPIURL
+}
+
+func (ac *APICache) fetchCoinGeckoPrice(ctx context.Context, code string) (_ float64, err error) {
+	ctx, span := tracer.Start(ctx, "coingecko.price", trace.WithAttributes(attribute.String("code", code)))
+	defer func() { endSpan(span, err) }()
+
+	if err := coingeckoScheduler.Wait(ctx); err != nil {
+		return 0, err
+	}
+
+	params := url.Values{}
+	params.Set("symbols", strings.ToLower(code))
+	params.Set("vs_currencies", "usd")
+	req, err := http.NewRequestWithContext(ctx, "GET", coingeckoBaseURL()+"/simple/price?"+params.Encode(), nil)
+	if err != nil {
+		return 0, err
+	}
+	setProviderHeaders(req, providerCoinGecko)
+	if credentials.coingeckoAPIKey != "" {
+		req.Header.Set("x-cg-pro-api-key", string(credentials.coingeckoAPIKey))
+	}
+
+	resp, err := ac.clientFor(providerCoinGecko).Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("status %s", resp.Status)
+	}
+
+	var result map[string]struct {
+		USD float64 `json:"usd"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxHTTPResponseSize)).Decode(&result); err != nil {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	price, ok := result[strings.ToLower(code)]
+	if !ok || !isValidFloat(price.USD) || price.USD <= 0 {
+		return 0, fmt.Errorf("no index price for %s", code)
+	}
+	return price.USD, nil
+}
+
+// cachedIndexPrice returns a cached index price without fetching.
+func (ac *APICache) cachedIndexPrice(code string) (indexPrice, bool) {
+	ac.mu.RLock()
+	defer ac.mu.RUnlock()
+	p, ok := ac.indexPrices[code]
+	if !ok || time.Since(p.LastUpdate) > coingeckoPriceTTL {
+		return indexPrice{}, false
+	}
+	return p, true
+}
+
+// GetIndexPrice returns the CoinGecko USD price of code, fetching it if the
+// cached value is missing or older than coingeckoPriceTTL.
+func (ac *APICache) GetIndexPrice(ctx context.Context, code string) (float64, error) {
+	if !providerEnabled(providerCoinGecko) {
+		return 0, fmt.Errorf("coingecko provider disabled")
+	}
+	if p, ok := ac.cachedIndexPrice(code); ok {
+		return p.USD, nil
+	}
+
+	var price float64
+	err := callProvider(ctx, func() (err error) {
+		ctx, cancel := context.WithTimeout(withPriority(context.WithoutCancel(ctx), priorityInteractive), coingeckoAPITimeout)
+		defer cancel()
+		price, err = ac.fetchCoinGeckoPrice(ctx, code)
+		return err
+	})
+	if errors.Is(err, errBudgetExhausted) {
+		// An expired price is better than none for a query out of budget
+		ac.mu.RLock()
+		p, ok := ac.indexPrices[code]
+		ac.mu.RUnlock()
+		if ok {
+			markApproximate(ctx)
+			return p.USD, nil
+		}
+	}
+	if err != nil {
+		return 0, fmt.Errorf("index price for %s unavailable: %w", code, err)
+	}
+
+	ac.mu.Lock()
+	ac.indexPrices[code] = indexPrice{USD: price, LastUpdate: time.Now()}
+	ac.mu.Unlock()
+	return price, nil
+}
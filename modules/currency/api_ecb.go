@@ -0,0 +1,113 @@
+package currency
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ECB publishes free end-of-day reference rates for ~30 currencies. They are
+// not used for conversions, only as a sanity baseline for Mastercard rates:
+// a Mastercard rate far away from the ECB mid-rate almost always means we
+// parsed the wrong field or the currency was redenominated.
+
+type ecbEnvelope struct {
+	Cube struct {
+		Cube struct {
+			Time  string `xml:"time,attr"`
+			Rates []struct {
+				Currency string `xml:"currency,attr"`
+				Rate     string `xml:"rate,attr"`
+			} `xml:"Cube"`
+		} `xml:"Cube"`
+	} `xml:"Cube"`
+}
+
+func (ac *APICache) fetchECBBaseline() error {
+	ctx, cancel := context.WithTimeout(context.Background(), ecbAPITimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", ecbBaselineURL, nil)
+	if err != nil {
+		return err
+	}
+	setProviderHeaders(req, providerECB)
+
+	resp, err := ac.clientFor(providerECB).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %s", resp.Status)
+	}
+
+	var envelope ecbEnvelope
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, maxHTTPResponseSize)).Decode(&envelope); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	// ECB quotes everything per EUR; rebase to the USD_XXX keys used for Mastercard
+	eurRates := make(map[string]float64)
+	for _, r := range envelope.Cube.Cube.Rates {
+		rate, err := strconv.ParseFloat(r.Rate, 64)
+		if err != nil || !isValidFloat(rate) {
+			continue
+		}
+		eurRates[r.Currency] = rate
+	}
+
+	eurUSD, ok := eurRates[CurrencyUSD]
+	if !ok {
+		return fmt.Errorf("USD rate missing from ECB baseline")
+	}
+
+	baseline := make(map[string]float64, len(eurRates))
+	baseline[fmt.Sprintf("USD_%s", CurrencyEUR)] = 1 / eurUSD
+	for code, rate := range eurRates {
+		if code == CurrencyUSD {
+			continue
+		}
+		baseline[fmt.Sprintf("USD_%s", code)] = rate / eurUSD
+	}
+
+	ac.mu.Lock()
+	ac.baselineRates = baseline
+	ac.baselineLastUpdate = time.Now()
+	ac.mu.Unlock()
+
+	log.Printf("ECB baseline updated: %d rates (reference date %s)", len(baseline), envelope.Cube.Cube.Time)
+	return nil
+}
+
+// rejectBaselineOutliers removes Mastercard rates that deviate from the ECB
+// baseline by more than mastercardBaselineTolerance, so the previously cached
+// value keeps being served. Rates without a baseline are accepted as-is.
+func (ac *APICache) rejectBaselineOutliers(provider string, rates map[string]float64) {
+	ac.mu.RLock()
+	defer ac.mu.RUnlock()
+
+	if time.Since(ac.baselineLastUpdate) > ecbBaselineMaxAge {
+		return
+	}
+
+	for key, rate := range rates {
+		baseline, ok := ac.baselineRates[key]
+		if !ok {
+			continue
+		}
+		deviation := math.Abs(rate/baseline - 1)
+		if deviation > mastercardBaselineTolerance {
+			log.Printf("Warning: %s %s=%.6f deviates %.1f%% from ECB baseline %.6f, keeping previous value",
+				provider, key, rate, deviation*100, baseline)
+			delete(rates, key)
+		}
+	}
+}
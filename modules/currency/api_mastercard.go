@@ -9,10 +9,14 @@ import (
 	"log"
 	"math/rand"
 	"net/http"
+	"sort"
 	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Priority currencies to fetch first (most commonly used)
@@ -21,19 +25,6 @@ var priorityFiatCurrencies = []string{
 	"SEK", "NOK", "DKK", "INR", "MXN", "BRL", "ZAR", "TRY", "PLN", "THB",
 }
 
-var userAgents = []string{
-	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36",
-	"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:133.0) Gecko/20100101 Firefox/133.0",
-	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36",
-	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.1 Safari/605.1.15",
-	"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36",
-	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/130.0.0.0 Safari/537.36 Edg/130.0.0.0",
-}
-
-func getRandomUserAgent() string {
-	return userAgents[rand.Intn(len(userAgents))]
-}
-
 type adaptiveFetcher struct {
 	successCount   atomic.Int32
 	failureCount   atomic.Int32
@@ -89,6 +80,18 @@ func (af *adaptiveFetcher) getWorkerCount() int {
 }
 
 func (ac *APICache) fetchMastercardRates() error {
+	return ac.fetchMastercard(true)
+}
+
+// fetchMastercardPriorityRates fetches only priorityFiatCurrencies, for a
+// fast start.
+func (ac *APICache) fetchMastercardPriorityRates() error {
+	return ac.fetchMastercard(false)
+}
+
+// fetchMastercard fetches the priority currencies and, withRegular, this
+// cycle's rotation of the others.
+func (ac *APICache) fetchMastercard(withRegular bool) error {
 	if !mastercardCircuit.CanAttempt() {
 		return fmt.Errorf("circuit breaker open")
 	}
@@ -117,6 +120,12 @@ func (ac *APICache) fetchMastercardRates() error {
 			regularCurrencies = append(regularCurrencies, fiat)
 		}
 	}
+	if withRegular {
+		regularCurrencies = ac.selectMastercardRotation(regularCurrencies)
+	} else {
+		regularCurrencies = nil
+	}
+	attempted := len(priorityCurrencies) + len(regularCurrencies)
 
 	log.Printf("Fetching %d priority currencies first, then %d regular currencies",
 		len(priorityCurrencies), len(regularCurrencies))
@@ -131,7 +140,7 @@ func (ac *APICache) fetchMastercardRates() error {
 	ac.fetchCurrencyBatch(ctx, regularCurrencies, fetchedRates, &mu, fetcher, 5)
 
 	successCount := len(fetchedRates)
-	failCount := len(supportedFiats) - 1 - successCount
+	failCount := attempted - successCount
 
 	log.Printf("Mastercard fetch complete: %d successes, %d failures", successCount, failCount)
 
@@ -143,12 +152,25 @@ func (ac *APICache) fetchMastercardRates() error {
 	// Even partial success is acceptable - record success
 	mastercardCircuit.RecordSuccess()
 
+	ac.rejectBaselineOutliers("Mastercard", fetchedRates)
+
+	now := time.Now()
 	ac.mu.Lock()
 	for key, rate := range fetchedRates {
+		if previous, ok := ac.mastercardRates[key]; ok {
+			candidate := QuarantinedRate{Provider: "mastercard", Key: key, Previous: previous, Proposed: rate, mastercardRate: rate}
+			if !ac.screenRateLocked(candidate, ac.mastercardFetchedAt[key], anomalyThresholdFiat, mastercardRefreshInterval()*2) {
+				continue
+			}
+		}
+		if previous, ok := ac.mastercardRates[key]; !ok || !floatEquals(previous, rate) {
+			ac.mastercardChangedAt[key] = now
+		}
 		ac.mastercardRates[key] = rate
 		ac.lastMastercardRates[key] = rate
+		ac.mastercardFetchedAt[key] = now
 	}
-	ac.mastercardLastUpdate = time.Now()
+	ac.mastercardLastUpdate = now
 	ac.mu.Unlock()
 
 	log.Printf("Mastercard rates updated: %d pairs", len(fetchedRates))
@@ -160,6 +182,53 @@ func (ac *APICache) fetchMastercardRates() error {
 	return nil
 }
 
+// selectMastercardRotation picks which non-priority currencies to refresh this
+// cycle: every currency without a cached rate, topped up to
+// mastercardRotationSize with the due ones. Currencies whose rate changed in
+// the last day go first, since most rates don't move intraday; ties go to the
+// least recently fetched. Fetch and change times are persisted, so the
+// rotation resumes where it left off after a restart.
+func (ac *APICache) selectMastercardRotation(currencies []string) []string {
+	// Classes refreshed less often than the loop runs sit out cycles until
+	// they're due; half a loop of slack avoids skipping on timer jitter.
+	slack := mastercardRefreshInterval() / 2
+
+	ac.mu.RLock()
+	fetchedAt := make(map[string]time.Time, len(currencies))
+	moving := make(map[string]bool, len(currencies))
+	var missing, cached []string
+	for _, fiat := range currencies {
+		key := fmt.Sprintf("USD_%s", fiat)
+		if _, ok := ac.mastercardRates[key]; !ok {
+			missing = append(missing, fiat)
+			continue
+		}
+		fetchedAt[fiat] = ac.mastercardFetchedAt[key]
+		moving[fiat] = time.Since(ac.mastercardChangedAt[key]) < mastercardMovingWindow
+		if time.Since(fetchedAt[fiat])+slack < refreshPolicies[fiatAssetClass(fiat)].RefreshInterval {
+			continue
+		}
+		cached = append(cached, fiat)
+	}
+	ac.mu.RUnlock()
+
+	sort.SliceStable(cached, func(i, j int) bool {
+		if moving[cached[i]] != moving[cached[j]] {
+			return moving[cached[i]]
+		}
+		return fetchedAt[cached[i]].Before(fetchedAt[cached[j]])
+	})
+
+	selected := missing
+	if room := mastercardRotationSize - len(selected); room > 0 {
+		if room > len(cached) {
+			room = len(cached)
+		}
+		selected = append(selected, cached[:room]...)
+	}
+	return selected
+}
+
 func (ac *APICache) fetchCurrencyBatch(ctx context.Context, currencies []string, fetchedRates map[string]float64,
 	mu *sync.Mutex, fetcher *adaptiveFetcher, maxWorkers int32) {
 
@@ -285,8 +354,12 @@ func containsAny(s string, substrs ...string) bool {
 	return false
 }
 
-func (ac *APICache) fetchMastercardRate(ctx context.Context, from, to string) (float64, error) {
-	if err := mastercardLimiter.Wait(ctx); err != nil {
+func (ac *APICache) fetchMastercardRate(ctx context.Context, from, to string) (_ float64, err error) {
+	ctx, span := tracer.Start(ctx, "mastercard.rate",
+		trace.WithAttributes(attribute.String("from", from), attribute.String("to", to)))
+	defer func() { endSpan(span, err) }()
+
+	if err := mastercardScheduler.Wait(ctx); err != nil {
 		return 0, err
 	}
 
@@ -300,6 +373,8 @@ func (ac *APICache) fetchMastercardRate(ctx context.Context, from, to string) (f
 	default:
 	}
 
+	ac.ensureMastercardSession(requestCtx)
+
 	url := fmt.Sprintf("%s?exchange_date=0000-00-00&transaction_currency=%s&cardholder_billing_currency=%s&bank_fee=0&transaction_amount=10000000",
 		mastercardAPIURL, from, to)
 
@@ -308,29 +383,18 @@ func (ac *APICache) fetchMastercardRate(ctx context.Context, from, to string) (f
 		return 0, err
 	}
 
-	// Use varied, realistic browser headers
-	userAgent := getRandomUserAgent()
-	req.Header.Set("User-Agent", userAgent)
-	req.Header.Set("Accept", "application/json, text/plain, */*")
-	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
-	req.Header.Set("Accept-Encoding", "gzip, deflate, br")
-	req.Header.Set("Referer", "https://www.mastercard.com/global/en/personal/get-support/currency-exchange-rate-converter.html")
-	req.Header.Set("Origin", "https://www.mastercard.com")
-	req.Header.Set("Connection", "keep-alive")
-	req.Header.Set("Sec-Fetch-Dest", "empty")
-	req.Header.Set("Sec-Fetch-Mode", "cors")
-	req.Header.Set("Sec-Fetch-Site", "same-origin")
-	req.Header.Set("DNT", "1")
-	req.Header.Set("Cache-Control", "no-cache")
-	req.Header.Set("Pragma", "no-cache")
-
-	resp, err := ac.client.Do(req)
+	setProviderHeaders(req, providerMastercard)
+
+	resp, err := ac.clientFor(providerMastercard).Do(req)
 	if err != nil {
 		return 0, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode == http.StatusForbidden {
+			ac.invalidateMastercardSession()
+		}
 		return 0, fmt.Errorf("status %s", resp.Status)
 	}
 
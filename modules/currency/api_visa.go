@@ -0,0 +1,147 @@
+package currency
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Visa's public exchange rate calculator. fromCurr is the cardholder's
+// billing currency and toCurr the transaction currency, so fromCurr=EUR,
+// toCurr=USD quotes how many EUR are billed for 1 USD spent - the same
+// orientation as the USD_XXX Mastercard keys.
+type visaResponse struct {
+	OriginalValues struct {
+		FxRateVisa string `json:"fxRateVisa"`
+	} `json:"originalValues"`
+}
+
+// fetchVisaRates refreshes the USD rate of every supported fiat, priority
+// currencies first. Visa publishes daily and the calculator is slow, so the
+// currencies are fetched one after another behind visaScheduler.
+func (ac *APICache) fetchVisaRates() error {
+	if !visaCircuit.CanAttempt() {
+		return fmt.Errorf("circuit breaker open")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), visaRefreshInterval/2)
+	defer cancel()
+
+	prioritySet := make(map[string]bool, len(priorityFiatCurrencies))
+	currencies := make([]string, 0, len(supportedFiats))
+	for _, fiat := range priorityFiatCurrencies {
+		prioritySet[fiat] = true
+		currencies = append(currencies, fiat)
+	}
+	for _, fiat := range supportedFiats {
+		if fiat != CurrencyUSD && !prioritySet[fiat] {
+			currencies = append(currencies, fiat)
+		}
+	}
+
+	fetchedRates := make(map[string]float64)
+	failures := 0
+	for _, fiat := range currencies {
+		rate, err := ac.fetchVisaRate(ctx, fiat)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			failures++
+			if failures <= 5 {
+				log.Printf("Failed to fetch Visa USD->%s: %v", fiat, err)
+			}
+			continue
+		}
+		fetchedRates[fmt.Sprintf("USD_%s", fiat)] = rate
+	}
+
+	log.Printf("Visa fetch complete: %d successes, %d failures", len(fetchedRates), failures)
+
+	if len(fetchedRates) == 0 {
+		visaCircuit.RecordFailure()
+		return fmt.Errorf("no rates fetched (all attempts failed)")
+	}
+	visaCircuit.RecordSuccess()
+
+	ac.rejectBaselineOutliers("Visa", fetchedRates)
+
+	now := time.Now()
+	ac.mu.Lock()
+	for key, rate := range fetchedRates {
+		ac.visaRates[key] = rate
+	}
+	ac.visaLastUpdate = now
+	ac.mu.Unlock()
+
+	return nil
+}
+
+func (ac *APICache) fetchVisaRate(ctx context.Context, fiat string) (_ float64, err error) {
+	ctx, span := tracer.Start(ctx, "visa.rate", trace.WithAttributes(attribute.String("to", fiat)))
+	defer func() { endSpan(span, err) }()
+
+	if err := visaScheduler.Wait(ctx); err != nil {
+		return 0, err
+	}
+
+	requestCtx, cancel := context.WithTimeout(ctx, visaAPITimeout)
+	defer cancel()
+
+	date := time.Now().UTC().Format("01/02/2006")
+	query := url.Values{
+		"amount":           {"1"},
+		"fee":              {"0"},
+		"utcConvertedDate": {date},
+		"exchangedate":     {date},
+		"fromCurr":         {fiat},
+		"toCurr":           {CurrencyUSD},
+	}
+
+	req, err := http.NewRequestWithContext(requestCtx, "GET", visaAPIURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return 0, err
+	}
+	setProviderHeaders(req, providerVisa)
+
+	resp, err := ac.clientFor(providerVisa).Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("status %s", resp.Status)
+	}
+
+	var result visaResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxHTTPResponseSize)).Decode(&result); err != nil {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	value := strings.ReplaceAll(result.OriginalValues.FxRateVisa, ",", "")
+	if value == "" {
+		return 0, fmt.Errorf("empty conversion rate in response")
+	}
+
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid conversion rate '%s': %w", value, err)
+	}
+
+	if rate <= 0 || !isValidFloat(rate) {
+		return 0, fmt.Errorf("invalid rate value: %f", rate)
+	}
+
+	return rate, nil
+}
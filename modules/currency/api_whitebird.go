@@ -4,14 +4,49 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net/http"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// whitebirdQuoteCache stores effective rates (output per unit of input) keyed
+// by amount bucket; see formatWhitebirdBucketKey.
+var whitebirdQuoteCache = &ConversionCache{
+	results: make(map[string]*cachedValue),
+	ttl:     whitebirdQuoteCacheTTL,
+	access:  whitebirdQuoteAccess,
+}
+
+// whitebirdLastRate holds the latest effective rate per "FROM/TO" pair,
+// whatever the amount. It stands in for a quote once a query has spent its
+// provider budget.
+var whitebirdLastRate sync.Map
+
+var errWhitebirdNoTargetQuote = errors.New("whitebird does not quote by output amount")
+
+// whitebirdStatusError is a calculation request answered with a status
+// other than 200.
+type whitebirdStatusError struct {
+	code   int
+	status string
+}
+
+func (e *whitebirdStatusError) Error() string { return "status " + e.status }
+
+// whitebirdTargetUnsupported is set once the API ignores an outputAsset
+// request, so later inverse conversions skip straight to forward quotes.
+var whitebirdTargetUnsupported atomic.Bool
+
 type whitebirdRequestPayload struct {
 	CurrencyPair whitebirdCurrencyPair `json:"currencyPair"`
 	Calculation  whitebirdCalculation  `json:"calculation"`
@@ -22,8 +57,11 @@ type whitebirdCurrencyPair struct {
 	ToCurrency   string `json:"toCurrency"`
 }
 
+// whitebirdCalculation sets exactly one side of the quote: the amount sent
+// (inputAsset) or the amount wanted (outputAsset).
 type whitebirdCalculation struct {
-	InputAsset float64 `json:"inputAsset"`
+	InputAsset  *float64 `json:"inputAsset,omitempty"`
+	OutputAsset *float64 `json:"outputAsset,omitempty"`
 }
 
 type whitebirdResponse struct {
@@ -32,6 +70,7 @@ type whitebirdResponse struct {
 		Ratio      string `json:"ratio"` // Effective rate with fees included
 	} `json:"rate"`
 	Calculation struct {
+		InputAsset  string `json:"inputAsset"`
 		OutputAsset string `json:"outputAsset"`
 	} `json:"calculation"`
 	Limit struct {
@@ -47,12 +86,20 @@ type whitebirdResponse struct {
 // GetWhitebirdRateForAmount fetches the Whitebird exchange rate for a specific amount.
 // This is essential because Whitebird rates are non-linear (vary with amount).
 // Returns the amount of target currency received (not the rate).
-func (ac *APICache) GetWhitebirdRateForAmount(from, to string, amount float64) (float64, error) {
+func (ac *APICache) GetWhitebirdRateForAmount(ctx context.Context, from, to string, amount float64) (float64, error) {
 	// FIXED: Validate amount before making API call
 	if err := ValidateAmount(amount); err != nil {
 		return 0, fmt.Errorf("invalid amount: %w", err)
 	}
 
+	bucketKey := formatWhitebirdBucketKey(from, to, amount)
+	if rate, ok := whitebirdQuoteCache.Get(bucketKey); ok {
+		return amount * rate, nil
+	}
+	if rate, ok := interpolatedWhitebirdRate(from, to, amount); ok {
+		return amount * rate * whitebirdCorrection(from, to), nil
+	}
+
 	if !whitebirdCircuit.CanAttempt() {
 		ac.mu.Lock()
 		ac.whitebirdStatus.Available = false
@@ -60,10 +107,21 @@ func (ac *APICache) GetWhitebirdRateForAmount(from, to string, amount float64) (
 		return 0, fmt.Errorf("whitebird service temporarily unavailable")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), whitebirdAPITimeout)
-	defer cancel()
-
-	outputAmount, err := ac.fetchSingleWhitebirdConversion(ctx, from, to, amount)
+	var outputAmount float64
+	err := callProvider(ctx, func() (err error) {
+		ctx, cancel := context.WithTimeout(withPriority(context.WithoutCancel(ctx), priorityInteractive), whitebirdAPITimeout)
+		defer cancel()
+		outputAmount, err = ac.fetchSingleWhitebirdConversion(ctx, from, to, amount)
+		return err
+	})
+	if errors.Is(err, errBudgetExhausted) {
+		// Rates vary with the amount, so another amount's rate is only an estimate
+		if rate, ok := whitebirdLastRate.Load(from + "/" + to); ok {
+			markApproximate(ctx)
+			return amount * rate.(float64) * whitebirdCorrection(from, to), nil
+		}
+		return 0, err
+	}
 	if err != nil {
 		whitebirdCircuit.RecordFailure()
 		ac.mu.Lock()
@@ -82,46 +140,208 @@ func (ac *APICache) GetWhitebirdRateForAmount(from, to string, amount float64) (
 	ac.whitebirdStatus.LastUpdate = time.Now()
 	ac.mu.Unlock()
 
+	whitebirdQuoteCache.Set(bucketKey, outputAmount/amount)
+	whitebirdLastRate.Store(from+"/"+to, outputAmount/amount)
+
 	return outputAmount, nil
 }
 
-func (ac *APICache) fetchSingleWhitebirdConversion(ctx context.Context, from, to string, amount float64) (float64, error) {
-	if err := whitebirdLimiter.Wait(ctx); err != nil {
+func (ac *APICache) fetchSingleWhitebirdConversion(ctx context.Context, from, to string, amount float64) (_ float64, err error) {
+	ctx, span := tracer.Start(ctx, "whitebird.quote", trace.WithAttributes(
+		attribute.String("from", from), attribute.String("to", to), attribute.Float64("amount", amount)))
+	defer func() { endSpan(span, err) }()
+
+	wbResp, err := ac.postWhitebirdCalculation(ctx, from, to, whitebirdCalculation{InputAsset: &amount})
+	if err != nil {
+		return 0, err
+	}
+
+	// Validate response
+	if wbResp.Calculation.OutputAsset == "" {
+		return 0, fmt.Errorf("empty output asset in response")
+	}
+
+	if err := wbResp.checkLimits(amount); err != nil {
 		return 0, err
 	}
 
+	outputAmount, err := strconv.ParseFloat(wbResp.Calculation.OutputAsset, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid output amount: %s", wbResp.Calculation.OutputAsset)
+	}
+
+	if !isValidFloat(outputAmount) || outputAmount <= 0 {
+		return 0, fmt.Errorf("invalid output amount: %f", outputAmount)
+	}
+
+	// Log the conversion for debugging
+	log.Printf("Whitebird %s->%s: input=%.6f, output=%.6f", from, to, amount, outputAmount)
+
+	return outputAmount, nil
+}
+
+// GetWhitebirdInputForOutput asks Whitebird how much of from must be sent to
+// receive output of to, fees included. Errors with errWhitebirdNoTargetQuote
+// when the API does not quote by output; callers then search with forward
+// quotes instead.
+func (ac *APICache) GetWhitebirdInputForOutput(ctx context.Context, from, to string, output float64) (float64, error) {
+	if err := ValidateAmount(output); err != nil {
+		return 0, fmt.Errorf("invalid amount: %w", err)
+	}
+	if !whitebirdTargetQuotes || whitebirdTargetUnsupported.Load() {
+		return 0, errWhitebirdNoTargetQuote
+	}
+
+	if !whitebirdCircuit.CanAttempt() {
+		ac.mu.Lock()
+		ac.whitebirdStatus.Available = false
+		ac.mu.Unlock()
+		return 0, fmt.Errorf("whitebird service temporarily unavailable")
+	}
+
+	var inputAmount float64
+	err := callProvider(ctx, func() (err error) {
+		ctx, cancel := context.WithTimeout(withPriority(context.WithoutCancel(ctx), priorityInteractive), whitebirdAPITimeout)
+		defer cancel()
+		inputAmount, err = ac.fetchWhitebirdInputForOutput(ctx, from, to, output)
+		return err
+	})
+	if errors.Is(err, errBudgetExhausted) {
+		return 0, err
+	}
+	if errors.Is(err, errWhitebirdNoTargetQuote) {
+		// The API answered but ignored outputAsset; stop asking
+		whitebirdTargetUnsupported.Store(true)
+		log.Printf("Warning: Whitebird does not quote by output amount, using forward quotes")
+		return 0, err
+	}
+	if err != nil {
+		whitebirdCircuit.RecordFailure()
+		ac.mu.Lock()
+		ac.whitebirdStatus.Available = false
+		ac.whitebirdStatus.LastError = err
+		ac.whitebirdStatus.ConsecutiveFails++
+		ac.mu.Unlock()
+		return 0, fmt.Errorf("failed to get exchange rate: %w", err)
+	}
+
+	whitebirdCircuit.RecordSuccess()
+	ac.mu.Lock()
+	ac.whitebirdStatus.Available = true
+	ac.whitebirdStatus.LastError = nil
+	ac.whitebirdStatus.ConsecutiveFails = 0
+	ac.whitebirdStatus.LastUpdate = time.Now()
+	ac.mu.Unlock()
+
+	// Seed the forward cache too: the forward check of this input is then free
+	whitebirdQuoteCache.Set(formatWhitebirdBucketKey(from, to, inputAmount), output/inputAmount)
+	whitebirdLastRate.Store(from+"/"+to, output/inputAmount)
+
+	return inputAmount, nil
+}
+
+func (ac *APICache) fetchWhitebirdInputForOutput(ctx context.Context, from, to string, output float64) (_ float64, err error) {
+	ctx, span := tracer.Start(ctx, "whitebird.quote_target", trace.WithAttributes(
+		attribute.String("from", from), attribute.String("to", to), attribute.Float64("output", output)))
+	defer func() { endSpan(span, err) }()
+
+	wbResp, err := ac.postWhitebirdCalculation(ctx, from, to, whitebirdCalculation{OutputAsset: &output})
+	// A rejected outputAsset request says nothing about Whitebird's health;
+	// only rate limiting counts against it
+	var statusErr *whitebirdStatusError
+	if errors.As(err, &statusErr) && statusErr.code >= 400 && statusErr.code < 500 && statusErr.code != http.StatusTooManyRequests {
+		return 0, errWhitebirdNoTargetQuote
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if wbResp.Calculation.InputAsset == "" {
+		return 0, errWhitebirdNoTargetQuote
+	}
+	inputAmount, err := strconv.ParseFloat(wbResp.Calculation.InputAsset, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid input amount: %s", wbResp.Calculation.InputAsset)
+	}
+	if !isValidFloat(inputAmount) || inputAmount <= 0 {
+		return 0, fmt.Errorf("invalid input amount: %f", inputAmount)
+	}
+
+	// An echo of a forward quote leaves the requested output unchanged only
+	// by accident; treat a mismatch as "not supported".
+	if wbResp.Calculation.OutputAsset != "" {
+		echoed, err := strconv.ParseFloat(wbResp.Calculation.OutputAsset, 64)
+		if err == nil && math.Abs(echoed-output) > output*0.01 {
+			return 0, errWhitebirdNoTargetQuote
+		}
+	}
+
+	if err := wbResp.checkLimits(inputAmount); err != nil {
+		return 0, err
+	}
+
+	log.Printf("Whitebird %s->%s: output=%.6f, input=%.6f", from, to, output, inputAmount)
+
+	return inputAmount, nil
+}
+
+// postWhitebirdCalculation sends one calculation request and returns the
+// decoded response of an enabled operation.
+func (ac *APICache) postWhitebirdCalculation(ctx context.Context, from, to string, calc whitebirdCalculation) (*whitebirdResponse, error) {
+	wbResp, err := ac.requestWhitebirdCalculation(ctx, from, to, calc)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if operation is enabled first (fail fast)
+	if !wbResp.OperationStatus.Enabled {
+		ac.recordWhitebirdOperation(false, wbResp.OperationStatus.Status)
+		return nil, fmt.Errorf("operation not enabled: %s", wbResp.OperationStatus.Status)
+	}
+	ac.recordWhitebirdOperation(true, wbResp.OperationStatus.Status)
+
+	return wbResp, nil
+}
+
+// requestWhitebirdCalculation sends one calculation request and returns the
+// decoded response, whatever the operation status.
+func (ac *APICache) requestWhitebirdCalculation(ctx context.Context, from, to string, calc whitebirdCalculation) (*whitebirdResponse, error) {
+	if err := whitebirdScheduler.Wait(ctx); err != nil {
+		return nil, err
+	}
+
 	select {
 	case <-ctx.Done():
-		return 0, ctx.Err()
+		return nil, ctx.Err()
 	default:
 	}
 
 	payload := whitebirdRequestPayload{
 		CurrencyPair: whitebirdCurrencyPair{from, to},
-		Calculation:  whitebirdCalculation{amount},
+		Calculation:  calc,
 	}
 
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", whitebirdAPIURL, bytes.NewBuffer(payloadBytes))
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
+	setProviderHeaders(req, providerWhitebird)
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Origin", "https://whitebird.io")
 
-	resp, err := ac.client.Do(req)
+	resp, err := ac.clientFor(providerWhitebird).Do(req)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("status %s", resp.Status)
+		return nil, &whitebirdStatusError{resp.StatusCode, resp.Status}
 	}
 
 	// Limit response body size
@@ -129,38 +349,19 @@ func (ac *APICache) fetchSingleWhitebirdConversion(ctx context.Context, from, to
 
 	var wbResp whitebirdResponse
 	if err := json.NewDecoder(limitedReader).Decode(&wbResp); err != nil {
-		return 0, fmt.Errorf("failed to decode response: %w", err)
-	}
-
-	// Check if operation is enabled first (fail fast)
-	if !wbResp.OperationStatus.Enabled {
-		return 0, fmt.Errorf("operation not enabled: %s", wbResp.OperationStatus.Status)
+		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	// Validate response
-	if wbResp.Calculation.OutputAsset == "" {
-		return 0, fmt.Errorf("empty output asset in response")
-	}
-
-	// Check amount limits if present
-	if wbResp.Limit.Min != nil && amount < *wbResp.Limit.Min {
-		return 0, fmt.Errorf("amount %.2f is below minimum limit %.2f", amount, *wbResp.Limit.Min)
-	}
-	if wbResp.Limit.Max != nil && amount > *wbResp.Limit.Max {
-		return 0, fmt.Errorf("amount %.2f exceeds maximum limit %.2f", amount, *wbResp.Limit.Max)
-	}
+	return &wbResp, nil
+}
 
-	outputAmount, err := strconv.ParseFloat(wbResp.Calculation.OutputAsset, 64)
-	if err != nil {
-		return 0, fmt.Errorf("invalid output amount: %s", wbResp.Calculation.OutputAsset)
+// checkLimits validates the input amount against the limits in the response.
+func (r *whitebirdResponse) checkLimits(amount float64) error {
+	if r.Limit.Min != nil && amount < *r.Limit.Min {
+		return fmt.Errorf("amount %.2f is below minimum limit %.2f", amount, *r.Limit.Min)
 	}
-
-	if !isValidFloat(outputAmount) || outputAmount <= 0 {
-		return 0, fmt.Errorf("invalid output amount: %f", outputAmount)
+	if r.Limit.Max != nil && amount > *r.Limit.Max {
+		return fmt.Errorf("amount %.2f exceeds maximum limit %.2f", amount, *r.Limit.Max)
 	}
-
-	// Log the conversion for debugging
-	log.Printf("Whitebird %s->%s: input=%.6f, output=%.6f", from, to, amount, outputAmount)
-
-	return outputAmount, nil
+	return nil
 }
@@ -0,0 +1,89 @@
+package currency
+
+import (
+	"context"
+	"fmt"
+
+	"answerflow/commontypes"
+)
+
+// Book sides a crypto query can ask to be priced at: "1 btc ask",
+// "1 btc bid in usd", "0.5 eth mid to eur".
+const (
+	sideBid = "bid"
+	sideAsk = "ask"
+	sideMid = "mid"
+)
+
+// bookSidePrice is the USDT price of one unit on the given side of the
+// top of symbol's book.
+func bookSidePrice(rate *BybitRate, side string) float64 {
+	switch side {
+	case sideBid:
+		return rate.BestBid
+	case sideAsk:
+		return rate.BestAsk
+	}
+	return bybitMidPrice(rate)
+}
+
+// generateBookSideResult values req.Amount of a crypto at one side of its
+// Bybit book, for traders checking both sides. Only the source currency's
+// book is read at that side; the target, USDT unless one is given, is
+// valued at its mid-market reference. No fees or depth are applied: this
+// is the quote, not an execution.
+func (m *CurrencyConverterModule) generateBookSideResult(ctx context.Context, req *ConversionRequest, apiCache *APICache) []commontypes.FlowResult {
+	to := CurrencyUSDT
+	if req.ToCurrency != "" {
+		resolved, err := m.currencyData.ResolveCurrency(req.ToCurrency)
+		if err != nil {
+			return m.requestedPairFailed(ctx, req, m.makeErrorResult(req, req.ToCurrency, err), apiCache)
+		}
+		to = resolved
+	}
+	fail := func(err error) []commontypes.FlowResult {
+		return m.requestedPairFailed(ctx, req, m.makeErrorResult(req, to, err), apiCache)
+	}
+
+	switch getCurrencyType(req.FromCurrency, apiCache) {
+	case "crypto", "TON":
+	default:
+		return fail(fmt.Errorf("%s applies to crypto order books only", req.Side))
+	}
+	symbol := req.FromCurrency + CurrencyUSDT
+	if err := apiCache.EnsureBybitSymbol(ctx, symbol); err != nil {
+		return fail(err)
+	}
+	rate, err := apiCache.GetBybitRate(symbol)
+	if err != nil {
+		return fail(err)
+	}
+	price := bookSidePrice(rate, req.Side)
+	toUSD, err := apiCache.referenceUSDPrice(ctx, to)
+	if err != nil {
+		return fail(err)
+	}
+	result := req.Amount * price / toUSD
+	if !isValidFloat(result) || result <= 0 {
+		return fail(fmt.Errorf("exchange rate not available"))
+	}
+
+	formatted := formatAmountAt(result, to, req.precision())
+	title := fmt.Sprintf("%s %s", formatted, to)
+	if !m.ShortDisplayFormat {
+		title = fmt.Sprintf("%s %s = %s %s", formatAmount(req.Amount, req.FromCurrency), req.FromCurrency, formatted, to)
+	}
+	subTitle := fmt.Sprintf("Bybit %s: 1 %s = %s USDT | bid %s, ask %s, spread %.3f%% | no fees or slippage",
+		req.Side, req.FromCurrency, formatRate(price), formatRate(rate.BestBid), formatRate(rate.BestAsk),
+		(rate.BestAsk/rate.BestBid-1)*100)
+	if to != CurrencyUSDT {
+		subTitle += fmt.Sprintf(" | %s at mid-market", to)
+	}
+	return []commontypes.FlowResult{{
+		Title:         title,
+		SubTitle:      subTitle,
+		IcoPath:       assetIcon(req.FromCurrency, to),
+		Score:         scoreSpecificConversion,
+		JsonRPCAction: commontypes.CopyToClipboard(fmt.Sprintf("%s %s", formatAmountForClipboardAt(result, to, req.precision()), to)),
+	}}
+}
@@ -0,0 +1,105 @@
+package currency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"answerflow/commontypes"
+)
+
+// Per-query provider budget. A query can fan out into lazy symbol loads,
+// order book fetches and inverse searches; once it has made
+// QUERY_PROVIDER_CALLS provider requests or spent QUERY_PROVIDER_TIME in
+// them, further values come from cache or approximations instead.
+var (
+	queryProviderCalls = int(getEnvFloatOrDefault("QUERY_PROVIDER_CALLS", 6))
+	queryProviderTime  = getEnvDurationOrDefault("QUERY_PROVIDER_TIME", 8*time.Second)
+)
+
+var errBudgetExhausted = errors.New("provider budget for this query exhausted")
+
+// budgetPool is what one query may still spend on provider requests.
+type budgetPool struct {
+	mu    sync.Mutex
+	limit int // provider calls allowed
+	calls int
+	spent time.Duration
+}
+
+// providerBudget is the view of a budgetPool held by one result. Results
+// share the pool but track their own approximations.
+type providerBudget struct {
+	pool        *budgetPool
+	approximate atomic.Bool
+}
+
+type budgetKey struct{}
+
+// withProviderBudget starts a fresh budget for a query. A fast-path query
+// gets none at all, so it is answered from cache alone.
+func withProviderBudget(ctx context.Context) context.Context {
+	pool := &budgetPool{limit: queryProviderCalls}
+	if commontypes.FastPathFromContext(ctx) {
+		pool.limit = 0
+	}
+	return context.WithValue(ctx, budgetKey{}, &providerBudget{pool: pool})
+}
+
+// withBudgetScope starts a result within the query's budget, so
+// approximations are attributed to the result that used them.
+func withBudgetScope(ctx context.Context) context.Context {
+	b := budgetFromContext(ctx)
+	if b == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, budgetKey{}, &providerBudget{pool: b.pool})
+}
+
+// budgetFromContext returns the query's budget, nil (unlimited) outside a query.
+func budgetFromContext(ctx context.Context) *providerBudget {
+	b, _ := ctx.Value(budgetKey{}).(*providerBudget)
+	return b
+}
+
+// callProvider runs fn, a provider request made for the query in ctx, and
+// charges it to the query's budget. It returns errBudgetExhausted without
+// calling fn once the budget is spent.
+func callProvider(ctx context.Context, fn func() error) error {
+	b := budgetFromContext(ctx)
+	if b == nil {
+		return fn()
+	}
+
+	b.pool.mu.Lock()
+	if b.pool.calls >= b.pool.limit || b.pool.spent >= queryProviderTime {
+		b.pool.mu.Unlock()
+		return errBudgetExhausted
+	}
+	b.pool.calls++
+	b.pool.mu.Unlock()
+
+	start := time.Now()
+	err := fn()
+	b.pool.mu.Lock()
+	b.pool.spent += time.Since(start)
+	b.pool.mu.Unlock()
+	return err
+}
+
+// markApproximate records that a value for the query in ctx was approximated
+// because the budget ran out.
+func markApproximate(ctx context.Context) {
+	if b := budgetFromContext(ctx); b != nil {
+		b.approximate.Store(true)
+	}
+}
+
+// isApproximate reports whether the result in ctx used an approximation.
+// Approximate values are neither cached nor shown as exact.
+func isApproximate(ctx context.Context) bool {
+	b := budgetFromContext(ctx)
+	return b != nil && b.approximate.Load()
+}
@@ -3,7 +3,9 @@ package currency
 import (
 	"context"
 	"fmt"
+	"log"
 	"net/http"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -17,23 +19,57 @@ type ProviderStatus struct {
 }
 
 type APICache struct {
-	client *http.Client
-	mu     sync.RWMutex
+	client          *http.Client
+	providerClients map[string]*http.Client // per provider transport settings and proxy
+	connStats       map[string]*connStats
+	mu              sync.RWMutex
 
 	// Bybit data
 	bybitRates      map[string]*BybitRate
 	bybitLastUpdate time.Time
-	lastBybitRates  map[string]*BybitRate
 	bybitStatus     ProviderStatus
 
+	// Bybit 24h USDT turnover per symbol, refreshed from the ticker batch
+	bybitVolumes       map[string]float64
+	bybitVolumesUpdate time.Time
+
 	// Mastercard data
 	mastercardRates      map[string]float64
 	mastercardLastUpdate time.Time
 	lastMastercardRates  map[string]float64
 	mastercardStatus     ProviderStatus
+	mastercardFetchedAt  map[string]time.Time // per-rate last successful fetch
+	mastercardChangedAt  map[string]time.Time // per-rate last value change
+	mastercardSession    mastercardSession    // converter page cookies, see mastercard_session.go
+
+	// Visa data, same USD_XXX keys as Mastercard
+	visaRates      map[string]float64
+	visaLastUpdate time.Time
+	visaStatus     ProviderStatus
 
 	// Whitebird status (no pre-cached rates - always query per-amount)
-	whitebirdStatus ProviderStatus
+	whitebirdStatus    ProviderStatus
+	whitebirdOperation WhitebirdOperation // last reported, see whitebird_probe.go
+
+	// ECB end-of-day baseline, used only to validate Mastercard rates
+	baselineRates      map[string]float64
+	baselineLastUpdate time.Time
+	ecbStatus          ProviderStatus
+
+	// Bank of Russia official rates, RUB per unit, shown next to RUB results
+	cbrRates      map[string]float64
+	cbrDate       string
+	cbrLastUpdate time.Time
+	cbrStatus     ProviderStatus
+
+	// CoinGecko USD index prices for assets without a Bybit order book
+	indexPrices map[string]indexPrice
+
+	// Rates held back by anomaly screening, keyed by quarantineID
+	quarantine map[string]*QuarantinedRate
+
+	// Recent provider HTTP exchanges; nil unless WIRE_LOG is enabled
+	wireLog *wireLogRing
 
 	// Metadata
 	validCryptos     map[string]bool
@@ -43,7 +79,7 @@ type APICache struct {
 	pairsLastCheck   time.Time
 
 	// Symbol fetching tracking
-	symbolsFetching map[string]bool
+	symbolsFetching map[string]*symbolFetch
 
 	// Health monitoring
 	healthTicker      *time.Ticker
@@ -53,16 +89,33 @@ type APICache struct {
 	bybitHealthy      atomic.Bool
 	mastercardHealthy atomic.Bool
 	whitebirdHealthy  atomic.Bool
+	ecbHealthy        atomic.Bool
+	cbrHealthy        atomic.Bool
+	visaHealthy       atomic.Bool
+
+	// Idle suspension of the update loops (see idle.go)
+	lastActivity  atomic.Int64
+	idleSuspended atomic.Bool
+	idleMu        sync.Mutex
+	idleWake      chan struct{}
 
 	// Shutdown
 	shutdownChan chan struct{}
 	shutdownOnce sync.Once
+
+	// Snapshot saves, see runSnapshotWriter
+	saveRequests chan struct{}
+	saveWriter   sync.Once
+
+	janitor janitor // periodic cache compaction, see janitor.go
 }
 
 func NewAPICache() *APICache {
 	validCryptos := make(map[string]bool, len(supportedCryptos))
-	for _, c := range supportedCryptos {
-		validCryptos[c] = true
+	if !fiatOnlyMode {
+		for _, c := range supportedCryptos {
+			validCryptos[c] = true
+		}
 	}
 
 	validFiats := make(map[string]bool, len(supportedFiats))
@@ -73,24 +126,43 @@ func NewAPICache() *APICache {
 	ac := &APICache{
 		client:              CreateHTTPClient(),
 		bybitRates:          make(map[string]*BybitRate),
+		bybitVolumes:        make(map[string]float64),
+		idleWake:            make(chan struct{}),
 		mastercardRates:     make(map[string]float64),
+		visaRates:           make(map[string]float64),
+		baselineRates:       make(map[string]float64),
+		cbrRates:            make(map[string]float64),
+		quarantine:          make(map[string]*QuarantinedRate),
+		indexPrices:         make(map[string]indexPrice),
 		validCryptos:        validCryptos,
 		validFiats:          validFiats,
 		currencyMetadata:    make(map[string]*CurrencyMetadata),
 		tradeablePairs:      make(map[string]bool),
-		lastBybitRates:      make(map[string]*BybitRate),
 		lastMastercardRates: make(map[string]float64),
-		symbolsFetching:     make(map[string]bool),
+		mastercardFetchedAt: make(map[string]time.Time),
+		mastercardChangedAt: make(map[string]time.Time),
+		symbolsFetching:     make(map[string]*symbolFetch),
 		bybitStatus:         ProviderStatus{Available: false},
 		mastercardStatus:    ProviderStatus{Available: false},
 		whitebirdStatus:     ProviderStatus{Available: false},
 		healthStopChan:      make(chan struct{}),
 		shutdownChan:        make(chan struct{}),
+		saveRequests:        make(chan struct{}, 1),
 	}
 
+	ac.providerClients, ac.connStats = newProviderClients()
 	ac.bybitHealthy.Store(false)
 	ac.mastercardHealthy.Store(false)
 	ac.whitebirdHealthy.Store(false)
+	ac.lastActivity.Store(time.Now().UnixNano())
+
+	if wireLogEnabled && wireLogSize > 0 {
+		ac.wireLog = newWireLogRing(wireLogSize)
+		ac.client.Transport = &wireLogTransport{next: ac.client.Transport, ring: ac.wireLog}
+		for _, client := range ac.providerClients {
+			client.Transport = &wireLogTransport{next: client.Transport, ring: ac.wireLog}
+		}
+	}
 
 	return ac
 }
@@ -108,15 +180,34 @@ func (ac *APICache) IsFiat(code string) bool {
 }
 
 func (ac *APICache) IsStale() bool {
+	return ac.IsStaleFor(assetCrypto, assetFiat)
+}
+
+// IsStaleFor reports whether data for any of the given asset classes is older
+// than its class's StaleAfter. For a provider on a refresh schedule, age is
+// counted from the first scheduled refresh after the last update instead.
+func (ac *APICache) IsStaleFor(classes ...string) bool {
 	ac.mu.RLock()
 	defer ac.mu.RUnlock()
 
 	now := time.Now()
-	if now.Sub(ac.bybitLastUpdate) > criticalStalenessThreshold {
-		return true
-	}
-	if now.Sub(ac.mastercardLastUpdate) > criticalStalenessThreshold*4 {
-		return true
+	for _, class := range classes {
+		provider, lastUpdate := providerMastercard, ac.mastercardLastUpdate
+		if class == assetCrypto {
+			provider, lastUpdate = providerBybit, ac.bybitLastUpdate
+		}
+		if !providerEnabled(provider) {
+			continue
+		}
+		due := lastUpdate
+		if schedule, ok := refreshSchedules[provider]; ok {
+			if next := schedule.Next(lastUpdate); !next.IsZero() {
+				due = next
+			}
+		}
+		if _, injected := faults.get(provider, faultStale); injected || now.Sub(due) > refreshPolicies[class].StaleAfter {
+			return true
+		}
 	}
 	return false
 }
@@ -126,10 +217,14 @@ func (ac *APICache) GetCacheStaleness() map[string]time.Duration {
 	defer ac.mu.RUnlock()
 
 	now := time.Now()
-	return map[string]time.Duration{
+	staleness := map[string]time.Duration{
 		"bybit":      now.Sub(ac.bybitLastUpdate),
 		"mastercard": now.Sub(ac.mastercardLastUpdate),
 	}
+	if providerEnabled(providerVisa) {
+		staleness["visa"] = now.Sub(ac.visaLastUpdate)
+	}
+	return staleness
 }
 
 func (ac *APICache) InitializeTradeablePairs() {
@@ -181,20 +276,26 @@ func (ac *APICache) GetBybitRate(symbol string) (*BybitRate, error) {
 	defer ac.mu.RUnlock()
 
 	if !ac.bybitStatus.Available {
+		bybitRateAccess.record(false)
 		return nil, fmt.Errorf("bybit service unavailable")
 	}
 
 	rate, ok := ac.bybitRates[symbol]
 	if !ok || rate == nil || !isValidFloat(rate.BestBid) || !isValidFloat(rate.BestAsk) {
+		bybitRateAccess.record(false)
 		return nil, fmt.Errorf("exchange rate not available for %s", symbol)
 	}
+	bybitRateAccess.record(true)
 
 	return &BybitRate{
 		BestBid:       rate.BestBid,
 		BestAsk:       rate.BestAsk,
+		Volume24h:     ac.bybitVolumes[symbol],
 		OrderBookBids: rate.OrderBookBids,
 		OrderBookAsks: rate.OrderBookAsks,
 		LastUpdate:    rate.LastUpdate,
+		DepthUpdate:   rate.DepthUpdate,
+		DepthLimit:    rate.DepthLimit,
 	}, nil
 }
 
@@ -222,12 +323,34 @@ func (ac *APICache) GetMastercardRate(from, to string) (float64, error) {
 	defer ac.mu.RUnlock()
 
 	if !ac.mastercardStatus.Available {
+		mastercardRateAccess.record(false)
 		return 0, fmt.Errorf("fiat exchange rates temporarily unavailable")
 	}
+	rate, err := usdCrossRate(ac.mastercardRates, from, to)
+	mastercardRateAccess.record(err == nil)
+	return rate, err
+}
 
+// GetVisaRate is GetMastercardRate for Visa's rates.
+func (ac *APICache) GetVisaRate(from, to string) (float64, error) {
+	if from == to {
+		return 1.0, nil
+	}
+
+	ac.mu.RLock()
+	defer ac.mu.RUnlock()
+
+	if !providerEnabled(providerVisa) || !ac.visaStatus.Available {
+		return 0, fmt.Errorf("visa exchange rates temporarily unavailable")
+	}
+	return usdCrossRate(ac.visaRates, from, to)
+}
+
+// usdCrossRate derives from->to from rates keyed USD_XXX.
+func usdCrossRate(rates map[string]float64, from, to string) (float64, error) {
 	if from == CurrencyUSD {
 		key := fmt.Sprintf("USD_%s", to)
-		rate, ok := ac.mastercardRates[key]
+		rate, ok := rates[key]
 		if !ok || !isValidFloat(rate) {
 			return 0, fmt.Errorf("exchange rate not available for %s", to)
 		}
@@ -236,7 +359,7 @@ func (ac *APICache) GetMastercardRate(from, to string) (float64, error) {
 
 	if to == CurrencyUSD {
 		key := fmt.Sprintf("USD_%s", from)
-		rate, ok := ac.mastercardRates[key]
+		rate, ok := rates[key]
 		if !ok || !isValidFloat(rate) {
 			return 0, fmt.Errorf("exchange rate not available for %s", from)
 		}
@@ -245,8 +368,8 @@ func (ac *APICache) GetMastercardRate(from, to string) (float64, error) {
 
 	fromKey := fmt.Sprintf("USD_%s", from)
 	toKey := fmt.Sprintf("USD_%s", to)
-	fromRate, okFrom := ac.mastercardRates[fromKey]
-	toRate, okTo := ac.mastercardRates[toKey]
+	fromRate, okFrom := rates[fromKey]
+	toRate, okTo := rates[toKey]
 
 	if !okFrom || !okTo || !isValidFloat(fromRate) || !isValidFloat(toRate) {
 		return 0, fmt.Errorf("exchange rate not available for %s or %s", from, to)
@@ -254,7 +377,103 @@ func (ac *APICache) GetMastercardRate(from, to string) (float64, error) {
 	return toRate / fromRate, nil
 }
 
+// cardRate returns the rate and fee of a card network (providerMastercard
+// or providerVisa) for from->to.
+func (ac *APICache) cardRate(network, from, to string) (rate, fee float64, err error) {
+	if network == providerVisa {
+		rate, err = ac.GetVisaRate(from, to)
+		return rate, feeVisa, err
+	}
+	rate, err = ac.GetMastercardRate(from, to)
+	return rate, feeMastercard, err
+}
+
+// selectCardNetwork picks the card network that prices from->to: the one
+// set by CARD_NETWORK, or in "best" and "both" mode whichever available
+// network gives more after fees.
+func (ac *APICache) selectCardNetwork(from, to string) (string, error) {
+	if cardNetwork == providerMastercard || cardNetwork == providerVisa {
+		if _, _, err := ac.cardRate(cardNetwork, from, to); err != nil {
+			return "", err
+		}
+		return cardNetwork, nil
+	}
+
+	best, bestValue := "", 0.0
+	var firstErr error
+	for _, network := range []string{providerMastercard, providerVisa} {
+		rate, fee, err := ac.cardRate(network, from, to)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if value := rate * (1 - fee); value > bestValue {
+			best, bestValue = network, value
+		}
+	}
+	if best == "" {
+		return "", firstErr
+	}
+	return best, nil
+}
+
+// cardNetworkLabel is the user-facing name of a card network.
+func cardNetworkLabel(network string) string {
+	if network == providerVisa {
+		return "Visa"
+	}
+	return "Mastercard"
+}
+
+// cardRateUpdatedAt returns when network's USD rate for fiat was last fetched.
+func (ac *APICache) cardRateUpdatedAt(network, fiat string) time.Time {
+	if network != providerVisa {
+		return ac.mastercardRateUpdatedAt(fiat)
+	}
+	ac.mu.RLock()
+	defer ac.mu.RUnlock()
+	return ac.visaLastUpdate
+}
+
+// mastercardRateUpdatedAt returns when the USD rate for fiat was last fetched,
+// falling back to the last full Mastercard update.
+func (ac *APICache) mastercardRateUpdatedAt(fiat string) time.Time {
+	ac.mu.RLock()
+	defer ac.mu.RUnlock()
+	if ts, ok := ac.mastercardFetchedAt[fmt.Sprintf("USD_%s", fiat)]; ok {
+		return ts
+	}
+	return ac.mastercardLastUpdate
+}
+
+// InitialFetch loads the persisted cache and then fetches every enabled
+// provider in full, returning once all critical ones have data.
 func (ac *APICache) InitialFetch() error {
+	return ac.initialFetch(false)
+}
+
+// WarmUp is InitialFetch for a server about to take queries: Mastercard
+// fetches only the priority fiats before it returns, and the long tail of
+// currencies follows in the background. Bybit's tickers batch already covers
+// the key pairs (TONUSDT, BTCUSDT, ETHUSDT) in one request.
+func (ac *APICache) WarmUp() error {
+	if err := ac.initialFetch(true); err != nil {
+		return err
+	}
+	if providerEnabled(providerMastercard) {
+		go func() {
+			start := time.Now()
+			ac.runUpdate(providerMastercard, mastercardRefreshInterval(), ac.fetchMastercardRates, &ac.mastercardStatus, &ac.mastercardHealthy)
+			log.Printf("Mastercard warm-up of remaining currencies finished in %v", time.Since(start).Round(time.Second))
+			ac.SaveToFileAsync()
+		}()
+	}
+	return nil
+}
+
+func (ac *APICache) initialFetch(priorityOnly bool) error {
 	// Try loading from persisted cache first
 	if err := ac.LoadFromFile(); err != nil {
 		// Log but don't fail - we'll fetch fresh data
@@ -262,60 +481,108 @@ func (ac *APICache) InitialFetch() error {
 	}
 
 	var wg sync.WaitGroup
-	var errBybit, errMastercard error
-
-	wg.Add(2)
+	var errBybit, errMastercard, errVisa error
+
+	if providerEnabled(providerBybit) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errBybit = retryWithBackoff(context.Background(), ac.fetchBybitRates)
+			ac.mu.Lock()
+			if errBybit != nil {
+				ac.bybitStatus.Available = false
+				ac.bybitStatus.LastError = errBybit
+				ac.bybitStatus.ConsecutiveFails++
+				ac.bybitHealthy.Store(false)
+			} else {
+				ac.bybitStatus.Available = true
+				ac.bybitStatus.LastError = nil
+				ac.bybitStatus.ConsecutiveFails = 0
+				ac.bybitStatus.LastUpdate = time.Now()
+				ac.bybitHealthy.Store(true)
+			}
+			ac.mu.Unlock()
+		}()
+	}
 
-	go func() {
-		defer wg.Done()
-		errBybit = retryWithBackoff(context.Background(), ac.fetchBybitRates)
-		ac.mu.Lock()
-		if errBybit != nil {
-			ac.bybitStatus.Available = false
-			ac.bybitStatus.LastError = errBybit
-			ac.bybitStatus.ConsecutiveFails++
-			ac.bybitHealthy.Store(false)
-		} else {
-			ac.bybitStatus.Available = true
-			ac.bybitStatus.LastError = nil
-			ac.bybitStatus.ConsecutiveFails = 0
-			ac.bybitStatus.LastUpdate = time.Now()
-			ac.bybitHealthy.Store(true)
-		}
-		ac.mu.Unlock()
-	}()
+	if providerEnabled(providerMastercard) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Baseline first so the initial Mastercard fetch is already validated
+			if providerEnabled(providerECB) {
+				if err := ac.fetchECBBaseline(); err != nil {
+					log.Printf("Warning: ECB baseline unavailable, Mastercard rates will not be validated: %v", err)
+				}
+			}
+			fetch := ac.fetchMastercardRates
+			if priorityOnly {
+				fetch = ac.fetchMastercardPriorityRates
+			}
+			errMastercard = retryWithBackoff(context.Background(), fetch)
+			ac.mu.Lock()
+			if errMastercard != nil {
+				ac.mastercardStatus.Available = false
+				ac.mastercardStatus.LastError = errMastercard
+				ac.mastercardStatus.ConsecutiveFails++
+				ac.mastercardHealthy.Store(false)
+			} else {
+				ac.mastercardStatus.Available = true
+				ac.mastercardStatus.LastError = nil
+				ac.mastercardStatus.ConsecutiveFails = 0
+				ac.mastercardStatus.LastUpdate = time.Now()
+				ac.mastercardHealthy.Store(true)
+			}
+			ac.mu.Unlock()
+		}()
+	}
 
-	go func() {
-		defer wg.Done()
-		errMastercard = retryWithBackoff(context.Background(), ac.fetchMastercardRates)
-		ac.mu.Lock()
-		if errMastercard != nil {
-			ac.mastercardStatus.Available = false
-			ac.mastercardStatus.LastError = errMastercard
-			ac.mastercardStatus.ConsecutiveFails++
-			ac.mastercardHealthy.Store(false)
-		} else {
-			ac.mastercardStatus.Available = true
-			ac.mastercardStatus.LastError = nil
-			ac.mastercardStatus.ConsecutiveFails = 0
-			ac.mastercardStatus.LastUpdate = time.Now()
-			ac.mastercardHealthy.Store(true)
+	// A full Visa pass takes minutes; only wait for it when it is critical
+	if providerEnabled(providerVisa) {
+		visaCritical := providerCritical(providerVisa)
+		if visaCritical {
+			wg.Add(1)
 		}
-		ac.mu.Unlock()
-	}()
+		go func() {
+			if visaCritical {
+				defer wg.Done()
+			}
+			err := ac.fetchVisaRates()
+			ac.mu.Lock()
+			if err != nil {
+				ac.visaStatus.Available = false
+				ac.visaStatus.LastError = err
+				ac.visaStatus.ConsecutiveFails++
+				ac.visaHealthy.Store(false)
+				log.Printf("Warning: initial Visa fetch failed: %v", err)
+			} else {
+				ac.visaStatus.Available = true
+				ac.visaStatus.LastError = nil
+				ac.visaStatus.ConsecutiveFails = 0
+				ac.visaStatus.LastUpdate = time.Now()
+				ac.visaHealthy.Store(true)
+			}
+			if visaCritical {
+				errVisa = err
+			}
+			ac.mu.Unlock()
+		}()
+	}
 
 	wg.Wait()
 
-	ac.mu.Lock()
-	ac.whitebirdStatus.Available = true
-	ac.whitebirdHealthy.Store(true)
-	ac.mu.Unlock()
+	if providerEnabled(providerWhitebird) {
+		ac.mu.Lock()
+		ac.whitebirdStatus.Available = true
+		ac.whitebirdHealthy.Store(true)
+		ac.mu.Unlock()
+	}
 
 	// Save to file after initial fetch (async, non-blocking)
 	ac.SaveToFileAsync()
 
-	if errBybit != nil {
-		return fmt.Errorf("critical provider Bybit failed: %w", errBybit)
+	if err := criticalFailure(map[string]error{providerBybit: errBybit, providerMastercard: errMastercard, providerVisa: errVisa}); err != nil {
+		return err
 	}
 
 	ac.refreshTradeablePairs()
@@ -325,7 +592,39 @@ func (ac *APICache) InitialFetch() error {
 func (ac *APICache) IsWhitebirdAvailable() bool {
 	ac.mu.RLock()
 	defer ac.mu.RUnlock()
-	return ac.whitebirdStatus.Available && whitebirdCircuit.CanAttempt()
+	return providerEnabled(providerWhitebird) && ac.whitebirdStatus.Available && whitebirdCircuit.CanAttempt()
+}
+
+// HasCachedRates reports whether the rate snapshots of all enabled providers
+// are populated, e.g. after LoadFromFile, so short-lived callers can skip the
+// full initial fetch.
+func (ac *APICache) HasCachedRates() bool {
+	ac.mu.RLock()
+	defer ac.mu.RUnlock()
+	return (ac.bybitStatus.Available || !providerEnabled(providerBybit)) &&
+		(ac.mastercardStatus.Available || !providerEnabled(providerMastercard)) &&
+		(ac.visaStatus.Available || !providerCritical(providerVisa))
+}
+
+// criticalFailure returns an error naming the first critical provider in
+// errs that failed; failures of optional providers are only logged.
+func criticalFailure(errs map[string]error) error {
+	names := make([]string, 0, len(errs))
+	for name := range errs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		err := errs[name]
+		if err == nil {
+			continue
+		}
+		if providerCritical(name) {
+			return fmt.Errorf("critical provider %s failed: %w", name, err)
+		}
+		log.Printf("Warning: optional provider %s failed: %v", name, err)
+	}
+	return nil
 }
 
 func (ac *APICache) IsMastercardAvailable() bool {
@@ -339,7 +638,7 @@ func (ac *APICache) Shutdown() {
 		close(ac.shutdownChan)
 		ac.StopHealthMonitoring()
 
-		// Save final state before shutdown
+		// Save final state before shutdown, even right after another save
 		if err := ac.SaveToFile(); err != nil {
 			fmt.Printf("Warning: Failed to save cache on shutdown: %v\n", err)
 		}
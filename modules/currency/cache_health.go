@@ -55,10 +55,26 @@ func (cb *CircuitBreaker) GetState() string {
 	return "closed"
 }
 
+// forceOpen opens the breaker until the given time, for fault injection.
+func (cb *CircuitBreaker) forceOpen(until time.Time) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.openUntil = until
+}
+
+// reset closes the breaker and forgets past failures.
+func (cb *CircuitBreaker) reset() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.failures = 0
+	cb.openUntil = time.Time{}
+}
+
 var (
 	whitebirdCircuit  = &CircuitBreaker{}
 	bybitCircuit      = &CircuitBreaker{}
 	mastercardCircuit = &CircuitBreaker{}
+	visaCircuit       = &CircuitBreaker{}
 )
 
 func (ac *APICache) startHealthMonitoring() {
@@ -82,21 +98,32 @@ func (ac *APICache) performHealthCheck() {
 	bybitFails := ac.bybitStatus.ConsecutiveFails
 	mastercardFails := ac.mastercardStatus.ConsecutiveFails
 	whitebirdFails := ac.whitebirdStatus.ConsecutiveFails
+	visaFails := ac.visaStatus.ConsecutiveFails
 	ac.mu.RUnlock()
 
-	if bybitFails > 0 || mastercardFails > 0 || whitebirdFails > 0 {
-		log.Printf("Health check: Bybit=%d fails, Mastercard=%d fails, Whitebird=%d fails",
-			bybitFails, mastercardFails, whitebirdFails)
+	checks := []struct {
+		name    string
+		fails   int
+		circuit *CircuitBreaker
+	}{
+		{providerBybit, bybitFails, bybitCircuit},
+		{providerMastercard, mastercardFails, mastercardCircuit},
+		{providerWhitebird, whitebirdFails, whitebirdCircuit},
+		{providerVisa, visaFails, visaCircuit},
 	}
-
-	if !bybitCircuit.CanAttempt() {
-		log.Printf("Health check: Bybit circuit breaker is %s", bybitCircuit.GetState())
-	}
-	if !mastercardCircuit.CanAttempt() {
-		log.Printf("Health check: Mastercard circuit breaker is %s", mastercardCircuit.GetState())
-	}
-	if !whitebirdCircuit.CanAttempt() {
-		log.Printf("Health check: Whitebird circuit breaker is %s", whitebirdCircuit.GetState())
+	for _, c := range checks {
+		if !providerEnabled(c.name) {
+			continue
+		}
+		if c.fails > 0 {
+			log.Printf("Health check: %s (%s) has %d consecutive fails", c.name, providerCriticality[c.name], c.fails)
+		}
+		if providerCritical(c.name) && c.fails >= maxConsecutiveFailures {
+			log.Printf("CRITICAL: health check: critical provider %s has failed %d times in a row", c.name, c.fails)
+		}
+		if !c.circuit.CanAttempt() {
+			log.Printf("Health check: %s circuit breaker is %s", c.name, c.circuit.GetState())
+		}
 	}
 }
 
@@ -0,0 +1,91 @@
+package currency
+
+import (
+	"log"
+	"sort"
+	"time"
+)
+
+// orderbookLevelBytes approximates one resident book level: the two-element
+// []float64 backing array plus its slice header.
+const orderbookLevelBytes = 16 + 24
+
+func bookBytes(rate *BybitRate) int {
+	return (len(rate.OrderBookBids) + len(rate.OrderBookAsks)) * orderbookLevelBytes
+}
+
+// truncateBook returns rate with at most levels levels per side. Rates are
+// shared with readers, so a shorter book is a copy, never an in-place cut.
+func truncateBook(rate *BybitRate, levels int) *BybitRate {
+	if len(rate.OrderBookBids) <= levels && len(rate.OrderBookAsks) <= levels {
+		return rate
+	}
+	trimmed := *rate
+	if len(trimmed.OrderBookBids) > levels {
+		trimmed.OrderBookBids = append([][]float64(nil), trimmed.OrderBookBids[:levels]...)
+	}
+	if len(trimmed.OrderBookAsks) > levels {
+		trimmed.OrderBookAsks = append([][]float64(nil), trimmed.OrderBookAsks[:levels]...)
+	}
+	if trimmed.DepthLimit > levels {
+		trimmed.DepthLimit = levels
+	}
+	return &trimmed
+}
+
+// enforceOrderbookBudgetLocked keeps the cached order books within
+// ORDERBOOK_MEMORY_BUDGET_MB by cutting the books fetched longest ago back to
+// the best level, as the tickers batch delivers them. Those symbols fetch
+// depth again on their next conversion that needs it. keep, the book just
+// fetched for a conversion in progress, is never cut. Caller holds ac.mu.
+func (ac *APICache) enforceOrderbookBudgetLocked(keep string) {
+	total := 0
+	var deep []string
+	for symbol, rate := range ac.bybitRates {
+		if rate == nil {
+			continue
+		}
+		total += bookBytes(rate)
+		if symbol != keep && (len(rate.OrderBookBids) > 1 || len(rate.OrderBookAsks) > 1) {
+			deep = append(deep, symbol)
+		}
+	}
+	if total <= orderbookMemoryBudget {
+		return
+	}
+
+	sort.Slice(deep, func(i, j int) bool {
+		return ac.bybitRates[deep[i]].DepthUpdate.Before(ac.bybitRates[deep[j]].DepthUpdate)
+	})
+	evicted := 0
+	for _, symbol := range deep {
+		rate := ac.bybitRates[symbol]
+		trimmed := truncateBook(rate, 1)
+		trimmed.DepthUpdate, trimmed.DepthLimit = time.Time{}, 0
+		total -= bookBytes(rate) - bookBytes(trimmed)
+		ac.bybitRates[symbol] = trimmed
+		evicted++
+		if total <= orderbookMemoryBudget {
+			break
+		}
+	}
+	log.Printf("Order book cache over budget: cut %d books to top of book (%d KB resident)", evicted, total/1024)
+}
+
+// CacheSizes counts the entries of the cache's maps that grow with use, so
+// leak checks can watch them. Order book memory has its own budget.
+func (ac *APICache) CacheSizes() map[string]int {
+	ac.mu.RLock()
+	sizes := map[string]int{
+		"bybit_rates":      len(ac.bybitRates),
+		"mastercard_rates": len(ac.mastercardRates),
+		"visa_rates":       len(ac.visaRates),
+		"index_prices":     len(ac.indexPrices),
+		"quarantine":       len(ac.quarantine),
+		"symbols_fetching": len(ac.symbolsFetching),
+	}
+	ac.mu.RUnlock()
+	sizes["conversions"] = globalConversionCache.len()
+	sizes["whitebird_quotes"] = whitebirdQuoteCache.len()
+	return sizes
+}
@@ -0,0 +1,50 @@
+package currency
+
+import "sync/atomic"
+
+// Cache access counters: each accessor counts whether it answered from
+// memory (hit) or came up empty (miss). A Whitebird or conversion cache miss
+// goes on to compute or fetch; a Bybit or Mastercard miss means the rate was
+// not loaded, so the conversion fails or waits on a lazy load.
+
+type accessCounter struct {
+	name         string
+	hits, misses atomic.Int64
+}
+
+func (c *accessCounter) record(hit bool) {
+	if c == nil {
+		return
+	}
+	if hit {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+}
+
+var (
+	bybitRateAccess       = &accessCounter{name: "bybit_rate"}
+	mastercardRateAccess  = &accessCounter{name: "mastercard_rate"}
+	whitebirdQuoteAccess  = &accessCounter{name: "whitebird_quote"}
+	conversionCacheAccess = &accessCounter{name: "conversion"}
+
+	cacheAccessCounters = []*accessCounter{bybitRateAccess, mastercardRateAccess, whitebirdQuoteAccess, conversionCacheAccess}
+)
+
+// CacheAccess is one cache accessor's hit and miss counts since start.
+type CacheAccess struct {
+	Accessor string `json:"accessor"`
+	Hits     int64  `json:"hits"`
+	Misses   int64  `json:"misses"`
+}
+
+// CacheAccessReport returns the hit and miss counts of every instrumented
+// cache accessor.
+func CacheAccessReport() []CacheAccess {
+	report := make([]CacheAccess, len(cacheAccessCounters))
+	for i, c := range cacheAccessCounters {
+		report[i] = CacheAccess{Accessor: c.name, Hits: c.hits.Load(), Misses: c.misses.Load()}
+	}
+	return report
+}
@@ -1,6 +1,7 @@
 package currency
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -10,10 +11,17 @@ import (
 	"time"
 )
 
-const (
-	persistenceFilePath = "data/exchange_rates.json"
-	persistenceVersion  = "1.0"
-)
+const persistenceVersion = "1.0"
+
+// persistenceFilePath is where the rates snapshot is kept.
+var persistenceFilePath = "data/exchange_rates.json"
+
+// SetPersistencePath moves the rates snapshot to path, e.g. a temporary
+// directory for a run that must leave the real one alone. Call it before
+// the cache is loaded or saved.
+func SetPersistencePath(path string) {
+	persistenceFilePath = path
+}
 
 type PersistedCache struct {
 	Version          string                `json:"version"`
@@ -22,12 +30,25 @@ type PersistedCache struct {
 	MastercardUpdate time.Time             `json:"mastercard_last_update"`
 	BybitRates       map[string]*BybitRate `json:"bybit_rates"`
 	MastercardRates  map[string]float64    `json:"mastercard_rates"`
+
+	// Per-rate timestamps let the Mastercard rotation resume after a restart
+	MastercardFetchedAt map[string]time.Time `json:"mastercard_fetched_at,omitempty"`
+	MastercardChangedAt map[string]time.Time `json:"mastercard_changed_at,omitempty"`
+
+	VisaUpdate time.Time          `json:"visa_last_update,omitempty"`
+	VisaRates  map[string]float64 `json:"visa_rates,omitempty"`
+
+	BybitVolumesUpdate time.Time          `json:"bybit_volumes_last_update,omitempty"`
+	BybitVolumes       map[string]float64 `json:"bybit_volumes_24h,omitempty"`
 }
 
+// Saves go through one writer goroutine per cache (runSnapshotWriter), so
+// a burst of rate updates becomes one write at most every minSaveInterval.
+// A failed write is retried with a growing delay, up to maxSaveBackoff.
 var (
-	saveMutex       sync.Mutex
-	lastSaveTime    time.Time
-	minSaveInterval = 30 * time.Second // Don't save more often than every 30 seconds
+	saveMutex       sync.Mutex // serializes writes to persistenceFilePath
+	minSaveInterval = 30 * time.Second
+	maxSaveBackoff  = 10 * time.Minute
 )
 
 // LoadFromFile attempts to load previously saved exchange rates from disk
@@ -35,19 +56,23 @@ func (ac *APICache) LoadFromFile() error {
 	ac.mu.Lock()
 	defer ac.mu.Unlock()
 
-	file, err := os.Open(persistenceFilePath)
+	data, err := os.ReadFile(persistenceFilePath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			log.Println("No persisted cache file found, will fetch fresh data")
 			return nil
 		}
-		return fmt.Errorf("failed to open cache file: %w", err)
+		return fmt.Errorf("failed to read cache file: %w", err)
+	}
+
+	// A snapshot that fails its signature may have been tampered with
+	if err := verifySnapshot(persistenceFilePath, data); err != nil {
+		log.Printf("Warning: Ignoring cache file %s: %v; will fetch fresh data", persistenceFilePath, err)
+		return nil
 	}
-	defer file.Close()
 
 	var persisted PersistedCache
-	decoder := json.NewDecoder(file)
-	if err := decoder.Decode(&persisted); err != nil {
+	if err := json.Unmarshal(data, &persisted); err != nil {
 		return fmt.Errorf("failed to decode cache file: %w", err)
 	}
 
@@ -66,11 +91,10 @@ func (ac *APICache) LoadFromFile() error {
 	// Load Bybit rates
 	if len(persisted.BybitRates) > 0 {
 		ac.bybitRates = persisted.BybitRates
-		ac.lastBybitRates = make(map[string]*BybitRate)
-		for k, v := range persisted.BybitRates {
-			ac.lastBybitRates[k] = v
+		for k := range persisted.BybitRates {
 			ac.tradeablePairs[k] = true
 		}
+		ac.enforceOrderbookBudgetLocked("")
 		ac.bybitLastUpdate = persisted.BybitLastUpdate
 		ac.bybitStatus.Available = true
 		ac.bybitStatus.LastUpdate = persisted.BybitLastUpdate
@@ -86,6 +110,12 @@ func (ac *APICache) LoadFromFile() error {
 		for k, v := range persisted.MastercardRates {
 			ac.lastMastercardRates[k] = v
 		}
+		for k, v := range persisted.MastercardFetchedAt {
+			ac.mastercardFetchedAt[k] = v
+		}
+		for k, v := range persisted.MastercardChangedAt {
+			ac.mastercardChangedAt[k] = v
+		}
 		ac.mastercardLastUpdate = persisted.MastercardUpdate
 		ac.mastercardStatus.Available = true
 		ac.mastercardStatus.LastUpdate = persisted.MastercardUpdate
@@ -94,20 +124,32 @@ func (ac *APICache) LoadFromFile() error {
 			len(ac.mastercardRates), time.Since(persisted.MastercardUpdate))
 	}
 
+	// Load Visa rates
+	if len(persisted.VisaRates) > 0 {
+		ac.visaRates = persisted.VisaRates
+		ac.visaLastUpdate = persisted.VisaUpdate
+		ac.visaStatus.Available = true
+		ac.visaStatus.LastUpdate = persisted.VisaUpdate
+		ac.visaHealthy.Store(true)
+		log.Printf("Loaded %d Visa rates from cache (last updated: %v ago)",
+			len(ac.visaRates), time.Since(persisted.VisaUpdate))
+	}
+
+	// Load Bybit 24h volumes; the next Bybit refresh renews them once they age out
+	if len(persisted.BybitVolumes) > 0 {
+		ac.bybitVolumes = persisted.BybitVolumes
+		ac.bybitVolumesUpdate = persisted.BybitVolumesUpdate
+	}
+
 	log.Printf("Successfully loaded exchange rates from cache file (saved %v ago)", time.Since(persisted.LastUpdated))
 	return nil
 }
 
-// SaveToFile saves current exchange rates to disk
+// SaveToFile saves current exchange rates to disk now. Everything but
+// shutdown should use SaveToFileAsync.
 func (ac *APICache) SaveToFile() error {
-	// Rate limiting: don't save too frequently
 	saveMutex.Lock()
-	if time.Since(lastSaveTime) < minSaveInterval {
-		saveMutex.Unlock()
-		return nil // Skip save, too soon
-	}
-	lastSaveTime = time.Now()
-	saveMutex.Unlock()
+	defer saveMutex.Unlock()
 
 	ac.mu.RLock()
 
@@ -119,12 +161,21 @@ func (ac *APICache) SaveToFile() error {
 		MastercardUpdate: ac.mastercardLastUpdate,
 		BybitRates:       make(map[string]*BybitRate),
 		MastercardRates:  make(map[string]float64),
+
+		MastercardFetchedAt: make(map[string]time.Time),
+		MastercardChangedAt: make(map[string]time.Time),
+
+		VisaUpdate: ac.visaLastUpdate,
+		VisaRates:  make(map[string]float64),
+
+		BybitVolumesUpdate: ac.bybitVolumesUpdate,
+		BybitVolumes:       make(map[string]float64, len(ac.bybitVolumes)),
 	}
 
-	// Copy Bybit rates
+	// Copy Bybit rates, deep books cut to shallow depth to keep the file small
 	for k, v := range ac.bybitRates {
 		if v != nil {
-			persisted.BybitRates[k] = v
+			persisted.BybitRates[k] = truncateBook(v, bybitShallowDepth)
 		}
 	}
 
@@ -132,6 +183,20 @@ func (ac *APICache) SaveToFile() error {
 	for k, v := range ac.mastercardRates {
 		persisted.MastercardRates[k] = v
 	}
+	for k, v := range ac.mastercardFetchedAt {
+		persisted.MastercardFetchedAt[k] = v
+	}
+	for k, v := range ac.mastercardChangedAt {
+		persisted.MastercardChangedAt[k] = v
+	}
+
+	for k, v := range ac.visaRates {
+		persisted.VisaRates[k] = v
+	}
+
+	for k, v := range ac.bybitVolumes {
+		persisted.BybitVolumes[k] = v
+	}
 
 	ac.mu.RUnlock()
 
@@ -141,44 +206,115 @@ func (ac *APICache) SaveToFile() error {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	// Write to temporary file first
-	tempFile := persistenceFilePath + ".tmp"
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(persisted); err != nil {
+		return fmt.Errorf("failed to encode cache: %w", err)
+	}
+
+	// A crash between the two renames leaves a snapshot and signature that
+	// don't match, which the next load ignores
+	if sig := signSnapshot(buf.Bytes()); sig != "" {
+		if err := writeFileAtomic(snapshotSignaturePath(persistenceFilePath), []byte(sig+"\n")); err != nil {
+			return err
+		}
+	}
+	if err := writeFileAtomic(persistenceFilePath, buf.Bytes()); err != nil {
+		return err
+	}
+
+	log.Printf("Saved %d Bybit rates and %d Mastercard rates to %s",
+		len(persisted.BybitRates), len(persisted.MastercardRates), persistenceFilePath)
+
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file, syncs it and renames it
+// over path, so a crash leaves either the old file or the new one.
+func writeFileAtomic(path string, data []byte) error {
+	tempFile := path + ".tmp"
 	file, err := os.Create(tempFile)
 	if err != nil {
 		return fmt.Errorf("failed to create temp file: %w", err)
 	}
-
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-
-	if err := encoder.Encode(persisted); err != nil {
+	if _, err := file.Write(data); err != nil {
 		file.Close()
 		os.Remove(tempFile)
-		return fmt.Errorf("failed to encode cache: %w", err)
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to sync temp file: %w", err)
 	}
-
 	if err := file.Close(); err != nil {
 		os.Remove(tempFile)
 		return fmt.Errorf("failed to close temp file: %w", err)
 	}
-
-	// Atomic rename
-	if err := os.Rename(tempFile, persistenceFilePath); err != nil {
+	if err := os.Rename(tempFile, path); err != nil {
 		os.Remove(tempFile)
 		return fmt.Errorf("failed to rename temp file: %w", err)
 	}
-
-	log.Printf("Saved %d Bybit rates and %d Mastercard rates to %s",
-		len(persisted.BybitRates), len(persisted.MastercardRates), persistenceFilePath)
-
+	// The rename itself is only durable once the directory is synced
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
 	return nil
 }
 
-// SaveToFileAsync saves to file in a goroutine, logging errors but not blocking
+// SaveToFileAsync asks the cache's writer goroutine for a save and returns
+// at once. Requests made while a save is pending are covered by it.
 func (ac *APICache) SaveToFileAsync() {
-	go func() {
+	ac.saveWriter.Do(func() { go ac.runSnapshotWriter() })
+	select {
+	case ac.saveRequests <- struct{}{}:
+	default:
+		// A save is already pending
+	}
+}
+
+// runSnapshotWriter writes the cache to disk on request, no sooner than
+// minSaveInterval after the previous write, until shutdown. Shutdown writes
+// the final state itself.
+func (ac *APICache) runSnapshotWriter() {
+	var lastWrite, retryAt time.Time
+	backoff := 2 * minSaveInterval
+	for {
+		select {
+		case <-ac.saveRequests:
+		case <-ac.shutdownChan:
+			return
+		}
+
+		wait := time.Until(lastWrite.Add(minSaveInterval))
+		if untilRetry := time.Until(retryAt); untilRetry > wait {
+			wait = untilRetry
+		}
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-ac.shutdownChan:
+				timer.Stop()
+				return
+			}
+		}
+		// Requests that came in while waiting are covered by this write
+		select {
+		case <-ac.saveRequests:
+		default:
+		}
+
+		lastWrite = time.Now()
 		if err := ac.SaveToFile(); err != nil {
-			log.Printf("Warning: Failed to save cache to file: %v", err)
+			log.Printf("Warning: Failed to save cache to file, retrying in %v: %v", backoff, err)
+			retryAt = time.Now().Add(backoff)
+			backoff = min(2*backoff, maxSaveBackoff)
+			ac.SaveToFileAsync()
+			continue
 		}
-	}()
+		retryAt, backoff = time.Time{}, 2*minSaveInterval
+	}
 }
@@ -7,59 +7,156 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"answerflow/cron"
+	"answerflow/events"
 )
 
 func (ac *APICache) StartBackgroundUpdaters() {
 	log.Println("Starting background currency updaters...")
-	go ac.updateLoop("bybit", backgroundUpdateTTL, ac.fetchBybitRates, &ac.bybitStatus, &ac.bybitHealthy)
-	go ac.updateLoop("mastercard", backgroundUpdateTTL*3, ac.fetchMastercardRates, &ac.mastercardStatus, &ac.mastercardHealthy)
+	// Only the serving cache persists; short-lived caches (doctor, CLI) don't
+	// start updaters
+	unsubscribe := events.Subscribe(events.RateUpdated, func(events.Event) { ac.SaveToFileAsync() })
+	go func() {
+		<-ac.shutdownChan
+		unsubscribe()
+	}()
+	if providerEnabled(providerBybit) {
+		go ac.updateLoop(providerBybit, refreshPolicies[assetCrypto].RefreshInterval, ac.fetchBybitRates, &ac.bybitStatus, &ac.bybitHealthy)
+	}
+	if providerEnabled(providerMastercard) {
+		go ac.updateLoop(providerMastercard, mastercardRefreshInterval(), ac.fetchMastercardRates, &ac.mastercardStatus, &ac.mastercardHealthy)
+	}
+	if providerEnabled(providerVisa) {
+		go ac.updateLoop(providerVisa, visaRefreshInterval, ac.fetchVisaRates, &ac.visaStatus, &ac.visaHealthy)
+	}
+	if providerEnabled(providerECB) {
+		go ac.updateLoop(providerECB, ecbRefreshInterval, ac.fetchECBBaseline, &ac.ecbStatus, &ac.ecbHealthy)
+	}
+	if providerEnabled(providerCBR) {
+		// Nothing fetches CBR rates at startup, so the loop starts with one
+		go func() {
+			ac.runUpdate(providerCBR, cbrRefreshInterval, ac.fetchCBRRates, &ac.cbrStatus, &ac.cbrHealthy)
+			ac.updateLoop(providerCBR, cbrRefreshInterval, ac.fetchCBRRates, &ac.cbrStatus, &ac.cbrHealthy)
+		}()
+	}
+	if providerEnabled(providerWhitebird) && whitebirdProbeInterval > 0 {
+		go func() {
+			ac.runUpdate(providerWhitebird, whitebirdProbeInterval, ac.probeWhitebird, &ac.whitebirdStatus, &ac.whitebirdHealthy)
+			ac.updateLoop(providerWhitebird, whitebirdProbeInterval, ac.probeWhitebird, &ac.whitebirdStatus, &ac.whitebirdHealthy)
+		}()
+	}
+	if providerEnabled(providerWhitebird) && whitebirdAnchorInterval > 0 {
+		go ac.runWhitebirdAnchors()
+	}
+	if providerEnabled(providerWhitebird) && providerEnabled(providerBybit) && feeCalibrationInterval > 0 {
+		go ac.runFeeCalibration()
+	}
 	go ac.startHealthMonitoring()
+	go ac.runJanitor()
 }
 
 func (ac *APICache) updateLoop(name string, interval time.Duration, fetchFn func() error, status *ProviderStatus, healthFlag *atomic.Bool) {
+	if schedule, ok := refreshSchedules[name]; ok {
+		if ac.scheduledUpdateLoop(name, schedule, interval, fetchFn, status, healthFlag) {
+			return
+		}
+		log.Printf("Warning: %s refresh schedule never fires, refreshing every %v instead", name, interval)
+	}
+
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			ctx, cancel := context.WithTimeout(context.Background(), interval/2)
-			err := retryWithBackoff(ctx, fetchFn)
-			cancel()
-
-			ac.mu.Lock()
-			if err != nil {
-				status.Available = false
-				status.LastError = err
-				status.ConsecutiveFails++
-				healthFlag.Store(false)
-
-				if status.ConsecutiveFails >= maxConsecutiveFailures {
-					log.Printf("CRITICAL: %s update failed %d consecutive times: %v", name, status.ConsecutiveFails, err)
-				}
-			} else {
-				wasDown := status.ConsecutiveFails > 0
-				status.Available = true
-				status.LastError = nil
-				status.ConsecutiveFails = 0
-				status.LastUpdate = time.Now()
-				healthFlag.Store(true)
-
-				if wasDown {
-					log.Printf("Info: %s service recovered", name)
-				}
-			}
-			ac.mu.Unlock()
+		case <-ac.idleWakeChan():
+			// First query after an idle pause; the data is at least a tick old
+			ticker.Reset(interval)
+		case <-ac.shutdownChan:
+			log.Printf("Shutting down %s update loop", name)
+			return
+		}
+		if ac.suspendedForIdle() {
+			continue
+		}
+		ac.runUpdate(name, interval, fetchFn, status, healthFlag)
+	}
+}
 
-			// Save to file after successful update
-			if err == nil {
-				ac.SaveToFileAsync()
-			}
+// scheduledUpdateLoop refreshes at the times schedule names instead of
+// every interval, which still bounds each refresh's timeout. It returns
+// false straight away if the schedule has no next time, and true on
+// shutdown.
+func (ac *APICache) scheduledUpdateLoop(name string, schedule *cron.Schedule, interval time.Duration, fetchFn func() error, status *ProviderStatus, healthFlag *atomic.Bool) bool {
+	next := schedule.Next(time.Now())
+	if next.IsZero() {
+		return false
+	}
+	log.Printf("%s refreshes on schedule, next at %s", name, next.Format(time.RFC3339))
+	timer := time.NewTimer(time.Until(next))
+	defer timer.Stop()
 
+	for {
+		select {
+		case <-timer.C:
+			if next = schedule.Next(time.Now()); next.IsZero() {
+				return false
+			}
+			timer.Reset(time.Until(next))
+		case <-ac.idleWakeChan():
+			// First query after an idle pause; refresh now, keep the schedule
 		case <-ac.shutdownChan:
 			log.Printf("Shutting down %s update loop", name)
-			return
+			return true
+		}
+		if ac.suspendedForIdle() {
+			continue
 		}
+		ac.runUpdate(name, interval, fetchFn, status, healthFlag)
+	}
+}
+
+// runUpdate performs one refresh of an update loop and records the outcome.
+func (ac *APICache) runUpdate(name string, interval time.Duration, fetchFn func() error, status *ProviderStatus, healthFlag *atomic.Bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), interval/2)
+	err := retryWithBackoff(ctx, fetchFn)
+	cancel()
+
+	// Events go out after the lock is released; subscribers may read the cache
+	var published []events.Event
+	ac.mu.Lock()
+	if err != nil {
+		status.Available = false
+		status.LastError = err
+		status.ConsecutiveFails++
+		healthFlag.Store(false)
+
+		if status.ConsecutiveFails >= maxConsecutiveFailures {
+			log.Printf("CRITICAL: %s update failed %d consecutive times: %v", name, status.ConsecutiveFails, err)
+			published = append(published, events.Event{Kind: events.ProviderDown, Provider: name, Err: err, Fails: status.ConsecutiveFails})
+		}
+	} else {
+		wasDown := status.ConsecutiveFails > 0
+		alerted := status.ConsecutiveFails >= maxConsecutiveFailures
+		status.Available = true
+		status.LastError = nil
+		status.ConsecutiveFails = 0
+		status.LastUpdate = time.Now()
+		healthFlag.Store(true)
+
+		if wasDown {
+			log.Printf("Info: %s service recovered", name)
+		}
+		if alerted {
+			published = append(published, events.Event{Kind: events.ProviderRecovered, Provider: name})
+		}
+		published = append(published, events.Event{Kind: events.RateUpdated, Provider: name})
+	}
+	ac.mu.Unlock()
+
+	for _, e := range published {
+		events.Publish(e)
 	}
 }
 
@@ -77,21 +174,28 @@ func (ac *APICache) ForceRefresh() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
 	defer cancel()
 
-	wg.Add(2)
-	go func() {
-		defer wg.Done()
-		err := retryWithBackoff(ctx, ac.fetchBybitRates)
-		mu.Lock()
-		errBybit = err
-		mu.Unlock()
-	}()
-	go func() {
-		defer wg.Done()
-		err := retryWithBackoff(ctx, ac.fetchMastercardRates)
-		mu.Lock()
-		errMastercard = err
-		mu.Unlock()
-	}()
+	if providerEnabled(providerBybit) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := retryWithBackoff(ctx, ac.fetchBybitRates)
+			mu.Lock()
+			errBybit = err
+			mu.Unlock()
+		}()
+	}
+	if providerEnabled(providerMastercard) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := retryWithBackoff(ctx, ac.fetchMastercardRates)
+			mu.Lock()
+			errMastercard = err
+			mu.Unlock()
+		}()
+	}
+
+	// Visa publishes daily and a full pass takes minutes; its own loop keeps it fresh
 
 	wg.Wait()
 
@@ -100,11 +204,5 @@ func (ac *APICache) ForceRefresh() error {
 		ac.SaveToFileAsync()
 	}
 
-	if errBybit != nil {
-		return fmt.Errorf("failed to force refresh bybit rates: %w", errBybit)
-	}
-	if errMastercard != nil {
-		return fmt.Errorf("failed to force refresh mastercard rates: %w", errMastercard)
-	}
-	return nil
+	return criticalFailure(map[string]error{providerBybit: errBybit, providerMastercard: errMastercard})
 }
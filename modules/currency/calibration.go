@@ -0,0 +1,199 @@
+package currency
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Whitebird's effective fee drifts, so the rates we model between quotes
+// (anchor interpolation, the last rate when the quote budget runs out) drift
+// from what Whitebird would actually quote. Every FEE_CALIBRATION_INTERVAL a
+// calibration run prices a few FEE_CALIBRATION_AMOUNTS (RUB) end to end to
+// USD both ways, from the model and from a live quote, and sets a correction
+// factor for modeled RUB->TON rates to the median quoted/modeled ratio. The
+// factor stays within FEE_CALIBRATION_MAX_CORRECTION of 1, so a bad run
+// can't skew results much. 0 turns calibration off.
+var (
+	feeCalibrationInterval      = getEnvDurationOrDefault("FEE_CALIBRATION_INTERVAL", 24*time.Hour)
+	feeCalibrationAmounts       = anchorAmountsSetting("FEE_CALIBRATION_AMOUNTS", []float64{3000, 30000, 300000})
+	feeCalibrationMaxCorrection = getEnvFloatOrDefault("FEE_CALIBRATION_MAX_CORRECTION", 0.03)
+)
+
+// feeCalibrationDelay leaves time for anchors and rates to load before the
+// first run.
+const feeCalibrationDelay = 10 * time.Minute
+
+// FeeCalibration is the outcome of the latest calibration run.
+type FeeCalibration struct {
+	Factor       float64             `json:"factor"`
+	Clamped      bool                `json:"clamped,omitempty"`
+	Samples      []CalibrationSample `json:"samples,omitempty"`
+	CalibratedAt time.Time           `json:"calibrated_at,omitempty"`
+	Error        string              `json:"error,omitempty"`
+}
+
+// CalibrationSample compares the modeled and quoted USD output for one
+// RUB amount.
+type CalibrationSample struct {
+	RUB        float64 `json:"rub"`
+	ModeledUSD float64 `json:"modeled_usd"`
+	QuotedUSD  float64 `json:"quoted_usd"`
+	Ratio      float64 `json:"ratio"`
+}
+
+var feeCalibration = struct {
+	mu    sync.RWMutex
+	state FeeCalibration
+}{state: FeeCalibration{Factor: 1}}
+
+// FeeCalibration reports the current correction factor and the run that
+// set it.
+func (ac *APICache) FeeCalibration() FeeCalibration {
+	feeCalibration.mu.RLock()
+	defer feeCalibration.mu.RUnlock()
+	state := feeCalibration.state
+	state.Samples = append([]CalibrationSample(nil), state.Samples...)
+	return state
+}
+
+// whitebirdCorrection is the factor applied to modeled from -> to rates;
+// only RUB->TON is calibrated.
+func whitebirdCorrection(from, to string) float64 {
+	if from != CurrencyRUB || to != CurrencyTON {
+		return 1
+	}
+	feeCalibration.mu.RLock()
+	defer feeCalibration.mu.RUnlock()
+	return feeCalibration.state.Factor
+}
+
+// modeledWhitebirdRate is the uncorrected rate the model gives amount
+// without a quote of its own.
+func modeledWhitebirdRate(from, to string, amount float64) (float64, bool) {
+	if rate, ok := interpolatedWhitebirdRate(from, to, amount); ok {
+		return rate, true
+	}
+	if rate, ok := whitebirdLastRate.Load(from + "/" + to); ok {
+		return rate.(float64), true
+	}
+	return 0, false
+}
+
+// tonToUSD prices ton received from Whitebird the way the RUB->USD route
+// does past Whitebird: withdrawal to Bybit, a sale at the best bid, and the
+// card conversion to USD.
+func (ac *APICache) tonToUSD(ton float64) (float64, error) {
+	rate, err := ac.GetBybitRate("TONUSDT")
+	if err != nil {
+		return 0, err
+	}
+	net := applyFixedFee(ton, feeTONWithdrawToBybit)
+	return net * rate.BestBid * (1 - feeBybitTrade) * (1 - feeUSDTToUSD), nil
+}
+
+// runFeeCalibration calibrates every feeCalibrationInterval until shutdown,
+// skipping runs while idle.
+func (ac *APICache) runFeeCalibration() {
+	timer := time.NewTimer(feeCalibrationDelay)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+		case <-ac.shutdownChan:
+			return
+		}
+		timer.Reset(feeCalibrationInterval)
+		if ac.suspendedForIdle() {
+			continue
+		}
+		ac.calibrateFees()
+	}
+}
+
+// calibrateFees runs one calibration. Samples the model can't price, or
+// Whitebird won't quote, are left out; a run without samples keeps the
+// previous factor.
+func (ac *APICache) calibrateFees() {
+	var samples []CalibrationSample
+	var lastErr error
+	for _, amount := range feeCalibrationAmounts {
+		sample, err := ac.calibrationSample(amount)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		samples = append(samples, sample)
+	}
+
+	feeCalibration.mu.Lock()
+	defer feeCalibration.mu.Unlock()
+	state := &feeCalibration.state
+	if len(samples) == 0 {
+		state.Error = fmt.Sprintf("no samples: %v", lastErr)
+		log.Printf("Warning: fee calibration kept factor %.4f: %s", state.Factor, state.Error)
+		return
+	}
+
+	ratios := make([]float64, len(samples))
+	for i, s := range samples {
+		ratios[i] = s.Ratio
+	}
+	sort.Float64s(ratios)
+	median := ratios[len(ratios)/2]
+	if len(ratios)%2 == 0 {
+		median = (ratios[len(ratios)/2-1] + ratios[len(ratios)/2]) / 2
+	}
+	factor := math.Max(1-feeCalibrationMaxCorrection, math.Min(1+feeCalibrationMaxCorrection, median))
+
+	previous := state.Factor
+	*state = FeeCalibration{
+		Factor:       factor,
+		Clamped:      factor != median,
+		Samples:      samples,
+		CalibratedAt: time.Now(),
+	}
+	if lastErr != nil {
+		state.Error = lastErr.Error()
+	}
+	if state.Clamped {
+		log.Printf("Warning: fee calibration measured %.4f, outside the allowed correction; factor %.4f -> %.4f", median, previous, factor)
+		return
+	}
+	log.Printf("Fee calibration: factor %.4f -> %.4f from %d samples", previous, factor, len(samples))
+}
+
+// calibrationSample prices amount RUB in USD from the model and from a live
+// Whitebird quote.
+func (ac *APICache) calibrationSample(amount float64) (CalibrationSample, error) {
+	rate, ok := modeledWhitebirdRate(CurrencyRUB, CurrencyTON, amount)
+	if !ok {
+		return CalibrationSample{}, fmt.Errorf("no modeled rate for %v RUB", amount)
+	}
+	if !whitebirdCircuit.CanAttempt() {
+		return CalibrationSample{}, fmt.Errorf("whitebird service temporarily unavailable")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), whitebirdAPITimeout)
+	quotedTON, err := ac.fetchSingleWhitebirdConversion(ctx, CurrencyRUB, CurrencyTON, amount)
+	cancel()
+	if err != nil {
+		return CalibrationSample{}, fmt.Errorf("quote for %v RUB: %w", amount, err)
+	}
+
+	modeledUSD, err := ac.tonToUSD(amount * rate)
+	if err != nil {
+		return CalibrationSample{}, err
+	}
+	quotedUSD, err := ac.tonToUSD(quotedTON)
+	if err != nil {
+		return CalibrationSample{}, err
+	}
+	if !(modeledUSD > 0) || !(quotedUSD > 0) {
+		return CalibrationSample{}, fmt.Errorf("%v RUB is too small to calibrate with", amount)
+	}
+	return CalibrationSample{RUB: amount, ModeledUSD: modeledUSD, QuotedUSD: quotedUSD, Ratio: quotedUSD / modeledUSD}, nil
+}
@@ -0,0 +1,102 @@
+package currency
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"answerflow/commontypes"
+)
+
+// SupportedCurrency is one code the converter accepts.
+type SupportedCurrency struct {
+	Code string
+	Name string
+	Kind string // "fiat" or "crypto"
+}
+
+// SupportedCurrencies lists the codes this deployment converts: fiats, then
+// cryptocurrencies unless in fiat-only mode, each sorted by code.
+func SupportedCurrencies() []SupportedCurrency {
+	fiats := append([]string(nil), supportedFiats...)
+	if !isFiatCode(CurrencyRUB) {
+		fiats = append(fiats, CurrencyRUB)
+	}
+	sort.Strings(fiats)
+	out := make([]SupportedCurrency, 0, len(fiats)+len(supportedCryptos))
+	for _, code := range fiats {
+		out = append(out, SupportedCurrency{Code: code, Name: currencyName(code), Kind: "fiat"})
+	}
+	if fiatOnlyMode {
+		return out
+	}
+
+	cryptos := append([]string(nil), supportedCryptos...)
+	sort.Strings(cryptos)
+	for _, code := range cryptos {
+		out = append(out, SupportedCurrency{Code: code, Name: "Cryptocurrency", Kind: "crypto"})
+	}
+	return out
+}
+
+// Fee is one charge conversions are modelled with, for display.
+type Fee struct {
+	Name   string
+	Detail string
+}
+
+// ActiveFees describes the fees conversions are priced with under the
+// current configuration and the requester's settings in ctx (USDT network,
+// personal markup).
+func ActiveFees(ctx context.Context) []Fee {
+	var fees []Fee
+	switch cardNetwork {
+	case providerMastercard, providerVisa:
+		fees = append(fees, Fee{cardNetworkLabel(cardNetwork) + " fiat conversion", formatFeePercent(cardNetworkFee(cardNetwork))})
+	case cardNetworkBoth:
+		fees = append(fees, Fee{"Card fiat conversion", fmt.Sprintf("%s Mastercard or %s Visa, the better priced, the other shown too",
+			formatFeePercent(feeMastercard), formatFeePercent(feeVisa))})
+	default:
+		fees = append(fees, Fee{"Card fiat conversion", fmt.Sprintf("%s Mastercard or %s Visa, whichever gives more",
+			formatFeePercent(feeMastercard), formatFeePercent(feeVisa))})
+	}
+	if fiatOnlyMode {
+		return append(fees, personalFeeEntries(ctx)...)
+	}
+
+	fees = append(fees,
+		Fee{"Bybit spot trade", formatFeePercent(feeBybitTrade)},
+		Fee{"Bybit card USDT → USD", formatFeePercent(feeUSDTToUSD)},
+		Fee{"Bybit card USD → USDT", formatFeePercent(feeUSDToUSDT)},
+	)
+	if rubBridgeEnabled {
+		fees = append(fees,
+			Fee{"Whitebird RUB ↔ TON", "included in Whitebird's quotes"},
+			Fee{"TON withdrawal", fmt.Sprintf("%s TON to Bybit, %s TON to Whitebird",
+				formatRate(feeTONWithdrawToBybit), formatRate(feeTONWithdrawToWhitebird))},
+		)
+	}
+	if network, fee, ok := usdtNetwork(ctx); ok {
+		fees = append(fees, Fee{"USDT withdrawal (" + network + ")", formatRate(fee) + " USDT"})
+	}
+	return append(fees, personalFeeEntries(ctx)...)
+}
+
+func personalFeeEntries(ctx context.Context) []Fee {
+	if fee, ok := commontypes.PersonalFeeFromContext(ctx); ok && fee != 0 {
+		return []Fee{{"Your bank markup", "+" + strconv.FormatFloat(fee, 'f', -1, 64) + "%"}}
+	}
+	return nil
+}
+
+func cardNetworkFee(network string) float64 {
+	if network == providerVisa {
+		return feeVisa
+	}
+	return feeMastercard
+}
+
+func formatFeePercent(fraction float64) string {
+	return strconv.FormatFloat(fraction*100, 'f', -1, 64) + "%"
+}
@@ -0,0 +1,53 @@
+package currency
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"answerflow/commontypes"
+)
+
+// generateChain executes a user-specified chain ("100 usd to btc to rub")
+// hop by hop, feeding each hop's output into the next. Each hop is routed
+// and charged fees like a standalone conversion, so the final amount reflects
+// actually performing every step.
+func (m *CurrencyConverterModule) generateChain(ctx context.Context, req *ConversionRequest, apiCache *APICache) []commontypes.FlowResult {
+	hops := append(append([]string{req.FromCurrency}, req.Via...), req.ToCurrency)
+
+	steps := []string{fmt.Sprintf("%s %s", formatAmount(req.Amount, req.FromCurrency), req.FromCurrency)}
+	current := req.Amount
+	for i := 0; i+1 < len(hops); i++ {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		from, to := hops[i], hops[i+1]
+		if from == to {
+			continue
+		}
+
+		out, err := m.convert(ctx, current, from, to, apiCache)
+		if err == nil && out < minAmountAfterFees {
+			err = fmt.Errorf("amount too small")
+		}
+		if err != nil {
+			hopReq := &ConversionRequest{Amount: current, FromCurrency: from}
+			return m.requestedPairFailed(ctx, req, m.makeErrorResult(hopReq, to, err), apiCache)
+		}
+
+		current = out
+		steps = append(steps, fmt.Sprintf("%s %s", formatAmount(current, to), to))
+	}
+
+	current = req.afterPersonalFee(current)
+	return []commontypes.FlowResult{{
+		Title:         fmt.Sprintf("%s %s", formatAmountAt(current, req.ToCurrency, req.precision()), req.ToCurrency),
+		SubTitle:      strings.Join(steps, " → ") + req.personalFeeInfo(),
+		IcoPath:       assetIcon(req.FromCurrency, req.ToCurrency),
+		Score:         scoreSpecificConversion,
+		JsonRPCAction: commontypes.CopyToClipboard(formatAmountForClipboardAt(current, req.ToCurrency, req.precision())),
+	}}
+}
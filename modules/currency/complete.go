@@ -0,0 +1,130 @@
+package currency
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// completion is one entry of the prefix index: a token the parser accepts
+// and the currency it stands for.
+type completion struct {
+	token  string
+	code   string
+	isCode bool
+}
+
+// completionIndex returns the prefix index, sorted by token.
+func (cd *CurrencyData) completionIndex() []completion {
+	cd.mu.RLock()
+	defer cd.mu.RUnlock()
+	return cd.completions
+}
+
+// rebuildCompletionsLocked rebuilds the prefix index after the currency
+// tables changed. Writers call it while they hold cd.mu, so the read path
+// never needs the write lock.
+func (cd *CurrencyData) rebuildCompletionsLocked() {
+	var index []completion
+	seen := make(map[string]bool)
+	add := func(token, code string) {
+		if seen[token] || strings.ContainsRune(token, ' ') {
+			return
+		}
+		seen[token] = true
+		index = append(index, completion{token: token, code: code, isCode: strings.ToLower(code) == token})
+	}
+	for token, code := range cd.validCodes {
+		add(token, code)
+	}
+	for alias, code := range cd.nameAliases {
+		add(alias, code)
+	}
+	sort.Slice(index, func(i, j int) bool { return index[i].token < index[j].token })
+	cd.completions = index
+}
+
+// CompleteCurrency returns up to limit tokens starting with prefix, codes
+// before aliases and shorter before longer, one per currency.
+func (cd *CurrencyData) CompleteCurrency(prefix string, limit int) []string {
+	prefix = strings.ToLower(prefix)
+	if prefix == "" || limit <= 0 {
+		return nil
+	}
+	index := cd.completionIndex()
+	start := sort.Search(len(index), func(i int) bool { return index[i].token >= prefix })
+	var matches []completion
+	for _, c := range index[start:] {
+		if !strings.HasPrefix(c.token, prefix) {
+			break
+		}
+		matches = append(matches, c)
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].isCode != matches[j].isCode {
+			return matches[i].isCode
+		}
+		return len(matches[i].token) < len(matches[j].token)
+	})
+
+	var out []string
+	codes := make(map[string]bool)
+	for _, c := range matches {
+		if codes[c.code] {
+			continue
+		}
+		codes[c.code] = true
+		out = append(out, c.token)
+		if len(out) == limit {
+			break
+		}
+	}
+	return out
+}
+
+// CompleteQuery suggests up to limit completed queries for a query being
+// typed: the last word completed to a currency, or, once the query names an
+// amount and a currency, the usual conversion targets appended.
+func (m *CurrencyConverterModule) CompleteQuery(query string, limit int) []string {
+	query = strings.Join(strings.Fields(query), " ")
+	if query == "" {
+		return nil
+	}
+
+	head, last := "", query
+	if i := strings.LastIndexByte(query, ' '); i >= 0 {
+		head, last = query[:i+1], query[i+1:]
+	}
+
+	var out []string
+	// A lone letter resolves to some currency, but the user is still typing it
+	if req, err := ParseQuery(query, m.currencyData); err == nil && req.ToCurrency == "" && req.FromCurrency != "" && len([]rune(last)) > 1 {
+		for _, target := range append([]string{m.baseConversionCurrency}, m.quickConversionTargets...) {
+			if target != req.FromCurrency && !containsString(out, query+" to "+strings.ToLower(target)) {
+				out = append(out, query+" to "+strings.ToLower(target))
+			}
+		}
+	}
+
+	if strings.IndexFunc(last, unicode.IsLetter) == 0 {
+		for _, token := range m.currencyData.CompleteCurrency(last, limit) {
+			if token != strings.ToLower(last) {
+				out = append(out, head+token)
+			}
+		}
+	}
+
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
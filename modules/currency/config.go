@@ -2,11 +2,17 @@
 package currency
 
 import (
+	"log"
 	"net"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	"answerflow/cron"
+	_ "answerflow/preset" // PRESET supplies defaults for the settings below
+
 	"golang.org/x/time/rate"
 )
 
@@ -21,19 +27,337 @@ const (
 
 // API URLs with environment variable override support
 var (
-	whitebirdAPIURL   = getEnvOrDefault("WHITEBIRD_API_URL", "https://admin-service.whitebird.io/api/v1/exchange/calculation")
-	bybitOrderbookURL = getEnvOrDefault("BYBIT_ORDERBOOK_URL", "https://api.bybit.com/v5/market/orderbook")
-	mastercardAPIURL  = getEnvOrDefault("MASTERCARD_API_URL", "https://www.mastercard.com/marketingservices/public/mccom-services/currency-conversions/conversion-rates")
+	whitebirdAPIURL    = getEnvOrDefault("WHITEBIRD_API_URL", "https://admin-service.whitebird.io/api/v1/exchange/calculation")
+	bybitOrderbookURL  = getEnvOrDefault("BYBIT_ORDERBOOK_URL", "https://api.bybit.com/v5/market/orderbook")
+	bybitTickersURL    = getEnvOrDefault("BYBIT_TICKERS_URL", "https://api.bybit.com/v5/market/tickers")
+	mastercardAPIURL   = getEnvOrDefault("MASTERCARD_API_URL", "https://www.mastercard.com/marketingservices/public/mccom-services/currency-conversions/conversion-rates")
+	visaAPIURL         = getEnvOrDefault("VISA_API_URL", "https://www.visa.co.uk/cmsapi/fx/rates")
+	ecbBaselineURL     = getEnvOrDefault("ECB_BASELINE_URL", "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml")
+	coingeckoAPIURL    = getEnvOrDefault("COINGECKO_API_URL", "https://api.coingecko.com/api/v3")
+	coingeckoProAPIURL = getEnvOrDefault("COINGECKO_PRO_API_URL", "https://pro-api.coingecko.com/api/v3")
+	cbrRatesURL        = getEnvOrDefault("CBR_RATES_URL", "https://www.cbr.ru/scripts/XML_daily.asp")
+)
+
+// Mastercard rates deviating from the ECB baseline by more than this fraction
+// are rejected. Mastercard's own spread is well under 1%, so 10% only trips on
+// real data errors.
+var mastercardBaselineTolerance = getEnvFloatOrDefault("MASTERCARD_BASELINE_TOLERANCE", 0.10)
+
+// Liquidity gating by Bybit 24h turnover (USDT). Symbols below
+// LOW_LIQUIDITY_VOLUME_USD are flagged in results. With LIQUIDITY_GATE on,
+// they also refuse large-amount conversions, and on other symbols a large
+// conversion may use at most LARGE_ORDER_MAX_VOLUME_SHARE of the day's
+// turnover.
+var (
+	liquidityGateEnabled     = getEnvBoolOrDefault("LIQUIDITY_GATE", false)
+	lowLiquidityVolumeUSD    = getEnvFloatOrDefault("LOW_LIQUIDITY_VOLUME_USD", 250000)
+	largeOrderMaxVolumeShare = getEnvFloatOrDefault("LARGE_ORDER_MAX_VOLUME_SHARE", 0.02)
+)
+
+// Source currency assumed when a query names only a target ("100 to usd").
+// Bare numbers are never converted, so plain arithmetic stays free of
+// conversion noise. Empty disables the interpretation.
+var defaultCurrency = strings.ToUpper(getEnvOrDefault("DEFAULT_CURRENCY", ""))
+
+// Fiat-only deployments never contact the crypto providers (Bybit, Whitebird,
+// CoinGecko): crypto codes and the RUB/TON bridge are unsupported and only
+// Mastercard fiat pairs are routed.
+var fiatOnlyMode = getEnvBoolOrDefault("FIAT_ONLY", false)
+
+// With RUB_BRIDGE=false RUB is a plain Mastercard fiat: no Whitebird/TON
+// cash-out chain and no buy/sell tags, for users who want reference rates.
+var rubBridgeEnabled = getEnvBoolOrDefault("RUB_BRIDGE", true)
+
+// Opt-in wire log of provider HTTP exchanges (method, URL, status, duration
+// and the start of both bodies, credentials redacted), kept in memory and
+// served by the admin API for debugging provider errors.
+var (
+	wireLogEnabled   = getEnvBoolOrDefault("WIRE_LOG", false)
+	wireLogSize      = int(getEnvFloatOrDefault("WIRE_LOG_SIZE", 200))
+	wireLogBodyBytes = int(getEnvFloatOrDefault("WIRE_LOG_BODY_BYTES", 2048))
+)
+
+// Longest client-side cache hint given on a result, however long its rates
+// stay current.
+var maxResultCacheTTL = getEnvDurationOrDefault("RESULT_CACHE_TTL_MAX", 5*time.Minute)
+
+// For CONVERSION_CACHE_STALE_WINDOW past its expiry a conversion is still
+// served from cache while one background refresh recomputes it, so a popular
+// entry expiring doesn't make every concurrent keystroke reprice the route.
+var conversionCacheStaleWindow = getEnvDurationOrDefault("CONVERSION_CACHE_STALE_WINDOW", calculationCacheTTL)
+
+// Results whose oldest rate is older than VALID_AS_OF_SUBTITLE_AFTER say so
+// in the subtitle ("as of 2h5m ago"); 0 leaves it to the structured
+// ValidAsOf field.
+var validAsOfSubtitleAfter = getEnvDurationOrDefault("VALID_AS_OF_SUBTITLE_AFTER", 0)
+
+// Show the Bank of Russia's official rate next to RUB results, and how far
+// the route's effective rate is from it. Enables the CBR provider.
+var cbrComparison = getEnvBoolOrDefault("CBR_COMPARISON", false)
+
+// Add a mid-market reference result (no fees, spreads or slippage) next to
+// each specific conversion, so reference and achievable amounts can be
+// compared. Requests may override it with ?reference=1 or ?reference=0.
+var referenceResults = getEnvBoolOrDefault("REFERENCE_RESULTS", false)
+
+// Add the converted amount written out in words, for filling in payment
+// documents. AMOUNT_WORDS=true adds it to every conversion; otherwise only
+// queries ending in "words" or "прописью" get it. AMOUNT_WORDS_LANG picks
+// "en" or "ru".
+var (
+	amountWordsResults = getEnvBoolOrDefault("AMOUNT_WORDS", false)
+	amountWordsLang    = loadAmountWordsLang()
+)
+
+// Results of at least HUMANIZE_AMOUNTS_ABOVE are shortened in the title
+// ("1.23B RUB"), with the exact figure in the subtitle and clipboard; 0
+// keeps titles exact.
+var humanizeAmountsAbove = getEnvFloatOrDefault("HUMANIZE_AMOUNTS_ABOVE", 0)
+
+// A quick conversion's inverse row within INVERSE_DEDUP_TOLERANCE (relative)
+// of its forward row says the same thing twice, so only the forward row is
+// shown; 0 keeps both.
+var inverseDedupTolerance = getEnvFloatOrDefault("INVERSE_DEDUP_TOLERANCE", 0.001)
+
+// Card network whose rates price fiat legs: "mastercard" or "visa" for the
+// card the user holds, "best" for whichever gives more, or "both" to price
+// with the better one and also show the other, labelled. Mastercard is the
+// default; the others are opt-in. A fiat pair always uses one network for
+// both of its legs.
+const (
+	cardNetworkBest = "best"
+	cardNetworkBoth = "both"
+)
+
+var cardNetwork = loadCardNetwork()
+
+func loadAmountWordsLang() string {
+	lang := strings.ToLower(getEnvOrDefault("AMOUNT_WORDS_LANG", "en"))
+	if lang != "en" && lang != "ru" {
+		InvalidSetting("AMOUNT_WORDS_LANG", lang, "not en or ru", "en")
+		return "en"
+	}
+	return lang
+}
+
+func loadCardNetwork() string {
+	switch value := strings.ToLower(getEnvOrDefault("CARD_NETWORK", providerMastercard)); value {
+	case providerMastercard, providerVisa, cardNetworkBest, cardNetworkBoth:
+		return value
+	default:
+		InvalidSetting("CARD_NETWORK", value, "not mastercard, visa, best or both", providerMastercard)
+		return providerMastercard
+	}
+}
+
+// Inverse RUB<->TON questions ("how much RUB for 10 TON") can ask Whitebird
+// to quote by output amount instead of searching with forward quotes. The
+// API doesn't document outputAsset requests, so this is opt-in.
+var whitebirdTargetQuotes = getEnvBoolOrDefault("WHITEBIRD_TARGET_QUOTES", false)
+
+// Asset class order for tokens with several meanings ("sol" is Solana or
+// the Peruvian sol). The preferred meaning is scored first, the others are
+// shown below it. Empty keeps the order in config/currency_ambiguous.json.
+var ambiguousPrecedence = splitList(strings.ToLower(getEnvOrDefault("AMBIGUOUS_PRECEDENCE", "")))
+
+// Home currency of the quick conversion matrix ("100 usd" -> home first).
+// RUB keeps the buy/sell matrix of the RUB cash-out route; any other home
+// currency gets plain reference conversions. Requests may override it.
+var homeCurrency = strings.ToUpper(getEnvOrDefault("HOME_CURRENCY", CurrencyRUB))
+
+// Anomaly thresholds per asset class: a rate moving further than this fraction
+// within one refresh interval is quarantined instead of served.
+var (
+	anomalyThresholdCrypto = getEnvFloatOrDefault("ANOMALY_THRESHOLD_CRYPTO", 0.25)
+	anomalyThresholdFiat   = getEnvFloatOrDefault("ANOMALY_THRESHOLD_FIAT", 0.05)
+)
+
+// Provider criticality. A failing critical provider fails InitialFetch and
+// ForceRefresh; optional providers degrade gracefully; disabled providers are
+// never contacted. Override with <PROVIDER>_CRITICALITY, e.g.
+// MASTERCARD_CRITICALITY=critical or WHITEBIRD_CRITICALITY=disabled.
+const (
+	criticalityCritical = "critical"
+	criticalityOptional = "optional"
+	criticalityDisabled = "disabled"
+)
+
+const (
+	providerBybit      = "bybit"
+	providerMastercard = "mastercard"
+	providerVisa       = "visa"
+	providerWhitebird  = "whitebird"
+	providerECB        = "ecb"
+	providerCoinGecko  = "coingecko"
+	providerCBR        = "cbr"
+)
+
+var providerCriticality = map[string]string{
+	providerBybit:      loadCriticality("BYBIT", criticalityCritical),
+	providerMastercard: loadCriticality("MASTERCARD", criticalityOptional),
+	providerVisa:       loadCriticality("VISA", criticalityOptional),
+	providerWhitebird:  loadCriticality("WHITEBIRD", criticalityOptional),
+	providerECB:        loadCriticality("ECB", criticalityOptional),
+	providerCoinGecko:  loadCriticality("COINGECKO", criticalityOptional),
+	providerCBR:        loadCriticality("CBR", criticalityOptional),
+}
+
+func init() {
+	if !cbrComparison {
+		providerCriticality[providerCBR] = criticalityDisabled
+	}
+	if !rubBridgeEnabled {
+		providerCriticality[providerWhitebird] = criticalityDisabled
+	}
+	// Mastercard card holders have no use for Visa rates
+	if cardNetwork == providerMastercard && os.Getenv("VISA_CRITICALITY") == "" {
+		providerCriticality[providerVisa] = criticalityDisabled
+	}
+	if !fiatOnlyMode {
+		return
+	}
+	for _, name := range []string{providerBybit, providerWhitebird, providerCoinGecko} {
+		providerCriticality[name] = criticalityDisabled
+	}
+	// Mastercard is the only rate source left, so it becomes critical
+	// unless explicitly configured otherwise.
+	if os.Getenv("MASTERCARD_CRITICALITY") == "" {
+		providerCriticality[providerMastercard] = criticalityCritical
+	}
+	log.Println("Fiat-only mode: crypto providers disabled")
+}
+
+func loadCriticality(prefix, defaultValue string) string {
+	switch value := strings.ToLower(getEnvOrDefault(prefix+"_CRITICALITY", defaultValue)); value {
+	case criticalityCritical, criticalityOptional, criticalityDisabled:
+		return value
+	default:
+		InvalidSetting(prefix+"_CRITICALITY", value, "not critical, optional or disabled", defaultValue)
+		return defaultValue
+	}
+}
+
+func providerEnabled(name string) bool {
+	return providerCriticality[name] != criticalityDisabled
+}
+
+func providerCritical(name string) bool {
+	return providerCriticality[name] == criticalityCritical
+}
+
+// Asset classes with their own refresh policy
+const (
+	assetCrypto = "crypto" // Bybit order books; RUB and TON route through them
+	assetFiat   = "fiat"   // Mastercard
+	assetMetal  = "metal"  // Mastercard, but moves slowly enough to refresh rarely
 )
 
+var metalCodes = map[string]bool{"XAU": true, "XAG": true, "XPT": true, "XPD": true}
+
+// refreshPolicy controls how often an asset class is refreshed in the
+// background and when its data is too old to convert with.
+type refreshPolicy struct {
+	RefreshInterval time.Duration
+	StaleAfter      time.Duration
+}
+
+// Override with <CLASS>_REFRESH_INTERVAL and <CLASS>_STALE_AFTER, e.g.
+// FIAT_REFRESH_INTERVAL=6h FIAT_STALE_AFTER=24h.
+var refreshPolicies = map[string]refreshPolicy{
+	assetCrypto: loadRefreshPolicy("CRYPTO", backgroundUpdateTTL, criticalStalenessThreshold),
+	assetFiat:   loadRefreshPolicy("FIAT", backgroundUpdateTTL*3, criticalStalenessThreshold*4),
+	assetMetal:  loadRefreshPolicy("METAL", time.Hour, criticalStalenessThreshold*8),
+}
+
+func loadRefreshPolicy(prefix string, interval, staleAfter time.Duration) refreshPolicy {
+	return refreshPolicy{
+		RefreshInterval: getEnvDurationOrDefault(prefix+"_REFRESH_INTERVAL", interval),
+		StaleAfter:      getEnvDurationOrDefault(prefix+"_STALE_AFTER", staleAfter),
+	}
+}
+
+// mastercardRefreshInterval is the Mastercard background loop interval: the
+// shortest interval of the classes it serves.
+func mastercardRefreshInterval() time.Duration {
+	interval := refreshPolicies[assetFiat].RefreshInterval
+	if metal := refreshPolicies[assetMetal].RefreshInterval; metal < interval {
+		interval = metal
+	}
+	return interval
+}
+
+// refreshSchedules replaces a provider's fixed-interval loop with a cron
+// schedule, set with <PROVIDER>_REFRESH_CRON, e.g.
+// MASTERCARD_REFRESH_CRON="CRON_TZ=Europe/Moscow */10 8-20 * * 1-5" to
+// refresh only during banking hours. Data is then stale only once a
+// scheduled refresh is StaleAfter overdue, so off-hours gaps don't count.
+var refreshSchedules = loadRefreshSchedules(providerBybit, providerMastercard, providerVisa, providerECB, providerCBR, providerWhitebird)
+
+func loadRefreshSchedules(providers ...string) map[string]*cron.Schedule {
+	schedules := make(map[string]*cron.Schedule)
+	for _, name := range providers {
+		key := strings.ToUpper(name) + "_REFRESH_CRON"
+		spec := os.Getenv(key)
+		if spec == "" {
+			continue
+		}
+		schedule, err := cron.Parse(spec)
+		if err != nil {
+			InvalidSetting(key, spec, err.Error(), "the refresh interval")
+			continue
+		}
+		schedules[name] = schedule
+	}
+	return schedules
+}
+
 // Timeouts
 const (
 	whitebirdAPITimeout        = 15 * time.Second
 	bybitAPITimeout            = 10 * time.Second
+	coingeckoAPITimeout        = 5 * time.Second
+	coingeckoPriceTTL          = 5 * time.Minute
 	backgroundUpdateTTL        = 5 * time.Minute
 	criticalStalenessThreshold = 15 * time.Minute
+
+	// Results whose oldest rate is older than this get a stale badge
+	staleBadgeAge = time.Hour
+
+	// A result whose rates are already due for refresh may still be reused
+	// briefly, so a client doesn't re-query on every keystroke while the
+	// background update runs
+	minResultCacheTTL = 5 * time.Second
+
+	// Visa publishes one set of rates per day
+	visaRefreshInterval = 6 * time.Hour
+	visaAPITimeout      = 15 * time.Second
+
+	// Non-priority Mastercard currencies refreshed per background cycle,
+	// recently changed and then least recently fetched first. Priority
+	// currencies and currencies with no cached rate are always fetched.
+	mastercardRotationSize = 40
+
+	// A Mastercard rate that changed within this window is still moving and
+	// jumps the rotation queue.
+	mastercardMovingWindow = 24 * time.Hour
+
+	// ECB publishes once per working day; a baseline older than a long
+	// weekend is not trusted for validation.
+	ecbAPITimeout      = 15 * time.Second
+	ecbRefreshInterval = 6 * time.Hour
+	ecbBaselineMaxAge  = 96 * time.Hour
+
+	// The Bank of Russia sets rates once per working day, the next day's
+	// in the afternoon; older ones than a long weekend aren't shown.
+	cbrAPITimeout      = 15 * time.Second
+	cbrRefreshInterval = 3 * time.Hour
+	cbrMaxAge          = 96 * time.Hour
 )
 
+// whitebirdProbeInterval is how often Whitebird's operation status is
+// checked between conversions; 0 turns the probe off.
+var whitebirdProbeInterval = getEnvDurationOrDefault("WHITEBIRD_PROBE_INTERVAL", 5*time.Minute)
+
 // Retry configuration
 const (
 	maxRetries     = 3
@@ -62,6 +386,9 @@ const (
 	// This correctly models the user receiving 2% less due to the fee
 	feeMastercard = 0.02 // 2%
 
+	// Visa fiat conversion fee, applied like feeMastercard
+	feeVisa = 0.02 // 2%
+
 	// Whitebird fee is included in their API response, no additional fee applied here
 	// Spec value: 1.5% (empirical observations show ~2.4-2.5%)
 
@@ -70,6 +397,35 @@ const (
 	feeTONWithdrawToWhitebird = 0.02   // Fixed TON fee to withdraw from Bybit to Whitebird
 )
 
+// Order book levels requested from Bybit (its spot limit is 1-200): shallow
+// for regular amounts, deep once a conversion reaches minLargeOrderUSDT.
+var (
+	bybitShallowDepth = loadOrderbookDepth("BYBIT_SHALLOW_DEPTH", 25)
+	bybitDeepDepth    = loadOrderbookDepth("BYBIT_DEEP_DEPTH", 200)
+)
+
+func loadOrderbookDepth(key string, defaultValue int) int {
+	depth := int(getEnvFloatOrDefault(key, float64(defaultValue)))
+	if depth < 1 || depth > 200 {
+		InvalidSetting(key, os.Getenv(key), "not between 1 and 200", defaultValue)
+		return defaultValue
+	}
+	return depth
+}
+
+// Approximate memory all cached Bybit order books may hold together. Past it
+// the books fetched longest ago are cut back to their best level.
+var orderbookMemoryBudget = int(getEnvFloatOrDefault("ORDERBOOK_MEMORY_BUDGET_MB", 32) * 1024 * 1024)
+
+// orderbookDepthForUSD is the order book depth needed to price a conversion
+// worth usdValue.
+func orderbookDepthForUSD(usdValue float64) int {
+	if shouldUseOrderBookByUSD(usdValue) {
+		return bybitDeepDepth
+	}
+	return bybitShallowDepth
+}
+
 // Order book thresholds
 const (
 	minLargeOrderUSDT         = 1000.0
@@ -84,24 +440,39 @@ const (
 	maxConversionAmount = 1e15
 
 	// Input validation limits
-	maxExpressionLength = 200
 	maxQueryLength      = 500
 	maxHTTPResponseSize = 5 * 1024 * 1024 // 5MB - sufficient for deep order books
+
+	// Concurrent queries allowed into the module; each may fan out into
+	// several provider calls (inverse searches, lazy symbol loads)
+	maxConcurrentQueries = 4
 )
 
 // Scoring
 const (
-	scoreSpecificConversion = 100
-	scoreBaseConversion     = 90
-	scoreReverseConversion  = 95 // Prioritize inverse "buy" operations for RUB/USD
-	scoreQuickConversion    = 80
-	scoreInverseConversion  = 95 // Prioritize inverse "buy" operations for EUR
+	scoreSpecificConversion  = 100
+	scoreBaseConversion      = 90
+	scoreReverseConversion   = 95 // Prioritize inverse "buy" operations for RUB/USD
+	scoreQuickConversion     = 80
+	scoreFavoriteConversion  = 79
+	scoreInverseConversion   = 95  // Prioritize inverse "buy" operations for EUR
+	scoreSuggestion          = 50  // "Did you mean" results for near-miss currency tokens
+	scoreAlternativeMeaning  = 70  // Other meanings of an ambiguous token ("sol": Peruvian sol)
+	scoreReferenceConversion = 97  // Mid-market reference right below the achievable amount
+	scoreAmountWords         = 96  // Amount in words right below the amount it spells out
+	scoreWatchlist           = 100 // Empty-query dashboard, in watchlist order
+	scoreRequestedPairError  = 100 // Why the requested pair failed, above the quick alternatives
 )
 
 // Cache settings
 const (
 	calculationCacheTTL = 2 * time.Minute
 	maxCacheSize        = 10000
+
+	// Whitebird quotes are cached per log-scale amount bucket so that typing
+	// "1", "10", "100" ... doesn't trigger a live POST for every keystroke.
+	whitebirdQuoteCacheTTL    = 30 * time.Second
+	whitebirdBucketsPerDecade = 50 // ~4.7% bucket width
 )
 
 // Health monitoring
@@ -119,6 +490,10 @@ const (
 	whitebirdRateBurst      = 15
 	mastercardRatePerMinute = 150 // Balanced rate with adaptive fetcher
 	mastercardRateBurst     = 20  // Moderate burst
+	coingeckoRatePerMinute  = 25  // Public API allows ~30/min
+	coingeckoRateBurst      = 5
+	visaRatePerMinute       = 60 // Calculator endpoint, no published limit
+	visaRateBurst           = 5
 )
 
 // Rate limiters
@@ -126,15 +501,20 @@ var (
 	bybitLimiter      = rate.NewLimiter(rate.Every(time.Minute/bybitRatePerMinute), bybitRateBurst)
 	whitebirdLimiter  = rate.NewLimiter(rate.Every(time.Minute/whitebirdRatePerMinute), whitebirdRateBurst)
 	mastercardLimiter = rate.NewLimiter(rate.Every(time.Minute/mastercardRatePerMinute), mastercardRateBurst)
+	coingeckoLimiter  = rate.NewLimiter(rate.Every(time.Minute/coingeckoRatePerMinute), coingeckoRateBurst)
+	visaLimiter       = rate.NewLimiter(rate.Every(time.Minute/visaRatePerMinute), visaRateBurst)
 )
 
 // Types
 type BybitRate struct {
 	BestBid       float64
 	BestAsk       float64
+	Volume24h     float64 `json:"-"` // USDT turnover, filled from the ticker batch on read
 	OrderBookBids [][]float64
 	OrderBookAsks [][]float64
 	LastUpdate    time.Time
+	DepthUpdate   time.Time // when the order book was fetched; zero for ticker top of book
+	DepthLimit    int       // levels requested with the order book
 }
 
 type CurrencyMetadata struct {
@@ -145,23 +525,48 @@ type CurrencyMetadata struct {
 	LastVerified       time.Time
 }
 
-// CreateHTTPClient creates an HTTP client with proper timeouts
+// CreateHTTPClient creates an HTTP client with proper timeouts. Hostnames
+// resolve through the provider DNS cache when DNS_CACHE is on, and
+// requests follow the outbound host policy (see outbound_policy.go).
 func CreateHTTPClient() *http.Client {
 	return &http.Client{
-		Timeout: 30 * time.Second,
-		Transport: &http.Transport{
-			DialContext: (&net.Dialer{
-				Timeout:   10 * time.Second,
-				KeepAlive: 30 * time.Second,
-			}).DialContext,
-			TLSHandshakeTimeout:   10 * time.Second,
-			ResponseHeaderTimeout: 10 * time.Second,
-			ExpectContinueTimeout: 1 * time.Second,
-			MaxIdleConns:          100,
-			MaxIdleConnsPerHost:   20,
-			IdleConnTimeout:       90 * time.Second,
-		},
+		Timeout:   30 * time.Second,
+		Transport: &hostPolicyTransport{next: stubbed(newBaseTransport())},
+	}
+}
+
+// newBaseTransport is the transport under every outbound client, pinned
+// certificates checked.
+func newBaseTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:       10 * time.Second,
+		KeepAlive:     30 * time.Second,
+		FallbackDelay: dialFallbackDelay,
+	}
+	dial := dialer.DialContext
+	if dnsCacheEnabled {
+		dial = providerDNS.dialContext(dialer)
 	}
+	return &http.Transport{
+		DialContext:           dial,
+		TLSClientConfig:       pinnedTLSConfig(),
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   20,
+		IdleConnTimeout:       90 * time.Second,
+	}
+}
+
+// EnvString and EnvDuration read settings for modules built on this one, with
+// the same validation; rejected values show up in ConfigIssues.
+func EnvString(key, defaultValue string) string {
+	return getEnvOrDefault(key, defaultValue)
+}
+
+func EnvDuration(key string, defaultValue time.Duration) time.Duration {
+	return getEnvDurationOrDefault(key, defaultValue)
 }
 
 // Helper function to get environment variable with default
@@ -171,3 +576,60 @@ func getEnvOrDefault(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// Helper function to get a float environment variable with default
+// splitList splits a comma-separated setting, dropping empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+func getEnvBoolOrDefault(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		InvalidSetting(key, value, "not a boolean", defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+func getEnvFloatOrDefault(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		InvalidSetting(key, value, "not a number", defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// Helper function to get a duration environment variable with default
+func getEnvDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		InvalidSetting(key, value, "not a duration such as 30s or 5m", defaultValue)
+		return defaultValue
+	}
+	// Zero is only meaningful ("off") for settings that default to it
+	if parsed < 0 || (parsed == 0 && defaultValue != 0) {
+		InvalidSetting(key, value, "must be positive", defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
@@ -0,0 +1,132 @@
+package currency
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/url"
+	"os"
+	"sync"
+)
+
+// ConfigIssue is one invalid setting: the environment variable, the value it
+// was given and what is wrong with it.
+type ConfigIssue struct {
+	Key     string
+	Value   string
+	Problem string
+}
+
+func (i ConfigIssue) String() string {
+	return fmt.Sprintf("%s=%q: %s", i.Key, i.Value, i.Problem)
+}
+
+// Settings rejected while being read, by this package or any other that
+// reports through InvalidSetting. Package variables are initialised before
+// main runs, so these are all known by the time ConfigIssues is called.
+var (
+	rejectedSettingsMu sync.Mutex
+	rejectedSettings   []ConfigIssue
+)
+
+// InvalidSetting logs that key's value was rejected and fallback used
+// instead, and keeps it for ConfigIssues.
+func InvalidSetting(key, value, problem string, fallback interface{}) {
+	log.Printf("Warning: invalid %s=%q (%s), using default %v", key, value, problem, fallback)
+	rejectedSettingsMu.Lock()
+	rejectedSettings = append(rejectedSettings, ConfigIssue{Key: key, Value: value, Problem: problem})
+	rejectedSettingsMu.Unlock()
+}
+
+// ConfigIssues lists the invalid settings: those replaced by defaults when
+// read, in any package, and values of this package that parse but make no
+// sense (an unknown currency, a negative share).
+func ConfigIssues() []ConfigIssue {
+	rejectedSettingsMu.Lock()
+	issues := append([]ConfigIssue(nil), rejectedSettings...)
+	rejectedSettingsMu.Unlock()
+
+	for _, key := range []string{"WHITEBIRD_API_URL", "BYBIT_ORDERBOOK_URL", "BYBIT_TICKERS_URL", "MASTERCARD_API_URL", "VISA_API_URL",
+		"ECB_BASELINE_URL", "COINGECKO_API_URL", "COINGECKO_PRO_API_URL", "CBR_RATES_URL", "MASTERCARD_SESSION_URL"} {
+		if value := os.Getenv(key); value != "" {
+			if u, err := url.Parse(value); err != nil || u.Scheme == "" || u.Host == "" {
+				issues = append(issues, ConfigIssue{key, value, "not an absolute URL"})
+			} else if !hostAllowed(u.Hostname()) {
+				issues = append(issues, ConfigIssue{key, value, "host not in OUTBOUND_ALLOWED_HOSTS"})
+			}
+		}
+	}
+	issues = append(issues, endpointIssues("BYBIT_ENDPOINTS", bybitEndpoints)...)
+	for _, entry := range splitList(os.Getenv("TLS_PINS")) {
+		if _, _, err := parseTLSPin(entry); err != nil {
+			issues = append(issues, ConfigIssue{"TLS_PINS", entry, err.Error()})
+		}
+	}
+	if !KnownCurrency(homeCurrency) {
+		issues = append(issues, ConfigIssue{"HOME_CURRENCY", homeCurrency, "not a supported currency"})
+	}
+	if defaultCurrency != "" && !KnownCurrency(defaultCurrency) {
+		issues = append(issues, ConfigIssue{"DEFAULT_CURRENCY", defaultCurrency, "not a supported currency"})
+	}
+	for _, pair := range watchlist {
+		from, to, _ := SplitWatchPair(pair)
+		if !KnownCurrency(from) || !KnownCurrency(to) {
+			issues = append(issues, ConfigIssue{"WATCHLIST", pair, "not a pair of supported currencies"})
+		}
+	}
+
+	// Only explicitly set values are checked; the defaults are in range
+	for _, r := range []struct {
+		key      string
+		value    float64
+		min, max float64
+	}{
+		{"MASTERCARD_BASELINE_TOLERANCE", mastercardBaselineTolerance, 0, 1},
+		{"LOW_LIQUIDITY_VOLUME_USD", lowLiquidityVolumeUSD, 0, math.Inf(1)},
+		{"LARGE_ORDER_MAX_VOLUME_SHARE", largeOrderMaxVolumeShare, 0, 1},
+		{"ANOMALY_THRESHOLD_CRYPTO", anomalyThresholdCrypto, 0, math.Inf(1)},
+		{"ANOMALY_THRESHOLD_FIAT", anomalyThresholdFiat, 0, math.Inf(1)},
+		{"SLO_SUCCESS_RATE", sloSuccessRate, 0, 1},
+		{"SLO_MIN_SAMPLES", float64(sloMinSamples), 0, math.Inf(1)},
+		{"QUERY_PROVIDER_CALLS", float64(queryProviderCalls), 0, math.Inf(1)},
+		{"PARSE_CACHE_SIZE", float64(parseCacheSize), 0, math.Inf(1)},
+		{"WIRE_LOG_SIZE", float64(wireLogSize), 1, math.Inf(1)},
+		{"WIRE_LOG_BODY_BYTES", float64(wireLogBodyBytes), 0, math.Inf(1)},
+		{"ORDERBOOK_MEMORY_BUDGET_MB", float64(orderbookMemoryBudget), 1, math.Inf(1)},
+		{"RATE_SIGNIFICANT_FIGURES", float64(rateSignificantFigures), 1, 17},
+		{"HUMANIZE_AMOUNTS_ABOVE", humanizeAmountsAbove, 0, math.Inf(1)},
+		{"INVERSE_DEDUP_TOLERANCE", inverseDedupTolerance, 0, 1},
+		{"CONSENSUS_OUTLIER_TOLERANCE", consensusOutlierTolerance, 0, 1},
+		{"CONSENSUS_WARNING_THRESHOLD", consensusWarningThreshold, 0, 1},
+		{"FEE_CALIBRATION_MAX_CORRECTION", feeCalibrationMaxCorrection, 0, 1},
+	} {
+		value := os.Getenv(r.key)
+		if value == "" || (r.value >= r.min && r.value <= r.max) {
+			continue
+		}
+		problem := fmt.Sprintf("must be between %v and %v", r.min, r.max)
+		if math.IsInf(r.max, 1) {
+			problem = fmt.Sprintf("must be at least %v", r.min)
+		}
+		issues = append(issues, ConfigIssue{r.key, value, problem})
+	}
+	return issues
+}
+
+// KnownCurrency reports whether code (upper case) is a currency this
+// deployment can convert.
+func KnownCurrency(code string) bool {
+	if code == CurrencyRUB || isFiatCode(code) {
+		return true
+	}
+	return !fiatOnlyMode && (code == CurrencyUSDT || isSupportedCrypto(code))
+}
+
+func isSupportedCrypto(code string) bool {
+	for _, c := range supportedCryptos {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
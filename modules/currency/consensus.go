@@ -0,0 +1,150 @@
+package currency
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Reference (mid-market) prices are the consensus of every source that can
+// price a currency in USD, not whichever answers first: the Bybit book mid
+// and the CoinGecko index for crypto, the Mastercard and Visa rates and the
+// ECB baseline for fiat. Execution-modeled conversions keep using the venue
+// the route goes through.
+//
+//	CONSENSUS_OUTLIER_TOLERANCE  a source further than this (relative) from
+//	                             the median is left out, when there are at
+//	                             least three to tell which one is off
+//	CONSENSUS_WARNING_THRESHOLD  disagreement between the sources kept above
+//	                             which the reference result says so
+var (
+	consensusOutlierTolerance = getEnvFloatOrDefault("CONSENSUS_OUTLIER_TOLERANCE", 0.02)
+	consensusWarningThreshold = getEnvFloatOrDefault("CONSENSUS_WARNING_THRESHOLD", 0.01)
+)
+
+// priceQuote is one source's USD price of a currency.
+type priceQuote struct {
+	source string
+	usd    float64
+}
+
+// consensusPrice is the USD price the sources agree on.
+type consensusPrice struct {
+	USD          float64
+	Sources      []string // sources the price is the median of, outliers left out
+	Disagreement float64  // (max-min)/median of the sources kept
+}
+
+// usdPriceQuotes collects the USD prices of code that are at hand. Only
+// cached data is read, except that a crypto no other source prices is
+// fetched from CoinGecko as before.
+func (ac *APICache) usdPriceQuotes(ctx context.Context, code string) []priceQuote {
+	var quotes []priceQuote
+	switch getCurrencyType(code, ac) {
+	case "crypto", "TON":
+		if rate, err := ac.GetBybitRate(code + "USDT"); err == nil {
+			if mid := bybitMidPrice(rate); isValidFloat(mid) {
+				quotes = append(quotes, priceQuote{"Bybit", mid})
+			}
+		}
+		if p, ok := ac.cachedIndexPrice(code); ok && isValidFloat(p.USD) {
+			quotes = append(quotes, priceQuote{coingeckoProvider, p.USD})
+		}
+		if len(quotes) == 0 {
+			if price, err := ac.GetIndexPrice(ctx, code); err == nil && isValidFloat(price) {
+				quotes = append(quotes, priceQuote{coingeckoProvider, price})
+			}
+		}
+	case "fiat":
+		for _, network := range []string{providerMastercard, providerVisa} {
+			if !providerEnabled(network) {
+				continue
+			}
+			if rate, _, err := ac.cardRate(network, CurrencyUSD, code); err == nil && isValidFloat(rate) {
+				quotes = append(quotes, priceQuote{network, 1 / rate})
+			}
+		}
+		ac.mu.RLock()
+		if baseline, ok := ac.baselineRates["USD_"+code]; ok && isValidFloat(baseline) && time.Since(ac.baselineLastUpdate) <= ecbBaselineMaxAge {
+			quotes = append(quotes, priceQuote{"ECB", 1 / baseline})
+		}
+		ac.mu.RUnlock()
+	}
+	return quotes
+}
+
+// consensus takes the median of quotes, drops those further than
+// consensusOutlierTolerance from it and takes the median of the rest.
+// Two sources that disagree are both kept: neither can be told wrong.
+func consensus(quotes []priceQuote) (consensusPrice, bool) {
+	if len(quotes) == 0 {
+		return consensusPrice{}, false
+	}
+	kept := quotes
+	if len(quotes) >= 3 {
+		m := medianUSD(quotes)
+		kept = nil
+		for _, q := range quotes {
+			if math.Abs(q.usd/m-1) <= consensusOutlierTolerance {
+				kept = append(kept, q)
+			}
+		}
+		if len(kept) == 0 {
+			kept = quotes
+		}
+	}
+
+	c := consensusPrice{USD: medianUSD(kept)}
+	low, high := kept[0].usd, kept[0].usd
+	for _, q := range kept {
+		c.Sources = append(c.Sources, q.source)
+		low, high = math.Min(low, q.usd), math.Max(high, q.usd)
+	}
+	c.Disagreement = (high - low) / c.USD
+	return c, true
+}
+
+func medianUSD(quotes []priceQuote) float64 {
+	prices := make([]float64, len(quotes))
+	for i, q := range quotes {
+		prices[i] = q.usd
+	}
+	sort.Float64s(prices)
+	n := len(prices)
+	if n%2 == 1 {
+		return prices[n/2]
+	}
+	return (prices[n/2-1] + prices[n/2]) / 2
+}
+
+// referenceUSDConsensus is the consensus USD price of code. USDT counts as
+// USD. RUB has no mid-market source while it is bridged through Whitebird.
+func (ac *APICache) referenceUSDConsensus(ctx context.Context, code string) (consensusPrice, error) {
+	switch code {
+	case CurrencyUSD, CurrencyUSDT:
+		return consensusPrice{USD: 1}, nil
+	}
+	if c, ok := consensus(ac.usdPriceQuotes(ctx, code)); ok {
+		return c, nil
+	}
+	return consensusPrice{}, fmt.Errorf("no mid-market rate for %s", code)
+}
+
+// disagreementWarning describes sources that disagree beyond
+// consensusWarningThreshold, for a reference result's subtitle; empty
+// otherwise.
+func disagreementWarning(prices ...consensusPrice) string {
+	worst := consensusPrice{}
+	for _, p := range prices {
+		if p.Disagreement > worst.Disagreement {
+			worst = p
+		}
+	}
+	if consensusWarningThreshold <= 0 || worst.Disagreement <= consensusWarningThreshold {
+		return ""
+	}
+	return fmt.Sprintf("sources disagree by %.1f%% (%s)", worst.Disagreement*100, strings.Join(worst.Sources, ", "))
+}
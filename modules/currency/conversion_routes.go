@@ -1,84 +1,101 @@
 package currency
 
 import (
+	"context"
 	"fmt"
+
+	"github.com/shopspring/decimal"
 )
 
 // routeConversion decides actual path and executes it.
-func (m *CurrencyConverterModule) routeConversion(amount float64, from, to string, apiCache *APICache) (float64, error) {
+func (m *CurrencyConverterModule) routeConversion(ctx context.Context, amount float64, from, to string, apiCache *APICache) (float64, error) {
+	out, err := m.routeLegs(ctx, decimal.NewFromFloat(amount), from, to, apiCache)
+	if err != nil {
+		return 0, err
+	}
+	return out.InexactFloat64(), nil
+}
+
+// routeLegs converts amount along the route for from -> to, carrying it
+// between legs in decimal.
+func (m *CurrencyConverterModule) routeLegs(ctx context.Context, amount decimal.Decimal, from, to string, apiCache *APICache) (decimal.Decimal, error) {
 	fromType := getCurrencyType(from, apiCache)
 	toType := getCurrencyType(to, apiCache)
 
-	// Direct RUB ↔ TON conversions
-	if fromType == "RUB" && toType == "TON" {
-		return m.convertRUBToTON(amount, apiCache)
+	if fiatOnlyMode {
+		if fromType == "fiat" && toType == "fiat" {
+			return m.convertFiatPair(ctx, amount, from, to, apiCache)
+		}
+		return decimal.Zero, fmt.Errorf("conversion route not available in fiat-only mode")
 	}
-	if fromType == "TON" && toType == "RUB" {
-		return m.convertTONToRUB(amount, apiCache)
+
+	// Direct RUB ↔ TON conversions
+	if (fromType == "RUB" && toType == "TON") || (fromType == "TON" && toType == "RUB") {
+		return m.convertDirectPair(ctx, amount, from, to, apiCache)
 	}
 
 	// RUB to other currencies via TON bridge
 	if fromType == "RUB" && toType == "crypto" {
-		return m.convertViaRoute(amount, from, to, apiCache, []string{"TON", "USDT"})
+		return m.convertViaRoute(ctx, amount, from, to, apiCache, []string{"TON", "USDT"})
 	}
 	if fromType == "RUB" && toType == "fiat" {
-		return m.convertViaRoute(amount, from, to, apiCache, []string{"TON", "USDT", "USD"})
+		return m.convertViaRoute(ctx, amount, from, to, apiCache, []string{"TON", "USDT", "USD"})
 	}
 
 	// Other currencies to RUB via TON bridge
 	if fromType == "crypto" && toType == "RUB" {
-		return m.convertViaRoute(amount, from, to, apiCache, []string{"USDT", "TON"})
+		return m.convertViaRoute(ctx, amount, from, to, apiCache, []string{"USDT", "TON"})
 	}
 	if fromType == "fiat" && toType == "RUB" {
-		return m.convertViaRoute(amount, from, to, apiCache, []string{"USD", "USDT", "TON"})
+		return m.convertViaRoute(ctx, amount, from, to, apiCache, []string{"USD", "USDT", "TON"})
 	}
 
 	// Crypto ↔ Crypto via USDT
 	if fromType == "crypto" && toType == "crypto" {
-		return m.convertCryptoPair(amount, from, to, apiCache)
+		return m.convertCryptoPair(ctx, amount, from, to, apiCache)
 	}
 
 	// Fiat ↔ Fiat via USD/Mastercard
 	if fromType == "fiat" && toType == "fiat" {
-		return m.convertFiatPair(amount, from, to, apiCache)
+		return m.convertFiatPair(ctx, amount, from, to, apiCache)
 	}
 
 	// TON ↔ Crypto via USDT
 	if fromType == "TON" && toType == "crypto" {
-		return m.convertViaRoute(amount, from, to, apiCache, []string{"USDT"})
+		return m.convertViaRoute(ctx, amount, from, to, apiCache, []string{"USDT"})
 	}
 	if fromType == "crypto" && toType == "TON" {
-		return m.convertViaRoute(amount, from, to, apiCache, []string{"USDT"})
+		return m.convertViaRoute(ctx, amount, from, to, apiCache, []string{"USDT"})
 	}
 
 	// TON ↔ Fiat via USDT and USD
 	if fromType == "TON" && toType == "fiat" {
-		return m.convertViaRoute(amount, from, to, apiCache, []string{"USDT", "USD"})
+		return m.convertViaRoute(ctx, amount, from, to, apiCache, []string{"USDT", "USD"})
 	}
 	if fromType == "fiat" && toType == "TON" {
-		return m.convertViaRoute(amount, from, to, apiCache, []string{"USD", "USDT"})
+		return m.convertViaRoute(ctx, amount, from, to, apiCache, []string{"USD", "USDT"})
 	}
 
 	// Crypto ↔ Fiat (non-USD) via USDT and USD
 	if fromType == "crypto" && toType == "fiat" && to != "USD" {
-		return m.convertViaRoute(amount, from, to, apiCache, []string{"USDT", "USD"})
+		return m.convertViaRoute(ctx, amount, from, to, apiCache, []string{"USDT", "USD"})
 	}
 	if fromType == "fiat" && toType == "crypto" && from != "USD" {
-		return m.convertViaRoute(amount, from, to, apiCache, []string{"USD", "USDT"})
+		return m.convertViaRoute(ctx, amount, from, to, apiCache, []string{"USD", "USDT"})
 	}
 
 	// Crypto ↔ USD (direct via USDT)
 	if fromType == "crypto" && to == "USD" {
-		return m.convertViaRoute(amount, from, to, apiCache, []string{"USDT"})
+		return m.convertViaRoute(ctx, amount, from, to, apiCache, []string{"USDT"})
 	}
 	if from == "USD" && toType == "crypto" {
-		return m.convertViaRoute(amount, from, to, apiCache, []string{"USDT"})
+		return m.convertViaRoute(ctx, amount, from, to, apiCache, []string{"USDT"})
 	}
 
-	return 0, fmt.Errorf("conversion route not available")
+	return decimal.Zero, fmt.Errorf("conversion route not available")
 }
 
-func (m *CurrencyConverterModule) convertViaRoute(amount float64, from, to string, apiCache *APICache, route []string) (float64, error) {
+func (m *CurrencyConverterModule) convertViaRoute(ctx context.Context, amount decimal.Decimal, from, to string, apiCache *APICache, route []string) (decimal.Decimal, error) {
 	current := amount
 	currentCurrency := from
 
@@ -88,58 +105,70 @@ func (m *CurrencyConverterModule) convertViaRoute(amount float64, from, to strin
 		}
 
 		var err error
-		current, err = m.convertDirectPair(current, currentCurrency, intermediate, apiCache)
+		current, err = m.convertDirectPair(ctx, current, currentCurrency, intermediate, apiCache)
 		if err != nil {
-			return 0, err
+			return decimal.Zero, err
 		}
 		currentCurrency = intermediate
 	}
 
 	if currentCurrency != to {
-		return m.convertDirectPair(current, currentCurrency, to, apiCache)
+		return m.convertDirectPair(ctx, current, currentCurrency, to, apiCache)
 	}
 
 	return current, nil
 }
 
-func (m *CurrencyConverterModule) convertDirectPair(amount float64, from, to string, apiCache *APICache) (float64, error) {
+// convertDirectPair converts one leg of a route and records it.
+func (m *CurrencyConverterModule) convertDirectPair(ctx context.Context, amount decimal.Decimal, from, to string, apiCache *APICache) (decimal.Decimal, error) {
 	if from == to {
 		return amount, nil
 	}
+	out, err := m.convertLeg(ctx, amount, from, to, apiCache)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	recordLeg(ctx, from, to, "", amount.InexactFloat64(), out.InexactFloat64(), apiCache)
+	return out, nil
+}
 
+// convertLeg converts amount over one leg. Order-book and Whitebird legs
+// price in float64 and take the amount across at the leg boundary.
+func (m *CurrencyConverterModule) convertLeg(ctx context.Context, amount decimal.Decimal, from, to string, apiCache *APICache) (decimal.Decimal, error) {
 	fromType := getCurrencyType(from, apiCache)
 	toType := getCurrencyType(to, apiCache)
+	f := amount.InexactFloat64()
 
 	// RUB ↔ TON direct conversions (CRITICAL FIX #2)
 	if from == "RUB" && to == "TON" {
-		return m.convertRUBToTON(amount, apiCache)
+		return floatLeg(m.convertRUBToTON(ctx, f, apiCache))
 	}
 	if from == "TON" && to == "RUB" {
-		return m.convertTONToRUB(amount, apiCache)
+		return floatLeg(m.convertTONToRUB(ctx, f, apiCache))
 	}
 
 	// TON ↔ USDT conversions
 	if from == "TON" && to == "USDT" {
-		return m.convertTONToUSDT(amount, apiCache)
+		return floatLeg(m.convertTONToUSDT(ctx, f, apiCache))
 	}
 	if from == "USDT" && to == "TON" {
-		return m.convertUSDTToTON(amount, apiCache)
+		return floatLeg(m.convertUSDTToTON(ctx, f, apiCache))
 	}
 
 	// USDT ↔ USD conversions (Bybit Card fee)
 	if from == "USDT" && to == "USD" {
-		return amount * (1 - feeUSDTToUSD), nil
+		return deductPercentFee(amount, feeUSDTToUSD), nil
 	}
 	if from == "USD" && to == "USDT" {
-		return amount * (1 - feeUSDToUSDT), nil
+		return deductPercentFee(amount, feeUSDToUSDT), nil
 	}
 
 	// Crypto ↔ USDT conversions
 	if fromType == "crypto" && to == "USDT" {
-		return m.convertCryptoToUSDT(amount, from, apiCache)
+		return floatLeg(m.convertCryptoToUSDT(ctx, f, from, apiCache))
 	}
 	if from == "USDT" && toType == "crypto" {
-		return m.convertUSDTToCrypto(amount, to, apiCache)
+		return floatLeg(m.convertUSDTToCrypto(ctx, f, to, apiCache))
 	}
 
 	// Fiat ↔ USD conversions (Mastercard)
@@ -150,7 +179,7 @@ func (m *CurrencyConverterModule) convertDirectPair(amount float64, from, to str
 		return m.convertUSDToFiat(amount, to, apiCache)
 	}
 
-	return 0, fmt.Errorf("conversion not available")
+	return decimal.Zero, fmt.Errorf("conversion not available")
 }
 
 // planRoute returns the sequence of currency "legs" used by the router, for fee display.
@@ -213,3 +242,34 @@ func (m *CurrencyConverterModule) planRoute(from, to string, apiCache *APICache)
 	}
 	return legs
 }
+
+// fiatAssetClass returns the refresh policy class of a Mastercard currency.
+func fiatAssetClass(code string) string {
+	if metalCodes[code] {
+		return assetMetal
+	}
+	return assetFiat
+}
+
+// assetClassesFor returns the asset classes whose data the from -> to route
+// depends on. USD and USDT are priced with fixed fees and need no data.
+func (m *CurrencyConverterModule) assetClassesFor(from, to string, apiCache *APICache) []string {
+	seen := make(map[string]bool)
+	var classes []string
+	for _, leg := range m.planRoute(from, to, apiCache) {
+		var class string
+		switch {
+		case leg == CurrencyUSD || leg == CurrencyUSDT:
+			continue
+		case getCurrencyType(leg, apiCache) == "fiat":
+			class = fiatAssetClass(leg)
+		default:
+			class = assetCrypto
+		}
+		if !seen[class] {
+			seen[class] = true
+			classes = append(classes, class)
+		}
+	}
+	return classes
+}
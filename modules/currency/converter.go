@@ -10,30 +10,84 @@ import (
 
 type ConversionCache struct {
 	results map[string]*cachedValue
+	ttl     time.Duration
+	stale   time.Duration // how long past ttl getStale still serves a value
+	access  *accessCounter
 	mu      sync.RWMutex
+
+	refreshing sync.Map // key -> struct{}, while a refresh of it runs
 }
 
 type cachedValue struct {
 	value     float64
+	legs      []ConversionStep // as recorded by the conversion that produced value
 	timestamp time.Time
 }
 
 var globalConversionCache = &ConversionCache{
 	results: make(map[string]*cachedValue),
+	ttl:     calculationCacheTTL,
+	stale:   conversionCacheStaleWindow,
+	access:  conversionCacheAccess,
 }
 
 func (c *ConversionCache) Get(key string) (float64, bool) {
+	value, ok := c.peek(key)
+	c.access.record(ok)
+	return value, ok
+}
+
+// peek is Get without counting the access, for lookups that only observe.
+func (c *ConversionCache) peek(key string) (float64, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
 	result, ok := c.results[key]
-	if !ok || time.Since(result.timestamp) >= calculationCacheTTL {
+	if !ok || time.Since(result.timestamp) >= c.ttl {
 		return 0, false
 	}
 	return result.value, true
 }
 
+// getStale is Get that also serves a value up to c.stale past its expiry,
+// reporting whether it is still fresh. Only the caller that gets true from
+// claimRefresh should recompute a stale value.
+func (c *ConversionCache) getStale(key string) (entry cachedValue, fresh, ok bool) {
+	c.mu.RLock()
+	result, found := c.results[key]
+	c.mu.RUnlock()
+
+	if !found {
+		c.access.record(false)
+		return cachedValue{}, false, false
+	}
+	age := time.Since(result.timestamp)
+	if age >= c.ttl+c.stale {
+		c.access.record(false)
+		return cachedValue{}, false, false
+	}
+	c.access.record(true)
+	return *result, age < c.ttl, true
+}
+
+// claimRefresh reports whether the caller is the one to refresh key; it
+// must call releaseRefresh when done.
+func (c *ConversionCache) claimRefresh(key string) bool {
+	_, running := c.refreshing.LoadOrStore(key, struct{}{})
+	return !running
+}
+
+func (c *ConversionCache) releaseRefresh(key string) {
+	c.refreshing.Delete(key)
+}
+
 func (c *ConversionCache) Set(key string, value float64) {
+	c.setRoute(key, value, nil)
+}
+
+// setRoute caches value together with the legs of the route that produced
+// it, so a cache hit can still show how the amount came about.
+func (c *ConversionCache) setRoute(key string, value float64, legs []ConversionStep) {
 	if !isValidFloat(value) {
 		return
 	}
@@ -41,64 +95,119 @@ func (c *ConversionCache) Set(key string, value float64) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if len(c.results) >= maxCacheSize {
-		for k, v := range c.results {
-			if time.Since(v.timestamp) > calculationCacheTTL*2 {
-				delete(c.results, k)
-			}
+	// A full cache drops what has expired, or else its oldest entry, rather
+	// than wait for a janitor run that may not come
+	if _, ok := c.results[key]; !ok && len(c.results) >= maxCacheSize {
+		c.compactLocked(time.Now())
+		if len(c.results) >= maxCacheSize {
+			c.evictOldestLocked()
 		}
 	}
+	c.results[key] = &cachedValue{value, legs, time.Now()}
+}
 
-	c.results[key] = &cachedValue{value, time.Now()}
+func (m *CurrencyConverterModule) convert(ctx context.Context, amount float64, from, to string, apiCache *APICache) (float64, error) {
+	result, _, err := m.convertRoute(ctx, amount, from, to, apiCache)
+	return result, err
 }
 
-func (m *CurrencyConverterModule) convert(amount float64, from, to string, apiCache *APICache) (float64, error) {
+// convertRoute is convert that also returns the legs the conversion took,
+// recorded while it ran or cached with its result, so the route shown next
+// to an amount is the one that produced it.
+func (m *CurrencyConverterModule) convertRoute(ctx context.Context, amount float64, from, to string, apiCache *APICache) (float64, []ConversionStep, error) {
 	if from == to {
-		return amount, nil
+		return amount, nil, nil
 	}
 
 	if err := ValidateAmount(amount); err != nil {
-		return 0, err
+		return 0, nil, err
 	}
 
-	if apiCache.IsStale() {
-		staleness := apiCache.GetCacheStaleness()
-		for _, duration := range staleness {
-			if duration > circuitBreakerTimeout {
-				return 0, fmt.Errorf("exchange rates outdated, please try again")
-			}
-		}
+	// Only the providers on this route matter: stale crypto data shouldn't
+	// block a fiat-only conversion
+	if apiCache.IsStaleFor(m.assetClassesFor(from, to, apiCache)...) {
+		return 0, nil, fmt.Errorf("exchange rates outdated, please try again")
 	}
 
-	if from == CurrencyUSDT && to == CurrencyUSD {
-		return amount * (1 - feeUSDTToUSD), nil
-	}
-	if from == CurrencyUSD && to == CurrencyUSDT {
-		return amount * (1 - feeUSDToUSDT), nil
+	if (from == CurrencyUSDT && to == CurrencyUSD) || (from == CurrencyUSD && to == CurrencyUSDT) {
+		fee := feeUSDTToUSD
+		if from == CurrencyUSD {
+			fee = feeUSDToUSDT
+		}
+		result := applyPercentFee(amount, fee)
+		return result, []ConversionStep{newConversionStep(from, to, "", amount, result, apiCache)}, nil
 	}
 
 	cacheKey := formatCacheKey(from, to, amount)
-	if cached, ok := globalConversionCache.Get(cacheKey); ok {
-		return cached, nil
+	if cached, fresh, ok := globalConversionCache.getStale(cacheKey); ok {
+		if !fresh && globalConversionCache.claimRefresh(cacheKey) {
+			go m.refreshConversion(ctx, cacheKey, amount, from, to, apiCache)
+		}
+		return cached.value, cached.legs, nil
+	}
+
+	// Fiats the background fetch hasn't reached yet are fetched inline
+	for _, code := range []string{from, to} {
+		if getCurrencyType(code, apiCache) == "fiat" {
+			apiCache.ensureMastercardRate(ctx, code)
+		}
 	}
 
-	result, err := m.routeConversion(amount, from, to, apiCache)
+	ctx, recorder := withLegRecorder(ctx)
+	started := time.Now()
+	result, err := m.routeConversion(ctx, amount, from, to, apiCache)
+	recordConversion(getCurrencyType(from, apiCache)+"/"+getCurrencyType(to, apiCache), time.Since(started), err)
 	if err != nil {
-		return 0, err
+		return 0, nil, err
 	}
 
 	if !isValidFloat(result) {
-		return 0, fmt.Errorf("invalid conversion result")
+		return 0, nil, fmt.Errorf("invalid conversion result")
+	}
+
+	// An approximation must not be served as exact to later queries
+	legs := recorder.steps()
+	if !isApproximate(ctx) {
+		globalConversionCache.setRoute(cacheKey, result, legs)
 	}
+	return result, legs, nil
+}
+
+// refreshConversion recomputes a conversion convert served stale and caches
+// the result. It outlives the query that found the value stale and gets a
+// provider budget of its own.
+func (m *CurrencyConverterModule) refreshConversion(ctx context.Context, cacheKey string, amount float64, from, to string, apiCache *APICache) {
+	defer globalConversionCache.releaseRefresh(cacheKey)
+
+	ctx, cancel := context.WithTimeout(withProviderBudget(context.WithoutCancel(ctx)), queryProviderTime)
+	defer cancel()
+	ctx, recorder := withLegRecorder(ctx)
 
-	globalConversionCache.Set(cacheKey, result)
-	return result, nil
+	started := time.Now()
+	result, err := m.routeConversion(ctx, amount, from, to, apiCache)
+	recordConversion(getCurrencyType(from, apiCache)+"/"+getCurrencyType(to, apiCache), time.Since(started), err)
+	if err != nil {
+		log.Printf("Warning: refreshing %s %s->%s failed, serving the stale value until it expires: %v", formatAmount(amount, from), from, to, err)
+		return
+	}
+	if !isValidFloat(result) || isApproximate(ctx) {
+		return
+	}
+	globalConversionCache.setRoute(cacheKey, result, recorder.steps())
 }
 
 func getCurrencyType(code string, apiCache *APICache) string {
+	if fiatOnlyMode {
+		if apiCache.IsFiat(code) {
+			return "fiat"
+		}
+		return "unknown"
+	}
 	switch code {
 	case "RUB":
-		return "RUB"
+		if rubBridgeEnabled {
+			return "RUB"
+		}
 	case "TON":
 		return "TON"
 	}
@@ -111,7 +220,7 @@ func getCurrencyType(code string, apiCache *APICache) string {
 	return "unknown"
 }
 
-func (m *CurrencyConverterModule) findInverseAmount(targetAmount float64, sourceCurrency, targetCurrency string, apiCache *APICache) (float64, error) {
+func (m *CurrencyConverterModule) findInverseAmount(ctx context.Context, targetAmount float64, sourceCurrency, targetCurrency string, apiCache *APICache) (float64, error) {
 	if err := ValidateAmount(targetAmount); err != nil {
 		return 0, err
 	}
@@ -121,6 +230,11 @@ func (m *CurrencyConverterModule) findInverseAmount(targetAmount float64, source
 		return cached, nil
 	}
 
+	if sourceNeeded, ok := m.inverseViaWhitebirdTarget(ctx, targetAmount, sourceCurrency, targetCurrency, apiCache); ok {
+		globalConversionCache.Set(cacheKey, sourceNeeded)
+		return sourceNeeded, nil
+	}
+
 	testAmount := 1.0
 	if sourceCurrency == CurrencyRUB || sourceCurrency == CurrencyTON {
 		testAmount = 1000.0
@@ -132,7 +246,7 @@ func (m *CurrencyConverterModule) findInverseAmount(targetAmount float64, source
 		}
 	}
 
-	resultFromTest, err := m.convert(testAmount, sourceCurrency, targetCurrency, apiCache)
+	resultFromTest, err := m.convert(ctx, testAmount, sourceCurrency, targetCurrency, apiCache)
 	if err != nil || resultFromTest <= 0 {
 		return 0, fmt.Errorf("failed to get rate")
 	}
@@ -145,7 +259,7 @@ func (m *CurrencyConverterModule) findInverseAmount(targetAmount float64, source
 		tolerance := 0.01
 
 		for i := 0; i < maxIterations; i++ {
-			actualResult, err := m.convert(sourceNeeded, sourceCurrency, targetCurrency, apiCache)
+			actualResult, err := m.convert(ctx, sourceNeeded, sourceCurrency, targetCurrency, apiCache)
 			if err != nil {
 				if i == 0 {
 					break
@@ -171,10 +285,46 @@ func (m *CurrencyConverterModule) findInverseAmount(targetAmount float64, source
 		return 0, err
 	}
 
-	globalConversionCache.Set(cacheKey, sourceNeeded)
+	if !isApproximate(ctx) {
+		globalConversionCache.Set(cacheKey, sourceNeeded)
+	}
 	return sourceNeeded, nil
 }
 
+// inverseViaWhitebirdTarget answers direct RUB<->TON inverse questions with
+// one output-targeted Whitebird quote, undoing the fixed TON withdrawal fees
+// of convertRUBToTON and convertTONToRUB. It reports false whenever the
+// quote is unavailable and the forward search should run instead.
+func (m *CurrencyConverterModule) inverseViaWhitebirdTarget(ctx context.Context, targetAmount float64, sourceCurrency, targetCurrency string, apiCache *APICache) (float64, bool) {
+	if getCurrencyType(sourceCurrency, apiCache) == "RUB" && getCurrencyType(targetCurrency, apiCache) == "TON" {
+		if !apiCache.IsWhitebirdAvailable() {
+			return 0, false
+		}
+		// convertRUBToTON pays the TON withdrawal fee out of Whitebird's output
+		rub, err := apiCache.GetWhitebirdInputForOutput(ctx, CurrencyRUB, CurrencyTON, targetAmount+feeTONWithdrawToBybit)
+		if err != nil || ValidateAmount(rub) != nil {
+			return 0, false
+		}
+		return rub, true
+	}
+	if getCurrencyType(sourceCurrency, apiCache) == "TON" && getCurrencyType(targetCurrency, apiCache) == "RUB" {
+		if !apiCache.IsWhitebirdAvailable() {
+			return 0, false
+		}
+		ton, err := apiCache.GetWhitebirdInputForOutput(ctx, CurrencyTON, CurrencyRUB, targetAmount)
+		if err != nil {
+			return 0, false
+		}
+		// convertTONToRUB withdraws from Bybit before sending to Whitebird
+		ton += feeTONWithdrawToWhitebird
+		if ValidateAmount(ton) != nil {
+			return 0, false
+		}
+		return ton, true
+	}
+	return 0, false
+}
+
 func retryWithBackoff(ctx context.Context, fn func() error) error {
 	var lastErr error
 	delay := baseRetryDelay
@@ -1,10 +1,13 @@
 package currency
 
 import (
+	"context"
 	"fmt"
+
+	"github.com/shopspring/decimal"
 )
 
-func (m *CurrencyConverterModule) convertTONToUSDT(amount float64, apiCache *APICache) (float64, error) {
+func (m *CurrencyConverterModule) convertTONToUSDT(ctx context.Context, amount float64, apiCache *APICache) (float64, error) {
 	rate, err := apiCache.GetBybitRate("TONUSDT")
 	if err != nil {
 		return 0, err
@@ -13,26 +16,22 @@ func (m *CurrencyConverterModule) convertTONToUSDT(amount float64, apiCache *API
 	var gross float64
 	usdValue := amount * rate.BestBid
 	if shouldUseOrderBookByUSD(usdValue) {
-		avgPrice, err := apiCache.GetBybitRateForAmount("TONUSDT", amount, false)
-		if err != nil {
-			return 0, fmt.Errorf("amount too large for current market liquidity")
+		if err := apiCache.checkLargeOrderLiquidity("TONUSDT", usdValue); err != nil {
+			return 0, err
 		}
-		gross = amount * avgPrice
-	} else {
-		if len(rate.OrderBookBids) > 0 && len(rate.OrderBookBids[0]) >= 2 {
-			bidSize := rate.OrderBookBids[0][1]
-			if bidSize < amount {
-				avgPrice, err := apiCache.GetBybitRateForAmount("TONUSDT", amount, false)
-				if err != nil {
-					return 0, fmt.Errorf("insufficient liquidity for this amount")
-				}
-				gross = amount * avgPrice
-			} else {
-				gross = amount * rate.BestBid
-			}
-		} else {
+		if !apiCache.ensureBybitDepth(ctx, "TONUSDT", usdValue) {
 			gross = amount * rate.BestBid
+		} else {
+			avgPrice, err := apiCache.GetBybitRateForAmount("TONUSDT", amount, false)
+			if err != nil {
+				return 0, fmt.Errorf("amount too large for current market liquidity")
+			}
+			gross = amount * avgPrice
 		}
+	} else {
+		// Below the order book threshold the best bid prices the sale, even
+		// when its level is thinner than amount
+		gross = amount * rate.BestBid
 	}
 
 	result := gross * (1 - feeBybitTrade)
@@ -43,10 +42,15 @@ func (m *CurrencyConverterModule) convertTONToUSDT(amount float64, apiCache *API
 	return result, nil
 }
 
-func (m *CurrencyConverterModule) convertUSDTToTON(usdt float64, apiCache *APICache) (float64, error) {
+func (m *CurrencyConverterModule) convertUSDTToTON(ctx context.Context, usdt float64, apiCache *APICache) (float64, error) {
 	var ton float64
 
 	if shouldUseOrderBookByUSD(usdt) {
+		if err := apiCache.checkLargeOrderLiquidity("TONUSDT", usdt); err != nil {
+			return 0, err
+		}
+	}
+	if shouldUseOrderBookByUSD(usdt) && apiCache.ensureBybitDepth(ctx, "TONUSDT", usdt) {
 		t, _, err := apiCache.CalculateBuyAmountWithUSDT("TONUSDT", usdt)
 		if err != nil {
 			return 0, fmt.Errorf("amount too large for current market liquidity")
@@ -68,10 +72,13 @@ func (m *CurrencyConverterModule) convertUSDTToTON(usdt float64, apiCache *APICa
 	return result, nil
 }
 
-func (m *CurrencyConverterModule) convertUSDTToCrypto(usdt float64, to string, apiCache *APICache) (float64, error) {
+func (m *CurrencyConverterModule) convertUSDTToCrypto(ctx context.Context, usdt float64, to string, apiCache *APICache) (float64, error) {
 	symbol := to + "USDT"
 
-	if err := apiCache.EnsureBybitSymbol(symbol); err != nil {
+	if err := apiCache.EnsureBybitSymbol(ctx, symbol); err != nil {
+		if price, perr := apiCache.GetIndexPrice(ctx, to); perr == nil {
+			return usdt / price, nil
+		}
 		return 0, fmt.Errorf("cryptocurrency %s not available: %w", to, err)
 	}
 
@@ -81,6 +88,11 @@ func (m *CurrencyConverterModule) convertUSDTToCrypto(usdt float64, to string, a
 
 	var crypto float64
 	if shouldUseOrderBookByUSD(usdt) {
+		if err := apiCache.checkLargeOrderLiquidity(symbol, usdt); err != nil {
+			return 0, err
+		}
+	}
+	if shouldUseOrderBookByUSD(usdt) && apiCache.ensureBybitDepth(ctx, symbol, usdt) {
 		c, _, err := apiCache.CalculateBuyAmountWithUSDT(symbol, usdt)
 		if err != nil {
 			return 0, fmt.Errorf("amount too large for current market liquidity")
@@ -102,10 +114,13 @@ func (m *CurrencyConverterModule) convertUSDTToCrypto(usdt float64, to string, a
 	return result, nil
 }
 
-func (m *CurrencyConverterModule) convertCryptoToUSDT(amount float64, from string, apiCache *APICache) (float64, error) {
+func (m *CurrencyConverterModule) convertCryptoToUSDT(ctx context.Context, amount float64, from string, apiCache *APICache) (float64, error) {
 	symbol := from + "USDT"
 
-	if err := apiCache.EnsureBybitSymbol(symbol); err != nil {
+	if err := apiCache.EnsureBybitSymbol(ctx, symbol); err != nil {
+		if price, perr := apiCache.GetIndexPrice(ctx, from); perr == nil {
+			return amount * price, nil
+		}
 		return 0, fmt.Errorf("cryptocurrency %s not available: %w", from, err)
 	}
 
@@ -121,26 +136,22 @@ func (m *CurrencyConverterModule) convertCryptoToUSDT(amount float64, from strin
 	var gross float64
 	usdValue := amount * rate.BestBid
 	if shouldUseOrderBookByUSD(usdValue) {
-		avgPrice, err := apiCache.GetBybitRateForAmount(symbol, amount, false)
-		if err != nil {
-			return 0, fmt.Errorf("amount too large for current market liquidity")
+		if err := apiCache.checkLargeOrderLiquidity(symbol, usdValue); err != nil {
+			return 0, err
 		}
-		gross = amount * avgPrice
-	} else {
-		if len(rate.OrderBookBids) > 0 && len(rate.OrderBookBids[0]) >= 2 {
-			bidSize := rate.OrderBookBids[0][1]
-			if bidSize < amount {
-				avgPrice, err := apiCache.GetBybitRateForAmount(symbol, amount, false)
-				if err != nil {
-					return 0, fmt.Errorf("insufficient liquidity for this amount")
-				}
-				gross = amount * avgPrice
-			} else {
-				gross = amount * rate.BestBid
-			}
-		} else {
+		if !apiCache.ensureBybitDepth(ctx, symbol, usdValue) {
 			gross = amount * rate.BestBid
+		} else {
+			avgPrice, err := apiCache.GetBybitRateForAmount(symbol, amount, false)
+			if err != nil {
+				return 0, fmt.Errorf("amount too large for current market liquidity")
+			}
+			gross = amount * avgPrice
 		}
+	} else {
+		// Below the order book threshold the best bid prices the sale, even
+		// when its level is thinner than amount
+		gross = amount * rate.BestBid
 	}
 
 	result := gross * (1 - feeBybitTrade)
@@ -151,32 +162,29 @@ func (m *CurrencyConverterModule) convertCryptoToUSDT(amount float64, from strin
 	return result, nil
 }
 
-func (m *CurrencyConverterModule) convertCryptoPair(amount float64, from, to string, apiCache *APICache) (float64, error) {
-	if from == CurrencyUSDT {
-		return m.convertUSDTToCrypto(amount, to, apiCache)
-	}
-	if to == CurrencyUSDT {
-		return m.convertCryptoToUSDT(amount, from, apiCache)
+func (m *CurrencyConverterModule) convertCryptoPair(ctx context.Context, amount decimal.Decimal, from, to string, apiCache *APICache) (decimal.Decimal, error) {
+	if from == CurrencyUSDT || to == CurrencyUSDT {
+		return m.convertDirectPair(ctx, amount, from, to, apiCache)
 	}
 
-	usdt, err := m.convertCryptoToUSDT(amount, from, apiCache)
+	usdt, err := m.convertDirectPair(ctx, amount, from, CurrencyUSDT, apiCache)
 	if err != nil {
-		return 0, err
+		return decimal.Zero, err
 	}
-	return m.convertUSDTToCrypto(usdt, to, apiCache)
+	return m.convertDirectPair(ctx, usdt, CurrencyUSDT, to, apiCache)
 }
 
-func (m *CurrencyConverterModule) convertRUBToTON(amount float64, apiCache *APICache) (float64, error) {
+func (m *CurrencyConverterModule) convertRUBToTON(ctx context.Context, amount float64, apiCache *APICache) (float64, error) {
 	if !apiCache.IsWhitebirdAvailable() {
-		return 0, fmt.Errorf("russian ruble exchange temporarily unavailable")
+		return 0, apiCache.whitebirdUnavailableError()
 	}
 
-	tonReceived, err := apiCache.GetWhitebirdRateForAmount(CurrencyRUB, CurrencyTON, amount)
+	tonReceived, err := apiCache.GetWhitebirdRateForAmount(ctx, CurrencyRUB, CurrencyTON, amount)
 	if err != nil {
 		return 0, err
 	}
 
-	tonNet := tonReceived - feeTONWithdrawToBybit
+	tonNet := applyFixedFee(tonReceived, feeTONWithdrawToBybit)
 	if tonNet <= 0 {
 		return 0, fmt.Errorf("amount too small after withdrawal fee")
 	}
@@ -188,17 +196,17 @@ func (m *CurrencyConverterModule) convertRUBToTON(amount float64, apiCache *APIC
 	return tonNet, nil
 }
 
-func (m *CurrencyConverterModule) convertTONToRUB(amount float64, apiCache *APICache) (float64, error) {
+func (m *CurrencyConverterModule) convertTONToRUB(ctx context.Context, amount float64, apiCache *APICache) (float64, error) {
 	if !apiCache.IsWhitebirdAvailable() {
-		return 0, fmt.Errorf("russian ruble exchange temporarily unavailable")
+		return 0, apiCache.whitebirdUnavailableError()
 	}
 
-	tonForWhitebird := amount - feeTONWithdrawToWhitebird
+	tonForWhitebird := applyFixedFee(amount, feeTONWithdrawToWhitebird)
 	if tonForWhitebird <= 0 {
 		return 0, fmt.Errorf("amount too small after withdrawal fee (need at least 0.02 TON for fee)")
 	}
 
-	rubReceived, err := apiCache.GetWhitebirdRateForAmount(CurrencyTON, CurrencyRUB, tonForWhitebird)
+	rubReceived, err := apiCache.GetWhitebirdRateForAmount(ctx, CurrencyTON, CurrencyRUB, tonForWhitebird)
 	if err != nil {
 		return 0, err
 	}
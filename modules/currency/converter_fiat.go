@@ -1,61 +1,86 @@
 package currency
 
 import (
-	"fmt"
+	"context"
+
+	"github.com/shopspring/decimal"
 )
 
-func (m *CurrencyConverterModule) convertFiatToUSD(amount float64, from string, apiCache *APICache) (float64, error) {
+func (m *CurrencyConverterModule) convertFiatToUSD(amount decimal.Decimal, from string, apiCache *APICache) (decimal.Decimal, error) {
 	if from == CurrencyUSD {
 		return amount, nil
 	}
 
-	rate, err := apiCache.GetMastercardRate(from, CurrencyUSD)
+	network, err := apiCache.selectCardNetwork(from, CurrencyUSD)
 	if err != nil {
-		return 0, err
-	}
-
-	result := amount * rate / (1 + feeMastercard)
-	if err := ValidateConversionResult(result, "fiat->USD"); err != nil {
-		return 0, err
+		return decimal.Zero, err
 	}
-
-	return result, nil
+	return m.convertCardLeg(amount, from, CurrencyUSD, network, apiCache)
 }
 
-func (m *CurrencyConverterModule) convertUSDToFiat(amount float64, to string, apiCache *APICache) (float64, error) {
+func (m *CurrencyConverterModule) convertUSDToFiat(amount decimal.Decimal, to string, apiCache *APICache) (decimal.Decimal, error) {
 	if to == CurrencyUSD {
 		return amount, nil
 	}
 
-	rate, err := apiCache.GetMastercardRate(CurrencyUSD, to)
+	network, err := apiCache.selectCardNetwork(CurrencyUSD, to)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return m.convertCardLeg(amount, CurrencyUSD, to, network, apiCache)
+}
+
+// convertCardLeg converts between USD and another fiat at a card network's
+// rate, fee included.
+func (m *CurrencyConverterModule) convertCardLeg(amount decimal.Decimal, from, to, network string, apiCache *APICache) (decimal.Decimal, error) {
+	rate, fee, err := apiCache.cardRate(network, from, to)
 	if err != nil {
-		return 0, err
+		return decimal.Zero, err
 	}
 
-	result := amount * rate / (1 + feeMastercard)
-	if err := ValidateConversionResult(result, "USD->fiat"); err != nil {
-		return 0, err
+	result := applyRateWithFee(amount, rate, fee)
+	if err := ValidateConversionResult(result.InexactFloat64(), from+"->"+to); err != nil {
+		return decimal.Zero, err
 	}
 
 	return result, nil
 }
 
-func (m *CurrencyConverterModule) convertFiatPair(amount float64, from, to string, apiCache *APICache) (float64, error) {
+func (m *CurrencyConverterModule) convertFiatPair(ctx context.Context, amount decimal.Decimal, from, to string, apiCache *APICache) (decimal.Decimal, error) {
 	if from == to {
 		return amount, nil
 	}
 
-	if !apiCache.IsMastercardAvailable() {
-		return 0, fmt.Errorf("fiat currency exchange temporarily unavailable")
+	// One card pays for both legs, so both use the same network
+	network, err := apiCache.selectCardNetwork(from, to)
+	if err != nil {
+		return decimal.Zero, err
 	}
+	return m.convertFiatPairVia(ctx, amount, from, to, network, apiCache)
+}
 
-	usd, err := m.convertFiatToUSD(amount, from, apiCache)
-	if err != nil {
-		return 0, err
+// convertFiatPairVia converts a fiat pair through USD with one card network.
+func (m *CurrencyConverterModule) convertFiatPairVia(ctx context.Context, amount decimal.Decimal, from, to, network string, apiCache *APICache) (decimal.Decimal, error) {
+	if from == to {
+		return amount, nil
+	}
+
+	usd := amount
+	if from != CurrencyUSD {
+		var err error
+		if usd, err = m.convertCardLeg(amount, from, CurrencyUSD, network, apiCache); err != nil {
+			return decimal.Zero, err
+		}
+		recordLeg(ctx, from, CurrencyUSD, network, amount.InexactFloat64(), usd.InexactFloat64(), apiCache)
 	}
 
 	if to == CurrencyUSD {
 		return usd, nil
 	}
-	return m.convertUSDToFiat(usd, to, apiCache)
+	out, err := m.convertCardLeg(usd, CurrencyUSD, to, network, apiCache)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	recordLeg(ctx, CurrencyUSD, to, network, usd.InexactFloat64(), out.InexactFloat64(), apiCache)
+	return out, nil
 }
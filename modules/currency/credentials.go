@@ -0,0 +1,91 @@
+package currency
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// secret holds an API credential. Its formatting methods redact the value so
+// it can't end up in logs or JSON by accident; use string(s) to read it.
+type secret string
+
+func (s secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return "[redacted]"
+}
+
+func (s secret) GoString() string { return s.String() }
+
+func (s secret) MarshalJSON() ([]byte, error) { return []byte(`"` + s.String() + `"`), nil }
+
+// loadSecret reads key from the environment, or from the file named by
+// key_FILE (Docker/Kubernetes secrets). Empty means not configured.
+func loadSecret(key string) secret {
+	if value := os.Getenv(key); value != "" {
+		return secret(strings.TrimSpace(value))
+	}
+	path := os.Getenv(key + "_FILE")
+	if path == "" {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("Warning: failed to read %s_FILE: %v", key, err)
+		return ""
+	}
+	return secret(strings.TrimSpace(string(data)))
+}
+
+// Optional provider credentials. Authenticated requests get higher rate
+// limits; everything works without them.
+var credentials = struct {
+	bybitAPIKey     secret
+	bybitAPISecret  secret
+	coingeckoAPIKey secret
+}{
+	bybitAPIKey:     loadSecret("BYBIT_API_KEY"),
+	bybitAPISecret:  loadSecret("BYBIT_API_SECRET"),
+	coingeckoAPIKey: loadSecret("COINGECKO_API_KEY"),
+}
+
+const (
+	bybitRecvWindow                 = "5000"
+	bybitAuthenticatedRatePerMinute = 400
+	bybitAuthenticatedRateBurst     = 60
+)
+
+func init() {
+	if credentials.bybitAPIKey != "" && credentials.bybitAPISecret != "" {
+		bybitLimiter.SetLimit(rate.Every(time.Minute / bybitAuthenticatedRatePerMinute))
+		bybitLimiter.SetBurst(bybitAuthenticatedRateBurst)
+		log.Println("Bybit API key configured, using authenticated requests")
+	}
+}
+
+// signBybitRequest adds Bybit v5 authentication headers to a GET request
+// when credentials are configured. The signature covers
+// timestamp + api key + recv window + query string.
+func signBybitRequest(req *http.Request) {
+	if credentials.bybitAPIKey == "" || credentials.bybitAPISecret == "" {
+		return
+	}
+	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(credentials.bybitAPISecret))
+	mac.Write([]byte(timestamp + string(credentials.bybitAPIKey) + bybitRecvWindow + req.URL.RawQuery))
+
+	req.Header.Set("X-BAPI-API-KEY", string(credentials.bybitAPIKey))
+	req.Header.Set("X-BAPI-TIMESTAMP", timestamp)
+	req.Header.Set("X-BAPI-RECV-WINDOW", bybitRecvWindow)
+	req.Header.Set("X-BAPI-SIGN", hex.EncodeToString(mac.Sum(nil)))
+}
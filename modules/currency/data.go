@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 	"sync"
+	"unicode/utf8"
 )
 
 //go:embed config/currency_symbols.json
@@ -15,10 +17,33 @@ var embeddedSymbolsJSON []byte
 //go:embed config/currency_name_aliases.json
 var embeddedNameAliasesJSON []byte
 
+//go:embed config/currency_ambiguous.json
+var embeddedAmbiguousJSON []byte
+
+// cryptoSubUnits maps denominations that are quoted as a fraction of their
+// asset ("50000 sats", "21 gwei") to that asset and the fraction.
+var cryptoSubUnits = map[string]struct {
+	code   string
+	factor float64
+}{
+	"sat":      {"BTC", 1e-8},
+	"sats":     {"BTC", 1e-8},
+	"satoshi":  {"BTC", 1e-8},
+	"satoshis": {"BTC", 1e-8},
+	"gwei":     {"ETH", 1e-9},
+	"wei":      {"ETH", 1e-18},
+	"nanoton":  {CurrencyTON, 1e-9},
+	"nanotons": {CurrencyTON, 1e-9},
+}
+
 type CurrencyData struct {
 	symbols     map[string]string
 	nameAliases map[string]string
 	validCodes  map[string]string
+	ambiguous   map[string][]string // token -> interpretations, preferred first
+	parseCache  *parseLRU
+	completions []completion // prefix index, rebuilt whenever the tables change
+	packs       packEntries  // entries added from ALIAS_PACKS_DIR
 	mu          sync.RWMutex
 	initialised bool
 }
@@ -36,22 +61,56 @@ func NewCurrencyData() *CurrencyData {
 		loadedAliases = make(map[string]string)
 	}
 
+	var loadedAmbiguous map[string][]string
+	if err := json.Unmarshal(embeddedAmbiguousJSON, &loadedAmbiguous); err != nil {
+		log.Printf("Warning: Failed to load ambiguous tokens: %v", err)
+	}
+
 	cd := &CurrencyData{
 		symbols:     make(map[string]string),
 		nameAliases: make(map[string]string),
 		validCodes:  make(map[string]string),
+		ambiguous:   make(map[string][]string),
+		parseCache:  newParseLRU(parseCacheSize),
 		initialised: false,
 	}
 
+	for token, codes := range loadedAmbiguous {
+		var usable []string
+		for _, code := range codes {
+			if !fiatOnlyMode || isFiatCode(code) {
+				usable = append(usable, code)
+			}
+		}
+		if len(usable) > 0 {
+			cd.ambiguous[strings.ToLower(token)] = orderByPrecedence(usable)
+		}
+	}
+
 	for symbol, code := range loadedSymbols {
 		canonicalCode := strings.ToUpper(code)
+		if fiatOnlyMode && !isFiatCode(canonicalCode) {
+			continue
+		}
 		cd.symbols[symbol] = canonicalCode
 		cd.validCodes[strings.ToLower(canonicalCode)] = canonicalCode
 	}
 
+	// The alias table holds colloquial and other-language names. ISO 4217
+	// names ("swiss franc") come from the dataset, unless the table maps
+	// them elsewhere ("som").
+	for code, c := range isoCurrencies {
+		if name := strings.ToLower(c.Name); loadedAliases[name] == "" {
+			loadedAliases[name] = code
+		}
+	}
+
 	for alias, code := range loadedAliases {
 		lcAlias := strings.ToLower(alias)
 		canonicalCode := strings.ToUpper(code)
+		if isAliasTooShort(lcAlias) || (fiatOnlyMode && !isFiatCode(canonicalCode)) {
+			continue
+		}
 		cd.nameAliases[lcAlias] = canonicalCode
 		cd.validCodes[strings.ToLower(canonicalCode)] = canonicalCode
 
@@ -60,9 +119,26 @@ func NewCurrencyData() *CurrencyData {
 		}
 	}
 
+	if aliasPacksDir != "" {
+		cd.loadAliasPacks()
+	} else {
+		cd.rebuildPatternsLocked()
+		cd.rebuildCompletionsLocked()
+	}
+
 	return cd
 }
 
+// isFiatCode reports whether code is a Mastercard-priced fiat or metal.
+func isFiatCode(code string) bool {
+	for _, f := range supportedFiats {
+		if f == code {
+			return true
+		}
+	}
+	return false
+}
+
 func loadConfigMap(data []byte, description string) (map[string]string, error) {
 	var configMap map[string]string
 	if err := json.Unmarshal(data, &configMap); err != nil {
@@ -106,12 +182,28 @@ func (cd *CurrencyData) PopulateDynamicAliases(allCurrencies map[string]string)
 		}
 	}
 	cd.initialised = true
+	// Queries that failed on an unknown code may parse now
+	cd.parseCache.clear()
+	cd.rebuildCompletionsLocked()
+}
+
+// isAliasTooShort reports whether alias is a single letter. Those are left to
+// the case-sensitive symbol table ("R" is ZAR): typed in lower case, a lone
+// letter is the start of a longer word ("100 r" on the way to "100 rand").
+func isAliasTooShort(alias string) bool {
+	return utf8.RuneCountInString(alias) < 2
 }
 
 func (cd *CurrencyData) ResolveCurrency(s string) (string, error) {
 	cd.mu.RLock()
 	defer cd.mu.RUnlock()
+	return cd.resolveCurrencyLocked(s)
+}
 
+// resolveCurrencyLocked is ResolveCurrency for callers already holding
+// cd.mu. A second RLock would deadlock against a waiting writer (an alias
+// pack reload).
+func (cd *CurrencyData) resolveCurrencyLocked(s string) (string, error) {
 	sTrimmed := strings.TrimSpace(s)
 	sLower := strings.ToLower(sTrimmed)
 
@@ -119,6 +211,10 @@ func (cd *CurrencyData) ResolveCurrency(s string) (string, error) {
 		return "", fmt.Errorf("empty currency")
 	}
 
+	if codes, ok := cd.ambiguous[sLower]; ok {
+		return codes[0], nil
+	}
+
 	if code, ok := cd.symbols[sTrimmed]; ok {
 		return code, nil
 	}
@@ -145,13 +241,74 @@ func (cd *CurrencyData) ResolveCurrency(s string) (string, error) {
 	return "", fmt.Errorf("unknown currency '%s'", sTrimmed)
 }
 
+// resolveAmountCurrency resolves the source currency of a query, converting
+// amounts given in a crypto sub-unit to the canonical asset.
+func (cd *CurrencyData) resolveAmountCurrency(s string, amount float64) (string, float64, error) {
+	if unit, ok := cryptoSubUnits[strings.ToLower(strings.TrimSpace(s))]; ok && !fiatOnlyMode {
+		return unit.code, amount * unit.factor, nil
+	}
+	code, err := cd.ResolveCurrency(s)
+	return code, amount, err
+}
+
+// Interpretations returns every currency an ambiguous token ("sol", "kr")
+// can mean, preferred first, or nil for unambiguous tokens.
+func (cd *CurrencyData) Interpretations(token string) []string {
+	cd.mu.RLock()
+	defer cd.mu.RUnlock()
+	return cd.ambiguous[strings.ToLower(strings.TrimSpace(token))]
+}
+
+// orderByPrecedence stably sorts interpretations by the asset class order in
+// AMBIGUOUS_PRECEDENCE ("crypto,fiat" or "fiat,crypto"); without it the
+// order of config/currency_ambiguous.json stands.
+func orderByPrecedence(codes []string) []string {
+	if len(ambiguousPrecedence) == 0 {
+		return codes
+	}
+	rank := func(code string) int {
+		class := "fiat"
+		for _, c := range supportedCryptos {
+			if c == code {
+				class = "crypto"
+				break
+			}
+		}
+		for i, p := range ambiguousPrecedence {
+			if p == class {
+				return i
+			}
+		}
+		return len(ambiguousPrecedence)
+	}
+	sort.SliceStable(codes, func(i, j int) bool { return rank(codes[i]) < rank(codes[j]) })
+	return codes
+}
+
+// isKnownCurrency reports whether s is a configured code, alias or symbol,
+// without ResolveCurrency's fallback of accepting any three-letter word.
+func (cd *CurrencyData) isKnownCurrency(s string) bool {
+	cd.mu.RLock()
+	defer cd.mu.RUnlock()
+
+	lower := strings.ToLower(s)
+	if _, ok := cd.symbols[s]; ok {
+		return true
+	}
+	if _, ok := cd.validCodes[lower]; ok {
+		return true
+	}
+	_, ok := cd.nameAliases[lower]
+	return ok
+}
+
 func (cd *CurrencyData) ExtractSymbol(currCandidate, amountStr string) (string, string) {
 	cd.mu.RLock()
 	defer cd.mu.RUnlock()
 
 	amountStr = strings.TrimSpace(amountStr)
 
-	if resolvedCode, err := cd.ResolveCurrency(currCandidate); err == nil {
+	if resolvedCode, err := cd.resolveCurrencyLocked(currCandidate); err == nil {
 		return resolvedCode, amountStr
 	}
 
@@ -175,7 +332,7 @@ func (cd *CurrencyData) ExtractSymbol(currCandidate, amountStr string) (string,
 		return bestSuffixCode, strings.TrimSpace(strings.TrimSuffix(amountStr, bestSuffix))
 	}
 
-	if resolved, err := cd.ResolveCurrency(currCandidate); err == nil {
+	if resolved, err := cd.resolveCurrencyLocked(currCandidate); err == nil {
 		return resolved, amountStr
 	}
 
@@ -0,0 +1,442 @@
+package currency
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// With DNS_CACHE on, provider hostnames are resolved through a small cache so
+// a DNS hiccup doesn't turn into failed conversions. Names are queried over
+// UDP from the nameservers in /etc/resolv.conf, then DNS_FALLBACK_SERVERS, and
+// kept for their record TTL (clamped to DNS_CACHE_MIN_TTL..DNS_CACHE_MAX_TTL).
+// When every server fails, the system resolver is asked, and failing that an
+// expired answer is served for up to DNS_CACHE_STALE. Single-label names go
+// straight to the system resolver, since they only make sense with
+// /etc/hosts or search domains. The cache is off by default: it bypasses
+// /etc/hosts for other names, so deployments that pin provider hosts there
+// should leave it off.
+var (
+	dnsCacheEnabled    = getEnvBoolOrDefault("DNS_CACHE", false)
+	dnsFallbackServers = splitList(getEnvOrDefault("DNS_FALLBACK_SERVERS", ""))
+	dnsCacheMinTTL     = getEnvDurationOrDefault("DNS_CACHE_MIN_TTL", 10*time.Second)
+	dnsCacheMaxTTL     = getEnvDurationOrDefault("DNS_CACHE_MAX_TTL", 10*time.Minute)
+	dnsCacheStale      = getEnvDurationOrDefault("DNS_CACHE_STALE", time.Hour)
+	dnsQueryTimeout    = getEnvDurationOrDefault("DNS_QUERY_TIMEOUT", 2*time.Second)
+
+	// Happy eyeballs: how long the first address family gets before the
+	// other one is tried in parallel.
+	dialFallbackDelay = getEnvDurationOrDefault("DIAL_FALLBACK_DELAY", 300*time.Millisecond)
+)
+
+var providerDNS = newDNSCache()
+
+type dnsEntry struct {
+	ips     []net.IP
+	expires time.Time
+}
+
+// DNSHostStats are resolution metrics for one hostname.
+type DNSHostStats struct {
+	Lookups        int64     `json:"lookups"`      // resolutions that went to a server
+	CacheHits      int64     `json:"cache_hits"`   // answered from a fresh entry
+	StaleServed    int64     `json:"stale_served"` // answered from an expired entry after a failure
+	Failures       int64     `json:"failures"`
+	LastDurationMs float64   `json:"last_duration_ms"`
+	AvgDurationMs  float64   `json:"avg_duration_ms"`
+	MaxDurationMs  float64   `json:"max_duration_ms"`
+	LastServer     string    `json:"last_server,omitempty"`
+	Addresses      []string  `json:"addresses,omitempty"`
+	Expires        time.Time `json:"expires,omitempty"`
+}
+
+type dnsCache struct {
+	mu      sync.Mutex
+	entries map[string]*dnsEntry
+	stats   map[string]*DNSHostStats
+	servers []string
+}
+
+func newDNSCache() *dnsCache {
+	return &dnsCache{
+		entries: make(map[string]*dnsEntry),
+		stats:   make(map[string]*DNSHostStats),
+		servers: append(systemNameservers("/etc/resolv.conf"), dnsFallbackServers...),
+	}
+}
+
+// systemNameservers reads the nameserver lines of a resolv.conf.
+func systemNameservers(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var servers []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			servers = append(servers, net.JoinHostPort(fields[1], "53"))
+		}
+	}
+	return servers
+}
+
+// DNSStats reports provider hostname resolution metrics.
+func DNSStats() map[string]interface{} {
+	return providerDNS.snapshot()
+}
+
+func (c *dnsCache) snapshot() map[string]interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	hosts := make(map[string]DNSHostStats, len(c.stats))
+	for host, s := range c.stats {
+		snap := *s
+		if e, ok := c.entries[host]; ok {
+			snap.Expires = e.expires
+			for _, ip := range e.ips {
+				snap.Addresses = append(snap.Addresses, ip.String())
+			}
+		}
+		hosts[host] = snap
+	}
+	return map[string]interface{}{
+		"enabled": dnsCacheEnabled,
+		"servers": c.servers,
+		"hosts":   hosts,
+	}
+}
+
+// lookup resolves host to its addresses, IPv4 first.
+func (c *dnsCache) lookup(ctx context.Context, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	stats, ok := c.stats[host]
+	if !ok {
+		stats = &DNSHostStats{}
+		c.stats[host] = stats
+	}
+	entry := c.entries[host]
+	if entry != nil && now.Before(entry.expires) {
+		stats.CacheHits++
+		c.mu.Unlock()
+		return entry.ips, nil
+	}
+	c.mu.Unlock()
+
+	ips, ttl, server, err := c.resolve(ctx, host)
+	elapsed := float64(time.Since(now).Microseconds()) / 1000
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats.Lookups++
+	stats.LastDurationMs = elapsed
+	stats.AvgDurationMs += (elapsed - stats.AvgDurationMs) / float64(stats.Lookups)
+	if elapsed > stats.MaxDurationMs {
+		stats.MaxDurationMs = elapsed
+	}
+	if err != nil {
+		stats.Failures++
+		if entry != nil && now.Before(entry.expires.Add(dnsCacheStale)) {
+			stats.StaleServed++
+			return entry.ips, nil
+		}
+		return nil, err
+	}
+	stats.LastServer = server
+	ttl = min(max(ttl, dnsCacheMinTTL), dnsCacheMaxTTL)
+	c.entries[host] = &dnsEntry{ips: ips, expires: time.Now().Add(ttl)}
+	return ips, nil
+}
+
+// resolve asks each server in turn, and the system resolver for names the
+// servers can't answer (single labels, answers too large for UDP).
+func (c *dnsCache) resolve(ctx context.Context, host string) ([]net.IP, time.Duration, string, error) {
+	if !strings.Contains(strings.TrimSuffix(host, "."), ".") || len(c.servers) == 0 {
+		return systemLookup(ctx, host)
+	}
+
+	var lastErr error
+	for _, server := range c.servers {
+		ips, ttl, err := queryServer(ctx, server, host)
+		if err == nil {
+			return ips, ttl, server, nil
+		}
+		lastErr = fmt.Errorf("dns %s via %s: %w", host, server, err)
+		if ctx.Err() != nil {
+			return nil, 0, "", lastErr
+		}
+	}
+	if ips, ttl, server, err := systemLookup(ctx, host); err == nil {
+		return ips, ttl, server, nil
+	}
+	return nil, 0, "", lastErr
+}
+
+// systemLookup resolves host with the system resolver, which reports no TTL.
+func systemLookup(ctx context.Context, host string) ([]net.IP, time.Duration, string, error) {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	ips := make([]net.IP, len(addrs))
+	for i, a := range addrs {
+		ips[i] = a.IP
+	}
+	return ips, dnsCacheMinTTL, "system", nil
+}
+
+// queryServer asks server for the A and AAAA records of host.
+func queryServer(ctx context.Context, server, host string) ([]net.IP, time.Duration, error) {
+	type answer struct {
+		ips []net.IP
+		ttl time.Duration
+		err error
+	}
+	answers := make(chan answer, 2)
+	for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		go func() {
+			ips, ttl, err := exchangeDNS(ctx, server, host, qtype)
+			answers <- answer{ips, ttl, err}
+		}()
+	}
+
+	var v4, v6 []net.IP
+	var ttl time.Duration
+	var firstErr error
+	for range 2 {
+		a := <-answers
+		if a.err != nil {
+			if firstErr == nil {
+				firstErr = a.err
+			}
+			continue
+		}
+		for _, ip := range a.ips {
+			if ip.To4() != nil {
+				v4 = append(v4, ip)
+			} else {
+				v6 = append(v6, ip)
+			}
+		}
+		if len(a.ips) > 0 && (ttl == 0 || a.ttl < ttl) {
+			ttl = a.ttl
+		}
+	}
+	ips := append(v4, v6...)
+	if len(ips) == 0 {
+		if firstErr == nil {
+			firstErr = errors.New("no addresses")
+		}
+		return nil, 0, firstErr
+	}
+	return ips, ttl, nil
+}
+
+// exchangeDNS sends one question over UDP and returns the addresses of
+// that type in the answer, CNAME chains included, with their lowest TTL.
+func exchangeDNS(ctx context.Context, server, host string, qtype dnsmessage.Type) ([]net.IP, time.Duration, error) {
+	name, err := dnsmessage.NewName(strings.TrimSuffix(host, ".") + ".")
+	if err != nil {
+		return nil, 0, err
+	}
+	id := uint16(rand.Uint32())
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, RecursionDesired: true})
+	b.EnableCompression()
+	if err := b.StartQuestions(); err != nil {
+		return nil, 0, err
+	}
+	if err := b.Question(dnsmessage.Question{Name: name, Type: qtype, Class: dnsmessage.ClassINET}); err != nil {
+		return nil, 0, err
+	}
+	query, err := b.Finish()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dnsQueryTimeout)
+	defer cancel()
+	conn, err := (&net.Dialer{}).DialContext(ctx, "udp", server)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if _, err := conn.Write(query); err != nil {
+		return nil, 0, err
+	}
+
+	buf := make([]byte, 1232)
+	var p dnsmessage.Parser
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, 0, err
+		}
+		h, err := p.Start(buf[:n])
+		if err != nil || h.ID != id || !h.Response {
+			continue // not our answer
+		}
+		if h.Truncated {
+			return nil, 0, errors.New("truncated response")
+		}
+		if h.RCode != dnsmessage.RCodeSuccess {
+			return nil, 0, fmt.Errorf("server answered %v", h.RCode)
+		}
+		break
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return nil, 0, err
+	}
+
+	var ips []net.IP
+	var ttl uint32
+	for {
+		rh, err := p.AnswerHeader()
+		if errors.Is(err, dnsmessage.ErrSectionDone) {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		switch rh.Type {
+		case dnsmessage.TypeA:
+			r, err := p.AResource()
+			if err != nil {
+				return nil, 0, err
+			}
+			ips = append(ips, net.IP(r.A[:]))
+		case dnsmessage.TypeAAAA:
+			r, err := p.AAAAResource()
+			if err != nil {
+				return nil, 0, err
+			}
+			ips = append(ips, net.IP(r.AAAA[:]))
+		default:
+			if err := p.SkipAnswer(); err != nil {
+				return nil, 0, err
+			}
+		}
+		if ttl == 0 || rh.TTL < ttl {
+			ttl = rh.TTL
+		}
+	}
+	return ips, time.Duration(ttl) * time.Second, nil
+}
+
+// dialContext dials addr with host resolved through the cache. Addresses
+// of the first family are tried in order; if that hasn't connected within
+// dialFallbackDelay the other family races it.
+func (c *dnsCache) dialContext(d *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		ips, err := c.lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		var primary, fallback []net.IP
+		for _, ip := range ips {
+			if (ip.To4() != nil) == (ips[0].To4() != nil) {
+				primary = append(primary, ip)
+			} else {
+				fallback = append(fallback, ip)
+			}
+		}
+		return dialParallel(ctx, d, network, port, primary, fallback)
+	}
+}
+
+func dialParallel(ctx context.Context, d *net.Dialer, network, port string, primary, fallback []net.IP) (net.Conn, error) {
+	if len(fallback) == 0 {
+		return dialSerial(ctx, d, network, port, primary)
+	}
+
+	type dialResult struct {
+		conn net.Conn
+		err  error
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan dialResult, 2)
+	start := func(ips []net.IP) {
+		go func() {
+			conn, err := dialSerial(ctx, d, network, port, ips)
+			results <- dialResult{conn, err}
+		}()
+	}
+
+	start(primary)
+	pending, fallbackStarted := 1, false
+	timer := time.NewTimer(dialFallbackDelay)
+	defer timer.Stop()
+	var firstErr error
+	for {
+		select {
+		case <-timer.C:
+			if !fallbackStarted {
+				start(fallback)
+				pending, fallbackStarted = pending+1, true
+			}
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				if pending > 0 {
+					// The loser is cancelled; close it if it connected anyway
+					go func() {
+						if late := <-results; late.conn != nil {
+							late.conn.Close()
+						}
+					}()
+				}
+				return res.conn, nil
+			}
+			if firstErr == nil {
+				firstErr = res.err
+			}
+			if !fallbackStarted {
+				start(fallback)
+				pending, fallbackStarted = pending+1, true
+			} else if pending == 0 {
+				return nil, firstErr
+			}
+		}
+	}
+}
+
+func dialSerial(ctx context.Context, d *net.Dialer, network, port string, ips []net.IP) (net.Conn, error) {
+	var firstErr error
+	for _, ip := range ips {
+		conn, err := d.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, firstErr
+}
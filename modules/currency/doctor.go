@@ -0,0 +1,103 @@
+package currency
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ProbeResult is the outcome of one provider connectivity check.
+type ProbeResult struct {
+	Provider    string
+	Criticality string
+	Latency     time.Duration
+	Err         error
+	Detail      string
+}
+
+// ProbeProviders makes one small live request to every enabled provider and
+// reports how long it took. Disabled providers are listed without a request.
+// Rates fetched by the probes are not stored, except for the ECB baseline
+// and the CBR official rates.
+func (ac *APICache) ProbeProviders(ctx context.Context) []ProbeResult {
+	ctx = withPriority(ctx, priorityInteractive)
+	probes := []struct {
+		provider string
+		run      func(context.Context) (string, error)
+	}{
+		{providerBybit, func(ctx context.Context) (string, error) {
+			tickers, err := ac.fetchBybitTickers(ctx)
+			if err != nil {
+				return "", err
+			}
+			ton, ok := tickers["TONUSDT"]
+			if !ok {
+				return "", fmt.Errorf("TONUSDT missing from %d tickers", len(tickers))
+			}
+			return fmt.Sprintf("%d USDT tickers, TONUSDT bid %s", len(tickers), formatRate(ton.rate.BestBid)), nil
+		}},
+		{providerMastercard, func(ctx context.Context) (string, error) {
+			rate, err := ac.fetchMastercardRate(ctx, CurrencyUSD, CurrencyEUR)
+			return fmt.Sprintf("1 USD = %s EUR", formatRate(rate)), err
+		}},
+		{providerVisa, func(ctx context.Context) (string, error) {
+			rate, err := ac.fetchVisaRate(ctx, CurrencyEUR)
+			return fmt.Sprintf("1 USD = %s EUR", formatRate(rate)), err
+		}},
+		{providerWhitebird, func(ctx context.Context) (string, error) {
+			ton, err := ac.fetchSingleWhitebirdConversion(ctx, CurrencyRUB, CurrencyTON, 10000)
+			return fmt.Sprintf("10000 RUB = %s TON", formatRate(ton)), err
+		}},
+		{providerECB, func(ctx context.Context) (string, error) {
+			return "", ac.fetchECBBaseline()
+		}},
+		{providerCBR, func(ctx context.Context) (string, error) {
+			if err := ac.fetchCBRRates(); err != nil {
+				return "", err
+			}
+			usd, _ := ac.cbrRate(CurrencyUSD)
+			return fmt.Sprintf("1 USD = %s RUB", formatRate(usd)), nil
+		}},
+		{providerCoinGecko, func(ctx context.Context) (string, error) {
+			price, err := ac.fetchCoinGeckoPrice(ctx, "BTC")
+			return fmt.Sprintf("BTC = %s USD", formatRate(price)), err
+		}},
+	}
+
+	results := make([]ProbeResult, len(probes))
+	done := make(chan struct{}, len(probes))
+	for i, p := range probes {
+		results[i] = ProbeResult{Provider: p.provider, Criticality: providerCriticality[p.provider]}
+		if !providerEnabled(p.provider) {
+			done <- struct{}{}
+			continue
+		}
+		go func(r *ProbeResult, run func(context.Context) (string, error)) {
+			defer func() { done <- struct{}{} }()
+			start := time.Now()
+			r.Detail, r.Err = run(ctx)
+			r.Latency = time.Since(start)
+		}(&results[i], p.run)
+	}
+	for range probes {
+		<-done
+	}
+	return results
+}
+
+// CheckPersistence verifies that the rate cache file can be written.
+func CheckPersistence() (string, error) {
+	dir := filepath.Dir(persistenceFilePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return persistenceFilePath, err
+	}
+	f, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		return persistenceFilePath, err
+	}
+	name := f.Name()
+	f.Close()
+	return persistenceFilePath, os.Remove(name)
+}
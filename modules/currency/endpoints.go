@@ -0,0 +1,258 @@
+package currency
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A provider reachable under several domains gets an endpoint pool, so a
+// regional block or outage of one domain fails over to the next instead of
+// taking the provider down:
+//
+//	BYBIT_ENDPOINTS       origins to send Bybit requests to, comma-separated,
+//	                      each optionally weighted as "origin=weight";
+//	                      defaults to api.bybit.com then api.bytick.com
+//	BYBIT_ENDPOINT_MODE   "failover" tries the origins in listed order;
+//	                      "weighted" spreads requests over the healthy ones
+//	                      by weight
+//	ENDPOINT_COOLDOWN     how long a failing origin is passed over before
+//	                      requests probe it again
+//
+// The configured request URLs (BYBIT_TICKERS_URL, BYBIT_ORDERBOOK_URL) keep
+// their paths; only the origin is swapped. Requests double as health
+// checks: a transport error, a 5xx or a block (403, 451) marks the origin
+// down and the request moves on to the next one.
+var (
+	endpointCooldown = getEnvDurationOrDefault("ENDPOINT_COOLDOWN", time.Minute)
+
+	bybitEndpoints = newEndpointPool(providerBybit, "BYBIT_ENDPOINTS", "BYBIT_ENDPOINT_MODE", defaultBybitEndpoints())
+)
+
+const (
+	endpointModeFailover = "failover"
+	endpointModeWeighted = "weighted"
+)
+
+// defaultBybitEndpoints are Bybit's public API domains, unless
+// BYBIT_ORDERBOOK_URL points elsewhere (a mirror or a test server), which
+// then is the only origin.
+func defaultBybitEndpoints() string {
+	if u, err := url.Parse(bybitOrderbookURL); err == nil && u.Host != "api.bybit.com" {
+		return u.Scheme + "://" + u.Host
+	}
+	return "https://api.bybit.com,https://api.bytick.com"
+}
+
+type endpoint struct {
+	origin *url.URL
+	weight float64
+
+	// Guarded by endpointPool.mu
+	failures  int
+	downUntil time.Time
+}
+
+// endpointPool picks the origin for each request to one provider and
+// tracks which origins are failing.
+type endpointPool struct {
+	provider  string
+	weighted  bool
+	mu        sync.Mutex
+	endpoints []*endpoint
+}
+
+// newEndpointPool reads a pool from the list and mode settings. A list with
+// a bad entry is rejected as a whole in favour of fallback.
+func newEndpointPool(provider, listKey, modeKey, fallback string) *endpointPool {
+	pool := &endpointPool{provider: provider}
+	switch mode := strings.ToLower(getEnvOrDefault(modeKey, endpointModeFailover)); mode {
+	case endpointModeFailover:
+	case endpointModeWeighted:
+		pool.weighted = true
+	default:
+		InvalidSetting(modeKey, mode, "not failover or weighted", endpointModeFailover)
+	}
+
+	value := getEnvOrDefault(listKey, fallback)
+	endpoints, err := parseEndpoints(value)
+	if err != nil {
+		InvalidSetting(listKey, value, err.Error(), fallback)
+		endpoints, _ = parseEndpoints(fallback)
+	}
+	pool.endpoints = endpoints
+	return pool
+}
+
+// parseEndpoints reads "origin[=weight]" entries.
+func parseEndpoints(value string) ([]*endpoint, error) {
+	var endpoints []*endpoint
+	for _, entry := range splitList(value) {
+		origin, weightText, weighted := strings.Cut(entry, "=")
+		u, err := url.Parse(strings.TrimSpace(origin))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("%q is not an http(s) origin", origin)
+		}
+		weight := 1.0
+		if weighted {
+			weight, err = strconv.ParseFloat(strings.TrimSpace(weightText), 64)
+			if err != nil || !(weight > 0) || !isValidFloat(weight) {
+				return nil, fmt.Errorf("%q has no positive weight", entry)
+			}
+		}
+		endpoints = append(endpoints, &endpoint{origin: &url.URL{Scheme: u.Scheme, Host: u.Host}, weight: weight})
+	}
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("no origins")
+	}
+	return endpoints, nil
+}
+
+// order returns the endpoints in the order to try them for one request:
+// the healthy ones first (by listing, or drawn by weight), then those
+// cooling down, soonest back first, as a last resort.
+func (p *endpointPool) order() []*endpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	var healthy, down []*endpoint
+	for _, e := range p.endpoints {
+		if !hostAllowed(e.origin.Hostname()) {
+			continue
+		}
+		if now.Before(e.downUntil) {
+			down = append(down, e)
+		} else {
+			healthy = append(healthy, e)
+		}
+	}
+	if p.weighted {
+		healthy = weightedShuffle(healthy)
+	}
+	sort.SliceStable(down, func(i, j int) bool { return down[i].downUntil.Before(down[j].downUntil) })
+	return append(healthy, down...)
+}
+
+// weightedShuffle orders endpoints by repeatedly drawing one with
+// probability proportional to its weight.
+func weightedShuffle(endpoints []*endpoint) []*endpoint {
+	remaining := append([]*endpoint(nil), endpoints...)
+	out := make([]*endpoint, 0, len(endpoints))
+	for len(remaining) > 0 {
+		total := 0.0
+		for _, e := range remaining {
+			total += e.weight
+		}
+		pick := rand.Float64() * total
+		i := 0
+		for ; i < len(remaining)-1; i++ {
+			if pick -= remaining[i].weight; pick < 0 {
+				break
+			}
+		}
+		out = append(out, remaining[i])
+		remaining = append(remaining[:i], remaining[i+1:]...)
+	}
+	return out
+}
+
+func (p *endpointPool) recordSuccess(e *endpoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if e.failures > 0 {
+		log.Printf("%s endpoint %s recovered", p.provider, e.origin.Host)
+	}
+	e.failures = 0
+	e.downUntil = time.Time{}
+}
+
+// recordFailure marks e down; the cooldown doubles with each consecutive
+// failure, up to eight times ENDPOINT_COOLDOWN.
+func (p *endpointPool) recordFailure(e *endpoint, reason string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	cooldown := endpointCooldown << min(e.failures, 3)
+	e.failures++
+	e.downUntil = time.Now().Add(cooldown)
+	log.Printf("Warning: %s endpoint %s failing (%s), passed over for %v", p.provider, e.origin.Host, reason, cooldown)
+}
+
+// endpointFailed reports whether a response means the origin, rather than
+// the request, is at fault.
+func endpointFailed(status int) bool {
+	return status >= 500 || status == http.StatusForbidden || status == http.StatusUnavailableForLegalReasons
+}
+
+// do sends the request built by newRequest for rawURL to each origin in
+// turn until one answers. The last origin's response or error is returned
+// when all fail.
+func (p *endpointPool) do(ctx context.Context, client *http.Client, rawURL string, newRequest func(ctx context.Context, url string) (*http.Request, error)) (*http.Response, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	endpoints := p.order()
+	for i, e := range endpoints {
+		target.Scheme, target.Host = e.origin.Scheme, e.origin.Host
+		req, err := newRequest(ctx, target.String())
+		if err != nil {
+			return nil, err
+		}
+		last := i == len(endpoints)-1
+		resp, err := client.Do(req)
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			p.recordFailure(e, err.Error())
+			if last {
+				return nil, err
+			}
+		case endpointFailed(resp.StatusCode):
+			p.recordFailure(e, resp.Status)
+			if last {
+				return resp, nil
+			}
+			resp.Body.Close()
+		default:
+			p.recordSuccess(e)
+			return resp, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no %s endpoint allowed", errHostNotAllowed, p.provider)
+}
+
+// hasHost reports whether host is one of the pool's origins.
+func (p *endpointPool) hasHost(host string) bool {
+	for _, e := range p.endpoints {
+		if e.origin.Host == host {
+			return true
+		}
+	}
+	return false
+}
+
+// endpointIssues reports origins set in key that OUTBOUND_ALLOWED_HOSTS
+// leaves out. Default origins that aren't allowed are skipped quietly.
+func endpointIssues(key string, p *endpointPool) []ConfigIssue {
+	if os.Getenv(key) == "" {
+		return nil
+	}
+	var issues []ConfigIssue
+	for _, e := range p.endpoints {
+		if !hostAllowed(e.origin.Hostname()) {
+			issues = append(issues, ConfigIssue{key, os.Getenv(key), e.origin.Host + " not in OUTBOUND_ALLOWED_HOSTS"})
+		}
+	}
+	return issues
+}
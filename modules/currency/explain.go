@@ -0,0 +1,264 @@
+package currency
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConversionStep is one leg of a conversion route with the amounts, rate,
+// fee and provider involved.
+type ConversionStep struct {
+	From          string    `json:"from"`
+	To            string    `json:"to"`
+	AmountIn      float64   `json:"amount_in"`
+	AmountOut     float64   `json:"amount_out"`
+	Rate          float64   `json:"rate"`
+	Fee           string    `json:"fee"`
+	Provider      string    `json:"provider"`
+	RateTimestamp time.Time `json:"rate_timestamp"`
+	Description   string    `json:"description"`
+	Volume24h     float64   `json:"volume_24h,omitempty"` // Bybit spot legs: 24h USDT turnover
+	LowLiquidity  bool      `json:"low_liquidity,omitempty"`
+}
+
+// Route is the structured description of how a conversion was executed.
+// Frontends render it (tooltips, /explain, bot replies) without recomputing.
+type Route struct {
+	Amount             float64          `json:"amount"`
+	From               string           `json:"from"`
+	To                 string           `json:"to"`
+	Result             float64          `json:"result"`
+	EffectiveRate      float64          `json:"effective_rate"`
+	Legs               []ConversionStep `json:"legs"`
+	Providers          []string         `json:"providers"`
+	SlippagePercent    float64          `json:"slippage_percent,omitempty"`
+	Approximate        bool             `json:"approximate,omitempty"`
+	PersonalFeePercent float64          `json:"personal_fee_percent,omitempty"` // user's own markup, deducted from Result
+	StalenessSeconds   float64          `json:"staleness_seconds"`              // age of the oldest rate used
+	QuotedAt           time.Time        `json:"quoted_at"`                      // timestamp of the oldest rate used
+	ValidUntil         time.Time        `json:"valid_until"`                    // first moment any rate used is due for refresh
+	Warnings           []string         `json:"warnings,omitempty"`             // thresholds crossed, see warnings.go
+}
+
+// Explain resolves from/to and converts amount, returning the route the
+// conversion took leg by leg. It shares the conversion cache with queries,
+// so it explains the amount a query shows.
+func (m *CurrencyConverterModule) Explain(amount float64, from, to string, apiCache *APICache) (*Route, error) {
+	if err := ValidateAmount(amount); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if from, err = m.currencyData.ResolveCurrency(from); err != nil {
+		return nil, err
+	}
+	if to, err = m.currencyData.ResolveCurrency(to); err != nil {
+		return nil, err
+	}
+	result, legs, err := m.convertRoute(context.Background(), amount, from, to, apiCache)
+	if err != nil {
+		return nil, err
+	}
+	if from != to && len(legs) == 0 {
+		return nil, fmt.Errorf("conversion route not available")
+	}
+	route := newRoute(amount, from, to, legs)
+	route.Result = result
+	route.EffectiveRate = result / amount
+	return route, nil
+}
+
+// legRecorder collects the legs of one conversion as they execute.
+type legRecorder struct {
+	mu   sync.Mutex
+	legs []ConversionStep
+}
+
+type legRecorderKey struct{}
+
+// withLegRecorder starts recording the legs converted with the returned
+// context, replacing any recorder of an enclosing conversion.
+func withLegRecorder(ctx context.Context) (context.Context, *legRecorder) {
+	r := &legRecorder{}
+	return context.WithValue(ctx, legRecorderKey{}, r), r
+}
+
+// recordLeg notes a leg that converted in from into out, if the conversion
+// in ctx is being recorded. network is the card network of a card leg, or
+// empty to use the one describeLeg selects.
+func recordLeg(ctx context.Context, from, to, network string, in, out float64, apiCache *APICache) {
+	r, _ := ctx.Value(legRecorderKey{}).(*legRecorder)
+	if r == nil {
+		return
+	}
+	step := newConversionStep(from, to, network, in, out, apiCache)
+	r.mu.Lock()
+	r.legs = append(r.legs, step)
+	r.mu.Unlock()
+}
+
+func (r *legRecorder) steps() []ConversionStep {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]ConversionStep(nil), r.legs...)
+}
+
+// newConversionStep describes a leg that converted in from into out, with
+// the provider, fee and rate timestamp current as it ran.
+func newConversionStep(from, to, network string, in, out float64, apiCache *APICache) ConversionStep {
+	provider, fee, ts := describeLeg(from, to, network, apiCache)
+	step := ConversionStep{
+		From:          from,
+		To:            to,
+		AmountIn:      in,
+		AmountOut:     out,
+		Rate:          out / in,
+		Fee:           fee,
+		Provider:      provider,
+		RateTimestamp: ts,
+	}
+	if provider == "Bybit Spot" {
+		symbol := from + "USDT"
+		if from == CurrencyUSDT {
+			symbol = to + "USDT"
+		}
+		step.Volume24h, _ = apiCache.volume24h(symbol)
+		step.LowLiquidity = apiCache.isIlliquid(symbol)
+	}
+	step.Description = fmt.Sprintf("%s %s → %s %s via %s at %s (%s)",
+		formatAmount(in, from), from, formatAmount(out, to), to, provider, formatRate(step.Rate), fee)
+	return step
+}
+
+// newRoute builds the route of a conversion from its recorded legs. The
+// staleness is measured now, so a route served from cache ages with it.
+func newRoute(amount float64, from, to string, legs []ConversionStep) *Route {
+	now := time.Now()
+	route := &Route{Amount: amount, From: from, To: to, Result: amount, EffectiveRate: 1, Legs: legs, QuotedAt: now}
+	seenProviders := make(map[string]bool)
+	var oldest, validUntil time.Time
+	for _, step := range legs {
+		if !seenProviders[step.Provider] {
+			seenProviders[step.Provider] = true
+			route.Providers = append(route.Providers, step.Provider)
+		}
+		ts := step.RateTimestamp
+		if !ts.IsZero() && (oldest.IsZero() || ts.Before(oldest)) {
+			oldest = ts
+		}
+		if ttl := providerValidity(step.Provider, step.From, step.To); ttl > 0 && !ts.IsZero() {
+			if expiry := ts.Add(ttl); validUntil.IsZero() || expiry.Before(validUntil) {
+				validUntil = expiry
+			}
+		}
+	}
+
+	if len(legs) > 0 {
+		route.Result = legs[len(legs)-1].AmountOut
+		route.EffectiveRate = route.Result / amount
+	}
+	if !oldest.IsZero() {
+		route.StalenessSeconds = now.Sub(oldest).Seconds()
+		route.QuotedAt = oldest
+	}
+	route.ValidUntil = validUntil
+	if validUntil.IsZero() {
+		// Only fixed-fee legs: nothing ages
+		route.ValidUntil = now.Add(refreshPolicies[assetCrypto].RefreshInterval)
+	}
+	return route
+}
+
+// String renders the route as plain text, one leg per line.
+func (r *Route) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s = %s %s (1 %s = %s %s)\n",
+		formatAmount(r.Amount, r.From), r.From, formatAmount(r.Result, r.To), r.To,
+		r.From, formatRate(r.EffectiveRate), r.To)
+	for i, step := range r.Legs {
+		fmt.Fprintf(&sb, "%d. %s, rate as of %s\n", i+1, step.Description, step.RateTimestamp.UTC().Format(time.RFC3339))
+	}
+	return sb.String()
+}
+
+// cacheTTL is the client-side cache hint for a result built on the route,
+// in seconds: until its first rate is due for refresh, capped at
+// RESULT_CACHE_TTL_MAX. Approximate results are not cached; the client
+// should ask again for the refined answer.
+func (r *Route) cacheTTL() int {
+	if r.Approximate {
+		return 0
+	}
+	ttl := time.Until(r.ValidUntil)
+	if ttl < minResultCacheTTL {
+		ttl = minResultCacheTTL
+	}
+	if ttl > maxResultCacheTTL {
+		ttl = maxResultCacheTTL
+	}
+	return int(ttl.Seconds())
+}
+
+// providerValidity is how long a rate from provider stays current: the
+// refresh interval of its data, or the quote cache TTL for Whitebird.
+// Fixed-fee legs return 0.
+func providerValidity(provider, from, to string) time.Duration {
+	switch provider {
+	case "Whitebird":
+		return whitebirdQuoteCacheTTL
+	case "Bybit Spot":
+		return refreshPolicies[assetCrypto].RefreshInterval
+	case "Visa":
+		return visaRefreshInterval
+	case "Mastercard":
+		fiat := to
+		if to == CurrencyUSD {
+			fiat = from
+		}
+		return refreshPolicies[fiatAssetClass(fiat)].RefreshInterval
+	case coingeckoProvider:
+		return coingeckoPriceTTL
+	}
+	return 0
+}
+
+// describeLeg returns the provider, fee description and rate timestamp for a
+// single leg, mirroring the branches in convertDirectPair. network is the
+// card network of a fiat pair; empty selects one per leg.
+func describeLeg(from, to, network string, apiCache *APICache) (provider, fee string, ts time.Time) {
+	switch {
+	case from == CurrencyRUB && to == CurrencyTON:
+		return "Whitebird", fmt.Sprintf("fee included in quote, %g TON network fee", feeTONWithdrawToBybit), time.Now()
+	case from == CurrencyTON && to == CurrencyRUB:
+		return "Whitebird", fmt.Sprintf("fee included in quote, %g TON network fee", feeTONWithdrawToWhitebird), time.Now()
+	case from == CurrencyUSDT && to == CurrencyUSD:
+		return "Bybit Card", fmt.Sprintf("%g%% fee", feeUSDTToUSD*100), time.Now()
+	case from == CurrencyUSD && to == CurrencyUSDT:
+		return "Bybit Card", fmt.Sprintf("%g%% fee", feeUSDToUSDT*100), time.Now()
+	case to == CurrencyUSDT || from == CurrencyUSDT:
+		symbol := from + "USDT"
+		if from == CurrencyUSDT {
+			symbol = to + "USDT"
+		}
+		rate, err := apiCache.GetBybitRate(symbol)
+		if err == nil {
+			ts = rate.LastUpdate
+		} else if p, ok := apiCache.cachedIndexPrice(strings.TrimSuffix(symbol, "USDT")); ok {
+			return coingeckoProvider, "index price, not executable", p.LastUpdate
+		}
+		return "Bybit Spot", fmt.Sprintf("%g%% trading fee", feeBybitTrade*100), ts
+	default:
+		fiat := to
+		if to == CurrencyUSD {
+			fiat = from
+		}
+		if network == "" {
+			network, _ = apiCache.selectCardNetwork(from, to)
+		}
+		_, cardFee, _ := apiCache.cardRate(network, from, to)
+		return cardNetworkLabel(network), fmt.Sprintf("%g%% fee", cardFee*100), apiCache.cardRateUpdatedAt(network, fiat)
+	}
+}
@@ -0,0 +1,164 @@
+package currency
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fault injection lets staging exercise the degradation paths (fallback
+// providers, error results, stale badges, health states) on demand. It is
+// compiled in but inert unless FAULT_INJECTION=true, and is driven through
+// the admin API only.
+var faultInjectionEnabled = getEnvBoolOrDefault("FAULT_INJECTION", false)
+
+// Fault kinds
+const (
+	faultCircuit = "circuit" // open the provider's circuit breaker
+	faultLatency = "latency" // delay every request by Latency
+	faultCorrupt = "corrupt" // answer 200 with a truncated, unparsable body
+	faultError   = "error"   // fail every request at the transport
+	faultStale   = "stale"   // report the provider's data as stale
+)
+
+// Fault is an active injected fault.
+type Fault struct {
+	Provider string        `json:"provider"`
+	Kind     string        `json:"kind"`
+	Latency  time.Duration `json:"latency,omitempty"`
+	Until    time.Time     `json:"until"`
+}
+
+type faultInjector struct {
+	mu     sync.RWMutex
+	active map[string]Fault // by provider + "/" + kind
+}
+
+var faults = &faultInjector{active: make(map[string]Fault)}
+
+var providerCircuits = map[string]*CircuitBreaker{
+	providerBybit:      bybitCircuit,
+	providerWhitebird:  whitebirdCircuit,
+	providerMastercard: mastercardCircuit,
+	providerVisa:       visaCircuit,
+}
+
+// FaultInjectionEnabled reports whether FAULT_INJECTION is on.
+func FaultInjectionEnabled() bool { return faultInjectionEnabled }
+
+// InjectFault activates a fault of kind on provider for d.
+func InjectFault(provider, kind string, d, latency time.Duration) error {
+	if !faultInjectionEnabled {
+		return fmt.Errorf("fault injection is disabled")
+	}
+	if _, ok := providerCriticality[provider]; !ok {
+		return fmt.Errorf("unknown provider %q", provider)
+	}
+	if d <= 0 {
+		return fmt.Errorf("duration must be positive")
+	}
+	f := Fault{Provider: provider, Kind: kind, Until: time.Now().Add(d)}
+	switch kind {
+	case faultCircuit:
+		cb, ok := providerCircuits[provider]
+		if !ok {
+			return fmt.Errorf("%s has no circuit breaker", provider)
+		}
+		cb.forceOpen(f.Until)
+	case faultLatency:
+		if latency <= 0 {
+			return fmt.Errorf("latency fault needs a positive latency")
+		}
+		f.Latency = latency
+	case faultStale:
+		if provider != providerBybit && provider != providerMastercard {
+			return fmt.Errorf("staleness is only tracked for %s and %s", providerBybit, providerMastercard)
+		}
+	case faultCorrupt, faultError:
+	default:
+		return fmt.Errorf("unknown fault %q", kind)
+	}
+
+	faults.mu.Lock()
+	faults.active[provider+"/"+kind] = f
+	faults.mu.Unlock()
+	log.Printf("Warning: injected %s fault into %s until %s", kind, provider, f.Until.Format(time.RFC3339))
+	return nil
+}
+
+// ClearFaults ends the faults of provider, or of every provider for "*".
+func ClearFaults(provider string) {
+	faults.mu.Lock()
+	defer faults.mu.Unlock()
+	for key, f := range faults.active {
+		if provider != "*" && f.Provider != provider {
+			continue
+		}
+		if f.Kind == faultCircuit {
+			providerCircuits[f.Provider].reset()
+		}
+		delete(faults.active, key)
+		log.Printf("Cleared %s fault on %s", f.Kind, f.Provider)
+	}
+}
+
+// Faults lists the active faults.
+func Faults() []Fault {
+	faults.mu.RLock()
+	defer faults.mu.RUnlock()
+	now := time.Now()
+	out := make([]Fault, 0, len(faults.active))
+	for _, f := range faults.active {
+		if now.Before(f.Until) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// get returns the active fault of kind on provider, if any.
+func (fi *faultInjector) get(provider, kind string) (Fault, bool) {
+	if !faultInjectionEnabled {
+		return Fault{}, false
+	}
+	fi.mu.RLock()
+	defer fi.mu.RUnlock()
+	f, ok := fi.active[provider+"/"+kind]
+	return f, ok && time.Now().Before(f.Until)
+}
+
+// faultTransport applies the latency, error and corrupt faults of one
+// provider to its requests.
+type faultTransport struct {
+	next     http.RoundTripper
+	provider string
+}
+
+func (t *faultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if f, ok := faults.get(t.provider, faultLatency); ok {
+		select {
+		case <-time.After(f.Latency):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+	if _, ok := faults.get(t.provider, faultError); ok {
+		return nil, fmt.Errorf("injected fault: %s unreachable", t.provider)
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := faults.get(t.provider, faultCorrupt); ok {
+		resp.Body.Close()
+		resp.StatusCode, resp.Status = http.StatusOK, "200 OK"
+		resp.Header.Del("Content-Encoding")
+		resp.Body = io.NopCloser(strings.NewReader(`{"result": {"list": [{"corrupted`))
+		resp.ContentLength = -1
+	}
+	return resp, nil
+}
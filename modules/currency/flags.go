@@ -0,0 +1,23 @@
+package currency
+
+// flagOverrides maps fiat codes whose first two letters are not the issuing
+// country's ISO 3166 code. An empty value means no single flag applies.
+var flagOverrides = map[string]string{
+	"EUR": "🇪🇺",
+	"ANG": "", // Netherlands Antilles no longer exists
+}
+
+// FlagEmoji returns the flag for a fiat currency code ("USD" -> "🇺🇸"), or ""
+// for crypto, metals and currencies shared by several countries (XOF, XAF,
+// XCD, XPF, XCG: every X-prefixed code is supranational or not a currency).
+func FlagEmoji(code string) string {
+	if flag, ok := flagOverrides[code]; ok {
+		return flag
+	}
+	// RUB is only a Mastercard fiat with the RUB bridge disabled
+	if len(code) != 3 || code[0] == 'X' || (!isFiatCode(code) && code != CurrencyRUB) {
+		return ""
+	}
+	// Regional indicator symbols: 'A' is U+1F1E6
+	return string([]rune{0x1F1E6 + rune(code[0]-'A'), 0x1F1E6 + rune(code[1]-'A')})
+}
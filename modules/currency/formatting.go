@@ -2,8 +2,10 @@ package currency
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/leekchan/accounting"
 )
@@ -20,11 +22,44 @@ func GetCurrencyDecimalPlaces(currencyCode string) int {
 	if decimals, ok := currencyDecimalPlaces[currencyCode]; ok {
 		return decimals
 	}
+	if iso, ok := isoCurrencies[currencyCode]; ok {
+		return iso.MinorUnits
+	}
 	return 2
 }
 
+// maxDisplayDecimals caps how far visiblePrecision goes for tiny amounts;
+// anything smaller still shows as zero.
+const maxDisplayDecimals = 12
+
+// visiblePrecision widens precision when amount, non-zero, would round to
+// zero at it, so 0.3 JPY or 0.0004 USD don't show as "0" or "0.00". Two
+// significant figures are shown then.
+func visiblePrecision(amount float64, precision int) int {
+	abs := math.Abs(amount)
+	if !isValidFloat(abs) || abs >= 0.5*math.Pow10(-precision) {
+		return precision
+	}
+	return min(int(math.Ceil(-math.Log10(abs)))+1, maxDisplayDecimals)
+}
+
 func formatAmount(amount float64, currencyCode string) string {
-	precision := GetCurrencyDecimalPlaces(currencyCode)
+	precision := visiblePrecision(amount, GetCurrencyDecimalPlaces(currencyCode))
+	ac := accounting.Accounting{
+		Symbol:    "",
+		Precision: precision,
+		Thousand:  ",",
+		Decimal:   ".",
+	}
+	return ac.FormatMoneyFloat64(amount)
+}
+
+// formatAmountAt is formatAmount with precision decimal places in place of
+// the currency's, unless precision is negative.
+func formatAmountAt(amount float64, currencyCode string, precision int) string {
+	if precision < 0 {
+		return formatAmount(amount, currencyCode)
+	}
 	ac := accounting.Accounting{
 		Symbol:    "",
 		Precision: precision,
@@ -34,6 +69,15 @@ func formatAmount(amount float64, currencyCode string) string {
 	return ac.FormatMoneyFloat64(amount)
 }
 
+// formatAmountForClipboardAt is formatAmountForClipboard with exactly
+// precision decimal places, trailing zeros kept, unless precision is negative.
+func formatAmountForClipboardAt(amount float64, currencyCode string, precision int) string {
+	if precision < 0 {
+		return formatAmountForClipboard(amount, currencyCode)
+	}
+	return strconv.FormatFloat(amount, 'f', precision, 64)
+}
+
 func formatAmountForClipboard(amount float64, currencyCode string) string {
 	precision := GetCurrencyDecimalPlaces(currencyCode)
 
@@ -44,6 +88,7 @@ func formatAmountForClipboard(amount float64, currencyCode string) string {
 			precision = 4
 		}
 	}
+	precision = visiblePrecision(amount, precision)
 
 	formatted := strconv.FormatFloat(amount, 'f', precision, 64)
 	if strings.Contains(formatted, ".") {
@@ -53,24 +98,42 @@ func formatAmountForClipboard(amount float64, currencyCode string) string {
 	return formatted
 }
 
+// Rate display: rates are shown with a fixed number of significant figures so
+// both SHIB/RUB and JPY/USD stay readable; values outside the scientific
+// thresholds switch to e-notation.
+var (
+	rateSignificantFigures = int(getEnvFloatOrDefault("RATE_SIGNIFICANT_FIGURES", 5))
+	rateScientificAbove    = getEnvFloatOrDefault("RATE_SCIENTIFIC_ABOVE", 1e9)
+	rateScientificBelow    = getEnvFloatOrDefault("RATE_SCIENTIFIC_BELOW", 1e-8)
+)
+
 func formatRate(rate float64) string {
 	if !isValidFloat(rate) {
 		return "N/A"
 	}
 
-	var formatted string
-	switch {
-	case rate < 0.0001:
-		formatted = strconv.FormatFloat(rate, 'f', 8, 64)
-	case rate < 1:
-		formatted = strconv.FormatFloat(rate, 'f', 4, 64)
-	case rate < 1000000:
-		formatted = strconv.FormatFloat(rate, 'f', 2, 64)
-	default:
-		formatted = strconv.FormatFloat(rate, 'e', 2, 64)
+	sigFigs := rateSignificantFigures
+	if sigFigs < 1 {
+		sigFigs = 1
+	}
+
+	if rate >= rateScientificAbove || rate < rateScientificBelow {
+		mantissa, exponent, _ := strings.Cut(strconv.FormatFloat(rate, 'e', sigFigs-1, 64), "e")
+		if strings.Contains(mantissa, ".") {
+			mantissa = strings.TrimRight(strings.TrimRight(mantissa, "0"), ".")
+		}
+		return mantissa + "e" + exponent
+	}
+
+	// Never round away integer digits; only limit the fractional part
+	magnitude := int(math.Floor(math.Log10(rate)))
+	decimals := sigFigs - 1 - magnitude
+	if decimals < 0 {
+		decimals = 0
 	}
 
-	if !strings.Contains(formatted, "e") && strings.Contains(formatted, ".") {
+	formatted := strconv.FormatFloat(rate, 'f', decimals, 64)
+	if strings.Contains(formatted, ".") {
 		formatted = strings.TrimRight(formatted, "0")
 		formatted = strings.TrimRight(formatted, ".")
 	}
@@ -78,6 +141,53 @@ func formatRate(rate float64) string {
 	return formatted
 }
 
+var humanizeSuffixes = []struct {
+	scale  float64
+	suffix string
+}{
+	{1e12, "T"},
+	{1e9, "B"},
+	{1e6, "M"},
+}
+
+// humanizeAmount shortens an amount of at least HUMANIZE_AMOUNTS_ABOVE to
+// three significant figures and a scale suffix: 1234567890 is "1.23B". It
+// reports false for amounts shown exactly.
+func humanizeAmount(amount float64) (string, bool) {
+	if humanizeAmountsAbove <= 0 || math.Abs(amount) < humanizeAmountsAbove || !isValidFloat(amount) {
+		return "", false
+	}
+	// Round first, so 999,999,999 becomes "1B" rather than "1000M"
+	amount, _ = strconv.ParseFloat(strconv.FormatFloat(amount, 'g', 3, 64), 64)
+	for _, s := range humanizeSuffixes {
+		if math.Abs(amount) >= s.scale {
+			scaled := amount / s.scale
+			decimals := max(2-int(math.Floor(math.Log10(math.Abs(scaled)))), 0)
+			formatted := strconv.FormatFloat(scaled, 'f', decimals, 64)
+			if strings.Contains(formatted, ".") {
+				formatted = strings.TrimRight(strings.TrimRight(formatted, "0"), ".")
+			}
+			return formatted + s.suffix, true
+		}
+	}
+	return "", false
+}
+
+// formatAge renders a data age for subtitles: "45s", "12m", "2h5m".
+func formatAge(age time.Duration) string {
+	if age < time.Minute {
+		return age.Round(time.Second).String()
+	}
+	return strings.TrimSuffix(age.Round(time.Minute).String(), "0s")
+}
+
 func formatCacheKey(from, to string, amount float64) string {
 	return fmt.Sprintf("%s_%s_%.8f", from, to, amount)
 }
+
+// formatWhitebirdBucketKey maps an amount to a log-scale bucket so nearby
+// amounts share a cached Whitebird quote.
+func formatWhitebirdBucketKey(from, to string, amount float64) string {
+	bucket := int(math.Floor(math.Log10(amount) * whitebirdBucketsPerDecade))
+	return fmt.Sprintf("wb_%s_%s_%d", from, to, bucket)
+}
@@ -0,0 +1,48 @@
+package currency
+
+import "testing"
+
+func TestVisiblePrecision(t *testing.T) {
+	tests := []struct {
+		amount    float64
+		precision int
+		want      int
+	}{
+		{12.34, 2, 2},
+		{0.005, 2, 2},
+		{0.004, 2, 4},
+		{0.0004, 2, 5},
+		{-0.0004, 2, 5},
+		{0.3, 0, 2},
+		{0.5, 0, 0},
+		{0, 2, 2},
+		{1e-20, 2, maxDisplayDecimals},
+	}
+	for _, tt := range tests {
+		if got := visiblePrecision(tt.amount, tt.precision); got != tt.want {
+			t.Errorf("visiblePrecision(%v, %d) = %d, want %d", tt.amount, tt.precision, got, tt.want)
+		}
+	}
+}
+
+func TestTinyAmountsDontShowAsZero(t *testing.T) {
+	tests := []struct {
+		amount    float64
+		currency  string
+		display   string
+		clipboard string
+	}{
+		{0.4, "JPY", "0.40", "0.4"},
+		{0.0004, "USD", "0.00040", "0.0004"},
+		{0.004, "EUR", "0.0040", "0.004"},
+		{1234.5, "USD", "1,234.50", "1234.5"},
+	}
+	for _, tt := range tests {
+		if got := formatAmount(tt.amount, tt.currency); got != tt.display {
+			t.Errorf("formatAmount(%v, %s) = %q, want %q", tt.amount, tt.currency, got, tt.display)
+		}
+		if got := formatAmountForClipboard(tt.amount, tt.currency); got != tt.clipboard {
+			t.Errorf("formatAmountForClipboard(%v, %s) = %q, want %q", tt.amount, tt.currency, got, tt.clipboard)
+		}
+	}
+}
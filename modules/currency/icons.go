@@ -0,0 +1,53 @@
+package currency
+
+import (
+	"strings"
+)
+
+// Per-asset icons for crypto results. CRYPTO_ICON_URL is a template with
+// {symbol} replaced by the lowercase ticker; it is only used for tickers in
+// cryptoIconPack, whose icons the default CDN is known to carry. Anything
+// else falls back to the module icon.
+var cryptoIconURLTemplate = getEnvOrDefault("CRYPTO_ICON_URL",
+	"https://cdn.jsdelivr.net/gh/spothq/cryptocurrency-icons@master/128/color/{symbol}.png")
+
+var cryptoIconPack = map[string]bool{
+	"BTC": true, "ETH": true, "USDT": true, "USDC": true, "XRP": true, "SOL": true, "ADA": true,
+	"DOGE": true, "DOT": true, "LTC": true, "TRX": true, "BNB": true, "LINK": true, "AVAX": true,
+	"ATOM": true, "XLM": true, "BCH": true, "ETC": true, "UNI": true, "DAI": true, "XTZ": true,
+	"AAVE": true, "ALGO": true, "FIL": true, "NEAR": true, "SHIB": true, "XMR": true, "ZEC": true,
+}
+
+// cryptoIconOverrides covers assets missing from the icon pack.
+var cryptoIconOverrides = map[string]string{
+	"TON": "https://ton.org/download/ton_symbol.png",
+}
+
+// assetIcon returns the icon for a from -> to result: the crypto side's icon
+// when one is known, "" (module default) otherwise.
+func assetIcon(from, to string) string {
+	codes := []string{from, to}
+	if from == CurrencyUSDT {
+		// USDT is usually just the quote side; prefer the other asset
+		codes = []string{to, from}
+	}
+	for _, code := range codes {
+		if icon := cryptoIcon(code); icon != "" {
+			return icon
+		}
+	}
+	return ""
+}
+
+func cryptoIcon(code string) string {
+	if fiatOnlyMode {
+		return ""
+	}
+	if icon, ok := cryptoIconOverrides[code]; ok {
+		return icon
+	}
+	if !cryptoIconPack[code] {
+		return ""
+	}
+	return strings.ReplaceAll(cryptoIconURLTemplate, "{symbol}", strings.ToLower(code))
+}
@@ -0,0 +1,54 @@
+package currency
+
+import (
+	"log"
+	"time"
+)
+
+// Background refreshes pause after IDLE_SUSPEND_AFTER without queries and
+// resume with the next one, so an idle launcher stops polling providers.
+// Zero keeps them running.
+var idleSuspendAfter = getEnvDurationOrDefault("IDLE_SUSPEND_AFTER", 0)
+
+// markActivity records a query. It reports whether the query ended an idle
+// suspension, in which case every update loop refreshes right away.
+func (ac *APICache) markActivity() bool {
+	ac.lastActivity.Store(time.Now().UnixNano())
+	if !ac.idleSuspended.Load() {
+		return false
+	}
+
+	ac.idleMu.Lock()
+	defer ac.idleMu.Unlock()
+	if !ac.idleSuspended.CompareAndSwap(true, false) {
+		return false
+	}
+	close(ac.idleWake)
+	ac.idleWake = make(chan struct{})
+	log.Println("Query received, resuming background refreshes")
+	return true
+}
+
+// idleWakeChan is closed when the current idle suspension ends.
+func (ac *APICache) idleWakeChan() <-chan struct{} {
+	ac.idleMu.Lock()
+	defer ac.idleMu.Unlock()
+	return ac.idleWake
+}
+
+// suspendedForIdle reports whether refreshes should be skipped because no
+// query arrived for IDLE_SUSPEND_AFTER, entering the suspension if needed.
+// The cache file is written once on entry so a restart resumes from it.
+func (ac *APICache) suspendedForIdle() bool {
+	if idleSuspendAfter <= 0 {
+		return false
+	}
+	if time.Since(time.Unix(0, ac.lastActivity.Load())) < idleSuspendAfter {
+		return false
+	}
+	if ac.idleSuspended.CompareAndSwap(false, true) {
+		log.Printf("No queries for %v, pausing background refreshes", idleSuspendAfter)
+		ac.SaveToFileAsync()
+	}
+	return true
+}
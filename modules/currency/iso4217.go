@@ -0,0 +1,69 @@
+package currency
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log"
+	"regexp"
+	"sort"
+)
+
+//go:embed config/iso4217.json
+var embeddedISO4217JSON []byte
+
+// isoCurrency is one ISO 4217 entry. Codes listed in config/iso4217.json
+// are the fiat currencies the converter supports, and their names are parsed
+// as aliases of the codes.
+type isoCurrency struct {
+	Code       string `json:"code"`
+	Numeric    string `json:"numeric"`
+	MinorUnits int    `json:"minor_units"`
+	Name       string `json:"name"`
+}
+
+var (
+	regexISOCode    = regexp.MustCompile(`^[A-Z]{3}$`)
+	regexISONumeric = regexp.MustCompile(`^[0-9]{3}$`)
+)
+
+var isoCurrencies = loadISO4217(embeddedISO4217JSON)
+
+// loadISO4217 parses the dataset, dropping malformed entries so a bad edit
+// can't put an invalid code into supportedFiats.
+func loadISO4217(data []byte) map[string]isoCurrency {
+	var entries []isoCurrency
+	if err := json.Unmarshal(data, &entries); err != nil {
+		log.Printf("Warning: Failed to load ISO 4217 data: %v", err)
+		return nil
+	}
+	currencies := make(map[string]isoCurrency, len(entries))
+	for _, e := range entries {
+		if !regexISOCode.MatchString(e.Code) || !regexISONumeric.MatchString(e.Numeric) ||
+			e.MinorUnits < 0 || e.MinorUnits > 4 || e.Name == "" {
+			log.Printf("Warning: skipping invalid ISO 4217 entry %+v", e)
+			continue
+		}
+		currencies[e.Code] = e
+	}
+	return currencies
+}
+
+// mastercardFiats returns the fiat codes priced through Mastercard: every
+// ISO entry except RUB, which goes through the Whitebird bridge unless that
+// is disabled.
+func mastercardFiats() []string {
+	codes := make([]string, 0, len(isoCurrencies))
+	for code := range isoCurrencies {
+		if code == CurrencyRUB && rubBridgeEnabled {
+			continue
+		}
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// currencyName returns the ISO 4217 name of a fiat code, or "".
+func currencyName(code string) string {
+	return isoCurrencies[code].Name
+}
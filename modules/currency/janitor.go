@@ -0,0 +1,115 @@
+package currency
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// JANITOR_INTERVAL is how often expired entries are dropped from the
+// in-memory caches. Caches don't sweep themselves while serving a request;
+// one goroutine per serving APICache does it for all of them.
+var janitorInterval = getEnvDurationOrDefault("JANITOR_INTERVAL", time.Minute)
+
+// janitorTask drops what has expired at now from one structure.
+//
+// Tasks run one after another on the janitor goroutine. A task takes only
+// the lock of the structure it compacts, and never calls back into the
+// APICache while holding it, so tasks can't deadlock with each other or
+// with requests.
+type janitorTask struct {
+	name string
+	run  func(now time.Time)
+}
+
+type janitor struct {
+	mu    sync.Mutex
+	tasks []janitorTask
+}
+
+// AddJanitorTask has the janitor also compact a structure owned outside
+// this package, under the rules of janitorTask.
+func (ac *APICache) AddJanitorTask(name string, run func(now time.Time)) {
+	ac.janitor.mu.Lock()
+	ac.janitor.tasks = append(ac.janitor.tasks, janitorTask{name, run})
+	ac.janitor.mu.Unlock()
+}
+
+// runJanitor compacts the caches every janitorInterval until shutdown.
+func (ac *APICache) runJanitor() {
+	if janitorInterval <= 0 {
+		return
+	}
+	ac.AddJanitorTask("conversions", globalConversionCache.compact)
+	ac.AddJanitorTask("whitebird quotes", whitebirdQuoteCache.compact)
+	ac.AddJanitorTask("dns", providerDNS.compact)
+
+	ticker := time.NewTicker(janitorInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			ac.janitor.mu.Lock()
+			tasks := append([]janitorTask(nil), ac.janitor.tasks...)
+			ac.janitor.mu.Unlock()
+			for _, task := range tasks {
+				runJanitorTask(task, now)
+			}
+		case <-ac.shutdownChan:
+			return
+		}
+	}
+}
+
+// runJanitorTask keeps a panicking task from taking the janitor down.
+func runJanitorTask(task janitorTask, now time.Time) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Warning: janitor task %s panicked: %v", task.name, r)
+		}
+	}()
+	task.run(now)
+}
+
+// compact drops the values getStale would no longer serve.
+func (c *ConversionCache) compact(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.compactLocked(now)
+}
+
+func (c *ConversionCache) compactLocked(now time.Time) {
+	for k, v := range c.results {
+		if now.Sub(v.timestamp) >= c.ttl+c.stale {
+			delete(c.results, k)
+		}
+	}
+}
+
+func (c *ConversionCache) evictOldestLocked() {
+	var oldest string
+	var oldestAt time.Time
+	for k, v := range c.results {
+		if oldest == "" || v.timestamp.Before(oldestAt) {
+			oldest, oldestAt = k, v.timestamp
+		}
+	}
+	delete(c.results, oldest)
+}
+
+func (c *ConversionCache) len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.results)
+}
+
+// compact drops the entries too old to be served even after a failure.
+func (c *dnsCache) compact(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for host, e := range c.entries {
+		if now.After(e.expires.Add(dnsCacheStale)) {
+			delete(c.entries, host)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package currency
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Lazy Bybit symbol loads are driven by whatever the user types, so they are
+// screened before spending quota: BYBIT_LAZY_DENY symbols are never fetched,
+// a non-empty BYBIT_LAZY_ALLOW admits only its symbols, and anything missing
+// from the last tickers batch (Bybit's list of USDT spot instruments) is
+// rejected. Entries are base codes ("PEPE") or symbols ("PEPEUSDT").
+var (
+	bybitLazyAllow = symbolSet(getEnvOrDefault("BYBIT_LAZY_ALLOW", ""))
+	bybitLazyDeny  = symbolSet(getEnvOrDefault("BYBIT_LAZY_DENY", ""))
+)
+
+var errSymbolNotLoadable = errors.New("symbol not loadable")
+
+func symbolSet(list string) map[string]bool {
+	set := make(map[string]bool)
+	for _, s := range splitList(list) {
+		s = strings.ToUpper(s)
+		if !strings.HasSuffix(s, "USDT") {
+			s += "USDT"
+		}
+		set[s] = true
+	}
+	return set
+}
+
+// checkLazySymbolLocked reports why symbol may not be lazily fetched, or nil.
+// Callers hold ac.mu.
+func (ac *APICache) checkLazySymbolLocked(symbol string) error {
+	if bybitLazyDeny[symbol] {
+		return fmt.Errorf("%s: %w (denied by BYBIT_LAZY_DENY)", symbol, errSymbolNotLoadable)
+	}
+	if len(bybitLazyAllow) > 0 && !bybitLazyAllow[symbol] {
+		return fmt.Errorf("%s: %w (not in BYBIT_LAZY_ALLOW)", symbol, errSymbolNotLoadable)
+	}
+	// Before the first tickers batch there is nothing to check against
+	if len(ac.bybitVolumes) > 0 {
+		if _, listed := ac.bybitVolumes[symbol]; !listed {
+			return fmt.Errorf("%s: %w (not listed on Bybit spot)", symbol, errSymbolNotLoadable)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package currency
+
+import "fmt"
+
+// volume24h returns the Bybit 24h USDT turnover of symbol, if the ticker
+// batch has been fetched and lists it.
+func (ac *APICache) volume24h(symbol string) (float64, bool) {
+	ac.mu.RLock()
+	defer ac.mu.RUnlock()
+	volume, ok := ac.bybitVolumes[symbol]
+	return volume, ok
+}
+
+// isIlliquid reports whether symbol traded less than LOW_LIQUIDITY_VOLUME_USD
+// in the last 24h. Symbols without a known volume are not flagged.
+func (ac *APICache) isIlliquid(symbol string) bool {
+	volume, ok := ac.volume24h(symbol)
+	return ok && volume < lowLiquidityVolumeUSD
+}
+
+// checkLargeOrderLiquidity gates large-amount conversions on symbol when
+// LIQUIDITY_GATE is on: thin markets are refused outright, others may take at
+// most LARGE_ORDER_MAX_VOLUME_SHARE of the day's turnover. An order book
+// snapshot of a thin market can look deep enough while nobody actually
+// trades there.
+func (ac *APICache) checkLargeOrderLiquidity(symbol string, usdValue float64) error {
+	if !liquidityGateEnabled {
+		return nil
+	}
+	volume, ok := ac.volume24h(symbol)
+	if !ok {
+		return nil
+	}
+	if volume < lowLiquidityVolumeUSD {
+		return fmt.Errorf("%s has low liquidity ($%s traded in 24h), large conversions are disabled",
+			symbol, formatAmount(volume, CurrencyUSD))
+	}
+	if usdValue > volume*largeOrderMaxVolumeShare {
+		return fmt.Errorf("amount exceeds %g%% of %s 24h volume ($%s)",
+			largeOrderMaxVolumeShare*100, symbol, formatAmount(volume, CurrencyUSD))
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package currency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Until the background fetch reaches it, a non-priority fiat has no
+// Mastercard rate. A conversion that needs one fetches just that rate inline,
+// charged to the query's provider budget, and caches it for everyone.
+
+const (
+	mastercardOnDemandTimeout = 5 * time.Second
+	// A fiat Mastercard couldn't price isn't asked for again for a while
+	mastercardOnDemandBackoff = 10 * time.Minute
+)
+
+var (
+	mastercardOnDemandGroup  singleflight.Group
+	mastercardOnDemandFailed sync.Map // fiat -> time.Time of the last failure
+)
+
+// ensureMastercardRate fetches the USD rate for fiat when none is cached. A
+// failure is only logged; the conversion then fails as it would have.
+func (ac *APICache) ensureMastercardRate(ctx context.Context, fiat string) {
+	if fiat == CurrencyUSD || !providerEnabled(providerMastercard) {
+		return
+	}
+	key := fmt.Sprintf("USD_%s", fiat)
+	ac.mu.RLock()
+	_, cached := ac.mastercardRates[key]
+	available := ac.mastercardStatus.Available
+	ac.mu.RUnlock()
+	if cached || !available || !mastercardCircuit.CanAttempt() {
+		return
+	}
+	if failed, ok := mastercardOnDemandFailed.Load(fiat); ok && time.Since(failed.(time.Time)) < mastercardOnDemandBackoff {
+		return
+	}
+
+	err := callProvider(ctx, func() error {
+		// Concurrent conversions into the same fiat share one request
+		_, err, _ := mastercardOnDemandGroup.Do(fiat, func() (interface{}, error) {
+			ctx, cancel := context.WithTimeout(withPriority(context.WithoutCancel(ctx), priorityInteractive), mastercardOnDemandTimeout)
+			defer cancel()
+			rate, err := ac.fetchMastercardRate(ctx, CurrencyUSD, fiat)
+			if err != nil {
+				return nil, err
+			}
+			rates := map[string]float64{key: rate}
+			ac.rejectBaselineOutliers("Mastercard", rates)
+			if _, ok := rates[key]; !ok {
+				return nil, fmt.Errorf("rate %.6f rejected against ECB baseline", rate)
+			}
+
+			now := time.Now()
+			ac.mu.Lock()
+			ac.mastercardRates[key] = rate
+			ac.lastMastercardRates[key] = rate
+			ac.mastercardFetchedAt[key] = now
+			ac.mastercardChangedAt[key] = now
+			ac.mu.Unlock()
+			log.Printf("Fetched Mastercard USD->%s on demand", fiat)
+			ac.SaveToFileAsync()
+			return nil, nil
+		})
+		return err
+	})
+	if errors.Is(err, errBudgetExhausted) {
+		return
+	}
+	if err != nil {
+		mastercardOnDemandFailed.Store(fiat, time.Now())
+		log.Printf("Warning: on-demand Mastercard fetch of USD->%s failed: %v", fiat, err)
+		return
+	}
+	mastercardOnDemandFailed.Delete(fiat)
+}
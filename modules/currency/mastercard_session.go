@@ -0,0 +1,107 @@
+package currency
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"sync"
+	"time"
+)
+
+// The Mastercard endpoint answers 403 less often to clients that carry the
+// cookies its converter page sets. With MASTERCARD_SESSION the Mastercard
+// client keeps a cookie jar, and the page is loaded before the first rate
+// request and again every MASTERCARD_SESSION_REFRESH, or sooner after a 403.
+var (
+	mastercardSessionEnabled = getEnvBoolOrDefault("MASTERCARD_SESSION", false)
+	mastercardSessionURL     = getEnvOrDefault("MASTERCARD_SESSION_URL", "https://www.mastercard.com/global/en/personal/get-support/currency-exchange-rate-converter.html")
+	mastercardSessionRefresh = getEnvDurationOrDefault("MASTERCARD_SESSION_REFRESH", 30*time.Minute)
+)
+
+// mastercardSessionRetry spaces out page loads while they fail, so a broken
+// page doesn't double the requests sent to Mastercard.
+const mastercardSessionRetry = time.Minute
+
+// mastercardSession tracks the converter page loads of one APICache.
+type mastercardSession struct {
+	mu          sync.Mutex
+	loadedAt    time.Time // last successful load; zero once invalidated
+	attemptedAt time.Time
+}
+
+// newMastercardJar returns the cookie jar for the Mastercard client, or nil
+// when sessions are off.
+func newMastercardJar() http.CookieJar {
+	if !mastercardSessionEnabled {
+		return nil
+	}
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		log.Printf("Warning: Mastercard session disabled: %v", err)
+		return nil
+	}
+	return jar
+}
+
+// ensureMastercardSession loads the converter page if the session is due
+// for a refresh. A failed load is logged, not returned: rate requests go
+// ahead with whatever cookies the jar holds.
+func (ac *APICache) ensureMastercardSession(ctx context.Context) {
+	if ac.clientFor(providerMastercard).Jar == nil {
+		return
+	}
+	s := &ac.mastercardSession
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.Sub(s.loadedAt) < mastercardSessionRefresh || now.Sub(s.attemptedAt) < mastercardSessionRetry {
+		return
+	}
+	s.attemptedAt = now
+	if err := ac.loadMastercardSession(ctx); err != nil {
+		log.Printf("Warning: Mastercard session not refreshed: %v", err)
+		return
+	}
+	s.loadedAt = now
+}
+
+// invalidateMastercardSession makes the next rate request reload the page.
+func (ac *APICache) invalidateMastercardSession() {
+	ac.mastercardSession.mu.Lock()
+	ac.mastercardSession.loadedAt = time.Time{}
+	ac.mastercardSession.mu.Unlock()
+}
+
+func (ac *APICache) loadMastercardSession(ctx context.Context) error {
+	if err := mastercardScheduler.Wait(ctx); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", mastercardSessionURL, nil)
+	if err != nil {
+		return err
+	}
+	// The page is a navigation, not the widget's XHR
+	setProviderHeaders(req, providerMastercard)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	req.Header.Set("Sec-Fetch-Dest", "document")
+	req.Header.Set("Sec-Fetch-Mode", "navigate")
+	req.Header.Set("Sec-Fetch-Site", "none")
+	req.Header.Del("Origin")
+	req.Header.Del("Referer")
+
+	resp, err := ac.clientFor(providerMastercard).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Drained so the connection can be reused for the rate requests
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxHTTPResponseSize))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("converter page: status %s", resp.Status)
+	}
+	log.Printf("Mastercard session refreshed: %d cookies", len(ac.clientFor(providerMastercard).Jar.Cookies(req.URL)))
+	return nil
+}
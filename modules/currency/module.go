@@ -2,13 +2,20 @@ package currency
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"strings"
 	"sync/atomic"
 	"time"
 
 	"answerflow/commontypes"
+	"answerflow/notify"
+
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type CurrencyConverterModule struct {
@@ -17,6 +24,7 @@ type CurrencyConverterModule struct {
 	defaultIconPath        string
 	currencyData           *CurrencyData
 	ShortDisplayFormat     bool
+	commontypes.Scoring
 }
 
 func NewCurrencyConverterModule(quickTargets []string, baseCurrency, iconPath string, shortDisplay bool) *CurrencyConverterModule {
@@ -27,11 +35,13 @@ func NewCurrencyConverterModule(quickTargets []string, baseCurrency, iconPath st
 
 	currencyData := NewCurrencyData()
 	apiCurrencies := make(map[string]string)
-	for _, crypto := range supportedCryptos {
-		apiCurrencies[crypto] = crypto + " Cryptocurrency"
+	if !fiatOnlyMode {
+		for _, crypto := range supportedCryptos {
+			apiCurrencies[crypto] = crypto + " Cryptocurrency"
+		}
 	}
 	for _, fiat := range supportedFiats {
-		apiCurrencies[fiat] = fiat + " Currency"
+		apiCurrencies[fiat] = currencyName(fiat)
 	}
 	currencyData.PopulateDynamicAliases(apiCurrencies)
 
@@ -52,9 +62,26 @@ func (m *CurrencyConverterModule) DefaultIconPath() string {
 	return m.defaultIconPath
 }
 
+func (m *CurrencyConverterModule) ResultGroup() string {
+	return "Currency"
+}
+
+func (m *CurrencyConverterModule) QueryHint() string {
+	return "100 usd to eur"
+}
+
+func (m *CurrencyConverterModule) MaxConcurrency() int {
+	return maxConcurrentQueries
+}
+
 var cacheRefreshInProgress atomic.Bool
 
-func (m *CurrencyConverterModule) ProcessQuery(ctx context.Context, query string, apiCache *APICache) ([]commontypes.FlowResult, error) {
+func (m *CurrencyConverterModule) ProcessQuery(ctx context.Context, query string, apiCache *APICache) (out []commontypes.FlowResult, err error) {
+	ctx, span := tracer.Start(ctx, "currency.ProcessQuery")
+	defer func() { endSpan(span, err) }()
+	defer func() { m.ApplyScoring(m.Name(), out) }()
+	ctx = withProviderBudget(ctx)
+
 	if apiCache == nil {
 		return nil, fmt.Errorf("API cache not initialized")
 	}
@@ -64,11 +91,15 @@ func (m *CurrencyConverterModule) ProcessQuery(ctx context.Context, query string
 		return nil, fmt.Errorf("query too long")
 	}
 
-	if apiCache.IsStale() {
+	// After an idle pause the update loops are refreshing already and the
+	// staleness is expected, not worth an alert
+	if resumed := apiCache.markActivity(); !resumed && apiCache.IsStale() {
 		staleness := apiCache.GetCacheStaleness()
 		for provider, duration := range staleness {
 			if duration > time.Hour*4 {
 				log.Printf("Warning: %s data critically stale (%v)", provider, duration)
+				alert("stale:"+provider, notify.LevelWarning, fmt.Sprintf("%s data stale", provider),
+					fmt.Sprintf("%s rates were last updated %v ago", provider, duration.Round(time.Minute)))
 			}
 		}
 		if cacheRefreshInProgress.CompareAndSwap(false, true) {
@@ -91,36 +122,61 @@ func (m *CurrencyConverterModule) ProcessQuery(ctx context.Context, query string
 	}
 
 	if strings.TrimSpace(query) == "" {
-		return nil, nil
+		return m.generateWatchlist(ctx, apiCache), nil
+	}
+
+	if stem, partial := detectPartialQuery(query); partial {
+		return m.generateProvisionalResults(ctx, stem, apiCache), nil
 	}
 
-	parsedRequest, err := ParseQuery(query, m.currencyData)
+	opts := ParseOptions{Home: m.requestHome(ctx, apiCache)}
+	if fee, ok := commontypes.PersonalFeeFromContext(ctx); ok {
+		opts.PersonalFee = fee
+	}
+	query = withDefaultSource(query)
+	parsedRequest, err := ParseQueryWithOptions(query, m.currencyData, opts)
 	if err != nil {
+		if suggestion := m.makeSuggestionResult(query); suggestion != nil {
+			return []commontypes.FlowResult{*suggestion}, nil
+		}
 		return nil, nil
 	}
 
 	if err := ValidateAmount(parsedRequest.Amount); err != nil {
 		return nil, nil
 	}
+	m.publishConversion(ctx, parsedRequest)
+
+	if parsedRequest.EntryPrice > 0 {
+		return m.generatePnLResult(ctx, parsedRequest, apiCache), nil
+	}
+	if parsedRequest.Side != "" {
+		return m.generateBookSideResult(ctx, parsedRequest, apiCache), nil
+	}
 
 	var results []commontypes.FlowResult
 
 	if parsedRequest.ToCurrency != "" {
 		toCurrency, err := m.currencyData.ResolveCurrency(parsedRequest.ToCurrency)
 		if err != nil {
+			// An unknown target is most likely a typo, not a failed pair
+			if suggestion := m.makeSuggestionResult(query); suggestion != nil {
+				return []commontypes.FlowResult{*suggestion}, nil
+			}
 			return nil, nil
 		}
 		parsedRequest.ToCurrency = toCurrency
 
+		if len(parsedRequest.Via) > 0 {
+			return m.generateChain(ctx, parsedRequest, apiCache), nil
+		}
+
 		if parsedRequest.FromCurrency == parsedRequest.ToCurrency {
 			result := commontypes.FlowResult{
-				Title:    fmt.Sprintf("%s %s", formatAmount(parsedRequest.Amount, parsedRequest.FromCurrency), parsedRequest.FromCurrency),
-				SubTitle: "Same currency",
-				Score:    100,
-				JsonRPCAction: commontypes.JsonRPCAction{
-					Method:     "copy_to_clipboard",
-					Parameters: []interface{}{formatAmountForClipboard(parsedRequest.Amount, parsedRequest.FromCurrency)},
-				},
+				Title:         fmt.Sprintf("%s %s", formatAmount(parsedRequest.Amount, parsedRequest.FromCurrency), parsedRequest.FromCurrency),
+				SubTitle:      "Same currency",
+				Score:         100,
+				JsonRPCAction: commontypes.CopyToClipboard(formatAmountForClipboard(parsedRequest.Amount, parsedRequest.FromCurrency)),
 			}
 			return []commontypes.FlowResult{result}, nil
 		}
@@ -132,24 +188,152 @@ func (m *CurrencyConverterModule) ProcessQuery(ctx context.Context, query string
 		default:
 		}
 
-		res, _, err := m.generateConversionResult(ctx, parsedRequest, parsedRequest.ToCurrency, apiCache, scoreSpecificConversion)
+		if parsedRequest.Table {
+			return m.generateTable(ctx, parsedRequest, apiCache), nil
+		}
+
+		if parsedRequest.Inverse {
+			return m.generateInverseResult(ctx, parsedRequest, apiCache), nil
+		}
+
+		res, route, err := m.generateConversionResult(ctx, parsedRequest, parsedRequest.ToCurrency, apiCache, scoreSpecificConversion)
 		if err == nil && res != nil {
 			results = append(results, *res)
-		} else if err != nil {
-			if er := m.makeErrorResult(parsedRequest, parsedRequest.ToCurrency, err); er != nil {
-				results = append(results, *er)
+			if cardNetwork == cardNetworkBoth {
+				results = m.addCardNetworkAlternative(ctx, parsedRequest, route, results, apiCache)
+			}
+			results = m.addUSDTNetworkAlternative(ctx, parsedRequest, results, apiCache)
+			results = m.addWhitebirdOppositeQuote(ctx, parsedRequest, results, apiCache)
+			if referenceEnabled(ctx) && route != nil {
+				if ref := m.generateReferenceResult(ctx, parsedRequest, parsedRequest.ToCurrency, route.Result, apiCache); ref != nil {
+					results = append(results, *ref)
+				}
+			}
+			if amountWordsEnabled(parsedRequest) && route != nil {
+				if words := m.generateWordsResult(ctx, parsedRequest.afterPersonalFee(route.Result), parsedRequest.ToCurrency); words != nil {
+					results = append(results, *words)
+				}
 			}
+		} else if err != nil {
+			results = m.requestedPairFailed(ctx, parsedRequest, m.makeErrorResult(parsedRequest, parsedRequest.ToCurrency, err), apiCache)
 		}
 	} else {
-		results = m.generateQuickConversions(ctx, parsedRequest, apiCache)
+		if amountWordsEnabled(parsedRequest) {
+			if words := m.generateWordsResult(ctx, parsedRequest.Amount, parsedRequest.FromCurrency); words != nil {
+				results = append(results, *words)
+			}
+		}
+		results = append(results, m.generateQuickConversions(ctx, parsedRequest, apiCache)...)
 	}
 
+	results = append(results, m.generateAlternativeMeanings(ctx, query, parsedRequest, apiCache)...)
 	return results, nil
 }
 
+// addCardNetworkAlternative labels the last result of a fiat pair with the
+// card network that priced it and appends the same conversion priced by the
+// other network, so holders of either card see their rate.
+func (m *CurrencyConverterModule) addCardNetworkAlternative(ctx context.Context, req *ConversionRequest, route *Route, results []commontypes.FlowResult, apiCache *APICache) []commontypes.FlowResult {
+	if getCurrencyType(req.FromCurrency, apiCache) != "fiat" || getCurrencyType(req.ToCurrency, apiCache) != "fiat" {
+		return results
+	}
+	used, err := apiCache.selectCardNetwork(req.FromCurrency, req.ToCurrency)
+	if err != nil || route == nil || len(route.Providers) != 1 || route.Providers[0] != cardNetworkLabel(used) {
+		return results
+	}
+	other := providerVisa
+	if used == providerVisa {
+		other = providerMastercard
+	}
+
+	main := &results[len(results)-1]
+	main.SubTitle += " | " + cardNetworkLabel(used)
+
+	out, err := m.convertFiatPairVia(ctx, decimal.NewFromFloat(req.Amount), req.FromCurrency, req.ToCurrency, other, apiCache)
+	if err != nil {
+		return results
+	}
+	amount := out.InexactFloat64()
+	if amount = req.afterPersonalFee(amount); amount < minAmountAfterFees {
+		return results
+	}
+	alt := m.formatResult(req, req.ToCurrency, amount, amount/req.Amount, main.Score-1, "", req.personalFeeInfo()+" | "+cardNetworkLabel(other))
+	return append(results, *alt)
+}
+
+// generateAlternativeMeanings adds a result for every other reading of an
+// ambiguous token in the query ("10 sol" is Solana, or the Peruvian sol),
+// scored below the preferred reading instead of silently dropping it.
+func (m *CurrencyConverterModule) generateAlternativeMeanings(ctx context.Context, query string, req *ConversionRequest, apiCache *APICache) []commontypes.FlowResult {
+	var results []commontypes.FlowResult
+	for _, token := range regexQueryToken.FindAllString(query, -1) {
+		codes := m.currencyData.Interpretations(token)
+		if len(codes) < 2 || (codes[0] != req.FromCurrency && codes[0] != req.ToCurrency) {
+			continue
+		}
+		for i, alt := range codes[1:] {
+			altReq := *req
+			if altReq.FromCurrency == codes[0] {
+				altReq.FromCurrency = alt
+			} else {
+				altReq.ToCurrency = alt
+			}
+			target := altReq.ToCurrency
+			if target == "" {
+				target = m.baseConversionCurrency
+			}
+			if target == "" || target == altReq.FromCurrency {
+				continue
+			}
+			res, _, err := m.generateConversionResult(ctx, &altReq, target, apiCache, scoreAlternativeMeaning-i)
+			if err != nil || res == nil {
+				continue
+			}
+			res.SubTitle += fmt.Sprintf(" | reading %q as %s", token, alt)
+			results = append(results, *res)
+		}
+	}
+	return results
+}
+
+// publishConversion shares the parsed amount and currency pair with the
+// modules that depend on this one.
+func (m *CurrencyConverterModule) publishConversion(ctx context.Context, req *ConversionRequest) {
+	board := commontypes.BlackboardFromContext(ctx)
+	if board == nil {
+		return
+	}
+	to := req.ToCurrency
+	if to != "" {
+		to, _ = m.currencyData.ResolveCurrency(to)
+	}
+	board.Publish(commontypes.EntryConversion, commontypes.ParsedConversion{
+		Amount:  req.Amount,
+		From:    req.FromCurrency,
+		To:      to,
+		Inverse: req.Inverse,
+	})
+}
+
+// requestHome returns the home currency attached to ctx if it is one we can
+// convert to, or "" for the configured default.
+func (m *CurrencyConverterModule) requestHome(ctx context.Context, apiCache *APICache) string {
+	home := commontypes.HomeCurrencyFromContext(ctx)
+	if home == "" || getCurrencyType(home, apiCache) == "unknown" {
+		return ""
+	}
+	return home
+}
+
 func (m *CurrencyConverterModule) generateQuickConversions(ctx context.Context, req *ConversionRequest, apiCache *APICache) []commontypes.FlowResult {
+	if req.Home == "" {
+		req.Home = m.requestHome(ctx, apiCache)
+	}
 	var results []commontypes.FlowResult
 	seen := make(map[string]bool)
+	// Target amount and row index of each target's forward and inverse row
+	forward := make(map[string]quickRow)
+	inverse := make(map[string]quickRow)
 
 	addResult := func(targetCurrency string, score int, isInverse bool) {
 		// Deduplication
@@ -166,16 +350,34 @@ func (m *CurrencyConverterModule) generateQuickConversions(ctx context.Context,
 		default:
 		}
 
+		// Skip targets that are not routable, e.g. RUB in fiat-only mode
+		if getCurrencyType(targetCurrency, apiCache) == "unknown" {
+			return
+		}
+
+		// While the query is still being typed, defer inverse searches and Whitebird quotes
+		if req.Provisional && (isInverse || m.routeUsesWhitebird(req.FromCurrency, targetCurrency, apiCache)) {
+			return
+		}
+
 		if isInverse {
-			amount, err := m.findInverseAmount(req.Amount, targetCurrency, req.FromCurrency, apiCache)
+			scoped := withBudgetScope(ctx)
+			amount, err := m.findInverseAmount(scoped, req.beforePersonalFee(req.Amount), targetCurrency, req.FromCurrency, apiCache)
 			if err == nil && amount > 0 {
-				if res := m.formatInverseResult(amount, targetCurrency, req.Amount, req.FromCurrency, score); res != nil {
+				if res := m.formatInverseResult(amount, targetCurrency, req.Amount, req.FromCurrency, score, req.home(), req.precision()); res != nil {
+					if isApproximate(scoped) {
+						res.Badges = append(res.Badges, commontypes.BadgeApproximate)
+					}
+					inverse[targetCurrency] = quickRow{len(results), amount}
 					results = append(results, *res)
 				}
 			}
 		} else {
-			res, _, err := m.generateConversionResult(ctx, req, targetCurrency, apiCache, score)
+			res, route, err := m.generateConversionResult(ctx, req, targetCurrency, apiCache, score)
 			if err == nil && res != nil {
+				if route != nil {
+					forward[targetCurrency] = quickRow{len(results), route.Result}
+				}
 				results = append(results, *res)
 			} else if err != nil {
 				if er := m.makeErrorResult(req, targetCurrency, err); er != nil {
@@ -185,6 +387,34 @@ func (m *CurrencyConverterModule) generateQuickConversions(ctx context.Context,
 		}
 	}
 
+	// The requester's favorites follow the configured targets, badged
+	addFavorites := func() {
+		for _, target := range commontypes.FavoriteCurrenciesFromContext(ctx) {
+			if target == req.FromCurrency {
+				continue
+			}
+			before := len(results)
+			addResult(target, scoreFavoriteConversion, false)
+			for i := before; i < len(results); i++ {
+				results[i].Badges = append(results[i].Badges, commontypes.BadgeFavorite)
+			}
+		}
+	}
+
+	if home := req.home(); !showTradeTags(home) {
+		// Plain reference rates around the home currency: forward
+		// conversions only, no buy/sell inverses
+		addResult(home, scoreReverseConversion, false)
+		if m.baseConversionCurrency != "" {
+			addResult(m.baseConversionCurrency, scoreBaseConversion, false)
+		}
+		for _, target := range m.quickConversionTargets {
+			addResult(target, scoreQuickConversion, false)
+		}
+		addFavorites()
+		return results
+	}
+
 	switch req.FromCurrency {
 	case "RUB":
 		addResult("USD", scoreBaseConversion, false)
@@ -233,53 +463,154 @@ func (m *CurrencyConverterModule) generateQuickConversions(ctx context.Context,
 			}
 		}
 	}
+	addFavorites()
 
-	return results
+	return dropRedundantInverses(results, forward, inverse)
+}
+
+// quickRow locates a quick conversion row and the target amount it shows.
+type quickRow struct {
+	index  int
+	amount float64
+}
+
+// dropRedundantInverses removes inverse rows whose target amount is within
+// inverseDedupTolerance of the forward row for the same target: with no
+// spread between buying and selling, the two rows show the same figure. The
+// forward row is kept, as it carries the route and fees, and takes the
+// higher of the two scores so it sits where the pair did.
+func dropRedundantInverses(results []commontypes.FlowResult, forward, inverse map[string]quickRow) []commontypes.FlowResult {
+	if inverseDedupTolerance <= 0 {
+		return results
+	}
+	drop := make(map[int]bool)
+	for target, inv := range inverse {
+		fwd, ok := forward[target]
+		if !ok || math.Abs(fwd.amount-inv.amount) > inverseDedupTolerance*math.Max(fwd.amount, inv.amount) {
+			continue
+		}
+		results[fwd.index].Score = max(results[fwd.index].Score, results[inv.index].Score)
+		drop[inv.index] = true
+	}
+	if len(drop) == 0 {
+		return results
+	}
+	kept := results[:0]
+	for i, res := range results {
+		if !drop[i] {
+			kept = append(kept, res)
+		}
+	}
+	return kept
 }
 
-func (m *CurrencyConverterModule) generateConversionResult(ctx context.Context, req *ConversionRequest, targetCurrency string, apiCache *APICache, baseScore int) (*commontypes.FlowResult, float64, error) {
+func (m *CurrencyConverterModule) generateConversionResult(ctx context.Context, req *ConversionRequest, targetCurrency string, apiCache *APICache, baseScore int) (_ *commontypes.FlowResult, _ *Route, err error) {
+	ctx, span := tracer.Start(ctx, "currency.convert", trace.WithAttributes(
+		attribute.String("from", req.FromCurrency), attribute.String("to", targetCurrency), attribute.Float64("amount", req.Amount)))
+	defer func() { endSpan(span, err) }()
+	ctx = withBudgetScope(ctx)
+
 	if req.FromCurrency == targetCurrency {
-		return nil, 0, nil
+		return nil, nil, nil
 	}
 
 	// Check context before expensive operation
 	select {
 	case <-ctx.Done():
-		return nil, 0, ctx.Err()
+		return nil, nil, ctx.Err()
 	default:
 	}
 
-	finalAmount, err := m.convert(req.Amount, req.FromCurrency, targetCurrency, apiCache)
+	diag := commontypes.DiagnosticsFromContext(ctx)
+	if diag != nil {
+		_, hit := globalConversionCache.peek(formatCacheKey(req.FromCurrency, targetCurrency, req.Amount))
+		diag.AddCacheLookup(hit)
+	}
+
+	finalAmount, legs, err := m.convertRoute(ctx, req.Amount, req.FromCurrency, targetCurrency, apiCache)
 	if err != nil {
-		return nil, 0, err
+		diag.AddError(fmt.Errorf("%s->%s: %w", req.FromCurrency, targetCurrency, err))
+		return nil, nil, err
 	}
 
+	finalAmount = req.afterPersonalFee(finalAmount)
 	if finalAmount < minAmountAfterFees {
-		return nil, 0, fmt.Errorf("amount too small")
+		return nil, nil, fmt.Errorf("amount too small")
 	}
 
 	displayRate := finalAmount / req.Amount
 	if !isValidFloat(displayRate) {
-		return nil, 0, fmt.Errorf("invalid rate")
+		return nil, nil, fmt.Errorf("invalid rate")
 	}
 
 	// Build route-based slippage and fee info
-	slippageInfo := m.calculateSlippageInfo(req, targetCurrency, apiCache)
+	slippagePercent := m.calculateSlippagePercent(ctx, req, targetCurrency, apiCache)
+	slippageInfo := ""
+	if slippagePercent > slippageWarningThreshold {
+		slippageInfo = fmt.Sprintf(" %s %.1f%% slip", commontypes.GlyphWarning, slippagePercent)
+	}
 	routeLegs := m.planRoute(req.FromCurrency, targetCurrency, apiCache)
-	feesInfo := m.buildFeesInfoFromRoute(routeLegs)
+	feesInfo := m.buildFeesInfoFromRoute(routeLegs) + req.personalFeeInfo() + apiCache.cbrComparisonInfo(req.FromCurrency, targetCurrency, displayRate)
+
+	// The legs recorded while converting; an amount cached without them
+	// still converts, just without timing
+	route := newRoute(req.Amount, req.FromCurrency, targetCurrency, legs)
+	traced := len(legs) > 0
+	if !traced {
+		route.ValidUntil = route.QuotedAt
+	}
+	route.Result = finalAmount
+	route.EffectiveRate = displayRate
+	route.SlippagePercent = slippagePercent
+	route.PersonalFeePercent = req.PersonalFee
+	route.Approximate = isApproximate(ctx)
+	for _, provider := range route.Providers {
+		if provider == coingeckoProvider {
+			feesInfo += " | index price, not executable"
+			break
+		}
+	}
+	for _, leg := range route.Legs {
+		if leg.LowLiquidity {
+			feesInfo += " | low liquidity"
+			break
+		}
+	}
+	if route.Approximate {
+		feesInfo += " | approximate"
+	}
+	route.Warnings = conversionWarnings(route)
+	feesInfo += formatWarnings(route.Warnings)
+	if age := time.Duration(route.StalenessSeconds * float64(time.Second)); validAsOfSubtitleAfter > 0 && age > validAsOfSubtitleAfter {
+		feesInfo += " | as of " + formatAge(age) + " ago"
+	}
 
-	return m.formatResult(req, targetCurrency, finalAmount, displayRate, baseScore, slippageInfo, feesInfo), finalAmount, nil
+	res := m.formatResult(req, targetCurrency, finalAmount, displayRate, baseScore, slippageInfo, feesInfo)
+	res.ContextData = route
+	res.ContextMenuItems = append(res.ContextMenuItems, sourceLinks(route)...)
+	if traced {
+		res.ValidAsOf = &route.QuotedAt
+	}
+	res.CacheTTL = route.cacheTTL()
+	diag.AddRoute(route, route.Providers)
+	if route.StalenessSeconds > staleBadgeAge.Seconds() {
+		res.Badges = append(res.Badges, commontypes.BadgeStale)
+	}
+	if route.Approximate {
+		res.Badges = append(res.Badges, commontypes.BadgeApproximate)
+	}
+	return res, route, nil
 }
 
-// calculateSlippageInfo inspects the route and provides a warning string
-// if order book slippage is significant for the given amount.
-func (m *CurrencyConverterModule) calculateSlippageInfo(req *ConversionRequest, targetCurrency string, apiCache *APICache) string {
+// calculateSlippagePercent inspects the route and returns the order book
+// slippage in percent for the given amount, or 0 if it doesn't apply.
+func (m *CurrencyConverterModule) calculateSlippagePercent(ctx context.Context, req *ConversionRequest, targetCurrency string, apiCache *APICache) float64 {
 	fromType := getCurrencyType(req.FromCurrency, apiCache)
 	toType := getCurrencyType(targetCurrency, apiCache)
 
 	// Only check slippage for crypto trades
 	if fromType != "crypto" && fromType != "TON" && toType != "crypto" && toType != "TON" {
-		return ""
+		return 0
 	}
 
 	var usdValue float64
@@ -293,7 +624,7 @@ func (m *CurrencyConverterModule) calculateSlippageInfo(req *ConversionRequest,
 	}
 
 	if !shouldUseOrderBookByUSD(usdValue) {
-		return ""
+		return 0
 	}
 
 	var slippagePercent float64
@@ -307,11 +638,7 @@ func (m *CurrencyConverterModule) calculateSlippageInfo(req *ConversionRequest,
 	if slippage, err := apiCache.CalculateSlippage(symbol, req.Amount, isBuy); err == nil {
 		slippagePercent = slippage
 	}
-
-	if slippagePercent > slippageWarningThreshold {
-		return fmt.Sprintf(" ⚠️ %.1f%% slip", slippagePercent)
-	}
-	return ""
+	return slippagePercent
 }
 
 // buildFeesInfoFromRoute generates a concise, accurate fee summary for the given route.
@@ -341,16 +668,63 @@ func (m *CurrencyConverterModule) buildFeesInfoFromRoute(legs []string) string {
 	return " | " + strings.Join(parts, "+")
 }
 
+// generateInverseResult answers an explicit inverse question: how much
+// req.ToCurrency is needed to end up with req.Amount req.FromCurrency.
+func (m *CurrencyConverterModule) generateInverseResult(ctx context.Context, req *ConversionRequest, apiCache *APICache) []commontypes.FlowResult {
+	ctx = withBudgetScope(ctx)
+	amount, err := m.findInverseAmount(ctx, req.beforePersonalFee(req.Amount), req.ToCurrency, req.FromCurrency, apiCache)
+	if err == nil && amount <= 0 {
+		err = fmt.Errorf("invalid amount")
+	}
+	if err != nil {
+		return m.requestedPairFailed(ctx, req, m.makeErrorResult(req, req.ToCurrency, err), apiCache)
+	}
+	if res := m.formatInverseResult(amount, req.ToCurrency, req.Amount, req.FromCurrency, scoreSpecificConversion, req.home(), req.precision()); res != nil {
+		if isApproximate(ctx) {
+			res.Badges = append(res.Badges, commontypes.BadgeApproximate)
+		}
+		return []commontypes.FlowResult{*res}
+	}
+	return nil
+}
+
+// requestedPairFailed answers a query whose requested conversion failed:
+// errRes, the reason, on top so it is clear the pair itself failed, then the
+// quick conversions of the amount as alternatives.
+func (m *CurrencyConverterModule) requestedPairFailed(ctx context.Context, req *ConversionRequest, errRes *commontypes.FlowResult, apiCache *APICache) []commontypes.FlowResult {
+	errRes.Score = scoreRequestedPairError
+	results := []commontypes.FlowResult{*errRes}
+	alt := &ConversionRequest{
+		Amount:       req.Amount,
+		FromCurrency: req.FromCurrency,
+		Home:         req.Home,
+		Precision:    req.Precision,
+		HasPrecision: req.HasPrecision,
+		AmountNote:   req.AmountNote,
+		PersonalFee:  req.PersonalFee,
+	}
+	for _, res := range m.generateQuickConversions(ctx, alt, apiCache) {
+		// The failed pair may be a quick target too; it is reported once
+		if res.Title != errRes.Title {
+			results = append(results, res)
+		}
+	}
+	return results
+}
+
 func (m *CurrencyConverterModule) makeErrorResult(req *ConversionRequest, target string, err error) *commontypes.FlowResult {
 	title := fmt.Sprintf("Conversion unavailable: %s → %s", req.FromCurrency, target)
 	sub := TranslateError(err)
-	return &commontypes.FlowResult{
-		Title:    title,
-		SubTitle: sub,
-		Score:    10,
-		JsonRPCAction: commontypes.JsonRPCAction{
-			Method:     "copy_to_clipboard",
-			Parameters: []interface{}{fmt.Sprintf("%s %s", formatAmountForClipboard(req.Amount, req.FromCurrency), req.FromCurrency)},
-		},
+	res := &commontypes.FlowResult{
+		Title:         title,
+		SubTitle:      sub,
+		Score:         10,
+		JsonRPCAction: commontypes.CopyToClipboard(fmt.Sprintf("%s %s", formatAmountForClipboard(req.Amount, req.FromCurrency), req.FromCurrency)),
+		Error:         true,
+	}
+	// Out of budget is not final: a full query can still answer
+	if errors.Is(err, errBudgetExhausted) {
+		res.Badges = append(res.Badges, commontypes.BadgeApproximate)
 	}
+	return res
 }
@@ -0,0 +1,51 @@
+package currency
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// Fiat and fee arithmetic runs in decimal so that rounding error doesn't
+// accumulate over 4-5 leg routes: a route carries its amount from leg to
+// leg as a decimal.Decimal and turns it into a float64 only once, at the
+// end. Order-book walks and Whitebird quotes stay in float64: they consume
+// float prices and sum many small fills, so their legs convert the amount
+// on the way in and out.
+//
+// Rounding rules: every leg result is rounded half-to-even to legPrecision
+// decimal places, well beyond any displayed precision, so the final figure
+// does not drift in either direction however many legs a route has.
+const legPrecision = 10
+
+func roundLeg(d decimal.Decimal) decimal.Decimal {
+	return d.RoundBank(legPrecision)
+}
+
+// applyRateWithFee converts amount at rate with a percentage fee charged on
+// top of the rate, as the card networks do: amount * rate / (1 + fee).
+func applyRateWithFee(amount decimal.Decimal, rate, fee float64) decimal.Decimal {
+	gross := amount.Mul(decimal.NewFromFloat(rate))
+	return roundLeg(gross.DivRound(decimal.NewFromInt(1).Add(decimal.NewFromFloat(fee)), legPrecision+2))
+}
+
+// deductPercentFee deducts a percentage fee: amount * (1 - fee).
+func deductPercentFee(amount decimal.Decimal, fee float64) decimal.Decimal {
+	return roundLeg(amount.Mul(decimal.NewFromInt(1).Sub(decimal.NewFromFloat(fee))))
+}
+
+// applyPercentFee is deductPercentFee for a float64 amount.
+func applyPercentFee(amount, fee float64) float64 {
+	return deductPercentFee(decimal.NewFromFloat(amount), fee).InexactFloat64()
+}
+
+// applyFixedFee deducts a fixed network fee: amount - fee.
+func applyFixedFee(amount, fee float64) float64 {
+	return roundLeg(decimal.NewFromFloat(amount).Sub(decimal.NewFromFloat(fee))).InexactFloat64()
+}
+
+// floatLeg brings the result of a float64 leg back into decimal.
+func floatLeg(out float64, err error) (decimal.Decimal, error) {
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return decimal.NewFromFloat(out), nil
+}
@@ -0,0 +1,77 @@
+package currency
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestMoneyLegs(t *testing.T) {
+	tests := []struct {
+		name string
+		got  decimal.Decimal
+		want string
+	}{
+		{"card leg", applyRateWithFee(decimal.NewFromInt(100), 0.92, 0.02), "90.1960784314"},
+		{"percent fee", deductPercentFee(decimal.NewFromInt(100), 0.001), "99.9"},
+		{"half to even, down", deductPercentFee(decimal.RequireFromString("0.00000000025"), 0), "0.0000000002"},
+		{"half to even, up", deductPercentFee(decimal.RequireFromString("0.00000000035"), 0), "0.0000000004"},
+	}
+	for _, tt := range tests {
+		if want := decimal.RequireFromString(tt.want); !tt.got.Equal(want) {
+			t.Errorf("%s = %s, want %s", tt.name, tt.got, want)
+		}
+	}
+}
+
+func TestMoneyFloatLegs(t *testing.T) {
+	tests := []struct {
+		name      string
+		got, want float64
+	}{
+		{"fixed fee", applyFixedFee(0.3, 0.1), 0.2},
+		{"percent fee", applyPercentFee(1000, 0.005), 995},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+// Golden results for routes of several legs: the amount stays decimal from
+// leg to leg, so the result is the per-leg rounded exact figure.
+func TestMoneyRouteGolden(t *testing.T) {
+	tests := []struct {
+		name string
+		legs func(decimal.Decimal) decimal.Decimal
+		in   int64
+		want string
+	}{
+		{
+			name: "EUR -> USD -> JPY by card",
+			legs: func(d decimal.Decimal) decimal.Decimal {
+				return applyRateWithFee(applyRateWithFee(d, 1.087, 0.02), 151.3, 0.02)
+			},
+			in:   100,
+			want: "15807.679738565",
+		},
+		{
+			name: "five 0.1% fees",
+			legs: func(d decimal.Decimal) decimal.Decimal {
+				for range 5 {
+					d = deductPercentFee(d, 0.001)
+				}
+				return d
+			},
+			in:   100,
+			want: "99.5009990005",
+		},
+	}
+	for _, tt := range tests {
+		got := tt.legs(decimal.NewFromInt(tt.in))
+		if want := decimal.RequireFromString(tt.want); !got.Equal(want) {
+			t.Errorf("%s: %d -> %s, want %s", tt.name, tt.in, got, want)
+		}
+	}
+}
@@ -1,41 +1,6 @@
 package currency
 
-import (
-	"regexp"
-	"strings"
-)
-
-func NormalizeNumberString(s string) string {
-	s = strings.ReplaceAll(s, " ", "")
-	s = strings.ReplaceAll(s, " ", "")
-
-	dotIdx := strings.LastIndex(s, ".")
-	commaIdx := strings.LastIndex(s, ",")
-
-	if dotIdx != -1 && commaIdx != -1 {
-		if commaIdx > dotIdx {
-			s = strings.ReplaceAll(s, ".", "")
-			s = strings.Replace(s, ",", ".", 1)
-		} else {
-			s = strings.ReplaceAll(s, ",", "")
-		}
-	} else if commaIdx != -1 {
-		parts := strings.Split(s, ",")
-		if len(parts) > 1 {
-			lastPart := parts[len(parts)-1]
-			if len(lastPart) >= 1 && len(lastPart) <= 3 && regexp.MustCompile(`^\d+$`).MatchString(lastPart) {
-				if strings.Count(s, ",") == 1 {
-					s = strings.Join(parts[:len(parts)-1], "") + "." + lastPart
-				} else {
-					s = strings.ReplaceAll(s, ",", "")
-				}
-			} else {
-				s = strings.ReplaceAll(s, ",", "")
-			}
-		}
-	}
-	return s
-}
+import "strings"
 
 func TranslateError(err error) string {
 	if err == nil {
@@ -56,6 +21,7 @@ func TranslateError(err error) string {
 		"amount too small after":                      "amount too small - fees would consume all value",
 		"no match":                                    "could not parse currency query",
 		"unknown currency":                            "currency not recognized",
+		"provider budget for this query exhausted":    "rate not loaded yet, please try again",
 	}
 
 	for pattern, friendly := range translations {
@@ -0,0 +1,135 @@
+package currency
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Outbound host policy, for deployments that must not trust DNS or the
+// network path to the providers:
+//
+//	OUTBOUND_ALLOWED_HOSTS  hosts requests may go to, comma-separated; an
+//	                        entry also allows its subdomains. Empty allows
+//	                        any host.
+//	TLS_PINS                host=pin entries, comma-separated, a pin being
+//	                        the base64 SHA-256 of a public key (SPKI), with
+//	                        or without a "sha256/" prefix. A host may have
+//	                        several pins for key rotation; a connection to a
+//	                        pinned host needs a certificate in its chain with
+//	                        one of them.
+//
+// A host given only unreadable pins is still pinned, so it fails closed
+// rather than quietly going unpinned.
+var (
+	outboundAllowedHosts = splitList(strings.ToLower(os.Getenv("OUTBOUND_ALLOWED_HOSTS")))
+	tlsPins              = loadTLSPins()
+)
+
+var errHostNotAllowed = errors.New("host not in OUTBOUND_ALLOWED_HOSTS")
+
+// hostAllowed reports whether host is in OUTBOUND_ALLOWED_HOSTS or a
+// subdomain of an entry.
+func hostAllowed(host string) bool {
+	if len(outboundAllowedHosts) == 0 {
+		return true
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, allowed := range outboundAllowedHosts {
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostPolicyTransport refuses requests, redirects included, to hosts
+// OUTBOUND_ALLOWED_HOSTS leaves out.
+type hostPolicyTransport struct {
+	next http.RoundTripper
+}
+
+func (t *hostPolicyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !hostAllowed(req.URL.Hostname()) {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("%w: %s", errHostNotAllowed, req.URL.Hostname())
+	}
+	return t.next.RoundTrip(req)
+}
+
+// loadTLSPins reads TLS_PINS into decoded SHA-256 hashes by lower-case host.
+func loadTLSPins() map[string][][]byte {
+	pins := make(map[string][][]byte)
+	for _, entry := range splitList(os.Getenv("TLS_PINS")) {
+		host, pin, err := parseTLSPin(entry)
+		if host == "" {
+			log.Printf("Warning: TLS_PINS entry %q: %v", entry, err)
+			continue
+		}
+		if err != nil {
+			log.Printf("Warning: TLS_PINS entry %q: %v; %s accepts no certificate until fixed", entry, err, host)
+			pins[host] = append(pins[host], nil)
+			continue
+		}
+		pins[host] = append(pins[host], pin)
+	}
+	return pins
+}
+
+// parseTLSPin splits a host=pin entry. The host is returned whenever the
+// entry names one, even if the pin is bad.
+func parseTLSPin(entry string) (host string, pin []byte, err error) {
+	host, value, ok := strings.Cut(entry, "=")
+	host = strings.ToLower(strings.TrimSpace(host))
+	if !ok || host == "" {
+		return "", nil, errors.New("not host=pin")
+	}
+	value = strings.TrimPrefix(strings.TrimSpace(value), "sha256/")
+	pin, err = base64.StdEncoding.DecodeString(value)
+	if err != nil || len(pin) != sha256.Size {
+		return host, nil, errors.New("pin is not a base64 SHA-256 hash")
+	}
+	return host, pin, nil
+}
+
+// pinnedTLSConfig checks TLS_PINS after the usual certificate verification;
+// nil when nothing is pinned.
+func pinnedTLSConfig() *tls.Config {
+	if len(tlsPins) == 0 {
+		return nil
+	}
+	return &tls.Config{
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			host := strings.ToLower(cs.ServerName)
+			pins, ok := tlsPins[host]
+			if !ok {
+				return nil
+			}
+			for _, cert := range cs.PeerCertificates {
+				if spkiPinned(cert, pins) {
+					return nil
+				}
+			}
+			return fmt.Errorf("no certificate of %s matches a pinned key", host)
+		},
+	}
+}
+
+func spkiPinned(cert *x509.Certificate, pins [][]byte) bool {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	for _, pin := range pins {
+		if pin != nil && string(pin) == string(sum[:]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package currency
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+)
+
+// PARSE_CACHE_SIZE bounds the number of queries whose parse results are
+// kept, least recently used first out. 0 disables the cache.
+var parseCacheSize = int(getEnvFloatOrDefault("PARSE_CACHE_SIZE", 1024))
+
+type parseEntry struct {
+	query string
+	req   *ConversionRequest
+	err   error
+}
+
+// parseLRU caches ParseQuery results, failures included: most keystrokes
+// are incomplete queries that fail to parse. Results depend on the known
+// currencies, so the cache is cleared when they change.
+type parseLRU struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // front is most recently used
+	entries map[string]*list.Element
+
+	hits, misses atomic.Int64
+}
+
+func newParseLRU(size int) *parseLRU {
+	return &parseLRU{size: size, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+func (c *parseLRU) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	clear(c.entries)
+}
+
+// get returns a copy of the cached request, as callers fill in fields.
+func (c *parseLRU) get(query string) (*ConversionRequest, error, bool) {
+	if c.size <= 0 {
+		return nil, nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[query]
+	if !ok {
+		c.misses.Add(1)
+		return nil, nil, false
+	}
+	c.hits.Add(1)
+	c.order.MoveToFront(el)
+	e := el.Value.(*parseEntry)
+	return copyRequest(e.req), e.err, true
+}
+
+func (c *parseLRU) put(query string, req *ConversionRequest, err error) {
+	if c.size <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := &parseEntry{query: query, req: copyRequest(req), err: err}
+	if el, ok := c.entries[query]; ok {
+		el.Value = entry
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[query] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*parseEntry).query)
+	}
+}
+
+func copyRequest(req *ConversionRequest) *ConversionRequest {
+	if req == nil {
+		return nil
+	}
+	c := *req
+	c.Via = append([]string(nil), req.Via...)
+	return &c
+}
+
+// parsePatternHits counts which query pattern matched, to show which
+// patterns carry the traffic. The order they are tried in is part of the
+// grammar (a table query also matches the amount patterns), so the counts
+// inform rather than reorder.
+var parsePatternHits sync.Map // pattern name -> *atomic.Int64
+
+func countPattern(name string) {
+	counter, _ := parsePatternHits.LoadOrStore(name, new(atomic.Int64))
+	counter.(*atomic.Int64).Add(1)
+}
+
+// ParseStats reports parse cache effectiveness and per-pattern match counts.
+type ParseStats struct {
+	CacheHits    int64            `json:"cache_hits"`
+	CacheMisses  int64            `json:"cache_misses"`
+	CacheEntries int              `json:"cache_entries"`
+	Patterns     map[string]int64 `json:"patterns"`
+}
+
+// ParseStats returns the parser's counters since start.
+func (m *CurrencyConverterModule) ParseStats() ParseStats {
+	cache := m.currencyData.parseCache
+	cache.mu.Lock()
+	entries := cache.order.Len()
+	cache.mu.Unlock()
+
+	stats := ParseStats{
+		CacheHits:    cache.hits.Load(),
+		CacheMisses:  cache.misses.Load(),
+		CacheEntries: entries,
+		Patterns:     make(map[string]int64),
+	}
+	parsePatternHits.Range(func(name, counter any) bool {
+		stats.Patterns[name.(string)] = counter.(*atomic.Int64).Load()
+		return true
+	})
+	return stats
+}
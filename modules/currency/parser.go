@@ -2,15 +2,57 @@ package currency
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 
-	"github.com/expr-lang/expr"
+	"answerflow/numparse"
+	"answerflow/safeexpr"
 )
 
 type ConversionRequest struct {
 	Amount       float64
 	FromCurrency string
 	ToCurrency   string
+	Table        bool     // "usd to rub table": show conversions for tableAmounts
+	Provisional  bool     // Query is still being typed; skip expensive provider calls
+	Via          []string // User-specified intermediate hops: "100 usd to btc to rub"
+	Inverse      bool     // "how much rub for 100 usd": Amount FromCurrency is wanted, paid in ToCurrency
+	Home         string   // Requester's home currency; empty means the configured homeCurrency
+	Precision    int      // Decimal places for the result from a ".8" hint, if HasPrecision
+	HasPrecision bool
+	AmountNote   string  // How an ambiguous amount such as "1,234" was read
+	PersonalFee  float64 // User's own bank markup in percent, on top of the modeled route
+	Words        bool    // "1234.56 usd words": also write the amount out in words
+	EntryPrice   float64 // "pnl 0.5 btc @ 42000": USDT paid per unit, to value the position against
+	Side         string  // "1 btc ask": price at this side of the book ("bid", "ask" or "mid")
+	Pattern      string  // Name of the query pattern that matched, as counted in ParseStats
+	Symbol       string  // Currency symbol read off the amount, such as "$" in "$100"
+}
+
+func (r *ConversionRequest) home() string {
+	if r.Home != "" {
+		return r.Home
+	}
+	return homeCurrency
+}
+
+// precision is the requested number of decimal places for the result, or
+// -1 for the currency's default.
+func (r *ConversionRequest) precision() int {
+	if r.HasPrecision {
+		return r.Precision
+	}
+	return -1
+}
+
+// maxPrecisionHint caps ".N" hints at what a float64 amount can carry.
+const maxPrecisionHint = 12
+
+// showTradeTags reports whether results carry buy/sell tags, which describe
+// the RUB cash-out route and mean nothing for other home currencies.
+func showTradeTags(home string) bool {
+	return rubBridgeEnabled && home == CurrencyRUB
 }
 
 func preprocessAmountExpression(exprStr string) string {
@@ -23,8 +65,11 @@ func preprocessAmountExpression(exprStr string) string {
 		} else if strings.HasSuffix(numPart, "m") {
 			multiplier = "*1000000"
 			numPart = strings.TrimSuffix(numPart, "m")
+		} else if strings.HasSuffix(numPart, "b") {
+			multiplier = "*1000000000"
+			numPart = strings.TrimSuffix(numPart, "b")
 		}
-		return NormalizeNumberString(numPart) + multiplier
+		return numparse.Normalize(numPart) + multiplier
 	})
 }
 
@@ -32,12 +77,11 @@ func evaluateAmountExpression(expressionStr string) (float64, error) {
 	cleanExpr := strings.ToLower(strings.TrimSpace(expressionStr))
 
 	// Input validation: check length
-	if len(cleanExpr) > maxExpressionLength {
+	if len(cleanExpr) > safeexpr.MaxLength {
 		return 0, fmt.Errorf("expression too long")
 	}
 
-	knownSymbols := []string{"us$", "a$", "c$", "nz$", "hk$", "s$", "cn¥", "tl", "zł", "zl", "nok", "dkk", "$", "€", "₽", "¥", "£", "kr", "฿", "r", "₫", "₩"}
-	for _, sym := range knownSymbols {
+	for _, sym := range currentPatterns().symbols {
 		cleanExpr = strings.ReplaceAll(cleanExpr, strings.ToLower(sym), "")
 	}
 	cleanExpr = strings.TrimSpace(cleanExpr)
@@ -45,14 +89,12 @@ func evaluateAmountExpression(expressionStr string) (float64, error) {
 		return 0, fmt.Errorf("empty expression")
 	}
 
-	processedExpr := preprocessAmountExpression(cleanExpr)
-
-	program, err := expr.Compile(processedExpr, expr.Env(nil))
+	processedExpr, err := rewritePercentages(preprocessAmountExpression(cleanExpr))
 	if err != nil {
 		return 0, err
 	}
 
-	output, err := expr.Run(program, nil)
+	output, err := safeexpr.Eval(processedExpr, nil)
 	if err != nil {
 		return 0, err
 	}
@@ -69,27 +111,211 @@ func evaluateAmountExpression(expressionStr string) (float64, error) {
 	}
 }
 
+// ParseQuery parses a conversion query. A trailing precision hint such as
+// "100 usd to btc .8" sets the decimal places shown and copied for the result.
+// Results are cached by the whitespace-normalized query, since a launcher
+// sends the same prefixes again and again while the user types.
 func ParseQuery(query string, currencyData *CurrencyData) (*ConversionRequest, error) {
-	query = strings.TrimSpace(query)
+	query = strings.Join(strings.Fields(query), " ")
+	if req, err, ok := currencyData.parseCache.get(query); ok {
+		return req, err
+	}
+	req, err := parseQueryWithHint(query, currencyData)
+	if err == nil {
+		req.AmountNote = ambiguousAmountNote(query)
+	}
+	currencyData.parseCache.put(query, req, err)
+	return req, err
+}
+
+// ParseOptions carries a caller's defaults for ParseQueryWithOptions.
+type ParseOptions struct {
+	Home        string  // Requester's home currency; empty means the configured homeCurrency
+	PersonalFee float64 // Bank markup in percent when the query carries no "+1.5%" of its own
+	NoCache     bool    // Parse without reading or filling the parse cache
+}
+
+// ParseQueryWithOptions parses query as ParseQuery does and applies opts to
+// the result. The request returned is the caller's own: cached requests are
+// shared, so options are applied to a copy.
+func ParseQueryWithOptions(query string, currencyData *CurrencyData, opts ParseOptions) (*ConversionRequest, error) {
+	var req *ConversionRequest
+	var err error
+	if opts.NoCache {
+		query = strings.Join(strings.Fields(query), " ")
+		if req, err = parseQueryWithHint(query, currencyData); err == nil {
+			req.AmountNote = ambiguousAmountNote(query)
+		}
+	} else {
+		req, err = ParseQuery(query, currencyData)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	own := *req
+	own.Via = append([]string(nil), req.Via...)
+	own.Home = opts.Home
+	if own.PersonalFee == 0 && validPersonalFee(opts.PersonalFee) {
+		own.PersonalFee = opts.PersonalFee
+	}
+	return &own, nil
+}
+
+// ParseConversion parses query with the module's currency data, for modules
+// that build on currency conversions.
+func (m *CurrencyConverterModule) ParseConversion(query string) (*ConversionRequest, error) {
+	return ParseQuery(query, m.currencyData)
+}
+
+// ambiguousAmountNote explains how the first amount in query that means
+// different things with a decimal dot and a decimal comma was read, unless
+// NUMBER_DECIMAL_SEPARATOR settles it.
+func ambiguousAmountNote(query string) string {
+	if numparse.DefaultStyle != numparse.StyleAuto {
+		return ""
+	}
+	for _, token := range regexNumberToken.FindAllString(query, -1) {
+		if numparse.Ambiguous(token) {
+			return fmt.Sprintf("%s read as %s, not %s", token, numparse.Normalize(token), strings.NewReplacer(".", "", ",", "").Replace(token))
+		}
+	}
+	return ""
+}
+
+// parseQueryWithHint strips the trailing hints, a personal fee ("+1.5%")
+// followed by a precision (".2") and a "words" keyword, and the book side
+// ("bid", "ask", "mid") after the source currency, and parses the rest.
+func parseQueryWithHint(query string, currencyData *CurrencyData) (*ConversionRequest, error) {
+	words := false
+	if matches := regexWordsHint.FindStringSubmatch(query); matches != nil {
+		words = true
+		query = matches[1]
+	}
+
+	precision, hasPrecision := 0, false
+	if matches := regexPrecisionHint.FindStringSubmatch(query); matches != nil {
+		var err error
+		if precision, err = strconv.Atoi(matches[2]); err != nil || precision > maxPrecisionHint {
+			return nil, fmt.Errorf("invalid precision")
+		}
+		hasPrecision = true
+		query = matches[1]
+	}
+
+	var personalFee float64
+	if matches := regexPersonalFee.FindStringSubmatch(query); matches != nil {
+		var err error
+		if personalFee, err = strconv.ParseFloat(numparse.Normalize(matches[2]), 64); err != nil || !validPersonalFee(personalFee) {
+			return nil, fmt.Errorf("invalid fee")
+		}
+		query = matches[1]
+	}
+
+	side := ""
+	if matches := regexBookSide.FindStringSubmatch(query); matches != nil {
+		side = strings.ToLower(matches[2])
+		query = strings.TrimSpace(matches[1] + " " + matches[3])
+	}
+
+	req, err := parseQuery(query, currencyData)
+	if err != nil {
+		return nil, err
+	}
+	req.Side = side
+	req.Precision = precision
+	req.HasPrecision = hasPrecision
+	req.PersonalFee = personalFee
+	req.Words = words
+	return req, nil
+}
+
+func parseQuery(query string, currencyData *CurrencyData) (parsed *ConversionRequest, err error) {
 	if query == "" {
 		return nil, fmt.Errorf("empty query")
 	}
+	query = normalizeAmountOrder(normalizeAmountExpression(query), currencyData)
 
+	p := currentPatterns()
 	var req ConversionRequest
+	// Named by the branch that matched, counted once parsing is done
+	pattern := ""
+	defer func() {
+		if pattern == "" {
+			return
+		}
+		countPattern(pattern)
+		if parsed != nil {
+			parsed.Pattern = pattern
+		}
+	}()
 
-	if matches := regexAmountCurrencyToCurrency.FindStringSubmatch(query); len(matches) == 4 {
-		return parseMatch(matches, currencyData, &req, 3)
+	if matches := p.pnl.FindStringSubmatch(query); len(matches) == 4 {
+		pattern = "pnl"
+		pnl, err := parseMatch(matches[:3], currencyData, &req, 2)
+		if err != nil {
+			return nil, err
+		}
+		if pnl.EntryPrice, err = evaluateAmountExpression(matches[3]); err != nil {
+			return nil, err
+		}
+		return pnl, nil
+	}
+
+	if matches := p.table.FindStringSubmatch(query); len(matches) == 3 {
+		pattern = "table"
+		var err error
+		req.FromCurrency, err = currencyData.ResolveCurrency(strings.TrimSpace(matches[1]))
+		if err != nil {
+			return nil, err
+		}
+		req.ToCurrency, err = currencyData.ResolveCurrency(strings.TrimSpace(matches[2]))
+		if err != nil {
+			return nil, err
+		}
+		req.Amount = 1
+		req.Table = true
+		return &req, nil
 	}
 
-	if matches := regexAmountSpacedTokens.FindStringSubmatch(query); len(matches) == 4 {
+	for _, re := range []*regexp.Regexp{p.inverseQuestion, p.inverseQuestionRU} {
+		if matches := re.FindStringSubmatch(query); len(matches) == 4 {
+			pattern = "inverse"
+			inverse, err := parseMatch([]string{matches[0], matches[2], matches[3], matches[1]}, currencyData, &req, 3)
+			if err != nil {
+				return nil, err
+			}
+			inverse.Inverse = true
+			return inverse, nil
+		}
+	}
+
+	if chain, err := parseChain(query, currencyData); err == nil {
+		pattern = "chain"
+		return chain, nil
+	}
+
+	if matches := p.amountCurrencyToCurrency.FindStringSubmatch(query); len(matches) == 4 {
+		pattern = "amount_to"
 		return parseMatch(matches, currencyData, &req, 3)
 	}
 
-	if matches := regexAmountCurrencyCurrency.FindStringSubmatch(query); len(matches) == 4 {
+	if matches := p.amountSpacedTokens.FindStringSubmatch(query); len(matches) == 4 {
+		// A multi-word name ("100 hong kong dollars") splits into two
+		// tokens here; it is left to the single-currency patterns below
+		if spaced, err := parseMatch(matches, currencyData, &ConversionRequest{}, 3); err == nil {
+			pattern = "amount_spaced"
+			return spaced, nil
+		}
+	}
+
+	if matches := p.amountCurrencyCurrency.FindStringSubmatch(query); len(matches) == 4 {
+		pattern = "amount_pair"
 		return parseMatch(matches, currencyData, &req, 3)
 	}
 
-	if matches := regexQuestion.FindStringSubmatch(query); len(matches) > 0 {
+	if matches := p.question.FindStringSubmatch(query); len(matches) > 0 {
+		pattern = "question"
 		amountStr := strings.TrimSpace(matches[1])
 		fromCurrStr := strings.TrimSpace(matches[2])
 		toCurrStr := ""
@@ -97,7 +323,7 @@ func ParseQuery(query string, currencyData *CurrencyData) (*ConversionRequest, e
 			toCurrStr = strings.TrimSpace(matches[3])
 		}
 
-		fromCurrStr, amountStr = currencyData.ExtractSymbol(fromCurrStr, amountStr)
+		fromCurrStr, amountStr = extractSymbol(currencyData, &req, fromCurrStr, amountStr)
 		if toCurrStr != "" {
 			toCurrStr, _ = currencyData.ExtractSymbol(toCurrStr, "")
 		}
@@ -107,7 +333,7 @@ func ParseQuery(query string, currencyData *CurrencyData) (*ConversionRequest, e
 		if err != nil {
 			return nil, err
 		}
-		req.FromCurrency, err = currencyData.ResolveCurrency(fromCurrStr)
+		req.FromCurrency, req.Amount, err = currencyData.resolveAmountCurrency(fromCurrStr, req.Amount)
 		if err != nil {
 			return nil, err
 		}
@@ -120,7 +346,8 @@ func ParseQuery(query string, currencyData *CurrencyData) (*ConversionRequest, e
 		return &req, nil
 	}
 
-	if matches := regexFromIn.FindStringSubmatch(query); len(matches) > 0 {
+	if matches := p.fromIn.FindStringSubmatch(query); len(matches) > 0 {
+		pattern = "from_in"
 		var amountStr, currStr string
 		if matches[1] != "" && matches[2] != "" {
 			amountStr = strings.TrimSpace(matches[1])
@@ -132,41 +359,66 @@ func ParseQuery(query string, currencyData *CurrencyData) (*ConversionRequest, e
 			return nil, fmt.Errorf("malformed query")
 		}
 
-		currStr, amountStr = currencyData.ExtractSymbol(currStr, amountStr)
+		currStr, amountStr = extractSymbol(currencyData, &req, currStr, amountStr)
 
 		var err error
 		req.Amount, err = evaluateAmountExpression(amountStr)
 		if err != nil {
 			return nil, err
 		}
-		req.FromCurrency, err = currencyData.ResolveCurrency(currStr)
+		req.FromCurrency, req.Amount, err = currencyData.resolveAmountCurrency(currStr, req.Amount)
 		if err != nil {
 			return nil, err
 		}
 		return &req, nil
 	}
 
-	if matches := regexAmountCurrency.FindStringSubmatch(query); len(matches) == 3 {
+	if matches := p.amountCurrency.FindStringSubmatch(query); len(matches) == 3 {
+		pattern = "amount"
 		amountExprStr := strings.TrimSpace(matches[1])
 		fromCurrStrCandidate := strings.TrimSpace(matches[2])
 
-		resolvedCurrStr, finalAmountStr := currencyData.ExtractSymbol(fromCurrStrCandidate, amountExprStr)
+		resolvedCurrStr, finalAmountStr := extractSymbol(currencyData, &req, fromCurrStrCandidate, amountExprStr)
 
 		var err error
 		req.Amount, err = evaluateAmountExpression(finalAmountStr)
 		if err != nil {
 			return nil, err
 		}
-		req.FromCurrency, err = currencyData.ResolveCurrency(resolvedCurrStr)
+		req.FromCurrency, req.Amount, err = currencyData.resolveAmountCurrency(resolvedCurrStr, req.Amount)
 		if err != nil {
 			return nil, err
 		}
 		return &req, nil
 	}
 
+	pattern = "none"
 	return nil, fmt.Errorf("no match")
 }
 
+// extractSymbol is CurrencyData.ExtractSymbol, noting in req the symbol it
+// took off the amount.
+func extractSymbol(currencyData *CurrencyData, req *ConversionRequest, currStr, amountStr string) (string, string) {
+	code, rest := currencyData.ExtractSymbol(currStr, amountStr)
+	if amountStr = strings.TrimSpace(amountStr); rest != amountStr {
+		req.Symbol = strings.TrimSpace(strings.Replace(amountStr, rest, "", 1))
+	}
+	return code, rest
+}
+
+// normalizeAmountOrder rewrites currency-first amounts ("usd 100", "btc0.5")
+// into the amount-first form every pattern below expects. Only known
+// currency words are moved, so "log10" and "from 100" are left alone.
+func normalizeAmountOrder(query string, currencyData *CurrencyData) string {
+	return regexCurrencyBeforeAmount.ReplaceAllStringFunc(query, func(match string) string {
+		m := regexCurrencyBeforeAmount.FindStringSubmatch(match)
+		if !currencyData.isKnownCurrency(m[2]) {
+			return match
+		}
+		return m[1] + m[3] + " " + m[2] + m[4]
+	})
+}
+
 func parseMatch(matches []string, currencyData *CurrencyData, req *ConversionRequest, groups int) (*ConversionRequest, error) {
 	amountExprStr := strings.TrimSpace(matches[1])
 	fromCurrStr := strings.TrimSpace(matches[2])
@@ -175,7 +427,7 @@ func parseMatch(matches []string, currencyData *CurrencyData, req *ConversionReq
 		toCurrStr = strings.TrimSpace(matches[3])
 	}
 
-	fromCurrStr, amountExprStr = currencyData.ExtractSymbol(fromCurrStr, amountExprStr)
+	fromCurrStr, amountExprStr = extractSymbol(currencyData, req, fromCurrStr, amountExprStr)
 	if toCurrStr != "" {
 		toCurrStr, _ = currencyData.ExtractSymbol(toCurrStr, "")
 	}
@@ -185,7 +437,7 @@ func parseMatch(matches []string, currencyData *CurrencyData, req *ConversionReq
 	if err != nil {
 		return nil, err
 	}
-	req.FromCurrency, err = currencyData.ResolveCurrency(fromCurrStr)
+	req.FromCurrency, req.Amount, err = currencyData.resolveAmountCurrency(fromCurrStr, req.Amount)
 	if err != nil {
 		return nil, err
 	}
@@ -197,3 +449,49 @@ func parseMatch(matches []string, currencyData *CurrencyData, req *ConversionReq
 	}
 	return req, nil
 }
+
+// parseChain handles explicit multi-hop queries with at least two targets,
+// e.g. "100 usd to btc to rub". Every hop after the first must be a bare
+// currency token.
+func parseChain(query string, currencyData *CurrencyData) (*ConversionRequest, error) {
+	parts := regexChainSeparator.Split(query, -1)
+	if len(parts) < 3 {
+		return nil, fmt.Errorf("no match")
+	}
+
+	p := currentPatterns()
+	matches := p.amountCurrency.FindStringSubmatch(parts[0])
+	if len(matches) != 3 {
+		return nil, fmt.Errorf("no match")
+	}
+
+	var req ConversionRequest
+	fromCurrStr, amountExprStr := extractSymbol(currencyData, &req, strings.TrimSpace(matches[2]), strings.TrimSpace(matches[1]))
+
+	var err error
+	req.Amount, err = evaluateAmountExpression(amountExprStr)
+	if err != nil {
+		return nil, err
+	}
+	req.FromCurrency, req.Amount, err = currencyData.resolveAmountCurrency(fromCurrStr, req.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	hops := make([]string, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		token := strings.TrimSpace(part)
+		if !p.currencyToken.MatchString(token) {
+			return nil, fmt.Errorf("no match")
+		}
+		code, err := currencyData.ResolveCurrency(token)
+		if err != nil {
+			return nil, err
+		}
+		hops = append(hops, code)
+	}
+
+	req.Via = hops[:len(hops)-1]
+	req.ToCurrency = hops[len(hops)-1]
+	return &req, nil
+}
@@ -0,0 +1,149 @@
+package currency
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// isAmountExpressionChar reports whether c can appear inside an amount
+// expression; k/m/b suffixes are handled separately by the scanner.
+func isAmountExpressionChar(c byte) bool {
+	return (c >= '0' && c <= '9') || strings.IndexByte(" .,+-*/%()", c) >= 0
+}
+
+// scanAmountExpression scans the amount expression starting at query[start],
+// keeping parentheses balanced, and returns the index just past it. Trailing
+// spaces and dangling operators ("100 ->") are not part of the expression.
+func scanAmountExpression(query string, start int) (int, bool) {
+	depth, end, digits := 0, start, false
+	for i := start; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth == 0 {
+				return 0, false
+			}
+			depth--
+		case c >= '0' && c <= '9':
+			digits = true
+		case c == 'k' || c == 'm' || c == 'b' || c == 'K' || c == 'M' || c == 'B':
+			// Suffix only directly after a digit and not the start of a word
+			if i == start || query[i-1] < '0' || query[i-1] > '9' ||
+				(i+1 < len(query) && isAlpha(query[i+1:i+2])) {
+				return finishAmountScan(query, start, end, depth, digits)
+			}
+		case !isAmountExpressionChar(c):
+			return finishAmountScan(query, start, end, depth, digits)
+		}
+		end = i + 1
+	}
+	return finishAmountScan(query, start, end, depth, digits)
+}
+
+func finishAmountScan(query string, start, end, depth int, digits bool) (int, bool) {
+	if depth != 0 || !digits {
+		return 0, false
+	}
+	for end > start && strings.IndexByte(" +-*/", query[end-1]) >= 0 {
+		end--
+	}
+	return end, end > start
+}
+
+// normalizeAmountExpression evaluates the first amount expression in query
+// that the amount regexes cannot express (parentheses, + and -, percentages)
+// and substitutes its value, so "(150+30) usd to eur" parses as
+// "180 usd to eur" in every pattern.
+func normalizeAmountExpression(query string) string {
+	for start := 0; start < len(query); start++ {
+		c := query[start]
+		if c != '(' && (c < '0' || c > '9') {
+			continue
+		}
+		// Digits inside a word ("usd100", "log10") are not an amount
+		if start > 0 && (isAlpha(query[start-1:start]) || query[start-1] == '.' || query[start-1] == ',') {
+			return query
+		}
+		end, ok := scanAmountExpression(query, start)
+		if !ok {
+			return query
+		}
+		expression := query[start:end]
+		if !strings.ContainsAny(expression, "()+-%") {
+			return query
+		}
+		value, err := evaluateAmountExpression(expression)
+		if err != nil || value <= 0 {
+			return query
+		}
+		return query[:start] + strconv.FormatFloat(value, 'f', -1, 64) + query[end:]
+	}
+	return query
+}
+
+// rewritePercentages turns "a - b%" into "(a)*(1-b/100)" and "a + b%" into
+// "(a)*(1+b/100)", so percentages apply to the left operand like on a
+// calculator; any other "%" means /100. Parenthesised groups are handled
+// recursively and count as single operands.
+func rewritePercentages(s string) (string, error) {
+	var terms, ops []string
+	var term strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '(':
+			depth, j := 1, i+1
+			for ; j < len(s) && depth > 0; j++ {
+				switch s[j] {
+				case '(':
+					depth++
+				case ')':
+					depth--
+				}
+			}
+			if depth != 0 {
+				return "", fmt.Errorf("unbalanced parentheses")
+			}
+			inner, err := rewritePercentages(s[i+1 : j-1])
+			if err != nil {
+				return "", err
+			}
+			term.WriteString("(" + inner + ")")
+			i = j - 1
+		case c == ')':
+			return "", fmt.Errorf("unbalanced parentheses")
+		case (c == '+' || c == '-') && endsWithOperand(term.String()):
+			terms = append(terms, term.String())
+			ops = append(ops, string(c))
+			term.Reset()
+		default:
+			term.WriteByte(c)
+		}
+	}
+	terms = append(terms, term.String())
+
+	acc := terms[0]
+	for i, op := range ops {
+		operand := strings.TrimSpace(terms[i+1])
+		if number := strings.TrimSuffix(operand, "%"); number != operand {
+			if _, err := strconv.ParseFloat(number, 64); err == nil {
+				acc = "(" + acc + ")*(1" + op + number + "/100)"
+				continue
+			}
+		}
+		acc += op + terms[i+1]
+	}
+	return strings.ReplaceAll(acc, "%", "/100"), nil
+}
+
+// endsWithOperand reports whether a +/- following s is binary.
+func endsWithOperand(s string) bool {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return false
+	}
+	return strings.IndexByte("+-*/(", s[len(s)-1]) < 0
+}
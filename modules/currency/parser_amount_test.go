@@ -0,0 +1,71 @@
+package currency
+
+import "testing"
+
+func TestParseQueryAmountExpressions(t *testing.T) {
+	cd := NewCurrencyData()
+	tests := []struct {
+		query    string
+		amount   float64
+		from, to string
+	}{
+		{"2*3.5k usd", 7000, "USD", ""},
+		{"1.5k usd to eur", 1500, "USD", "EUR"},
+		{"(100-15%) eur in rub", 85, "EUR", "RUB"},
+		{"(150+30) usd to eur", 180, "USD", "EUR"},
+		{"(2+3)*10 usd", 50, "USD", ""},
+		{"100 - 10% usd", 90, "USD", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			req, err := ParseQuery(tt.query, cd)
+			if err != nil {
+				t.Fatalf("ParseQuery(%q): %v", tt.query, err)
+			}
+			if req.Amount != tt.amount || req.FromCurrency != tt.from || req.ToCurrency != tt.to {
+				t.Errorf("ParseQuery(%q) = %v %s -> %q, want %v %s -> %q", tt.query, req.Amount, req.FromCurrency, req.ToCurrency, tt.amount, tt.from, tt.to)
+			}
+		})
+	}
+}
+
+func TestScanAmountExpression(t *testing.T) {
+	tests := []struct {
+		query string
+		start int
+		end   int
+		ok    bool
+	}{
+		{"(150+30) usd", 0, 8, true},
+		{"100 -> eur", 0, 3, true},
+		{"2*3.5k usd", 0, 6, true},
+		{"100 kzt", 0, 3, true},
+		{"((1+2) usd", 0, 0, false},
+		{"1+2) usd", 0, 0, false},
+		{"() usd", 0, 0, false},
+		{"eur (10+5)", 4, 10, true},
+	}
+	for _, tt := range tests {
+		end, ok := scanAmountExpression(tt.query, tt.start)
+		if ok != tt.ok || (ok && end != tt.end) {
+			t.Errorf("scanAmountExpression(%q, %d) = %d, %v, want %d, %v", tt.query, tt.start, end, ok, tt.end, tt.ok)
+		}
+	}
+}
+
+func TestRewritePercentages(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"100-15%", "(100)*(1-15/100)"},
+		{"100+10%", "(100)*(1+10/100)"},
+		{"50%", "50/100"},
+		{"100", "100"},
+	}
+	for _, tt := range tests {
+		got, err := rewritePercentages(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("rewritePercentages(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
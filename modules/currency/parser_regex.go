@@ -1,34 +1,196 @@
 package currency
 
-import "regexp"
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// AmountSymbols returns the currency symbols the query patterns accept glued
+// to an amount ("US$100", "100zł"), longest first: every symbol of the loaded
+// symbol table, config/currency_symbols.json plus alias packs. They are
+// stripped in this order before an amount is evaluated, so "US$" goes before
+// "$".
+func AmountSymbols() []string {
+	return append([]string(nil), currentPatterns().symbols...)
+}
 
 var (
-	amountRegexPart          = `[0-9]+(?:[0-9\s ,.]*[0-9])?(?:[km]\b)?`
-	amountExpressionPart     = amountRegexPart + `(?:\s*[*\/]\s*` + amountRegexPart + `)*`
-	symbolPrefixPart         = `(?:[$€₽¥£]|US\$|A\$|C\$|NZ\$|HK\$|S\$|CN¥|TL|zł|zl|kr|NOK|DKK|฿|R|₫|₩)?`
-	fullAmountExpressionPart = symbolPrefixPart + `\s*` + amountExpressionPart
-	currencyTokenRegexPart   = `(?:[a-zA-Z]{1,10}|[$€₽¥£]|US\$|A\$|C\$|NZ\$|HK\$|S\$|CN¥|TL|zł|zl|kr|NOK|DKK|฿|R|₫|₩)`
-	currencyCodeStrictPart   = `[a-zA-Z]{3,10}`
+	amountRegexPart      = `[0-9]+(?:[0-9\s ,.]*[0-9])?(?:[kmb]\b)?`
+	amountExpressionPart = amountRegexPart + `(?:\s*[*\/]\s*` + amountRegexPart + `)*`
+	// A currency name of up to three words: "usd", "рублей", "us dollars".
+	// Extra words are taken lazily, so "usd in eur" stays two tokens.
+	currencyNamePart       = `\p{L}{1,20}(?:[ \t]\p{L}{1,20}){0,2}?`
+	currencyCodeStrictPart = `[a-zA-Z]{3,10}`
 )
 
+// queryPatterns are the query regexps built around the amount symbols.
+type queryPatterns struct {
+	symbols []string // longest first, the order they are stripped in
+
+	amountCurrencyToCurrency *regexp.Regexp
+	amountSpacedTokens       *regexp.Regexp
+	amountCurrencyCurrency   *regexp.Regexp
+	amountCurrency           *regexp.Regexp
+	question                 *regexp.Regexp
+	fromIn                   *regexp.Regexp
+	inverseQuestion          *regexp.Regexp
+	inverseQuestionRU        *regexp.Regexp
+	pnl                      *regexp.Regexp
+	table                    *regexp.Regexp
+	currencyToken            *regexp.Regexp
+}
+
+// patterns holds the queryPatterns in use. It starts out built from the
+// embedded symbol table and is rebuilt whenever the loaded table changes.
+var patterns atomic.Pointer[queryPatterns]
+
+func init() {
+	table, _ := loadConfigMap(embeddedSymbolsJSON, "symbols")
+	patterns.Store(newQueryPatterns(symbolKeys(table)))
+}
+
+// currentPatterns returns the query patterns for the loaded symbol table.
+func currentPatterns() *queryPatterns {
+	return patterns.Load()
+}
+
+// newQueryPatterns compiles the query patterns for symbols.
+func newQueryPatterns(symbols []string) *queryPatterns {
+	symbols = append([]string(nil), symbols...)
+	sort.SliceStable(symbols, func(i, j int) bool { return len(symbols[i]) > len(symbols[j]) })
+
+	amountSymbolPart := symbolAlternation(symbols)
+	fullAmountExpressionPart := `(?:` + amountSymbolPart + `)?\s*` + amountExpressionPart
+	currencyTokenRegexPart := `(?:` + currencyNamePart + `|` + amountSymbolPart + `)`
+	// A single word or symbol; also matches Cyrillic names: "рублей"
+	currencyWordPart := `(?:\p{L}{1,20}|` + amountSymbolPart + `)`
+
+	return &queryPatterns{
+		symbols: symbols,
+
+		amountCurrencyToCurrency: regexp.MustCompile(
+			`(?i)^\s*(` + fullAmountExpressionPart + `)\s*(` + currencyTokenRegexPart + `)\s*(?:to\b|in\b|=|-?>|→|2)\s*(` + currencyTokenRegexPart + `)\s*$`),
+
+		amountSpacedTokens: regexp.MustCompile(
+			`(?i)^\s*(` + fullAmountExpressionPart + `)\s+(` + currencyTokenRegexPart + `)\s+(` + currencyTokenRegexPart + `)\s*$`),
+
+		amountCurrencyCurrency: regexp.MustCompile(
+			`(?i)^\s*(` + fullAmountExpressionPart + `)\s*(` + currencyCodeStrictPart + `)\s*(` + currencyCodeStrictPart + `)\s*$`),
+
+		amountCurrency: regexp.MustCompile(
+			`(?i)^\s*(` + fullAmountExpressionPart + `)\s*(` + currencyTokenRegexPart + `)\s*$`),
+
+		question: regexp.MustCompile(
+			`(?i)^\s*(?:how\s+much\s+is|what\s*'?s|what\s+is)\s+(` + fullAmountExpressionPart + `)\s*(` + currencyTokenRegexPart + `)(?:\s+(?:in\b|to\b)\s+(` + currencyTokenRegexPart + `))?\??\s*$`),
+
+		fromIn: regexp.MustCompile(
+			`(?i)^\s*(?:from|in)\s+(?:(` + fullAmountExpressionPart + `)\s*(` + currencyTokenRegexPart + `)|(` + currencyTokenRegexPart + `)\s*(` + fullAmountExpressionPart + `))\s*$`),
+
+		// Explicit inverse questions: "how much rub for 100 usd",
+		// "сколько рублей нужно на 100 евро". Group 1 is the currency paid with,
+		// groups 2-3 the amount and currency wanted.
+		inverseQuestion: regexp.MustCompile(
+			`(?i)^\s*(?:how\s+(?:much|many)|what)\s+(` + currencyWordPart + `)\s+(?:do\s+i\s+need\s+|is\s+needed\s+)?(?:for|to\s+(?:buy|get))\s+(` +
+				fullAmountExpressionPart + `)\s*(` + currencyWordPart + `)\s*\??\s*$`),
+		inverseQuestionRU: regexp.MustCompile(
+			`(?i)^\s*сколько\s+(` + currencyWordPart + `)\s+(?:нужно\s+|надо\s+)?(?:на|за|для|чтобы\s+купить)\s+(` +
+				fullAmountExpressionPart + `)\s*(` + currencyWordPart + `)\s*\??\s*$`),
+
+		// Position profit/loss against an entry price in USDT: "pnl 0.5 btc @ 42000 now"
+		pnl: regexp.MustCompile(
+			`(?i)^\s*pnl\s+(` + fullAmountExpressionPart + `)\s*(` + currencyTokenRegexPart + `)\s*@\s*(` + amountExpressionPart + `)(?:\s+now)?\s*$`),
+
+		table: regexp.MustCompile(
+			`(?i)^\s*(` + currencyTokenRegexPart + `)(?:\s*(?:to\b|in\b|=|-?>|→|2)\s*|\s+)(` + currencyTokenRegexPart + `)\s+table\s*$`),
+
+		currencyToken: regexp.MustCompile(`(?i)^` + currencyTokenRegexPart + `$`),
+	}
+}
+
+// symbolKeys returns the symbols of a symbol table, sorted.
+func symbolKeys(table map[string]string) []string {
+	symbols := make([]string, 0, len(table))
+	for sym := range table {
+		if sym != "" {
+			symbols = append(symbols, sym)
+		}
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// rebuildPatternsLocked rebuilds the query patterns after the symbol table
+// changed. cd.mu must be held.
+func (cd *CurrencyData) rebuildPatternsLocked() {
+	patterns.Store(newQueryPatterns(symbolKeys(cd.symbols)))
+}
+
 var (
-	regexAmountCurrencyToCurrency = regexp.MustCompile(
-		`(?i)^\s*(` + fullAmountExpressionPart + `)\s*(` + currencyTokenRegexPart + `)\s*(?:to\b|in\b|=|-?>|→|2)\s*(` + currencyTokenRegexPart + `)\s*$`)
+	// Currency written before its amount: "usd 100", "btc0.5", "from usd 100"
+	regexCurrencyBeforeAmount = regexp.MustCompile(
+		`(?i)(^|\s)(\p{L}{2,20})\s*(` + amountExpressionPart + `)(\s|$)`)
+
+	// Word or symbol tokens of a query, for ambiguity lookups
+	regexQueryToken = regexp.MustCompile(`[\p{L}\p{Sc}]+`)
+
+	// Splits "100 usd to btc -> rub" into its hops
+	regexChainSeparator = regexp.MustCompile(`(?i)\s+(?:to|in)\s+|\s*(?:=|-?>|→)\s*`)
 
-	regexAmountSpacedTokens = regexp.MustCompile(
-		`(?i)^\s*(` + fullAmountExpressionPart + `)\s+(` + currencyTokenRegexPart + `)\s+(` + currencyTokenRegexPart + `)\s*$`)
+	// Trailing precision hint after a currency or fee: "100 usd to btc .8"
+	regexPrecisionHint = regexp.MustCompile(`^(.*[\p{L}\p{Sc}%])\s+\.([0-9]{1,2})\s*$`)
 
-	regexAmountCurrencyCurrency = regexp.MustCompile(
-		`(?i)^\s*(` + fullAmountExpressionPart + `)\s*(` + currencyCodeStrictPart + `)\s*(` + currencyCodeStrictPart + `)\s*$`)
+	// Trailing request for the amount in words: "1234.56 usd words"
+	regexWordsHint = regexp.MustCompile(`(?i)^(.*\S)\s+(?:words|прописью)$`)
 
-	regexAmountCurrency = regexp.MustCompile(
-		`(?i)^\s*(` + fullAmountExpressionPart + `)\s*(` + currencyTokenRegexPart + `)\s*$`)
+	// Book side after a currency, before or after the target: "1 btc ask",
+	// "1 btc bid in usd", "1 btc to eur mid"
+	regexBookSide = regexp.MustCompile(`(?i)^(.*[\p{L}\p{Sc}])\s+(bid|ask|mid)(?:\s+(.*\S))?\s*$`)
 
-	regexQuestion = regexp.MustCompile(
-		`(?i)^\s*(?:how\s+much\s+is|what\s*'?s|what\s+is)\s+(` + fullAmountExpressionPart + `)\s*(` + currencyTokenRegexPart + `)(?:\s+(?:in\b|to\b)\s+(` + currencyTokenRegexPart + `))?\??\s*$`)
+	// Trailing personal fee after a currency: "100 usd to eur +1.5%"
+	regexPersonalFee = regexp.MustCompile(`^(.*[\p{L}\p{Sc}])\s*\+\s*([0-9]+(?:[.,][0-9]+)?)\s*%$`)
 
-	regexFromIn = regexp.MustCompile(
-		`(?i)^\s*(?:from|in)\s+(?:(` + fullAmountExpressionPart + `)\s*(` + currencyTokenRegexPart + `)|(` + currencyTokenRegexPart + `)\s*(` + fullAmountExpressionPart + `))\s*$`)
+	// Digit runs with their separators, for ambiguity checks
+	regexNumberToken = regexp.MustCompile(`\d[\d.,]*\d`)
 
-	numberWithSuffixRegex = regexp.MustCompile(`[0-9]+(?:[0-9\s ,.]*[0-9])?(?:[km]\b)?`)
+	numberWithSuffixRegex = regexp.MustCompile(`[0-9]+(?:[0-9\s ,.]*[0-9])?(?:[kmb]\b)?`)
 )
+
+// Pattern is one query shape the parser recognizes. Name is the label the
+// shape is counted under in the parse metrics.
+type Pattern struct {
+	Name   string
+	Regexp *regexp.Regexp
+}
+
+// Patterns lists the query shapes ParseQuery tries, in order, so frontends
+// can screen input exactly as the parser does. A "chain" query is split on
+// its Regexp and each hop is matched separately. The patterns follow the
+// loaded symbol table, so call Patterns again after alias packs reload.
+func Patterns() []Pattern {
+	p := currentPatterns()
+	return []Pattern{
+		{"pnl", p.pnl},
+		{"table", p.table},
+		{"inverse", p.inverseQuestion},
+		{"inverse", p.inverseQuestionRU},
+		{"chain", regexChainSeparator},
+		{"amount_to", p.amountCurrencyToCurrency},
+		{"amount_spaced", p.amountSpacedTokens},
+		{"amount_pair", p.amountCurrencyCurrency},
+		{"question", p.question},
+		{"from_in", p.fromIn},
+		{"amount", p.amountCurrency},
+	}
+}
+
+// symbolAlternation joins symbols into a regexp alternation, keeping their
+// order.
+func symbolAlternation(symbols []string) string {
+	quoted := make([]string, len(symbols))
+	for i, sym := range symbols {
+		quoted[i] = regexp.QuoteMeta(sym)
+	}
+	return strings.Join(quoted, "|")
+}
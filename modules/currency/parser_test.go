@@ -0,0 +1,64 @@
+package currency
+
+import "testing"
+
+func TestParseQueryAmountOrder(t *testing.T) {
+	cd := NewCurrencyData()
+	tests := []struct {
+		query    string
+		amount   float64
+		from, to string
+	}{
+		{"100 usd", 100, "USD", ""},
+		{"usd 100", 100, "USD", ""},
+		{"btc0.5", 0.5, "BTC", ""},
+		{"100usd to eur", 100, "USD", "EUR"},
+		{"usd 100 to eur", 100, "USD", "EUR"},
+		{"usd100 in rub", 100, "USD", "RUB"},
+		{"$100 to eur", 100, "USD", "EUR"},
+		{"€50 to usd", 50, "EUR", "USD"},
+		{"50€ to usd", 50, "EUR", "USD"},
+		{"from usd 100", 100, "USD", ""},
+		{"what is eur 20 in usd", 20, "EUR", "USD"},
+		{"pnl btc 0.5 @ 42000", 0.5, "BTC", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			req, err := ParseQuery(tt.query, cd)
+			if err != nil {
+				t.Fatalf("ParseQuery(%q): %v", tt.query, err)
+			}
+			if req.Amount != tt.amount || req.FromCurrency != tt.from || req.ToCurrency != tt.to {
+				t.Errorf("ParseQuery(%q) = %v %s -> %q, want %v %s -> %q", tt.query, req.Amount, req.FromCurrency, req.ToCurrency, tt.amount, tt.from, tt.to)
+			}
+		})
+	}
+}
+
+func TestParseQueryLeavesNonAmountsAlone(t *testing.T) {
+	cd := NewCurrencyData()
+	for _, query := range []string{"log10", "from 100", "hello world", "100 r"} {
+		if req, err := ParseQuery(query, cd); err == nil {
+			t.Errorf("ParseQuery(%q) = %+v, want an error", query, req)
+		}
+	}
+}
+
+func TestNormalizeAmountOrder(t *testing.T) {
+	cd := NewCurrencyData()
+	tests := []struct {
+		in, want string
+	}{
+		{"usd 100", "100 usd"},
+		{"btc0.5", "0.5 btc"},
+		{"usd 100 to eur", "100 usd to eur"},
+		{"from usd 100", "from 100 usd"},
+		{"100 usd", "100 usd"},
+		{"log10", "log10"},
+	}
+	for _, tt := range tests {
+		if got := normalizeAmountOrder(tt.in, cd); got != tt.want {
+			t.Errorf("normalizeAmountOrder(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package currency
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"answerflow/commontypes"
+)
+
+var (
+	// Trailing arithmetic operator or an opening bracket: "100 +", "(100 *".
+	regexTrailingOperator = regexp.MustCompile(`[+\-*/^(]\s*$`)
+
+	// Trailing conversion connector with nothing after it: "100 usd to".
+	regexTrailingConnector = regexp.MustCompile(`(?i)(?:\s+(?:to|in)|\s*(?:=|-?>|→))\s*$`)
+
+	// Trailing lowercase letter is almost always the start of a currency code
+	// ("100 u"); single-letter tickers and symbols are typed in upper case.
+	regexTrailingLetter = regexp.MustCompile(`\s+[a-z]\s*$`)
+
+	// Nothing but an amount expression: "100", "1.5k".
+	regexBareAmount = regexp.MustCompile(`(?i)^\s*` + amountExpressionPart + `\s*$`)
+
+	// An amount and a target with no source: "100 to usd", "100 in eur".
+	regexMissingSource = regexp.MustCompile(`(?i)^\s*(` + amountExpressionPart + `)\s+(?:to|in)\s+\S`)
+)
+
+// detectPartialQuery reports whether query looks like it is still being typed.
+// If the query has a complete prefix worth showing provisional results for,
+// that prefix is returned as stem; otherwise stem is empty.
+func detectPartialQuery(query string) (stem string, partial bool) {
+	trimmed := strings.TrimSpace(query)
+	if trimmed == "" {
+		return "", false
+	}
+
+	if regexBareAmount.MatchString(trimmed) {
+		return "", true
+	}
+
+	stem = trimmed
+	if loc := regexTrailingLetter.FindStringIndex(stem); loc != nil {
+		stem = strings.TrimSpace(stem[:loc[0]])
+		partial = true
+	}
+	if loc := regexTrailingConnector.FindStringIndex(stem); loc != nil {
+		stem = strings.TrimSpace(stem[:loc[0]])
+		partial = true
+	} else if loc := regexTrailingOperator.FindStringIndex(stem); loc != nil {
+		stem = strings.TrimSpace(stem[:loc[0]])
+		partial = true
+	}
+
+	if !partial {
+		return "", false
+	}
+	return stem, true
+}
+
+// withDefaultSource reads an amount with a target but no source currency
+// ("100 to usd") as an amount of defaultCurrency. Bare numbers have no
+// currency token and are left to the calculator.
+func withDefaultSource(query string) string {
+	if defaultCurrency == "" {
+		return query
+	}
+	loc := regexMissingSource.FindStringSubmatchIndex(query)
+	if loc == nil {
+		return query
+	}
+	return query[:loc[3]] + " " + defaultCurrency + query[loc[3]:]
+}
+
+// routeUsesWhitebird reports whether converting from -> to needs a live
+// Whitebird quote, which is the slowest provider call on the hot path.
+func (m *CurrencyConverterModule) routeUsesWhitebird(from, to string, apiCache *APICache) bool {
+	for _, leg := range m.planRoute(from, to, apiCache) {
+		if leg == CurrencyRUB && rubBridgeEnabled {
+			return true
+		}
+	}
+	return false
+}
+
+// generateProvisionalResults answers a partially typed query from its stem
+// using only cheap routes: no inverse searches and no Whitebird quotes.
+func (m *CurrencyConverterModule) generateProvisionalResults(ctx context.Context, stem string, apiCache *APICache) []commontypes.FlowResult {
+	if stem == "" {
+		return nil
+	}
+
+	req, err := ParseQuery(stem, m.currencyData)
+	if err != nil || req.Table || ValidateAmount(req.Amount) != nil {
+		return nil
+	}
+	req.Provisional = true
+
+	if req.ToCurrency == "" {
+		return m.generateQuickConversions(ctx, req, apiCache)
+	}
+
+	if req.FromCurrency == req.ToCurrency || m.routeUsesWhitebird(req.FromCurrency, req.ToCurrency, apiCache) {
+		return nil
+	}
+	res, _, err := m.generateConversionResult(ctx, req, req.ToCurrency, apiCache, scoreSpecificConversion)
+	if err != nil || res == nil {
+		return nil
+	}
+	return []commontypes.FlowResult{*res}
+}
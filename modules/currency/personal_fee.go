@@ -0,0 +1,36 @@
+package currency
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// The personal fee is the user's own bank or card markup, which no provider
+// models: "100 usd to eur +1.5%", or ?fee=1.5 on the query endpoint. It is
+// deducted from what the route delivers, so the result is what actually
+// lands in the user's account.
+
+// maxPersonalFee rejects fees that can only be typos.
+const maxPersonalFee = 50
+
+func validPersonalFee(percent float64) bool {
+	return isValidFloat(percent) && percent >= 0 && percent <= maxPersonalFee
+}
+
+// afterPersonalFee is what arrives of amount once the personal fee is paid.
+func (r *ConversionRequest) afterPersonalFee(amount float64) float64 {
+	return amount * (1 - r.PersonalFee/100)
+}
+
+// beforePersonalFee is what the route must deliver for amount to arrive.
+func (r *ConversionRequest) beforePersonalFee(amount float64) float64 {
+	return amount / (1 - r.PersonalFee/100)
+}
+
+// personalFeeInfo is the subtitle note for a personal fee, if any.
+func (r *ConversionRequest) personalFeeInfo() string {
+	if r.PersonalFee == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" | +%s%% own fee", strconv.FormatFloat(r.PersonalFee, 'f', -1, 64))
+}
@@ -0,0 +1,61 @@
+package currency
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"answerflow/commontypes"
+)
+
+// generatePnLResult values a crypto position bought at req.EntryPrice USDT
+// per unit at the current Bybit best bid, what selling it would fetch, and
+// shows the unrealized profit or loss in USDT and the home currency.
+func (m *CurrencyConverterModule) generatePnLResult(ctx context.Context, req *ConversionRequest, apiCache *APICache) []commontypes.FlowResult {
+	fail := func(err error) []commontypes.FlowResult {
+		return m.requestedPairFailed(ctx, req, m.makeErrorResult(req, CurrencyUSDT, err), apiCache)
+	}
+	// Positions are priced in USDT, so USDT itself has nothing to gain or lose
+	if req.FromCurrency == CurrencyUSDT {
+		return fail(fmt.Errorf("%s positions are priced in %s already", CurrencyUSDT, CurrencyUSDT))
+	}
+	symbol := req.FromCurrency + CurrencyUSDT
+	if err := apiCache.EnsureBybitSymbol(ctx, symbol); err != nil {
+		return fail(err)
+	}
+	rate, err := apiCache.GetBybitRate(symbol)
+	if err != nil {
+		return fail(err)
+	}
+
+	cost := req.Amount * req.EntryPrice
+	pnl := req.Amount*rate.BestBid - cost
+	res := formatPnLResult(req, rate.BestBid, pnl, pnl/cost*100)
+
+	if home := req.home(); home != CurrencyUSDT && home != CurrencyUSD {
+		if homeAmount, err := m.convert(ctx, math.Abs(pnl), CurrencyUSDT, home, apiCache); err == nil {
+			res.SubTitle += fmt.Sprintf(" | %s%s %s", pnlSign(pnl), formatAmount(homeAmount, home), home)
+		}
+	}
+	return []commontypes.FlowResult{res}
+}
+
+func formatPnLResult(req *ConversionRequest, price, pnl, percent float64) commontypes.FlowResult {
+	amount := pnlSign(pnl) + formatAmountAt(math.Abs(pnl), CurrencyUSDT, req.precision())
+	return commontypes.FlowResult{
+		Title: fmt.Sprintf("%s %s (%s%.2f%%)", amount, CurrencyUSDT, pnlSign(pnl), math.Abs(percent)),
+		SubTitle: fmt.Sprintf("%s %s @ %s → %s bid",
+			formatAmount(req.Amount, req.FromCurrency), req.FromCurrency, formatRate(req.EntryPrice), formatRate(price)),
+		IcoPath:       assetIcon(req.FromCurrency, CurrencyUSDT),
+		Score:         scoreSpecificConversion,
+		JsonRPCAction: commontypes.CopyToClipboard(strings.ReplaceAll(amount, ",", "")),
+	}
+}
+
+func pnlSign(pnl float64) string {
+	if pnl < 0 {
+		return "-"
+	}
+	return "+"
+}
@@ -0,0 +1,141 @@
+package currency
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+)
+
+// headerPolicy is what a provider request looks like on the wire: a pool of
+// User-Agent strings, one picked per request, and fixed extra headers.
+type headerPolicy struct {
+	UserAgents []string          `json:"user_agents,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+}
+
+// browserUserAgents impersonate current desktop browsers for providers that
+// serve their public web widgets only to browsers.
+var browserUserAgents = []string{
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:133.0) Gecko/20100101 Firefox/133.0",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.1 Safari/605.1.15",
+	"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/130.0.0.0 Safari/537.36 Edg/130.0.0.0",
+}
+
+// defaultHeaderPolicies are the built-in policies. Providers not listed send
+// Go's default User-Agent and no extra headers.
+var defaultHeaderPolicies = map[string]headerPolicy{
+	providerMastercard: {
+		UserAgents: browserUserAgents,
+		Headers: map[string]string{
+			"Accept":          "application/json, text/plain, */*",
+			"Accept-Language": "en-US,en;q=0.9",
+			"Accept-Encoding": "gzip, deflate, br",
+			"Referer":         "https://www.mastercard.com/global/en/personal/get-support/currency-exchange-rate-converter.html",
+			"Origin":          "https://www.mastercard.com",
+			"Connection":      "keep-alive",
+			"Sec-Fetch-Dest":  "empty",
+			"Sec-Fetch-Mode":  "cors",
+			"Sec-Fetch-Site":  "same-origin",
+			"DNT":             "1",
+			"Cache-Control":   "no-cache",
+			"Pragma":          "no-cache",
+		},
+	},
+	providerVisa: {
+		UserAgents: browserUserAgents,
+		Headers:    map[string]string{"Accept": "application/json, text/plain, */*"},
+	},
+	providerWhitebird: {
+		Headers: map[string]string{"Origin": "https://whitebird.io"},
+	},
+	providerCoinGecko: {
+		Headers: map[string]string{"Accept": "application/json"},
+	},
+}
+
+// PROVIDER_HEADERS_FILE adjusts the policies without a new binary when a
+// provider changes its bot heuristics. It maps provider names, or "*" for
+// all providers, to a policy:
+//
+//	{"*": {"headers": {"X-Contact": "ops@example.com"}},
+//	 "mastercard": {"user_agents": ["Mozilla/5.0 ..."], "headers": {"DNT": ""}}}
+//
+// A user_agents list replaces the built-in pool; headers are merged over the
+// built-in ones, and an empty value removes a header.
+var providerHeaders = loadProviderHeaders(getEnvOrDefault("PROVIDER_HEADERS_FILE", ""))
+
+func loadProviderHeaders(path string) map[string]headerPolicy {
+	policies := make(map[string]headerPolicy, len(defaultHeaderPolicies))
+	for name, p := range defaultHeaderPolicies {
+		policies[name] = p
+	}
+	if path == "" {
+		return policies
+	}
+
+	overrides, err := readHeaderPolicies(path)
+	if err != nil {
+		log.Printf("Warning: provider headers not loaded, using built-in ones: %v", err)
+		return policies
+	}
+	all := overrides["*"]
+	for name := range providerCriticality {
+		policies[name] = mergeHeaderPolicy(mergeHeaderPolicy(policies[name], all), overrides[name])
+	}
+	log.Printf("Loaded provider header policies from %s", path)
+	return policies
+}
+
+func readHeaderPolicies(path string) (map[string]headerPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var overrides map[string]headerPolicy
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for name := range overrides {
+		if _, ok := providerCriticality[name]; !ok && name != "*" {
+			return nil, fmt.Errorf("%s: unknown provider %q", path, name)
+		}
+	}
+	return overrides, nil
+}
+
+func mergeHeaderPolicy(base, override headerPolicy) headerPolicy {
+	merged := headerPolicy{UserAgents: base.UserAgents, Headers: make(map[string]string)}
+	if len(override.UserAgents) > 0 {
+		merged.UserAgents = override.UserAgents
+	}
+	for k, v := range base.Headers {
+		merged.Headers[k] = v
+	}
+	for k, v := range override.Headers {
+		if v == "" {
+			delete(merged.Headers, k)
+		} else {
+			merged.Headers[k] = v
+		}
+	}
+	return merged
+}
+
+// setProviderHeaders applies provider's header policy to req. Headers a
+// request needs to work (content type, signatures, API keys) are set by the
+// caller afterwards and are not configurable.
+func setProviderHeaders(req *http.Request, provider string) {
+	policy := providerHeaders[provider]
+	if len(policy.UserAgents) > 0 {
+		req.Header.Set("User-Agent", policy.UserAgents[rand.Intn(len(policy.UserAgents))])
+	}
+	for k, v := range policy.Headers {
+		req.Header.Set(k, v)
+	}
+}
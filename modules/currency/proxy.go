@@ -0,0 +1,46 @@
+package currency
+
+import (
+	"log"
+	"net/url"
+	"strings"
+)
+
+// Outbound proxies for providers that are blocked where the service runs.
+// PROVIDER_PROXY applies to every provider; <PROVIDER>_PROXY (e.g.
+// MASTERCARD_PROXY) overrides it for one, and "direct" sends that provider
+// around the proxy. Values are http://, https://, socks5:// or socks5h://
+// URLs, with credentials as user:password@ if the proxy needs them. Like
+// API keys they may also come from a file named by <KEY>_FILE.
+var providerProxies = loadProviderProxies()
+
+const proxyDirect = "direct"
+
+func loadProviderProxies() map[string]*url.URL {
+	fallback := string(loadSecret("PROVIDER_PROXY"))
+	proxies := make(map[string]*url.URL)
+	for name := range providerCriticality {
+		key := strings.ToUpper(name) + "_PROXY"
+		value := string(loadSecret(key))
+		if value == "" {
+			value, key = fallback, "PROVIDER_PROXY"
+		}
+		if value == "" || strings.EqualFold(value, proxyDirect) {
+			continue
+		}
+		u, err := url.Parse(value)
+		if err != nil || u.Host == "" {
+			log.Printf("Warning: invalid %s, %s goes direct", key, name)
+			continue
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			log.Printf("Warning: unsupported %s scheme %q, %s goes direct", key, u.Scheme, name)
+			continue
+		}
+		proxies[name] = u
+		log.Printf("Provider %s uses proxy %s", name, u.Redacted())
+	}
+	return proxies
+}
@@ -0,0 +1,110 @@
+package currency
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+)
+
+// QuarantinedRate is a fetched rate that moved too far from the previous value
+// too quickly to be trusted. It is held back from conversions until it is
+// approved, discarded, or the previous value ages out of the anomaly window.
+type QuarantinedRate struct {
+	ID         string    `json:"id"`
+	Provider   string    `json:"provider"`
+	Key        string    `json:"key"`
+	Previous   float64   `json:"previous"`
+	Proposed   float64   `json:"proposed"`
+	Deviation  float64   `json:"deviation"`
+	DetectedAt time.Time `json:"detected_at"`
+
+	bybitRate      *BybitRate
+	mastercardRate float64
+}
+
+func quarantineID(provider, key string) string {
+	return provider + ":" + key
+}
+
+func bybitMidPrice(rate *BybitRate) float64 {
+	return (rate.BestBid + rate.BestAsk) / 2
+}
+
+// screenRateLocked reports whether proposed may replace previous. A rate that
+// deviates by more than threshold from a value fetched less than window ago is
+// quarantined instead. Callers must hold ac.mu for writing.
+func (ac *APICache) screenRateLocked(q QuarantinedRate, previousAt time.Time, threshold float64, window time.Duration) bool {
+	id := quarantineID(q.Provider, q.Key)
+	if !isValidFloat(q.Previous) || time.Since(previousAt) > window {
+		delete(ac.quarantine, id)
+		return true
+	}
+
+	deviation := math.Abs(q.Proposed/q.Previous - 1)
+	if deviation <= threshold {
+		delete(ac.quarantine, id)
+		return true
+	}
+
+	q.ID = id
+	q.Deviation = deviation
+	q.DetectedAt = time.Now()
+	ac.quarantine[id] = &q
+	log.Printf("Warning: quarantined %s %s: %.6f -> %.6f (%.1f%% within %v)",
+		q.Provider, q.Key, q.Previous, q.Proposed, deviation*100, time.Since(previousAt).Round(time.Second))
+	return false
+}
+
+// QuarantinedRates lists rates currently held back for review, oldest first.
+func (ac *APICache) QuarantinedRates() []QuarantinedRate {
+	ac.mu.RLock()
+	defer ac.mu.RUnlock()
+
+	list := make([]QuarantinedRate, 0, len(ac.quarantine))
+	for _, q := range ac.quarantine {
+		list = append(list, *q)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].DetectedAt.Before(list[j].DetectedAt)
+	})
+	return list
+}
+
+// ApproveQuarantined applies a quarantined rate as if it had passed screening.
+func (ac *APICache) ApproveQuarantined(id string) error {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	q, ok := ac.quarantine[id]
+	if !ok {
+		return fmt.Errorf("no quarantined rate %q", id)
+	}
+	delete(ac.quarantine, id)
+
+	switch {
+	case q.bybitRate != nil:
+		ac.bybitRates[q.Key] = q.bybitRate
+	case q.mastercardRate != 0:
+		ac.mastercardRates[q.Key] = q.mastercardRate
+		ac.lastMastercardRates[q.Key] = q.mastercardRate
+		ac.mastercardFetchedAt[q.Key] = q.DetectedAt
+		ac.mastercardChangedAt[q.Key] = q.DetectedAt
+	}
+	log.Printf("Approved quarantined rate %s = %.6f", id, q.Proposed)
+	return nil
+}
+
+// DiscardQuarantined drops a quarantined rate, keeping the previous value.
+func (ac *APICache) DiscardQuarantined(id string) error {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	if _, ok := ac.quarantine[id]; !ok {
+		return fmt.Errorf("no quarantined rate %q", id)
+	}
+	delete(ac.quarantine, id)
+	log.Printf("Discarded quarantined rate %s", id)
+	return nil
+}
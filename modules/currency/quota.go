@@ -0,0 +1,100 @@
+package currency
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Provider quota usage: every request granted by a provider's scheduler is
+// counted in per-minute buckets, so the last minute and hour can be compared
+// with the configured rate limit before the provider starts blocking us.
+
+const quotaBuckets = 60 // one hour of minutes
+
+type quotaCounter struct {
+	mu      sync.Mutex
+	buckets [quotaBuckets]struct {
+		minute int64
+		count  int
+	}
+	total uint64
+}
+
+func (q *quotaCounter) record(now time.Time) {
+	minute := now.Unix() / 60
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	b := &q.buckets[minute%quotaBuckets]
+	if b.minute != minute {
+		b.minute, b.count = minute, 0
+	}
+	b.count++
+	q.total++
+}
+
+// counts returns the requests in the trailing 60 seconds (the current and
+// previous minute, weighted by overlap), the trailing hour, and all time.
+func (q *quotaCounter) counts(now time.Time) (lastMinute float64, lastHour int, total uint64) {
+	minute := now.Unix() / 60
+	elapsed := float64(now.Unix()%60) / 60
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, b := range q.buckets {
+		age := minute - b.minute
+		if age < 0 || age >= quotaBuckets {
+			continue
+		}
+		lastHour += b.count
+		switch age {
+		case 0:
+			lastMinute += float64(b.count)
+		case 1:
+			lastMinute += float64(b.count) * (1 - elapsed)
+		}
+	}
+	return lastMinute, lastHour, q.total
+}
+
+// QuotaUsage is one provider's request rate against its limit.
+type QuotaUsage struct {
+	Provider       string     `json:"provider"`
+	LastMinute     float64    `json:"last_minute"`
+	LastHour       int        `json:"last_hour"`
+	Total          uint64     `json:"total"`
+	LimitPerMinute float64    `json:"limit_per_minute"`
+	LimitPerHour   float64    `json:"limit_per_hour"`
+	Burst          int        `json:"burst"`
+	Utilization    float64    `json:"utilization"`            // last minute over the per-minute limit
+	PausedUntil    *time.Time `json:"paused_until,omitempty"` // the provider asked us to back off until then
+}
+
+// QuotaReport returns the quota usage of every rate-limited provider,
+// sorted by provider.
+func QuotaReport() []QuotaUsage {
+	now := time.Now()
+	report := make([]QuotaUsage, 0, len(providerSchedulers))
+	for _, s := range providerSchedulers {
+		lastMinute, lastHour, total := s.usage.counts(now)
+		perMinute := float64(s.limiter.Limit()) * 60
+		u := QuotaUsage{
+			Provider:       s.provider,
+			LastMinute:     lastMinute,
+			LastHour:       lastHour,
+			Total:          total,
+			LimitPerMinute: perMinute,
+			LimitPerHour:   perMinute * 60,
+			Burst:          s.limiter.Burst(),
+		}
+		if perMinute > 0 {
+			u.Utilization = lastMinute / perMinute
+		}
+		if s.pauseRemaining(now) > 0 {
+			until := time.Unix(0, s.pausedUntil.Load())
+			u.PausedUntil = &until
+		}
+		report = append(report, u)
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].Provider < report[j].Provider })
+	return report
+}
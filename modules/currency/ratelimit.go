@@ -0,0 +1,119 @@
+package currency
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// When a provider answers 429, or says its quota is spent, its scheduler is
+// paused for everyone until the provider's reset time. Without this every
+// concurrent caller retried on its own backoff, and the extra requests
+// extended the ban. The reset time comes from, in order:
+//
+//	Retry-After                   seconds or an HTTP date
+//	X-Bapi-Limit-Reset-Timestamp  Bybit, milliseconds since the epoch
+//	X-RateLimit-Reset             seconds since the epoch, or seconds to wait
+//
+// A 429 without any of them pauses for rateLimitDefaultPause.
+const (
+	rateLimitDefaultPause = 5 * time.Second
+	rateLimitMaxPause     = 10 * time.Minute
+)
+
+var schedulerByProvider = func() map[string]*providerScheduler {
+	m := make(map[string]*providerScheduler, len(providerSchedulers))
+	for _, s := range providerSchedulers {
+		m[s.provider] = s
+	}
+	return m
+}()
+
+// rateLimitTransport pauses the provider's scheduler on rate-limit answers.
+type rateLimitTransport struct {
+	next      http.RoundTripper
+	scheduler *providerScheduler
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	now := time.Now()
+	if until, ok := rateLimitResetTime(resp, now); ok {
+		t.scheduler.pause(now, until)
+	}
+	return resp, nil
+}
+
+// rateLimitResetTime reports until when resp asks us to stop sending: after
+// a 429, or once the remaining quota it reports is zero.
+func rateLimitResetTime(resp *http.Response, now time.Time) (time.Time, bool) {
+	exhausted := resp.StatusCode == http.StatusTooManyRequests ||
+		resp.Header.Get("X-Bapi-Limit-Status") == "0" || resp.Header.Get("X-RateLimit-Remaining") == "0"
+	if !exhausted {
+		return time.Time{}, false
+	}
+
+	until := now.Add(rateLimitDefaultPause)
+	if v := resp.Header.Get("Retry-After"); v != "" {
+		if seconds, err := strconv.Atoi(v); err == nil {
+			until = now.Add(time.Duration(seconds) * time.Second)
+		} else if at, err := http.ParseTime(v); err == nil {
+			until = at
+		}
+	} else if v := resp.Header.Get("X-Bapi-Limit-Reset-Timestamp"); v != "" {
+		if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+			until = time.UnixMilli(ms)
+		}
+	} else if v := resp.Header.Get("X-RateLimit-Reset"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			// Small values are a delay, large ones a timestamp
+			if n > 1e9 {
+				until = time.Unix(n, 0)
+			} else {
+				until = now.Add(time.Duration(n) * time.Second)
+			}
+		}
+	} else if resp.StatusCode != http.StatusTooManyRequests {
+		// Quota spent but no reset time: the limiter's own pace will do
+		return time.Time{}, false
+	}
+
+	if until.Before(now) {
+		return time.Time{}, false
+	}
+	if until.Sub(now) > rateLimitMaxPause {
+		until = now.Add(rateLimitMaxPause)
+	}
+	return until, true
+}
+
+// pause holds back all requests through s until until, extending but never
+// shortening an earlier pause.
+func (s *providerScheduler) pause(now, until time.Time) {
+	for {
+		current := s.pausedUntil.Load()
+		if until.UnixNano() <= current {
+			return
+		}
+		if s.pausedUntil.CompareAndSwap(current, until.UnixNano()) {
+			log.Printf("Warning: %s rate limit reached, pausing requests for %v", s.provider, until.Sub(now).Round(time.Millisecond))
+			return
+		}
+	}
+}
+
+// pauseRemaining is how long s stays paused from now.
+func (s *providerScheduler) pauseRemaining(now time.Time) time.Duration {
+	return time.Duration(s.pausedUntil.Load() - now.UnixNano())
+}
+
+// errRateLimited is returned by Wait when a pause outlasts the caller's
+// deadline, so the caller fails fast instead of waiting in vain.
+func errRateLimited(provider string, remaining time.Duration) error {
+	return fmt.Errorf("%s rate limited for another %v", provider, remaining.Round(time.Second))
+}
@@ -0,0 +1,73 @@
+package currency
+
+import (
+	"context"
+	"fmt"
+
+	"answerflow/commontypes"
+)
+
+// referenceUSDPrice is the mid-market USD value of one unit of code: the
+// consensus of the sources that price it (see consensus.go).
+func (ac *APICache) referenceUSDPrice(ctx context.Context, code string) (float64, error) {
+	c, err := ac.referenceUSDConsensus(ctx, code)
+	return c.USD, err
+}
+
+// referenceConversion converts at mid-market rates, without fees, spreads
+// or slippage. It also returns the warning when the sources behind either
+// side disagree.
+func (m *CurrencyConverterModule) referenceConversion(ctx context.Context, amount float64, from, to string, apiCache *APICache) (float64, string, error) {
+	fromUSD, err := apiCache.referenceUSDConsensus(ctx, from)
+	if err != nil {
+		return 0, "", err
+	}
+	toUSD, err := apiCache.referenceUSDConsensus(ctx, to)
+	if err != nil {
+		return 0, "", err
+	}
+	result := amount * fromUSD.USD / toUSD.USD
+	if !isValidFloat(result) || result <= 0 {
+		return 0, "", fmt.Errorf("invalid reference amount")
+	}
+	return result, disagreementWarning(fromUSD, toUSD), nil
+}
+
+// referenceEnabled reports whether reference results are wanted: the
+// request's preference if it stated one, REFERENCE_RESULTS otherwise.
+func referenceEnabled(ctx context.Context) bool {
+	if enabled, ok := commontypes.ReferenceRatesFromContext(ctx); ok {
+		return enabled
+	}
+	return referenceResults
+}
+
+// generateReferenceResult shows the mid-market conversion next to the
+// achievable amount, with how much the realistic execution gives up.
+func (m *CurrencyConverterModule) generateReferenceResult(ctx context.Context, req *ConversionRequest, targetCurrency string, achievable float64, apiCache *APICache) *commontypes.FlowResult {
+	reference, warning, err := m.referenceConversion(ctx, req.Amount, req.FromCurrency, targetCurrency, apiCache)
+	if err != nil {
+		return nil
+	}
+
+	formatted := formatAmount(reference, targetCurrency)
+	title := fmt.Sprintf("≈ %s %s", formatted, targetCurrency)
+	if !m.ShortDisplayFormat {
+		title = fmt.Sprintf("%s %s ≈ %s %s", formatAmount(req.Amount, req.FromCurrency), req.FromCurrency, formatted, targetCurrency)
+	}
+	subTitle := fmt.Sprintf("Mid-market reference: 1 %s = %s %s", req.FromCurrency, formatRate(reference/req.Amount), targetCurrency)
+	if achievable > 0 {
+		subTitle += fmt.Sprintf(" | achievable %+.2f%%", (achievable/reference-1)*100)
+	}
+	if warning != "" {
+		subTitle += formatWarnings([]string{warning})
+	}
+
+	return &commontypes.FlowResult{
+		Title:         title,
+		SubTitle:      subTitle,
+		IcoPath:       assetIcon(req.FromCurrency, targetCurrency),
+		Score:         scoreReferenceConversion,
+		JsonRPCAction: commontypes.CopyToClipboard(fmt.Sprintf("%s %s", formatAmountForClipboard(reference, targetCurrency), targetCurrency)),
+	}
+}
@@ -4,10 +4,17 @@ package currency
 import (
 	"fmt"
 	"math"
+	"strconv"
 
 	"answerflow/commontypes"
 )
 
+// Trade tags appended to the rate line when showTradeTags says so.
+const (
+	tagBuy  = " " + commontypes.GlyphBuy + " купить"
+	tagSell = " " + commontypes.GlyphSell + " продать"
+)
+
 func (m *CurrencyConverterModule) formatResult(req *ConversionRequest, targetCurrency string, finalAmount, displayRate float64, score int, slippageInfo string, feesInfo string) *commontypes.FlowResult {
 	var title, subTitle string
 
@@ -18,26 +25,30 @@ func (m *CurrencyConverterModule) formatResult(req *ConversionRequest, targetCur
 
 	// ALWAYS determine buy/sell tag based on RUB relationship
 	var tag string
-	if hasRubFrom {
+	if !showTradeTags(req.home()) {
+		// Plain reference rates: nothing is bought or sold
+	} else if hasRubFrom {
 		// FROM RUB: buying foreign currency
-		tag = " 🛍️ купить"
+		tag = tagBuy
 	} else if hasRubTo {
 		// TO RUB: selling foreign currency for RUB
-		tag = " 🏷️ продать"
+		tag = tagSell
 	} else {
 		// Foreign to Foreign: selling foreign currency (could ultimately be sold to RUB)
-		tag = " 🏷️ продать"
+		tag = tagSell
 	}
 
-	clipboardText := fmt.Sprintf("%s %s", formatAmountForClipboard(finalAmount, targetCurrency), targetCurrency)
-	formattedAmount := formatAmount(finalAmount, targetCurrency)
+	clipboardAmount := formatAmountForClipboardAt(finalAmount, targetCurrency, req.precision())
+	clipboardText := fmt.Sprintf("%s %s", clipboardAmount, targetCurrency)
+	formattedAmount := formatAmountAt(finalAmount, targetCurrency, req.precision())
+	titleAmount, exactNote := humanizedTitleAmount(finalAmount, formattedAmount, targetCurrency, req.HasPrecision)
 
 	if m.ShortDisplayFormat {
-		title = fmt.Sprintf("%s %s", formattedAmount, targetCurrency)
+		title = fmt.Sprintf("%s %s", titleAmount, targetCurrency)
 	} else {
 		title = fmt.Sprintf("%s %s = %s %s",
 			formatAmount(req.Amount, req.FromCurrency), req.FromCurrency,
-			formattedAmount, targetCurrency)
+			titleAmount, targetCurrency)
 	}
 
 	// Rate display with special handling for RUB<->USD pairs
@@ -53,22 +64,27 @@ func (m *CurrencyConverterModule) formatResult(req *ConversionRequest, targetCur
 		}
 	} else {
 		rateStr = fmt.Sprintf("1 %s = %s %s", req.FromCurrency, formatRate(displayRate), targetCurrency)
+		if name := currencyName(targetCurrency); name != "" {
+			rateStr += " (" + name + ")"
+		}
 	}
 
-	subTitle = rateStr + tag + slippageInfo + feesInfo
+	subTitle = exactNote + rateStr + tag + slippageInfo + feesInfo
+	if req.AmountNote != "" {
+		subTitle += " | " + req.AmountNote
+	}
 
 	return &commontypes.FlowResult{
-		Title:    title,
-		SubTitle: subTitle,
-		Score:    score,
-		JsonRPCAction: commontypes.JsonRPCAction{
-			Method:     "copy_to_clipboard",
-			Parameters: []interface{}{clipboardText},
-		},
+		Title:            title,
+		SubTitle:         subTitle,
+		IcoPath:          assetIcon(req.FromCurrency, targetCurrency),
+		Score:            score,
+		JsonRPCAction:    commontypes.CopyToClipboard(clipboardText),
+		ContextMenuItems: commontypes.CopyVariants(clipboardAmount, finalAmount, conversionFormula(req.Amount, finalAmount)),
 	}
 }
 
-func (m *CurrencyConverterModule) formatInverseResult(sourceAmount float64, sourceCurrency string, targetAmount float64, targetCurrency string, score int) *commontypes.FlowResult {
+func (m *CurrencyConverterModule) formatInverseResult(sourceAmount float64, sourceCurrency string, targetAmount float64, targetCurrency string, score int, home string, precision int) *commontypes.FlowResult {
 	// For inverse, we calculated sourceAmount to get targetAmount. The rate is how much source is needed for 1 unit of target.
 	marketRate := sourceAmount / targetAmount
 
@@ -79,15 +95,17 @@ func (m *CurrencyConverterModule) formatInverseResult(sourceAmount float64, sour
 
 	// ALWAYS determine buy/sell tag based on RUB relationship
 	var tag string
-	if hasRubSource {
+	if !showTradeTags(home) {
+		// Plain reference rates: nothing is bought or sold
+	} else if hasRubSource {
 		// Source is RUB: spending RUB to buy foreign currency
-		tag = " 🛍️ купить"
+		tag = tagBuy
 	} else if hasRubTarget {
 		// Target is RUB: getting RUB from foreign currency
-		tag = " 🏷️ продать"
+		tag = tagSell
 	} else {
 		// Foreign to foreign inverse: buying foreign currency (would need RUB first)
-		tag = " 🛍️ купить"
+		tag = tagBuy
 	}
 
 	// Rate display with special handling for RUB<->USD pairs
@@ -108,25 +126,52 @@ func (m *CurrencyConverterModule) formatInverseResult(sourceAmount float64, sour
 		rateStr = fmt.Sprintf("1 %s = %s %s", targetCurrency, formatRate(marketRate), sourceCurrency)
 	}
 
-	clipboardText := fmt.Sprintf("%s %s", formatAmountForClipboard(sourceAmount, sourceCurrency), sourceCurrency)
-	formattedSource := formatAmount(sourceAmount, sourceCurrency)
+	clipboardAmount := formatAmountForClipboardAt(sourceAmount, sourceCurrency, precision)
+	clipboardText := fmt.Sprintf("%s %s", clipboardAmount, sourceCurrency)
+	formattedSource := formatAmountAt(sourceAmount, sourceCurrency, precision)
+	titleSource, exactNote := humanizedTitleAmount(sourceAmount, formattedSource, sourceCurrency, precision >= 0)
 
 	var title string
 	if m.ShortDisplayFormat {
-		title = fmt.Sprintf("%s %s", formattedSource, sourceCurrency)
+		title = fmt.Sprintf("%s %s", titleSource, sourceCurrency)
 	} else {
 		title = fmt.Sprintf("%s %s = %s %s",
-			formattedSource, sourceCurrency,
+			titleSource, sourceCurrency,
 			formatAmount(targetAmount, targetCurrency), targetCurrency)
 	}
 
 	return &commontypes.FlowResult{
-		Title:    title,
-		SubTitle: rateStr + tag,
-		Score:    score,
-		JsonRPCAction: commontypes.JsonRPCAction{
-			Method:     "copy_to_clipboard",
-			Parameters: []interface{}{clipboardText},
-		},
+		Title:            title,
+		SubTitle:         exactNote + rateStr + tag,
+		IcoPath:          assetIcon(sourceCurrency, targetCurrency),
+		Score:            score,
+		JsonRPCAction:    commontypes.CopyToClipboard(clipboardText),
+		ContextMenuItems: commontypes.CopyVariants(clipboardAmount, sourceAmount, conversionFormula(targetAmount, sourceAmount)),
+	}
+}
+
+// humanizedTitleAmount returns the amount to put in a title: humanized when
+// it is large enough, with a subtitle prefix giving the exact formatted
+// figure, or formatted unchanged. An explicit precision hint asks for the
+// digits, so it is never humanized.
+func humanizedTitleAmount(amount float64, formatted, code string, hasPrecision bool) (string, string) {
+	if hasPrecision {
+		return formatted, ""
+	}
+	short, ok := humanizeAmount(amount)
+	if !ok {
+		return formatted, ""
+	}
+	return short, "= " + formatted + " " + code + " | "
+}
+
+// conversionFormula writes a conversion as amount times its effective rate,
+// fees included, for pasting into a spreadsheet: "100*0.9213". It is empty
+// when there is no rate to show.
+func conversionFormula(amount, result float64) string {
+	rate := result / amount
+	if amount == 0 || !isValidFloat(rate) {
+		return ""
 	}
+	return strconv.FormatFloat(amount, 'f', -1, 64) + "*" + strconv.FormatFloat(rate, 'f', -1, 64)
 }
@@ -0,0 +1,53 @@
+package currency
+
+import (
+	"context"
+	"sync"
+)
+
+// RUBRate is what one unit of Code costs and fetches in RUB along the full
+// conversion route, fees included, at a representative amount.
+type RUBRate struct {
+	Code  string  `json:"code"`
+	Buy   float64 `json:"buy,omitempty"`  // RUB paid per unit, converting RUB to Code
+	Sell  float64 `json:"sell,omitempty"` // RUB received per unit, converting Code back to RUB
+	Error string  `json:"error,omitempty"`
+}
+
+// EffectiveRUBRates prices each of codes by converting rubAmount RUB into it
+// and the units obtained back, so both directions are priced at the same
+// size. Codes are priced concurrently and returned in the order given.
+func (m *CurrencyConverterModule) EffectiveRUBRates(ctx context.Context, codes []string, rubAmount float64, apiCache *APICache) []RUBRate {
+	rates := make([]RUBRate, len(codes))
+	var wg sync.WaitGroup
+	for i, code := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rates[i] = m.effectiveRUBRate(ctx, code, rubAmount, apiCache)
+		}()
+	}
+	wg.Wait()
+	return rates
+}
+
+func (m *CurrencyConverterModule) effectiveRUBRate(ctx context.Context, code string, rubAmount float64, apiCache *APICache) RUBRate {
+	rate := RUBRate{Code: code}
+	if code == CurrencyRUB {
+		rate.Buy, rate.Sell = 1, 1
+		return rate
+	}
+	units, err := m.convert(ctx, rubAmount, CurrencyRUB, code, apiCache)
+	if err != nil {
+		rate.Error = TranslateError(err)
+		return rate
+	}
+	rate.Buy = rubAmount / units
+	rub, err := m.convert(ctx, units, code, CurrencyRUB, apiCache)
+	if err != nil {
+		rate.Error = TranslateError(err)
+		return rate
+	}
+	rate.Sell = rub / units
+	return rate
+}
@@ -0,0 +1,107 @@
+package currency
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+type requestPriority int
+
+const (
+	priorityBackground requestPriority = iota
+	priorityInteractive
+)
+
+type priorityContextKey struct{}
+
+// withPriority marks provider calls made under ctx with the given priority.
+// Calls without a marked context are treated as background work.
+func withPriority(ctx context.Context, p requestPriority) context.Context {
+	return context.WithValue(ctx, priorityContextKey{}, p)
+}
+
+func priorityFromContext(ctx context.Context) requestPriority {
+	if p, ok := ctx.Value(priorityContextKey{}).(requestPriority); ok {
+		return p
+	}
+	return priorityBackground
+}
+
+// providerScheduler sits in front of a provider's rate limiter and lets
+// interactive, query-driven requests jump ahead of background refreshes.
+// Background requests only take a token when one is immediately available and
+// no interactive request is queued, so they never hold reservations that an
+// interactive request would have to wait behind.
+type providerScheduler struct {
+	provider           string
+	limiter            *rate.Limiter
+	interactiveWaiting atomic.Int32
+	usage              quotaCounter
+	pausedUntil        atomic.Int64 // unix nanoseconds; see ratelimit.go
+}
+
+func newProviderScheduler(provider string, limiter *rate.Limiter) *providerScheduler {
+	return &providerScheduler{provider: provider, limiter: limiter}
+}
+
+func (s *providerScheduler) Wait(ctx context.Context) error {
+	if err := s.waitPause(ctx); err != nil {
+		return err
+	}
+	if priorityFromContext(ctx) == priorityInteractive {
+		s.interactiveWaiting.Add(1)
+		defer s.interactiveWaiting.Add(-1)
+		if err := s.limiter.Wait(ctx); err != nil {
+			return err
+		}
+		s.usage.record(time.Now())
+		return nil
+	}
+
+	pollInterval := time.Duration(float64(time.Second) / float64(s.limiter.Limit()))
+	for {
+		if s.interactiveWaiting.Load() == 0 && s.limiter.Allow() {
+			s.usage.record(time.Now())
+			return nil
+		}
+		timer := time.NewTimer(pollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// waitPause blocks while the provider has asked us to back off.
+func (s *providerScheduler) waitPause(ctx context.Context) error {
+	remaining := s.pauseRemaining(time.Now())
+	if remaining <= 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < remaining {
+		return errRateLimited(s.provider, remaining)
+	}
+	timer := time.NewTimer(remaining)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+var (
+	bybitScheduler      = newProviderScheduler(providerBybit, bybitLimiter)
+	whitebirdScheduler  = newProviderScheduler(providerWhitebird, whitebirdLimiter)
+	mastercardScheduler = newProviderScheduler(providerMastercard, mastercardLimiter)
+	coingeckoScheduler  = newProviderScheduler(providerCoinGecko, coingeckoLimiter)
+	visaScheduler       = newProviderScheduler(providerVisa, visaLimiter)
+
+	providerSchedulers = []*providerScheduler{bybitScheduler, whitebirdScheduler, mastercardScheduler, coingeckoScheduler, visaScheduler}
+)
@@ -0,0 +1,147 @@
+package currency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"answerflow/notify"
+)
+
+// Conversion SLOs, tracked per route type ("fiat/fiat", "RUB/crypto", ...)
+// over a rolling window. Only conversions that reach routing count; cache
+// hits and rejected input don't. A route type with at least SLO_MIN_SAMPLES
+// conversions in the window breaches when its success rate drops below
+// SLO_SUCCESS_RATE or its p95 latency exceeds SLO_P95_LATENCY; that notifies
+// and degrades /health until it recovers.
+var (
+	sloWindow      = getEnvDurationOrDefault("SLO_WINDOW", 15*time.Minute)
+	sloSuccessRate = getEnvFloatOrDefault("SLO_SUCCESS_RATE", 0.95)
+	sloP95Latency  = getEnvDurationOrDefault("SLO_P95_LATENCY", 3*time.Second)
+	sloMinSamples  = int(getEnvFloatOrDefault("SLO_MIN_SAMPLES", 20))
+)
+
+const (
+	sloMaxSamples       = 2000 // per route type; older ones fall out of the window early
+	sloEvaluateInterval = 10 * time.Second
+)
+
+type sloSample struct {
+	at       time.Time
+	duration time.Duration
+	ok       bool
+}
+
+type sloRoute struct {
+	samples      []sloSample
+	lastEvaluate time.Time
+	status       SLOStatus
+}
+
+// SLOStatus is the state of one route type's SLO.
+type SLOStatus struct {
+	Samples      int     `json:"samples"`
+	SuccessRate  float64 `json:"success_rate"`
+	P95LatencyMs float64 `json:"p95_latency_ms"`
+	Breached     bool    `json:"breached"`
+	Reason       string  `json:"reason,omitempty"`
+}
+
+var slo = struct {
+	mu     sync.Mutex
+	routes map[string]*sloRoute
+}{routes: make(map[string]*sloRoute)}
+
+// recordConversion adds one routed conversion to its route type's window.
+func recordConversion(routeType string, d time.Duration, err error) {
+	// Neither a client going away nor a spent query budget says anything
+	// about the route
+	if errors.Is(err, context.Canceled) || errors.Is(err, errBudgetExhausted) {
+		return
+	}
+	now := time.Now()
+	slo.mu.Lock()
+	defer slo.mu.Unlock()
+	r, ok := slo.routes[routeType]
+	if !ok {
+		r = &sloRoute{}
+		slo.routes[routeType] = r
+	}
+	r.samples = append(r.samples, sloSample{at: now, duration: d, ok: err == nil})
+	if len(r.samples) > sloMaxSamples {
+		r.samples = r.samples[len(r.samples)-sloMaxSamples:]
+	}
+	if now.Sub(r.lastEvaluate) >= sloEvaluateInterval {
+		r.lastEvaluate = now
+		r.evaluate(routeType, now)
+	}
+}
+
+// evaluate recomputes the status over the window and notifies on changes.
+// Callers hold slo.mu.
+func (r *sloRoute) evaluate(routeType string, now time.Time) {
+	cutoff := now.Add(-sloWindow)
+	i := sort.Search(len(r.samples), func(i int) bool { return r.samples[i].at.After(cutoff) })
+	r.samples = r.samples[i:]
+
+	status := SLOStatus{Samples: len(r.samples)}
+	if len(r.samples) > 0 {
+		durations := make([]time.Duration, len(r.samples))
+		succeeded := 0
+		for i, s := range r.samples {
+			durations[i] = s.duration
+			if s.ok {
+				succeeded++
+			}
+		}
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+		p95 := durations[(len(durations)*95-1)/100]
+		status.SuccessRate = float64(succeeded) / float64(len(r.samples))
+		status.P95LatencyMs = float64(p95.Milliseconds())
+
+		if len(r.samples) >= sloMinSamples {
+			switch {
+			case status.SuccessRate < sloSuccessRate:
+				status.Breached = true
+				status.Reason = fmt.Sprintf("success rate %.1f%% below %.1f%%", status.SuccessRate*100, sloSuccessRate*100)
+			case p95 > sloP95Latency:
+				status.Breached = true
+				status.Reason = fmt.Sprintf("p95 latency %v above %v", p95.Round(time.Millisecond), sloP95Latency)
+			}
+		}
+	}
+
+	switch {
+	case status.Breached && !r.status.Breached:
+		log.Printf("Warning: %s conversions breach their SLO: %s", routeType, status.Reason)
+		clearAlert("slo-ok:" + routeType)
+		alert("slo:"+routeType, notify.LevelWarning, fmt.Sprintf("%s conversions below SLO", routeType),
+			fmt.Sprintf("%s over the last %v (%d conversions)", status.Reason, sloWindow, status.Samples))
+	case !status.Breached && r.status.Breached:
+		log.Printf("Info: %s conversions meet their SLO again", routeType)
+		clearAlert("slo:" + routeType)
+		alert("slo-ok:"+routeType, notify.LevelInfo, fmt.Sprintf("%s conversions within SLO", routeType),
+			fmt.Sprintf("success rate %.1f%%, p95 latency %.0fms", status.SuccessRate*100, status.P95LatencyMs))
+	}
+	r.status = status
+}
+
+// SLOReport returns the SLO status of every route type seen so far and
+// whether any of them is breached.
+func SLOReport() (map[string]SLOStatus, bool) {
+	slo.mu.Lock()
+	defer slo.mu.Unlock()
+	now := time.Now()
+	report := make(map[string]SLOStatus, len(slo.routes))
+	breached := false
+	for routeType, r := range slo.routes {
+		r.evaluate(routeType, now)
+		report[routeType] = r.status
+		breached = breached || r.status.Breached
+	}
+	return report, breached
+}
@@ -0,0 +1,67 @@
+package currency
+
+import (
+	"fmt"
+	"net/url"
+
+	"answerflow/commontypes"
+)
+
+// SOURCE_LINKS adds a context menu item per provider leg of a conversion
+// that opens where the rate comes from, to check it or to execute the leg
+// by hand: the Bybit spot pair, the Mastercard and Visa converters and the
+// Whitebird exchange. Off by default.
+var sourceLinksEnabled = getEnvBoolOrDefault("SOURCE_LINKS", false)
+
+const (
+	bybitSpotPageURL           = "https://www.bybit.com/en/trade/spot/"
+	mastercardConverterPageURL = "https://www.mastercard.com/global/en/personal/get-support/currency-exchange-rate-converter.html"
+	visaCalculatorPageURL      = "https://www.visa.co.uk/support/consumer/travel-support/exchange-rate-calculator.html"
+	whitebirdPageURL           = "https://whitebird.io/"
+)
+
+// sourceLinks returns the "open source page" menu items for route, one per
+// distinct page.
+func sourceLinks(route *Route) []commontypes.ContextMenuItem {
+	if !sourceLinksEnabled || route == nil {
+		return nil
+	}
+	var items []commontypes.ContextMenuItem
+	seen := make(map[string]bool)
+	for _, leg := range route.Legs {
+		title, link := sourceLink(leg)
+		if link == "" || seen[link] {
+			continue
+		}
+		seen[link] = true
+		action, err := commontypes.OpenURL(link)
+		if err != nil {
+			continue
+		}
+		items = append(items, commontypes.ContextMenuItem{
+			Title:         title,
+			SubTitle:      "Open the source of this rate in the browser",
+			JsonRPCAction: action,
+		})
+	}
+	return items
+}
+
+// sourceLink is the page behind one leg, if its provider has one.
+func sourceLink(leg ConversionStep) (title, link string) {
+	switch leg.Provider {
+	case "Bybit Spot":
+		base := leg.From
+		if base == CurrencyUSDT {
+			base = leg.To
+		}
+		return fmt.Sprintf("Open %s/USDT on Bybit", base), bybitSpotPageURL + url.PathEscape(base) + "/USDT"
+	case "Mastercard":
+		return "Open the Mastercard currency converter", mastercardConverterPageURL
+	case "Visa":
+		return "Open the Visa exchange rate calculator", visaCalculatorPageURL
+	case "Whitebird":
+		return "Open Whitebird", whitebirdPageURL
+	}
+	return "", ""
+}
@@ -0,0 +1,172 @@
+package currency
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Stub providers answer provider requests in-process with fixed, plausible
+// data, so load tests (answerflow soak) exercise the whole pipeline without
+// sending their traffic to the real APIs. Bybit, Mastercard, Visa and
+// Whitebird are stubbed; every other provider fails with 503 and takes its
+// degraded path.
+var stubProvidersEnabled atomic.Bool
+
+// UseStubProviders makes API caches created afterwards talk to the stub
+// providers instead of the network.
+func UseStubProviders() {
+	stubProvidersEnabled.Store(true)
+}
+
+// stubbed returns the stub providers in place of base while they are in use.
+func stubbed(base http.RoundTripper) http.RoundTripper {
+	if stubProvidersEnabled.Load() {
+		return stubProviders{}
+	}
+	return base
+}
+
+// stubUSDPrices are the stub Bybit prices in USDT.
+var stubUSDPrices = map[string]float64{
+	"TON": 3.1, "BTC": 65000, "ETH": 3200, "SOL": 150, "DOGE": 0.15, "XRP": 0.55, "LTC": 80,
+}
+
+// stubFiatPerUSD are the stub card rates; other fiats are priced at par.
+var stubFiatPerUSD = map[string]float64{
+	"USD": 1, "EUR": 0.92, "GBP": 0.79, "JPY": 150, "CHF": 0.88, "CNY": 7.2, "RUB": 90,
+	"KZT": 470, "TRY": 32, "PLN": 4, "CAD": 1.36, "AUD": 1.52, "INR": 83,
+}
+
+// stubRUBPerTON is the Whitebird stub rate, fees included.
+const stubRUBPerTON = 290.0
+
+type stubProviders struct{}
+
+func (stubProviders) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		defer req.Body.Close()
+	}
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	q := req.URL.Query()
+	var body interface{}
+	switch {
+	case strings.HasSuffix(req.URL.Path, "/market/tickers"):
+		body = stubBybitTickers()
+	case strings.HasSuffix(req.URL.Path, "/market/orderbook"):
+		limit, _ := strconv.Atoi(q.Get("limit"))
+		book, ok := stubBybitOrderbook(q.Get("symbol"), max(limit, 1))
+		if !ok {
+			return stubResponse(req, http.StatusOK, map[string]interface{}{"retCode": 10001, "retMsg": "unknown symbol"}), nil
+		}
+		body = book
+	case req.URL.Host == "www.mastercard.com" && q.Has("transaction_currency"):
+		rate := stubFiatRate(q.Get("transaction_currency"), q.Get("cardholder_billing_currency"))
+		body = map[string]interface{}{"data": map[string]string{"conversionRate": strconv.FormatFloat(rate, 'f', -1, 64)}}
+	case req.URL.Host == "www.mastercard.com":
+		// The converter page, loaded for its session cookies
+		return stubResponse(req, http.StatusOK, nil), nil
+	case strings.Contains(req.URL.Host, "visa."):
+		// Visa quotes fromCurr per toCurr
+		rate := stubFiatRate(q.Get("toCurr"), q.Get("fromCurr"))
+		body = map[string]interface{}{"originalValues": map[string]string{"fxRateVisa": strconv.FormatFloat(rate, 'f', -1, 64)}}
+	case strings.Contains(req.URL.Host, "whitebird"):
+		var payload whitebirdRequestPayload
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			return stubResponse(req, http.StatusBadRequest, nil), nil
+		}
+		body = stubWhitebirdQuote(payload)
+	default:
+		return stubResponse(req, http.StatusServiceUnavailable, nil), nil
+	}
+	return stubResponse(req, http.StatusOK, body), nil
+}
+
+func stubResponse(req *http.Request, status int, body interface{}) *http.Response {
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}
+}
+
+func stubBybitTickers() interface{} {
+	list := make([]map[string]string, 0, len(stubUSDPrices))
+	for coin, price := range stubUSDPrices {
+		size := strconv.FormatFloat(50000/price, 'f', -1, 64)
+		list = append(list, map[string]string{
+			"symbol":      coin + "USDT",
+			"bid1Price":   strconv.FormatFloat(price*0.9995, 'f', -1, 64),
+			"bid1Size":    size,
+			"ask1Price":   strconv.FormatFloat(price*1.0005, 'f', -1, 64),
+			"ask1Size":    size,
+			"turnover24h": "250000000",
+		})
+	}
+	return map[string]interface{}{"retCode": 0, "result": map[string]interface{}{"list": list}}
+}
+
+// stubBybitOrderbook builds depth levels 0.05% apart, each worth about
+// 50,000 USDT.
+func stubBybitOrderbook(symbol string, depth int) (interface{}, bool) {
+	price, ok := stubUSDPrices[strings.TrimSuffix(symbol, "USDT")]
+	if !ok {
+		return nil, false
+	}
+	size := strconv.FormatFloat(50000/price, 'f', -1, 64)
+	asks := make([][]string, depth)
+	bids := make([][]string, depth)
+	for i := range depth {
+		step := 0.0005 * float64(i+1)
+		asks[i] = []string{strconv.FormatFloat(price*(1+step), 'f', -1, 64), size}
+		bids[i] = []string{strconv.FormatFloat(price*(1-step), 'f', -1, 64), size}
+	}
+	return map[string]interface{}{"retCode": 0, "result": map[string]interface{}{"s": symbol, "a": asks, "b": bids}}, true
+}
+
+func stubFiatRate(from, to string) float64 {
+	perUSD := func(code string) float64 {
+		if rate, ok := stubFiatPerUSD[strings.ToUpper(code)]; ok {
+			return rate
+		}
+		return 1
+	}
+	return perUSD(to) / perUSD(from)
+}
+
+func stubWhitebirdQuote(payload whitebirdRequestPayload) interface{} {
+	ratio := 1 / stubRUBPerTON
+	if payload.CurrencyPair.FromCurrency == CurrencyTON {
+		ratio = stubRUBPerTON * 0.97
+	}
+	input, output := 0.0, 0.0
+	switch calc := payload.Calculation; {
+	case calc.InputAsset != nil:
+		input, output = *calc.InputAsset, *calc.InputAsset*ratio
+	case calc.OutputAsset != nil:
+		input, output = *calc.OutputAsset/ratio, *calc.OutputAsset
+	}
+	format := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	return map[string]interface{}{
+		"rate":            map[string]string{"plainRatio": format(ratio), "ratio": format(ratio)},
+		"calculation":     map[string]string{"inputAsset": format(input), "outputAsset": format(output)},
+		"operationStatus": map[string]interface{}{"enabled": true, "status": "ACTIVE"},
+	}
+}
@@ -0,0 +1,130 @@
+package currency
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"answerflow/commontypes"
+)
+
+// SuggestCurrency returns the closest known currency token for an unrecognised
+// input, or "" when nothing is close enough to be a plausible typo.
+func (cd *CurrencyData) SuggestCurrency(token string) string {
+	cd.mu.RLock()
+	defer cd.mu.RUnlock()
+
+	token = strings.ToLower(strings.TrimSpace(token))
+	if len(token) < 3 {
+		return ""
+	}
+
+	maxDistance := 1
+	if len(token) > 4 {
+		maxDistance = 2
+	}
+
+	best, bestDistance, bestIsCode := "", maxDistance+1, false
+	consider := func(candidate string, isCode bool) {
+		if strings.ContainsRune(candidate, ' ') {
+			return
+		}
+		d := levenshtein(token, candidate)
+		if d == 0 || d > maxDistance {
+			return
+		}
+		// Prefer lower distance, then canonical codes over aliases, then lexical order
+		// so that the same typo always yields the same suggestion.
+		if d < bestDistance ||
+			(d == bestDistance && isCode && !bestIsCode) ||
+			(d == bestDistance && isCode == bestIsCode && candidate < best) {
+			best, bestDistance, bestIsCode = candidate, d, isCode
+		}
+	}
+
+	for key, code := range cd.validCodes {
+		consider(key, strings.ToLower(code) == key)
+	}
+	for alias := range cd.nameAliases {
+		consider(alias, false)
+	}
+
+	return best
+}
+
+// SuggestQuery rewrites unrecognised currency tokens in query with their
+// closest known match. It returns false if nothing was corrected or the
+// corrected query still does not parse.
+func SuggestQuery(query string, currencyData *CurrencyData) (string, bool) {
+	if !strings.ContainsFunc(query, unicode.IsDigit) {
+		return "", false
+	}
+
+	tokens := strings.Fields(query)
+	corrected := false
+	for i, token := range tokens {
+		if !isAlpha(token) || isQueryKeyword(token) {
+			continue
+		}
+		if _, err := currencyData.ResolveCurrency(token); err == nil {
+			continue
+		}
+		if suggestion := currencyData.SuggestCurrency(token); suggestion != "" {
+			tokens[i] = suggestion
+			corrected = true
+		}
+	}
+	if !corrected {
+		return "", false
+	}
+
+	suggested := strings.Join(tokens, " ")
+	if _, err := ParseQuery(suggested, currencyData); err != nil {
+		return "", false
+	}
+	return suggested, true
+}
+
+var queryKeywords = map[string]bool{
+	"to": true, "in": true, "from": true, "how": true, "much": true,
+	"is": true, "what": true, "whats": true, "table": true,
+}
+
+func isQueryKeyword(token string) bool {
+	return queryKeywords[strings.ToLower(token)]
+}
+
+// levenshtein computes the edit distance between two strings, rune-wise.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func (m *CurrencyConverterModule) makeSuggestionResult(query string) *commontypes.FlowResult {
+	suggested, ok := SuggestQuery(query, m.currencyData)
+	if !ok {
+		return nil
+	}
+	return &commontypes.FlowResult{
+		Title:         fmt.Sprintf("Did you mean: %s?", suggested),
+		SubTitle:      "Press Enter to use the corrected query",
+		Score:         scoreSuggestion,
+		JsonRPCAction: commontypes.ChangeQuery(suggested, false),
+	}
+}
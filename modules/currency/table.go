@@ -0,0 +1,65 @@
+package currency
+
+import (
+	"context"
+	"fmt"
+
+	"answerflow/commontypes"
+)
+
+// tableAmounts are the standard source amounts shown for "usd to rub table"
+// queries. Spanning several orders of magnitude makes order-book depth and
+// Whitebird's amount-dependent pricing visible at a glance.
+var tableAmounts = []float64{1, 10, 100, 1000, 10000}
+
+// generateTable converts each of tableAmounts and returns one row per amount,
+// annotated with how far its effective rate deviates from the smallest
+// amount that converted successfully.
+func (m *CurrencyConverterModule) generateTable(ctx context.Context, req *ConversionRequest, apiCache *APICache) []commontypes.FlowResult {
+	var results []commontypes.FlowResult
+	var referenceRate float64
+	var firstErr error
+
+	for i, amount := range tableAmounts {
+		select {
+		case <-ctx.Done():
+			return results
+		default:
+		}
+
+		finalAmount, err := m.convert(ctx, amount, req.FromCurrency, req.ToCurrency, apiCache)
+		finalAmount = req.afterPersonalFee(finalAmount)
+		if err == nil && finalAmount < minAmountAfterFees {
+			err = fmt.Errorf("amount too small")
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		rate := finalAmount / amount
+		deviation := ""
+		if referenceRate == 0 {
+			referenceRate = rate
+		} else if diff := (rate/referenceRate - 1) * 100; diff >= 0.05 || diff <= -0.05 {
+			deviation = fmt.Sprintf(" (%+.2f%%)", diff)
+		}
+
+		results = append(results, commontypes.FlowResult{
+			Title: fmt.Sprintf("%s %s → %s %s",
+				formatAmount(amount, req.FromCurrency), req.FromCurrency,
+				formatAmountAt(finalAmount, req.ToCurrency, req.precision()), req.ToCurrency),
+			SubTitle: fmt.Sprintf("1 %s = %s %s%s", req.FromCurrency, formatRate(rate), req.ToCurrency, deviation),
+			// Keep rows in ascending amount order
+			Score:         scoreSpecificConversion - i,
+			JsonRPCAction: commontypes.CopyToClipboard(fmt.Sprintf("%s %s", formatAmountForClipboardAt(finalAmount, req.ToCurrency, req.precision()), req.ToCurrency)),
+		})
+	}
+
+	if len(results) == 0 && firstErr != nil {
+		results = m.requestedPairFailed(ctx, req, m.makeErrorResult(req, req.ToCurrency, firstErr), apiCache)
+	}
+	return results
+}
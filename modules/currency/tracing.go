@@ -0,0 +1,21 @@
+package currency
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer is a no-op until main installs an exporting TracerProvider.
+var tracer = otel.Tracer("answerflow/modules/currency")
+
+// endSpan records err on span, if any, and ends it. Use with named results:
+//
+//	defer func() { endSpan(span, err) }()
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,180 @@
+package currency
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Connection handling per provider, since providers disagree about it:
+// Bybit rewards long-lived keep-alive connections while the Mastercard
+// endpoint seems to punish them. Each setting is <PROVIDER>_<SETTING>:
+//
+//	_HTTP2                   negotiate HTTP/2 (default false)
+//	_KEEPALIVE               reuse connections (default true)
+//	_MAX_CONNS_PER_HOST      cap on open connections, 0 for none (default 0)
+//	_MAX_IDLE_CONNS_PER_HOST idle connections kept for reuse (default 20)
+//	_IDLE_CONN_TIMEOUT       how long an idle connection is kept (default 90s)
+type transportConfig struct {
+	HTTP2               bool          `json:"http2"`
+	KeepAlive           bool          `json:"keep_alive"`
+	MaxConnsPerHost     int           `json:"max_conns_per_host"`
+	MaxIdleConnsPerHost int           `json:"max_idle_conns_per_host"`
+	IdleConnTimeout     time.Duration `json:"idle_conn_timeout"`
+}
+
+func loadTransportConfig(provider string) transportConfig {
+	prefix := strings.ToUpper(provider) + "_"
+	return transportConfig{
+		HTTP2:               getEnvBoolOrDefault(prefix+"HTTP2", false),
+		KeepAlive:           getEnvBoolOrDefault(prefix+"KEEPALIVE", true),
+		MaxConnsPerHost:     int(getEnvFloatOrDefault(prefix+"MAX_CONNS_PER_HOST", 0)),
+		MaxIdleConnsPerHost: int(getEnvFloatOrDefault(prefix+"MAX_IDLE_CONNS_PER_HOST", 20)),
+		IdleConnTimeout:     getEnvDurationOrDefault(prefix+"IDLE_CONN_TIMEOUT", 90*time.Second),
+	}
+}
+
+// connStats counts how a provider's connections are used, to check that
+// keep-alive and HTTP/2 actually lead to reuse.
+type connStats struct {
+	config         transportConfig
+	requests       atomic.Int64
+	reused         atomic.Int64
+	dials          atomic.Int64
+	dialErrors     atomic.Int64
+	open           atomic.Int64
+	http2Responses atomic.Int64
+}
+
+// ConnectionStats is a snapshot of one provider's connection pool.
+type ConnectionStats struct {
+	Config         transportConfig `json:"config"`
+	Proxied        bool            `json:"proxied"`
+	Requests       int64           `json:"requests"`
+	ReusedConns    int64           `json:"reused_conns"`    // requests sent on an existing connection
+	ReuseRatio     float64         `json:"reuse_ratio"`     // reused_conns / requests
+	Dials          int64           `json:"dials"`           // new connections attempted
+	DialErrors     int64           `json:"dial_errors"`     // of which failed
+	OpenConns      int64           `json:"open_conns"`      // currently open, idle or busy
+	HTTP2Responses int64           `json:"http2_responses"` // responses that came over HTTP/2
+}
+
+// newProviderClients builds one client per provider with its transport
+// settings, proxy and connection counters.
+func newProviderClients() (map[string]*http.Client, map[string]*connStats) {
+	clients := make(map[string]*http.Client, len(providerCriticality))
+	stats := make(map[string]*connStats, len(providerCriticality))
+	for name := range providerCriticality {
+		client := &http.Client{Timeout: 30 * time.Second}
+		t := newBaseTransport()
+		cfg := loadTransportConfig(name)
+		t.ForceAttemptHTTP2 = cfg.HTTP2
+		t.DisableKeepAlives = !cfg.KeepAlive
+		t.MaxConnsPerHost = cfg.MaxConnsPerHost
+		t.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+		t.IdleConnTimeout = cfg.IdleConnTimeout
+		if proxy, ok := providerProxies[name]; ok {
+			t.Proxy = http.ProxyURL(proxy)
+		}
+
+		s := &connStats{config: cfg}
+		t.DialContext = s.countDials(t.DialContext)
+		client.Transport = &connStatsTransport{next: stubbed(t), stats: s}
+		if faultInjectionEnabled {
+			client.Transport = &faultTransport{next: client.Transport, provider: name}
+		}
+		if name == providerMastercard {
+			client.Jar = newMastercardJar()
+		}
+		if scheduler, ok := schedulerByProvider[name]; ok {
+			client.Transport = &rateLimitTransport{next: client.Transport, scheduler: scheduler}
+		}
+		client.Transport = &hostPolicyTransport{next: client.Transport}
+		clients[name], stats[name] = client, s
+	}
+	return clients, stats
+}
+
+// clientFor returns the HTTP client requests to provider go through.
+func (ac *APICache) clientFor(provider string) *http.Client {
+	if client, ok := ac.providerClients[provider]; ok {
+		return client
+	}
+	return ac.client
+}
+
+func (s *connStats) countDials(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		s.dials.Add(1)
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			s.dialErrors.Add(1)
+			return nil, err
+		}
+		s.open.Add(1)
+		return &countedConn{Conn: conn, open: &s.open}, nil
+	}
+}
+
+// countedConn decrements the open connection gauge once when closed.
+type countedConn struct {
+	net.Conn
+	open   *atomic.Int64
+	closed atomic.Bool
+}
+
+func (c *countedConn) Close() error {
+	if c.closed.CompareAndSwap(false, true) {
+		c.open.Add(-1)
+	}
+	return c.Conn.Close()
+}
+
+// connStatsTransport records per request whether the connection was reused
+// and which protocol answered.
+type connStatsTransport struct {
+	next  http.RoundTripper
+	stats *connStats
+}
+
+func (t *connStatsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.stats.requests.Add(1)
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				t.stats.reused.Add(1)
+			}
+		},
+	}
+	resp, err := t.next.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+	if err == nil && resp.ProtoMajor == 2 {
+		t.stats.http2Responses.Add(1)
+	}
+	return resp, err
+}
+
+// ConnectionStats reports the connection pool of every provider.
+func (ac *APICache) ConnectionStats() map[string]ConnectionStats {
+	out := make(map[string]ConnectionStats, len(ac.connStats))
+	for name, s := range ac.connStats {
+		snap := ConnectionStats{
+			Config:         s.config,
+			Requests:       s.requests.Load(),
+			ReusedConns:    s.reused.Load(),
+			Dials:          s.dials.Load(),
+			DialErrors:     s.dialErrors.Load(),
+			OpenConns:      s.open.Load(),
+			HTTP2Responses: s.http2Responses.Load(),
+		}
+		_, snap.Proxied = providerProxies[name]
+		if snap.Requests > 0 {
+			snap.ReuseRatio = float64(snap.ReusedConns) / float64(snap.Requests)
+		}
+		out[name] = snap
+	}
+	return out
+}
@@ -0,0 +1,95 @@
+package currency
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"answerflow/commontypes"
+)
+
+// Routes assume USDT sits on Bybit. Users who keep it in a wallet pay a
+// network fee to move it there, or to withdraw what a conversion bought.
+// With USDT_NETWORK set (or ?usdt_network= on a request), conversions from
+// or to USDT get an extra result with that flat fee deducted. Fees are in
+// USDT per transfer, USDT_NETWORK_FEES overriding the defaults below as
+// "network=fee" pairs, e.g. "trc20=1.5,ton=0.1".
+var (
+	defaultUSDTNetwork = strings.ToLower(getEnvOrDefault("USDT_NETWORK", ""))
+	usdtNetworkFees    = loadUSDTNetworkFees(getEnvOrDefault("USDT_NETWORK_FEES", ""))
+)
+
+func loadUSDTNetworkFees(overrides string) map[string]float64 {
+	fees := map[string]float64{
+		"trc20":    1,
+		"erc20":    3,
+		"bep20":    0.3,
+		"ton":      0.3,
+		"sol":      1,
+		"arbitrum": 0.8,
+		"polygon":  0.8,
+	}
+	for _, pair := range splitList(overrides) {
+		network, value, ok := strings.Cut(pair, "=")
+		fee, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if !ok || err != nil || fee < 0 {
+			InvalidSetting("USDT_NETWORK_FEES", pair, "entries must be network=fee with a non-negative fee", "built-in fees")
+			continue
+		}
+		fees[strings.ToLower(strings.TrimSpace(network))] = fee
+	}
+	return fees
+}
+
+// usdtNetwork returns the requester's USDT network and its fee, if they
+// set a known one.
+func usdtNetwork(ctx context.Context) (string, float64, bool) {
+	network := commontypes.USDTNetworkFromContext(ctx)
+	if network == "" {
+		network = defaultUSDTNetwork
+	}
+	fee, ok := usdtNetworkFees[network]
+	return network, fee, ok
+}
+
+// addUSDTNetworkAlternative appends the conversion as it works out for USDT
+// held on the user's network: the fee comes off the USDT sent to the
+// exchange, or off the USDT withdrawn from it.
+func (m *CurrencyConverterModule) addUSDTNetworkAlternative(ctx context.Context, req *ConversionRequest, results []commontypes.FlowResult, apiCache *APICache) []commontypes.FlowResult {
+	if (req.FromCurrency == CurrencyUSDT) == (req.ToCurrency == CurrencyUSDT) {
+		return results
+	}
+	network, fee, ok := usdtNetwork(ctx)
+	if !ok || fee == 0 {
+		return results
+	}
+
+	var amount float64
+	var note string
+	if req.FromCurrency == CurrencyUSDT {
+		if req.Amount <= fee {
+			return results
+		}
+		converted, err := m.convert(ctx, req.Amount-fee, req.FromCurrency, req.ToCurrency, apiCache)
+		if err != nil {
+			return results
+		}
+		amount = converted
+		note = fmt.Sprintf(" | after %s USDT %s transfer to Bybit", strconv.FormatFloat(fee, 'f', -1, 64), strings.ToUpper(network))
+	} else {
+		converted, err := m.convert(ctx, req.Amount, req.FromCurrency, req.ToCurrency, apiCache)
+		if err != nil {
+			return results
+		}
+		amount = converted - fee
+		note = fmt.Sprintf(" | after %s USDT %s withdrawal", strconv.FormatFloat(fee, 'f', -1, 64), strings.ToUpper(network))
+	}
+	if amount = req.afterPersonalFee(amount); amount < minAmountAfterFees {
+		return results
+	}
+
+	main := results[len(results)-1]
+	alt := m.formatResult(req, req.ToCurrency, amount, amount/req.Amount, main.Score-1, "", req.personalFeeInfo()+note)
+	return append(results, *alt)
+}
@@ -0,0 +1,128 @@
+package currency
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"strings"
+
+	"answerflow/commontypes"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Conversion warnings flag amounts that cross legal or provider thresholds:
+// reporting requirements, exchange daily limits, card monthly limits. They
+// are data, not code, so the rules live in a YAML file; see
+// config/conversion_warnings.yaml for the format and the defaults.
+
+//go:embed config/conversion_warnings.yaml
+var embeddedWarningRulesYAML []byte
+
+type warningRule struct {
+	Name      string   `yaml:"name"`
+	Currency  string   `yaml:"currency"`
+	Min       float64  `yaml:"min"`
+	Providers []string `yaml:"providers"`
+	Message   string   `yaml:"message"`
+}
+
+var warningRules = loadWarningRules(getEnvOrDefault("CONVERSION_WARNINGS_FILE", ""))
+
+// loadWarningRules reads the rules from path, or the embedded defaults when
+// path is empty or invalid.
+func loadWarningRules(path string) []warningRule {
+	defaults, err := parseWarningRules(embeddedWarningRulesYAML)
+	if err != nil {
+		panic(fmt.Sprintf("embedded conversion warnings: %v", err))
+	}
+	if path == "" {
+		return defaults
+	}
+	data, err := os.ReadFile(path)
+	if err == nil {
+		var rules []warningRule
+		if rules, err = parseWarningRules(data); err == nil {
+			return rules
+		}
+	}
+	InvalidSetting("CONVERSION_WARNINGS_FILE", path, err.Error(), "built-in rules")
+	return defaults
+}
+
+func parseWarningRules(data []byte) ([]warningRule, error) {
+	var file struct {
+		Rules []warningRule `yaml:"rules"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	for i := range file.Rules {
+		r := &file.Rules[i]
+		r.Currency = strings.ToUpper(strings.TrimSpace(r.Currency))
+		switch {
+		case r.Currency == "":
+			return nil, fmt.Errorf("rule %d (%s): currency is required", i+1, r.Name)
+		case r.Min <= 0 || !isValidFloat(r.Min):
+			return nil, fmt.Errorf("rule %d (%s): min must be positive", i+1, r.Name)
+		case r.Message == "":
+			return nil, fmt.Errorf("rule %d (%s): message is required", i+1, r.Name)
+		}
+	}
+	return file.Rules, nil
+}
+
+// conversionWarnings returns the messages of the rules route triggers, in
+// file order.
+func conversionWarnings(route *Route) []string {
+	var warnings []string
+	for _, rule := range warningRules {
+		if len(rule.Providers) > 0 && !routeUsesAny(route, rule.Providers) {
+			continue
+		}
+		if route.largestAmountIn(rule.Currency) >= rule.Min {
+			warnings = append(warnings, rule.Message)
+		}
+	}
+	return warnings
+}
+
+// largestAmountIn is the most of code the route handles at any point.
+func (r *Route) largestAmountIn(code string) float64 {
+	var largest float64
+	if r.From == code {
+		largest = r.Amount
+	}
+	if r.To == code {
+		largest = max(largest, r.Result)
+	}
+	for _, leg := range r.Legs {
+		if leg.From == code {
+			largest = max(largest, leg.AmountIn)
+		}
+		if leg.To == code {
+			largest = max(largest, leg.AmountOut)
+		}
+	}
+	return largest
+}
+
+func routeUsesAny(route *Route, providers []string) bool {
+	for _, used := range route.Providers {
+		for _, p := range providers {
+			if strings.EqualFold(used, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// formatWarnings renders warnings for a result subtitle.
+func formatWarnings(warnings []string) string {
+	var b strings.Builder
+	for _, w := range warnings {
+		b.WriteString(" | " + commontypes.GlyphWarning + " " + w)
+	}
+	return b.String()
+}
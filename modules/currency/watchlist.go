@@ -0,0 +1,75 @@
+package currency
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"answerflow/commontypes"
+)
+
+// WATCHLIST is the pairs shown as a dashboard when the query is empty, i.e.
+// the launcher was just opened with the plugin keyword:
+//
+//	WATCHLIST=USD/RUB,TON/USDT,BTC/USD
+//
+// A client's profile can set its own list. Each pair is priced for one unit
+// of its first currency, with the same fees as any other conversion.
+var watchlist = loadWatchlist(getEnvOrDefault("WATCHLIST", ""))
+
+func loadWatchlist(value string) []string {
+	var pairs []string
+	for _, pair := range splitList(value) {
+		pair = strings.ToUpper(pair)
+		if _, _, ok := SplitWatchPair(pair); !ok {
+			InvalidSetting("WATCHLIST", value, fmt.Sprintf("%q is not a FROM/TO pair", pair), "no watchlist")
+			return nil
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs
+}
+
+// SplitWatchPair splits a watchlist entry such as "USD/RUB".
+func SplitWatchPair(pair string) (from, to string, ok bool) {
+	from, to, ok = strings.Cut(pair, "/")
+	from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+	return from, to, ok && from != "" && to != "" && !strings.Contains(to, "/")
+}
+
+// generateWatchlist prices the requester's watchlist, or the configured one,
+// in list order. Pairs with a currency this deployment doesn't know are
+// skipped.
+func (m *CurrencyConverterModule) generateWatchlist(ctx context.Context, apiCache *APICache) []commontypes.FlowResult {
+	pairs := commontypes.WatchlistFromContext(ctx)
+	if len(pairs) == 0 {
+		pairs = watchlist
+	}
+	home := m.requestHome(ctx, apiCache)
+	var results []commontypes.FlowResult
+	for i, pair := range pairs {
+		from, to, ok := SplitWatchPair(pair)
+		if !ok {
+			continue
+		}
+		var err error
+		if from, err = m.currencyData.ResolveCurrency(from); err != nil {
+			continue
+		}
+		if to, err = m.currencyData.ResolveCurrency(to); err != nil || from == to {
+			continue
+		}
+		req := &ConversionRequest{Amount: 1, FromCurrency: from, ToCurrency: to, Home: home}
+		res, route, err := m.generateConversionResult(ctx, req, to, apiCache, scoreWatchlist-i)
+		if err != nil {
+			if er := m.makeErrorResult(req, to, err); er != nil {
+				results = append(results, *er)
+			}
+			continue
+		}
+		// One unit reads as a rate: "1 TON = 5.41 USDT"
+		res.Title = fmt.Sprintf("1 %s = %s %s", from, formatRate(route.Result), to)
+		results = append(results, *res)
+	}
+	return results
+}
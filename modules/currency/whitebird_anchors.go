@@ -0,0 +1,164 @@
+package currency
+
+import (
+	"context"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Whitebird's effective rate depends on the amount, so a quote is only good
+// for amounts near the one quoted. Instead of a live quote per amount, a
+// handful of anchor amounts per direction are quoted every
+// WHITEBIRD_ANCHOR_INTERVAL, and amounts between two anchors get a rate
+// interpolated between theirs. Amounts outside the anchors still get a live
+// quote. Anchors are quoted around the clock whether or not anyone asks, so
+// anchoring is off (0) unless an interval is set.
+var (
+	whitebirdAnchorInterval = getEnvDurationOrDefault("WHITEBIRD_ANCHOR_INTERVAL", 0)
+
+	// Anchor amounts are in the source currency of each direction
+	whitebirdAnchorDirections = []struct {
+		from, to string
+		amounts  []float64
+	}{
+		{CurrencyRUB, CurrencyTON, anchorAmountsSetting("WHITEBIRD_ANCHORS_RUB", []float64{1000, 10000, 100000, 1000000})},
+		{CurrencyTON, CurrencyRUB, anchorAmountsSetting("WHITEBIRD_ANCHORS_TON", []float64{5, 50, 500, 5000})},
+	}
+)
+
+// whitebirdAnchor is the effective rate quoted for one anchor amount.
+type whitebirdAnchor struct {
+	amount    float64
+	rate      float64
+	fetchedAt time.Time
+}
+
+// whitebirdAnchors holds the latest anchors per "FROM/TO" pair, ordered by
+// amount.
+var whitebirdAnchors = struct {
+	mu    sync.RWMutex
+	pairs map[string][]whitebirdAnchor
+}{pairs: make(map[string][]whitebirdAnchor)}
+
+// anchorAmountsSetting reads a comma-separated list of positive amounts,
+// sorted ascending. A list with a bad entry is rejected as a whole.
+func anchorAmountsSetting(key string, fallback []float64) []float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	var amounts []float64
+	for _, part := range splitList(value) {
+		amount, err := strconv.ParseFloat(part, 64)
+		if err != nil || !(amount > 0) || math.IsInf(amount, 0) {
+			InvalidSetting(key, value, "not a list of positive amounts", fallback)
+			return fallback
+		}
+		amounts = append(amounts, amount)
+	}
+	sort.Float64s(amounts)
+	return amounts
+}
+
+// interpolatedWhitebirdRate returns the effective rate for amount from the
+// two fresh anchors around it, linear in the logarithm of the amount: the
+// rate drifts with the order of magnitude rather than the amount itself.
+func interpolatedWhitebirdRate(from, to string, amount float64) (float64, bool) {
+	if whitebirdAnchorInterval <= 0 {
+		return 0, false
+	}
+	whitebirdAnchors.mu.RLock()
+	anchors := whitebirdAnchors.pairs[from+"/"+to]
+	whitebirdAnchors.mu.RUnlock()
+
+	i := sort.Search(len(anchors), func(i int) bool { return anchors[i].amount >= amount })
+	if i == len(anchors) {
+		return 0, false
+	}
+	hi := anchors[i]
+	if !hi.fresh() {
+		return 0, false
+	}
+	if hi.amount == amount {
+		return hi.rate, true
+	}
+	if i == 0 {
+		return 0, false
+	}
+	lo := anchors[i-1]
+	if !lo.fresh() {
+		return 0, false
+	}
+	t := math.Log(amount/lo.amount) / math.Log(hi.amount/lo.amount)
+	return lo.rate + t*(hi.rate-lo.rate), true
+}
+
+// fresh reports whether the anchor is recent enough to interpolate from;
+// one missed refresh is tolerated.
+func (a whitebirdAnchor) fresh() bool {
+	return time.Since(a.fetchedAt) < 2*whitebirdAnchorInterval
+}
+
+// runWhitebirdAnchors refreshes the anchors every whitebirdAnchorInterval
+// until shutdown, pausing with the other updaters while idle.
+func (ac *APICache) runWhitebirdAnchors() {
+	ac.refreshWhitebirdAnchors()
+
+	ticker := time.NewTicker(whitebirdAnchorInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ac.idleWakeChan():
+			ticker.Reset(whitebirdAnchorInterval)
+		case <-ac.shutdownChan:
+			return
+		}
+		if ac.suspendedForIdle() {
+			continue
+		}
+		ac.refreshWhitebirdAnchors()
+	}
+}
+
+// refreshWhitebirdAnchors quotes every anchor amount. An anchor that fails
+// keeps its previous quote until it goes stale, so one failure only widens
+// the gap between its neighbours.
+func (ac *APICache) refreshWhitebirdAnchors() {
+	if !whitebirdCircuit.CanAttempt() {
+		return
+	}
+	for _, dir := range whitebirdAnchorDirections {
+		pair := dir.from + "/" + dir.to
+		fetched := make(map[float64]whitebirdAnchor, len(dir.amounts))
+		for _, amount := range dir.amounts {
+			ctx, cancel := context.WithTimeout(context.Background(), whitebirdAPITimeout)
+			output, err := ac.fetchSingleWhitebirdConversion(ctx, dir.from, dir.to, amount)
+			cancel()
+			if err != nil {
+				log.Printf("Warning: Whitebird anchor quote %s %v failed: %v", pair, amount, err)
+				continue
+			}
+			fetched[amount] = whitebirdAnchor{amount: amount, rate: output / amount, fetchedAt: time.Now()}
+		}
+
+		whitebirdAnchors.mu.Lock()
+		for _, previous := range whitebirdAnchors.pairs[pair] {
+			if _, ok := fetched[previous.amount]; !ok {
+				fetched[previous.amount] = previous
+			}
+		}
+		anchors := make([]whitebirdAnchor, 0, len(fetched))
+		for _, anchor := range fetched {
+			anchors = append(anchors, anchor)
+		}
+		sort.Slice(anchors, func(i, j int) bool { return anchors[i].amount < anchors[j].amount })
+		whitebirdAnchors.pairs[pair] = anchors
+		whitebirdAnchors.mu.Unlock()
+	}
+}
@@ -0,0 +1,83 @@
+package currency
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Whitebird switches its RUB operations off now and then (maintenance,
+// outside banking hours), which a quote only reveals when one is asked for.
+// A small probe quote every WHITEBIRD_PROBE_INTERVAL finds out ahead of
+// conversions, so RUB routes and /health can say why the bridge is down.
+
+// whitebirdProbeAmount is a RUB amount well inside Whitebird's limits.
+const whitebirdProbeAmount = 10000
+
+// WhitebirdOperation is what Whitebird last reported about its RUB
+// operations, by the probe or a conversion.
+type WhitebirdOperation struct {
+	Enabled   bool      `json:"enabled"`
+	Status    string    `json:"status,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// probeWhitebird asks for one RUB->TON quote and records the reported
+// operation status. A disabled operation is an error, so the update loop
+// marks the provider down and alerts as for any failing provider.
+func (ac *APICache) probeWhitebird() error {
+	ctx, cancel := context.WithTimeout(context.Background(), whitebirdAPITimeout)
+	defer cancel()
+
+	amount := float64(whitebirdProbeAmount)
+	wbResp, err := ac.requestWhitebirdCalculation(ctx, CurrencyRUB, CurrencyTON, whitebirdCalculation{InputAsset: &amount})
+	if err != nil {
+		return err
+	}
+	ac.recordWhitebirdOperation(wbResp.OperationStatus.Enabled, wbResp.OperationStatus.Status)
+	if !wbResp.OperationStatus.Enabled {
+		return fmt.Errorf("operation not enabled: %s", wbResp.OperationStatus.Status)
+	}
+	return nil
+}
+
+// recordWhitebirdOperation keeps the latest operation status, logging when
+// it changes.
+func (ac *APICache) recordWhitebirdOperation(enabled bool, status string) {
+	ac.mu.Lock()
+	previous := ac.whitebirdOperation
+	ac.whitebirdOperation = WhitebirdOperation{Enabled: enabled, Status: status, CheckedAt: time.Now()}
+	ac.mu.Unlock()
+
+	if previous.CheckedAt.IsZero() || previous.Enabled != enabled {
+		if enabled {
+			log.Printf("Info: Whitebird operations enabled")
+		} else {
+			log.Printf("Warning: Whitebird operations disabled: %s", status)
+		}
+	}
+}
+
+// WhitebirdOperation returns the latest reported operation status, and false
+// if Whitebird hasn't been asked yet or the RUB bridge is off.
+func (ac *APICache) WhitebirdOperation() (WhitebirdOperation, bool) {
+	if !providerEnabled(providerWhitebird) {
+		return WhitebirdOperation{}, false
+	}
+	ac.mu.RLock()
+	defer ac.mu.RUnlock()
+	return ac.whitebirdOperation, !ac.whitebirdOperation.CheckedAt.IsZero()
+}
+
+// whitebirdUnavailableError explains why a RUB route can't be quoted,
+// quoting Whitebird's own status message when it has reported one.
+func (ac *APICache) whitebirdUnavailableError() error {
+	if op, ok := ac.WhitebirdOperation(); ok && !op.Enabled {
+		if op.Status == "" {
+			return fmt.Errorf("russian ruble exchange is disabled by Whitebird")
+		}
+		return fmt.Errorf("russian ruble exchange is disabled by Whitebird: %s", op.Status)
+	}
+	return fmt.Errorf("russian ruble exchange temporarily unavailable")
+}
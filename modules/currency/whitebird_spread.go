@@ -0,0 +1,72 @@
+package currency
+
+import (
+	"context"
+	"fmt"
+
+	"answerflow/commontypes"
+)
+
+// Whitebird buys and sells TON at noticeably different prices, which a
+// one-way quote hides. With WHITEBIRD_BOTH_DIRECTIONS, a direct RUB↔TON
+// conversion also gets the opposite direction's quote for the same amount,
+// with both prices and the spread between them.
+var whitebirdBothDirections = getEnvBoolOrDefault("WHITEBIRD_BOTH_DIRECTIONS", false)
+
+// addWhitebirdOppositeQuote appends the reverse of a direct RUB↔TON
+// conversion: selling back the TON that rubles buy, or buying back the TON
+// that was sold. Prices are Whitebird's own, before the TON withdrawal fees
+// the main result includes.
+func (m *CurrencyConverterModule) addWhitebirdOppositeQuote(ctx context.Context, req *ConversionRequest, results []commontypes.FlowResult, apiCache *APICache) []commontypes.FlowResult {
+	if !whitebirdBothDirections || req.Provisional || len(results) == 0 || !apiCache.IsWhitebirdAvailable() {
+		return results
+	}
+
+	var rub, ton, back float64
+	var err error
+	var title, clipboard string
+	switch {
+	case req.FromCurrency == CurrencyRUB && req.ToCurrency == CurrencyTON:
+		rub = req.Amount
+		if ton, err = apiCache.GetWhitebirdRateForAmount(ctx, CurrencyRUB, CurrencyTON, rub); err != nil {
+			return results
+		}
+		if back, err = apiCache.GetWhitebirdRateForAmount(ctx, CurrencyTON, CurrencyRUB, ton); err != nil {
+			return results
+		}
+		title = fmt.Sprintf("%s TON → %s RUB", formatAmount(ton, CurrencyTON), formatAmount(back, CurrencyRUB))
+		clipboard = formatAmountForClipboard(back, CurrencyRUB) + " RUB"
+	case req.FromCurrency == CurrencyTON && req.ToCurrency == CurrencyRUB:
+		ton = req.Amount
+		if rub, err = apiCache.GetWhitebirdRateForAmount(ctx, CurrencyTON, CurrencyRUB, ton); err != nil {
+			return results
+		}
+		if back, err = apiCache.GetWhitebirdRateForAmount(ctx, CurrencyRUB, CurrencyTON, rub); err != nil {
+			return results
+		}
+		title = fmt.Sprintf("%s RUB → %s TON", formatAmount(rub, CurrencyRUB), formatAmount(back, CurrencyTON))
+		clipboard = formatAmountForClipboard(back, CurrencyTON) + " TON"
+	default:
+		return results
+	}
+
+	// RUB per TON on each side; a round trip loses the spread
+	buyPrice, sellPrice := rub/ton, back/ton
+	if req.FromCurrency == CurrencyTON {
+		buyPrice, sellPrice = rub/back, rub/ton
+	}
+	if !isValidFloat(buyPrice) || !isValidFloat(sellPrice) || buyPrice <= 0 {
+		return results
+	}
+	spread := (buyPrice - sellPrice) / buyPrice * 100
+
+	res := commontypes.FlowResult{
+		Title: title,
+		SubTitle: fmt.Sprintf("Whitebird, opposite direction | buy 1 TON = %s RUB, sell 1 TON = %s RUB | spread %.2f%%",
+			formatRate(buyPrice), formatRate(sellPrice), spread),
+		IcoPath:       assetIcon(req.ToCurrency, req.FromCurrency),
+		Score:         results[len(results)-1].Score - 1,
+		JsonRPCAction: commontypes.CopyToClipboard(clipboard),
+	}
+	return append(results, res)
+}
@@ -0,0 +1,237 @@
+package currency
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WireLogEntry is one provider HTTP exchange as recorded by the wire log.
+// Credentials are redacted and bodies truncated to wireLogBodyBytes.
+type WireLogEntry struct {
+	Time         time.Time `json:"time"`
+	Provider     string    `json:"provider"`
+	Method       string    `json:"method"`
+	URL          string    `json:"url"`
+	Status       int       `json:"status,omitempty"`
+	Duration     string    `json:"duration"`
+	Error        string    `json:"error,omitempty"`
+	RequestBody  string    `json:"request_body,omitempty"`
+	ResponseBody string    `json:"response_body,omitempty"`
+	Truncated    bool      `json:"truncated,omitempty"`
+}
+
+// wireLogRing keeps the last wireLogSize exchanges.
+type wireLogRing struct {
+	mu      sync.Mutex
+	entries []WireLogEntry
+	next    int
+	full    bool
+}
+
+func newWireLogRing(size int) *wireLogRing {
+	return &wireLogRing{entries: make([]WireLogEntry, size)}
+}
+
+func (r *wireLogRing) add(e WireLogEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// snapshot returns the recorded exchanges, newest first.
+func (r *wireLogRing) snapshot() []WireLogEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := r.next
+	if r.full {
+		n = len(r.entries)
+	}
+	out := make([]WireLogEntry, 0, n)
+	for i := 1; i <= n; i++ {
+		out = append(out, r.entries[(r.next-i+len(r.entries))%len(r.entries)])
+	}
+	return out
+}
+
+// WireLog returns recent provider exchanges, newest first, optionally only
+// those of one provider. It is empty unless WIRE_LOG is enabled.
+func (ac *APICache) WireLog(provider string) []WireLogEntry {
+	if ac.wireLog == nil {
+		return []WireLogEntry{}
+	}
+	entries := ac.wireLog.snapshot()
+	if provider == "" {
+		return entries
+	}
+	filtered := entries[:0]
+	for _, e := range entries {
+		if e.Provider == provider {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+// wireLogTransport records every round trip of the provider client.
+type wireLogTransport struct {
+	next http.RoundTripper
+	ring *wireLogRing
+}
+
+func (t *wireLogTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	entry := WireLogEntry{
+		Time:     time.Now(),
+		Provider: providerForURL(req.URL),
+		Method:   req.Method,
+		URL:      redactURL(req.URL),
+	}
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			prefix, truncated := readPrefix(body, wireLogBodyBytes)
+			body.Close()
+			entry.RequestBody = redactBody(string(prefix))
+			entry.Truncated = truncated
+		}
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	entry.Duration = time.Since(entry.Time).Round(time.Millisecond).String()
+	if err != nil {
+		entry.Error = err.Error()
+		t.ring.add(entry)
+		return nil, err
+	}
+	entry.Status = resp.StatusCode
+	// The response is recorded once the caller has read and closed the body
+	resp.Body = &wireLogBody{
+		ReadCloser: resp.Body,
+		entry:      entry,
+		encoding:   resp.Header.Get("Content-Encoding"),
+		ring:       t.ring,
+	}
+	return resp, nil
+}
+
+// wireLogBody copies the first wireLogBodyBytes read by the caller.
+type wireLogBody struct {
+	io.ReadCloser
+	entry    WireLogEntry
+	encoding string
+	ring     *wireLogRing
+	buf      bytes.Buffer
+	seen     int
+	eof      bool
+	once     sync.Once
+}
+
+func (b *wireLogBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if room := wireLogBodyBytes - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(n, room)])
+	}
+	b.seen += n
+	b.eof = b.eof || err == io.EOF
+	return n, err
+}
+
+func (b *wireLogBody) Close() error {
+	b.once.Do(func() {
+		// Error responses are usually closed unread; keep their start anyway
+		if room := wireLogBodyBytes - b.buf.Len(); !b.eof && room > 0 {
+			n, _ := io.CopyN(&b.buf, b.ReadCloser, int64(room))
+			b.seen += int(n)
+		}
+		body := b.buf.Bytes()
+		if b.encoding == "gzip" {
+			// A truncated gzip stream still decompresses up to the cut
+			if zr, err := gzip.NewReader(bytes.NewReader(body)); err == nil {
+				body, _ = io.ReadAll(io.LimitReader(zr, int64(wireLogBodyBytes)))
+			}
+		} else if b.encoding != "" && b.encoding != "identity" {
+			body = []byte("[" + b.encoding + "-encoded body omitted]")
+		}
+		b.entry.ResponseBody = redactBody(string(body))
+		b.entry.Truncated = b.entry.Truncated || b.seen > wireLogBodyBytes
+		b.ring.add(b.entry)
+	})
+	return b.ReadCloser.Close()
+}
+
+func readPrefix(r io.Reader, limit int) ([]byte, bool) {
+	data, _ := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if len(data) > limit {
+		return data[:limit], true
+	}
+	return data, false
+}
+
+// providerForURL names the provider whose configured endpoint shares the
+// request's host, falling back to the host itself.
+func providerForURL(u *url.URL) string {
+	endpoints := []struct{ provider, url string }{
+		{providerBybit, bybitOrderbookURL},
+		{providerMastercard, mastercardAPIURL},
+		{providerWhitebird, whitebirdAPIURL},
+		{providerECB, ecbBaselineURL},
+		{providerCBR, cbrRatesURL},
+		{providerCoinGecko, coingeckoAPIURL},
+		{providerCoinGecko, coingeckoProAPIURL},
+	}
+	for _, e := range endpoints {
+		if parsed, err := url.Parse(e.url); err == nil && parsed.Host == u.Host {
+			return e.provider
+		}
+	}
+	if bybitEndpoints.hasHost(u.Host) {
+		return providerBybit
+	}
+	return u.Host
+}
+
+// isSensitiveName matches query parameter and JSON field names that carry
+// credentials or signatures.
+func isSensitiveName(name string) bool {
+	name = strings.ToLower(name)
+	for _, marker := range []string{"key", "sign", "token", "secret", "password", "auth", "cookie"} {
+		if strings.Contains(name, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactURL(u *url.URL) string {
+	query := u.Query()
+	for name := range query {
+		if isSensitiveName(name) {
+			query.Set(name, "[redacted]")
+		}
+	}
+	redacted := *u
+	redacted.User = nil
+	redacted.RawQuery = query.Encode()
+	return redacted.String()
+}
+
+var regexJSONStringField = regexp.MustCompile(`"([^"]+)"\s*:\s*"[^"]*"`)
+
+func redactBody(body string) string {
+	return regexJSONStringField.ReplaceAllStringFunc(body, func(field string) string {
+		name := regexJSONStringField.FindStringSubmatch(field)[1]
+		if !isSensitiveName(name) {
+			return field
+		}
+		return `"` + name + `":"[redacted]"`
+	})
+}
@@ -0,0 +1,224 @@
+package currency
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"answerflow/commontypes"
+)
+
+// maxWordsAmount is the largest amount written out in words: the scales
+// below stop at trillions.
+const maxWordsAmount = 1e15
+
+var (
+	enOnes  = []string{"", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine", "ten", "eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen", "seventeen", "eighteen", "nineteen"}
+	enTens  = []string{"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety"}
+	enScale = []string{"", "thousand", "million", "billion", "trillion"}
+
+	ruOnesMasc = []string{"", "один", "два", "три", "четыре", "пять", "шесть", "семь", "восемь", "девять", "десять", "одиннадцать", "двенадцать", "тринадцать", "четырнадцать", "пятнадцать", "шестнадцать", "семнадцать", "восемнадцать", "девятнадцать"}
+	ruTens     = []string{"", "", "двадцать", "тридцать", "сорок", "пятьдесят", "шестьдесят", "семьдесят", "восемьдесят", "девяносто"}
+	ruHundreds = []string{"", "сто", "двести", "триста", "четыреста", "пятьсот", "шестьсот", "семьсот", "восемьсот", "девятьсот"}
+)
+
+// ruForms are the Russian forms of a noun after 1, 2-4 and 5+ ("рубль",
+// "рубля", "рублей"), and whether it is feminine.
+type ruForms struct {
+	one, few, many string
+	feminine       bool
+}
+
+func (f ruForms) pick(n int64) string {
+	switch {
+	case n%100 >= 11 && n%100 <= 14:
+		return f.many
+	case n%10 == 1:
+		return f.one
+	case n%10 >= 2 && n%10 <= 4:
+		return f.few
+	}
+	return f.many
+}
+
+var ruScale = []ruForms{
+	{},
+	{"тысяча", "тысячи", "тысяч", true},
+	{"миллион", "миллиона", "миллионов", false},
+	{"миллиард", "миллиарда", "миллиардов", false},
+	{"триллион", "триллиона", "триллионов", false},
+}
+
+// ruCurrencyNames are the currencies Russian payment documents name in
+// full, with their minor unit; others are written with their code.
+var ruCurrencyNames = map[string][2]ruForms{
+	CurrencyRUB: {{"рубль", "рубля", "рублей", false}, {"копейка", "копейки", "копеек", true}},
+	CurrencyUSD: {{"доллар США", "доллара США", "долларов США", false}, {"цент", "цента", "центов", false}},
+	CurrencyEUR: {{"евро", "евро", "евро", false}, {"цент", "цента", "центов", false}},
+}
+
+// amountInWords writes amount of code out in words, the way payment
+// documents want it: the whole units spelled out, the minor units as digits.
+// "1234.56 USD" is "One thousand two hundred thirty-four and 56/100 USD" in
+// English and "Одна тысяча двести тридцать четыре доллара США 56 центов" in
+// Russian. It returns "" for amounts it cannot spell.
+func amountInWords(amount float64, code, lang string) string {
+	decimals := GetCurrencyDecimalPlaces(code)
+	scale := math.Pow10(decimals)
+	if !isValidFloat(amount) || amount >= maxWordsAmount || amount*scale >= math.MaxInt64 {
+		return ""
+	}
+	minorTotal := int64(math.Round(amount * scale))
+	whole, minor := minorTotal/int64(scale), minorTotal%int64(scale)
+
+	if lang == "ru" {
+		names, named := ruCurrencyNames[code]
+		text := ruNumberWords(whole, names[0].feminine)
+		if named {
+			text += " " + names[0].pick(whole)
+			if decimals > 0 {
+				text += fmt.Sprintf(" %0*d %s", decimals, minor, names[1].pick(minor))
+			}
+		} else {
+			text += " " + code
+			if decimals > 0 {
+				text += fmt.Sprintf(" %0*d/%d", decimals, minor, int64(scale))
+			}
+		}
+		return capitalize(text)
+	}
+
+	text := enNumberWords(whole)
+	if decimals > 0 {
+		text += fmt.Sprintf(" and %0*d/%d", decimals, minor, int64(scale))
+	}
+	return capitalize(text + " " + code)
+}
+
+func enNumberWords(n int64) string {
+	if n == 0 {
+		return "zero"
+	}
+	var parts []string
+	for i := len(enScale) - 1; i >= 0; i-- {
+		group := (n / int64(math.Pow10(3*i))) % 1000
+		if group == 0 {
+			continue
+		}
+		words := enGroupWords(group)
+		if enScale[i] != "" {
+			words += " " + enScale[i]
+		}
+		parts = append(parts, words)
+	}
+	return strings.Join(parts, " ")
+}
+
+// enGroupWords spells out 1-999: "two hundred thirty-four".
+func enGroupWords(n int64) string {
+	var parts []string
+	if n >= 100 {
+		parts = append(parts, enOnes[n/100]+" hundred")
+		n %= 100
+	}
+	switch {
+	case n >= 20 && n%10 != 0:
+		parts = append(parts, enTens[n/10]+"-"+enOnes[n%10])
+	case n >= 20:
+		parts = append(parts, enTens[n/10])
+	case n > 0:
+		parts = append(parts, enOnes[n])
+	}
+	return strings.Join(parts, " ")
+}
+
+// ruNumberWords spells out n, with one and two in the feminine form when
+// the counted noun is feminine.
+func ruNumberWords(n int64, feminine bool) string {
+	if n == 0 {
+		return "ноль"
+	}
+	var parts []string
+	for i := len(ruScale) - 1; i >= 0; i-- {
+		group := (n / int64(math.Pow10(3*i))) % 1000
+		if group == 0 {
+			continue
+		}
+		groupFeminine := ruScale[i].feminine
+		if i == 0 {
+			groupFeminine = feminine
+		}
+		words := ruGroupWords(group, groupFeminine)
+		if i > 0 {
+			words += " " + ruScale[i].pick(group)
+		}
+		parts = append(parts, words)
+	}
+	return strings.Join(parts, " ")
+}
+
+// ruGroupWords spells out 1-999: "двести тридцать четыре".
+func ruGroupWords(n int64, feminine bool) string {
+	var parts []string
+	if n >= 100 {
+		parts = append(parts, ruHundreds[n/100])
+		n %= 100
+	}
+	if n >= 20 {
+		parts = append(parts, ruTens[n/10])
+		n %= 10
+	}
+	switch {
+	case n == 1 && feminine:
+		parts = append(parts, "одна")
+	case n == 2 && feminine:
+		parts = append(parts, "две")
+	case n > 0:
+		parts = append(parts, ruOnesMasc[n])
+	}
+	return strings.Join(parts, " ")
+}
+
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+// amountWordsEnabled reports whether req gets its amount in words.
+func amountWordsEnabled(req *ConversionRequest) bool {
+	return req.Words || amountWordsResults
+}
+
+// wordsLang is the requester's language when words exist for it,
+// AMOUNT_WORDS_LANG otherwise.
+func wordsLang(ctx context.Context) string {
+	switch lang := commontypes.LanguageFromContext(ctx); lang {
+	case "en", "ru":
+		return lang
+	}
+	return amountWordsLang
+}
+
+// generateWordsResult shows amount of code written out in words, copied as
+// shown.
+func (m *CurrencyConverterModule) generateWordsResult(ctx context.Context, amount float64, code string) *commontypes.FlowResult {
+	lang := wordsLang(ctx)
+	text := amountInWords(amount, code, lang)
+	if text == "" {
+		return nil
+	}
+	subtitle := fmt.Sprintf("%s %s in words", formatAmount(amount, code), code)
+	if lang == "ru" {
+		subtitle = fmt.Sprintf("%s %s прописью", formatAmount(amount, code), code)
+	}
+	return &commontypes.FlowResult{
+		Title:         text,
+		SubTitle:      subtitle,
+		IcoPath:       assetIcon(code, code),
+		Score:         scoreAmountWords,
+		JsonRPCAction: commontypes.CopyToClipboard(text),
+	}
+}
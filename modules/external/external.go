@@ -0,0 +1,127 @@
+// Package external runs answer sources that live outside the binary, declared
+// in a JSON file (EXTERNAL_MODULES_FILE):
+//
+//	[{"name": "notes", "command": ["python3", "notes.py"], "timeout": "2s"},
+//	 {"name": "work", "url": "https://answers.internal/", "score_scale": 0.5},
+//	 {"name": "units", "wasm": "plugins/units.wasm"}]
+//
+// A module is a subprocess (command), a remote service (url) or a sandboxed
+// WebAssembly plugin (wasm).
+package external
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"time"
+
+	"answerflow/commontypes"
+	"answerflow/modules"
+)
+
+const (
+	defaultTimeout        = 3 * time.Second
+	defaultHealthInterval = 30 * time.Second
+	defaultMaxConcurrency = 4
+)
+
+// Config declares one external module.
+type Config struct {
+	Name           string   `json:"name"`
+	Command        []string `json:"command,omitempty"`         // program and arguments of a subprocess module
+	URL            string   `json:"url,omitempty"`             // query endpoint of a remote module
+	Wasm           string   `json:"wasm,omitempty"`            // path of a WebAssembly plugin
+	Icon           string   `json:"icon,omitempty"`            // default icon for its results
+	Group          string   `json:"group,omitempty"`           // section label for its results, default the name
+	Timeout        string   `json:"timeout,omitempty"`         // per query, e.g. "2s"
+	HealthInterval string   `json:"health_interval,omitempty"` // between pings, "0" disables
+	MaxConcurrency int      `json:"max_concurrency,omitempty"`
+	ScoreScale     float64  `json:"score_scale,omitempty"` // multiplies result scores, default 1
+
+	timeout        time.Duration
+	healthInterval time.Duration
+}
+
+// LoadConfig reads and validates an external module file.
+func LoadConfig(path string) ([]Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var configs []Config
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	seen := make(map[string]bool)
+	for i := range configs {
+		c := &configs[i]
+		if c.Name == "" {
+			return nil, fmt.Errorf("module %d: missing name", i)
+		}
+		if seen[c.Name] {
+			return nil, fmt.Errorf("module %s: declared twice", c.Name)
+		}
+		seen[c.Name] = true
+		kinds := 0
+		for _, set := range []bool{len(c.Command) > 0, c.URL != "", c.Wasm != ""} {
+			if set {
+				kinds++
+			}
+		}
+		if kinds != 1 {
+			return nil, fmt.Errorf("module %s: needs exactly one of command, url and wasm", c.Name)
+		}
+		if c.URL != "" {
+			if u, err := url.Parse(c.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				return nil, fmt.Errorf("module %s: invalid url %q", c.Name, c.URL)
+			}
+		}
+		if c.timeout, err = parseDuration(c.Timeout, defaultTimeout); err != nil {
+			return nil, fmt.Errorf("module %s: timeout: %w", c.Name, err)
+		}
+		if c.healthInterval, err = parseDuration(c.HealthInterval, defaultHealthInterval); err != nil {
+			return nil, fmt.Errorf("module %s: health_interval: %w", c.Name, err)
+		}
+		if c.MaxConcurrency <= 0 {
+			c.MaxConcurrency = defaultMaxConcurrency
+		}
+		if c.ScoreScale <= 0 {
+			c.ScoreScale = 1
+		}
+		if c.Group == "" {
+			c.Group = c.Name
+		}
+	}
+	return configs, nil
+}
+
+// New returns the module declared by cfg.
+func New(cfg Config) (modules.Module, error) {
+	switch {
+	case cfg.URL != "":
+		return NewRemoteModule(cfg), nil
+	case cfg.Wasm != "":
+		return NewWasmModule(cfg)
+	}
+	return NewSubprocessModule(cfg), nil
+}
+
+// scaleScores applies the module's score scale to results in place.
+func (c Config) scaleScores(results []commontypes.FlowResult) {
+	if c.ScoreScale == 1 {
+		return
+	}
+	for i := range results {
+		results[i].Score = int(math.Round(float64(results[i].Score) * c.ScoreScale))
+	}
+}
+
+func parseDuration(s string, defaultValue time.Duration) (time.Duration, error) {
+	if s == "" {
+		return defaultValue, nil
+	}
+	return time.ParseDuration(s)
+}
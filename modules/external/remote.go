@@ -0,0 +1,68 @@
+package external
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"answerflow/commontypes"
+	"answerflow/modules/currency"
+)
+
+// maxRemoteResponse bounds how much of a remote's answer is read.
+const maxRemoteResponse = 4 << 20
+
+// RemoteModule forwards queries to another answer service: GET <url>?q=
+// returning a JSON array of results, which is what answerflow's own query
+// endpoint serves, so instances can be chained.
+type RemoteModule struct {
+	cfg    Config
+	client *http.Client
+}
+
+// NewRemoteModule prepares a module for cfg.
+func NewRemoteModule(cfg Config) *RemoteModule {
+	return &RemoteModule{cfg: cfg, client: &http.Client{}}
+}
+
+func (m *RemoteModule) Name() string            { return m.cfg.Name }
+func (m *RemoteModule) DefaultIconPath() string { return m.cfg.Icon }
+func (m *RemoteModule) MaxConcurrency() int     { return m.cfg.MaxConcurrency }
+func (m *RemoteModule) ResultGroup() string     { return m.cfg.Group }
+
+func (m *RemoteModule) ProcessQuery(ctx context.Context, query string, _ *currency.APICache) ([]commontypes.FlowResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.timeout)
+	defer cancel()
+
+	u, err := url.Parse(m.cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	params := u.Query()
+	params.Set("q", query)
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("module %s: %w", m.cfg.Name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("module %s: status %d", m.cfg.Name, resp.StatusCode)
+	}
+
+	var results []commontypes.FlowResult
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxRemoteResponse)).Decode(&results); err != nil {
+		return nil, fmt.Errorf("module %s: failed to decode results: %w", m.cfg.Name, err)
+	}
+	m.cfg.scaleScores(results)
+	return results, nil
+}
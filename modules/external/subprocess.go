@@ -0,0 +1,266 @@
+package external
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"sync"
+	"time"
+
+	"answerflow/commontypes"
+	"answerflow/modules/currency"
+)
+
+// SubprocessModule talks to a long-running child process over stdin/stdout,
+// one JSON object per line:
+//
+//	→ {"id": 1, "query": "todo buy milk"}
+//	← {"id": 1, "results": [{"Title": "...", "SubTitle": "..."}]}
+//	← {"id": 1, "error": "..."}
+//
+// Requests carry increasing ids and may be answered out of order. A request
+// with "ping": true is a health check, answered with just its id. The
+// process should exit when stdin closes; anything it writes to stderr is
+// logged. A process that dies or fails a health check is restarted on the
+// next query.
+type SubprocessModule struct {
+	cfg Config
+
+	mu      sync.Mutex
+	proc    *process
+	nextID  int64
+	stopped chan struct{}
+}
+
+type request struct {
+	ID    int64  `json:"id"`
+	Query string `json:"query,omitempty"`
+	Ping  bool   `json:"ping,omitempty"`
+}
+
+type response struct {
+	ID      int64                    `json:"id"`
+	Results []commontypes.FlowResult `json:"results"`
+	Error   string                   `json:"error,omitempty"`
+}
+
+// process is one run of the child; a restart replaces it as a whole.
+type process struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	pending map[int64]chan response
+	done    chan struct{} // closed when stdout ends
+}
+
+// NewSubprocessModule prepares a module for cfg. The process starts with
+// the first query, so a broken module doesn't hold up server start.
+func NewSubprocessModule(cfg Config) *SubprocessModule {
+	m := &SubprocessModule{cfg: cfg, stopped: make(chan struct{})}
+	if cfg.healthInterval > 0 {
+		go m.healthLoop()
+	}
+	return m
+}
+
+func (m *SubprocessModule) Name() string            { return m.cfg.Name }
+func (m *SubprocessModule) DefaultIconPath() string { return m.cfg.Icon }
+func (m *SubprocessModule) MaxConcurrency() int     { return m.cfg.MaxConcurrency }
+func (m *SubprocessModule) ResultGroup() string     { return m.cfg.Group }
+
+func (m *SubprocessModule) ProcessQuery(ctx context.Context, query string, _ *currency.APICache) ([]commontypes.FlowResult, error) {
+	resp, err := m.call(ctx, request{Query: query})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	m.cfg.scaleScores(resp.Results)
+	return resp.Results, nil
+}
+
+// Close stops health checks and the process.
+func (m *SubprocessModule) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	select {
+	case <-m.stopped:
+		return
+	default:
+	}
+	close(m.stopped)
+	if m.proc != nil {
+		m.proc.kill()
+		m.proc = nil
+	}
+}
+
+// call sends req to the process, starting it if needed, and waits for the
+// matching response within the module's timeout.
+func (m *SubprocessModule) call(ctx context.Context, req request) (response, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.timeout)
+	defer cancel()
+
+	m.mu.Lock()
+	proc, err := m.processLocked()
+	m.nextID++
+	req.ID = m.nextID
+	m.mu.Unlock()
+	if err != nil {
+		return response{}, err
+	}
+
+	ch := make(chan response, 1)
+	proc.mu.Lock()
+	proc.pending[req.ID] = ch
+	proc.mu.Unlock()
+	defer func() {
+		proc.mu.Lock()
+		delete(proc.pending, req.ID)
+		proc.mu.Unlock()
+	}()
+
+	line, err := json.Marshal(req)
+	if err != nil {
+		return response{}, err
+	}
+	proc.writeMu.Lock()
+	_, err = proc.stdin.Write(append(line, '\n'))
+	proc.writeMu.Unlock()
+	if err != nil {
+		m.restart(proc)
+		return response{}, fmt.Errorf("module %s: write: %w", m.cfg.Name, err)
+	}
+
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-proc.done:
+		m.restart(proc)
+		return response{}, fmt.Errorf("module %s: process exited", m.cfg.Name)
+	case <-ctx.Done():
+		return response{}, ctx.Err()
+	}
+}
+
+// processLocked returns the running process, starting one if there is none.
+func (m *SubprocessModule) processLocked() (*process, error) {
+	select {
+	case <-m.stopped:
+		return nil, fmt.Errorf("module %s: stopped", m.cfg.Name)
+	default:
+	}
+	if m.proc != nil {
+		return m.proc, nil
+	}
+
+	cmd := exec.Command(m.cfg.Command[0], m.cfg.Command[1:]...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("module %s: start: %w", m.cfg.Name, err)
+	}
+	log.Printf("External module %s started (pid %d)", m.cfg.Name, cmd.Process.Pid)
+
+	proc := &process{cmd: cmd, stdin: stdin, pending: make(map[int64]chan response), done: make(chan struct{})}
+	go proc.readResponses(m.cfg.Name, stdout)
+	go logStderr(m.cfg.Name, stderr)
+	go func() {
+		<-proc.done
+		if err := cmd.Wait(); err != nil {
+			log.Printf("Warning: external module %s exited: %v", m.cfg.Name, err)
+		}
+	}()
+	m.proc = proc
+	return proc, nil
+}
+
+// restart drops proc if it is still the current process; the next query
+// starts a new one.
+func (m *SubprocessModule) restart(proc *process) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.proc == proc {
+		m.proc = nil
+		proc.kill()
+	}
+}
+
+// healthLoop pings a running process and restarts it if it doesn't answer.
+// An idle module isn't started just to be checked.
+func (m *SubprocessModule) healthLoop() {
+	ticker := time.NewTicker(m.cfg.healthInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-m.stopped:
+			return
+		}
+
+		m.mu.Lock()
+		proc := m.proc
+		m.mu.Unlock()
+		if proc == nil {
+			continue
+		}
+		if _, err := m.call(context.Background(), request{Ping: true}); err != nil {
+			log.Printf("Warning: external module %s failed health check, restarting: %v", m.cfg.Name, err)
+			m.restart(proc)
+		}
+	}
+}
+
+func (p *process) readResponses(name string, stdout io.Reader) {
+	defer close(p.done)
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var resp response
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+			log.Printf("Warning: external module %s wrote invalid JSON: %v", name, err)
+			continue
+		}
+		p.mu.Lock()
+		ch, ok := p.pending[resp.ID]
+		p.mu.Unlock()
+		if ok {
+			select {
+			case ch <- resp:
+			default: // duplicate answer
+			}
+		}
+	}
+}
+
+func (p *process) kill() {
+	p.stdin.Close()
+	if p.cmd.Process != nil {
+		p.cmd.Process.Kill()
+	}
+}
+
+func logStderr(name string, stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		log.Printf("[%s] %s", name, scanner.Text())
+	}
+}
@@ -0,0 +1,146 @@
+//go:build wasmplugins
+
+package external
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"answerflow/commontypes"
+	"answerflow/modules"
+	"answerflow/modules/currency"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// wasmMemoryLimitPages caps a plugin's linear memory at 64 MiB.
+const wasmMemoryLimitPages = 1024
+
+// WasmModule runs a WebAssembly plugin in the wazero sandbox. The plugin
+// gets WASI with clocks, randomness and stderr (logged) only: no files, no
+// environment, no network. It exports
+//
+//	alloc(size i32) i32                  memory for the host to write a query into
+//	process_query(ptr i32, len i32) i64  results as a JSON array, (ptr << 32) | len
+//
+// and may export _initialize, which runs once per instance. Every query gets
+// a fresh instance, so a plugin keeps no state between queries and a crash
+// or runaway loop only costs that query.
+type WasmModule struct {
+	cfg      Config
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+}
+
+// NewWasmModule compiles the plugin at cfg.Wasm.
+func NewWasmModule(cfg Config) (modules.Module, error) {
+	bin, err := os.ReadFile(cfg.Wasm)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithCloseOnContextDone(true).
+		WithMemoryLimitPages(wasmMemoryLimitPages))
+	wasi_snapshot_preview1.MustInstantiate(ctx, runtime)
+
+	compiled, err := runtime.CompileModule(ctx, bin)
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("module %s: compile %s: %w", cfg.Name, cfg.Wasm, err)
+	}
+	for _, export := range []string{"alloc", "process_query"} {
+		if _, ok := compiled.ExportedFunctions()[export]; !ok {
+			runtime.Close(ctx)
+			return nil, fmt.Errorf("module %s: plugin does not export %s", cfg.Name, export)
+		}
+	}
+	return &WasmModule{cfg: cfg, runtime: runtime, compiled: compiled}, nil
+}
+
+func (m *WasmModule) Name() string            { return m.cfg.Name }
+func (m *WasmModule) DefaultIconPath() string { return m.cfg.Icon }
+func (m *WasmModule) MaxConcurrency() int     { return m.cfg.MaxConcurrency }
+func (m *WasmModule) ResultGroup() string     { return m.cfg.Group }
+
+func (m *WasmModule) ProcessQuery(ctx context.Context, query string, _ *currency.APICache) ([]commontypes.FlowResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.timeout)
+	defer cancel()
+
+	stderr := logWriter(m.cfg.Name)
+	defer stderr.Close()
+	mod, err := m.runtime.InstantiateModule(ctx, m.compiled, wazero.NewModuleConfig().
+		WithName("").
+		WithStartFunctions("_initialize").
+		WithStderr(stderr).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithRandSource(rand.Reader))
+	if err != nil {
+		return nil, fmt.Errorf("module %s: instantiate: %w", m.cfg.Name, err)
+	}
+	defer mod.Close(ctx)
+
+	out, err := callPlugin(ctx, mod, []byte(query))
+	if err != nil {
+		return nil, fmt.Errorf("module %s: %w", m.cfg.Name, err)
+	}
+
+	var results []commontypes.FlowResult
+	if err := json.Unmarshal(out, &results); err != nil {
+		return nil, fmt.Errorf("module %s: failed to decode results: %w", m.cfg.Name, err)
+	}
+	m.cfg.scaleScores(results)
+	return results, nil
+}
+
+// Close releases the runtime and every compiled artifact.
+func (m *WasmModule) Close() {
+	m.runtime.Close(context.Background())
+}
+
+// callPlugin writes query into the plugin's memory, runs process_query and
+// copies the answer out before the instance goes away.
+func callPlugin(ctx context.Context, mod api.Module, query []byte) ([]byte, error) {
+	alloc := mod.ExportedFunction("alloc")
+	ret, err := alloc.Call(ctx, uint64(len(query)))
+	if err != nil {
+		return nil, fmt.Errorf("alloc: %w", err)
+	}
+	ptr := uint32(ret[0])
+	if !mod.Memory().Write(ptr, query) {
+		return nil, fmt.Errorf("alloc returned out-of-range memory")
+	}
+
+	ret, err = mod.ExportedFunction("process_query").Call(ctx, uint64(ptr), uint64(len(query)))
+	if err != nil {
+		return nil, fmt.Errorf("process_query: %w", err)
+	}
+	outPtr, outLen := uint32(ret[0]>>32), uint32(ret[0])
+	out, ok := mod.Memory().Read(outPtr, outLen)
+	if !ok {
+		return nil, fmt.Errorf("process_query returned out-of-range memory")
+	}
+	return append([]byte(nil), out...), nil
+}
+
+// logWriter logs what a plugin writes to stderr, line by line.
+func logWriter(name string) io.WriteCloser {
+	r, w := io.Pipe()
+	go func() {
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			log.Printf("[%s] %s", name, scanner.Text())
+		}
+	}()
+	return w
+}
@@ -0,0 +1,15 @@
+//go:build !wasmplugins
+
+package external
+
+import (
+	"fmt"
+
+	"answerflow/modules"
+)
+
+// NewWasmModule needs a build with the wasmplugins tag, which pulls in the
+// wazero runtime.
+func NewWasmModule(cfg Config) (modules.Module, error) {
+	return nil, fmt.Errorf("module %s: WebAssembly plugins need a build with -tags wasmplugins", cfg.Name)
+}
@@ -0,0 +1,183 @@
+// Package help answers questions about the service itself:
+//
+//	help                 one row per module with an example query
+//	currencies [filter]  supported codes, paged ("currencies 2", "currencies eu 2")
+//	fees                 the fee profile conversions are priced with
+//	version              build and enabled modules, for bug reports
+//
+// Everything is read from the live module registry and configuration.
+package help
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"answerflow/buildinfo"
+	"answerflow/commontypes"
+	"answerflow/modules"
+	"answerflow/modules/currency"
+)
+
+// Keyword queries are unambiguous, so their answer goes on top.
+const scoreHelp = 100
+
+// currenciesPerPage keeps a page within what launchers show without scrolling.
+const currenciesPerPage = 8
+
+var (
+	regexHelpQuery       = regexp.MustCompile(`(?i)^\s*(?:help|\?)(?:\s+(.+?))?\s*$`)
+	regexCurrenciesQuery = regexp.MustCompile(`(?i)^\s*currencies(?:\s+(.+?))?(?:\s+(\d+))?\s*$`)
+	regexFeesQuery       = regexp.MustCompile(`(?i)^\s*fees\s*$`)
+	regexVersionQuery    = regexp.MustCompile(`(?i)^\s*version\s*$`)
+	regexPageOnly        = regexp.MustCompile(`^\d+$`)
+)
+
+// HelpModule answers "help", "currencies", "fees" and "version". registry
+// returns the modules currently enabled.
+type HelpModule struct {
+	registry func() []modules.Module
+	iconPath string
+	commontypes.Scoring
+}
+
+func NewHelpModule(registry func() []modules.Module, iconPath string) *HelpModule {
+	return &HelpModule{registry: registry, iconPath: iconPath}
+}
+
+func (m *HelpModule) Name() string            { return "Help" }
+func (m *HelpModule) DefaultIconPath() string { return m.iconPath }
+func (m *HelpModule) ResultGroup() string     { return "Help" }
+func (m *HelpModule) QueryHint() string       { return "help" }
+
+func (m *HelpModule) ProcessQuery(ctx context.Context, query string, apiCache *currency.APICache) ([]commontypes.FlowResult, error) {
+	var results []commontypes.FlowResult
+	if matches := regexHelpQuery.FindStringSubmatch(query); matches != nil {
+		results = m.moduleResults(matches[1])
+	} else if matches := regexCurrenciesQuery.FindStringSubmatch(query); matches != nil {
+		filter, page := matches[1], matches[2]
+		// "currencies 2" is a page, not a filter
+		if page == "" && regexPageOnly.MatchString(filter) {
+			filter, page = "", filter
+		}
+		results = m.currencyResults(filter, page)
+	} else if regexFeesQuery.MatchString(query) {
+		results = m.feeResults(ctx)
+	} else if regexVersionQuery.MatchString(query) {
+		results = m.versionResults()
+	}
+	m.ApplyScoring(m.Name(), results)
+	return results, nil
+}
+
+// moduleResults lists the enabled modules whose name or group contains
+// filter, each offering its example query.
+func (m *HelpModule) moduleResults(filter string) []commontypes.FlowResult {
+	filter = strings.ToLower(filter)
+	var results []commontypes.FlowResult
+	for _, mod := range m.registry() {
+		title := mod.Name()
+		if g, ok := mod.(modules.ResultGrouper); ok && g.ResultGroup() != "" && g.ResultGroup() != title {
+			title += " (" + g.ResultGroup() + ")"
+		}
+		if filter != "" && !strings.Contains(strings.ToLower(title), filter) {
+			continue
+		}
+		res := commontypes.FlowResult{
+			Title:    title,
+			SubTitle: "No example query",
+			IcoPath:  mod.DefaultIconPath(),
+			Score:    scoreHelp - len(results),
+		}
+		if h, ok := mod.(modules.QueryHinter); ok && h.QueryHint() != "" {
+			res.SubTitle = "Try: " + h.QueryHint()
+			res.JsonRPCAction = changeQuery(h.QueryHint())
+		}
+		results = append(results, res)
+	}
+	if len(results) == 0 {
+		results = append(results, m.result(fmt.Sprintf("No module matches %q", filter), "Type help to list all modules", scoreHelp))
+		results[0].JsonRPCAction = changeQuery("help")
+	}
+	return results
+}
+
+// currencyResults shows one page of the supported currencies whose code or
+// name contains filter, followed by a row that opens the next page.
+func (m *HelpModule) currencyResults(filter, pageArg string) []commontypes.FlowResult {
+	filter = strings.TrimSpace(filter)
+	needle := strings.ToLower(filter)
+	var matched []currency.SupportedCurrency
+	for _, c := range currency.SupportedCurrencies() {
+		if needle == "" || strings.Contains(strings.ToLower(c.Code), needle) || strings.Contains(strings.ToLower(c.Name), needle) {
+			matched = append(matched, c)
+		}
+	}
+	if len(matched) == 0 {
+		return []commontypes.FlowResult{m.result(fmt.Sprintf("No supported currency matches %q", filter), "Type currencies to list them all", scoreHelp)}
+	}
+
+	pages := (len(matched) + currenciesPerPage - 1) / currenciesPerPage
+	page, _ := strconv.Atoi(pageArg)
+	page = min(max(page, 1), pages)
+	start := (page - 1) * currenciesPerPage
+	end := min(start+currenciesPerPage, len(matched))
+
+	results := make([]commontypes.FlowResult, 0, end-start+1)
+	for i, c := range matched[start:end] {
+		res := m.result(c.Code, fmt.Sprintf("%s | %s | %d of %d", c.Name, c.Kind, start+i+1, len(matched)), scoreHelp-i)
+		res.JsonRPCAction = commontypes.CopyToClipboard(c.Code)
+		results = append(results, res)
+	}
+	if page < pages {
+		next := strings.TrimSpace("currencies " + filter + " " + strconv.Itoa(page+1))
+		res := m.result(fmt.Sprintf("Next page (%d of %d)", page+1, pages), next, scoreHelp-currenciesPerPage)
+		res.JsonRPCAction = changeQuery(next)
+		results = append(results, res)
+	}
+	return results
+}
+
+// feeResults lists the active fee profile, one fee per row.
+func (m *HelpModule) feeResults(ctx context.Context) []commontypes.FlowResult {
+	fees := currency.ActiveFees(ctx)
+	results := make([]commontypes.FlowResult, 0, len(fees))
+	for i, fee := range fees {
+		results = append(results, m.result(fee.Name+": "+fee.Detail, "Fee profile in use", scoreHelp-i))
+	}
+	return results
+}
+
+// versionResults describes the build in one row; copying it gives the full
+// line to paste into a bug report.
+func (m *HelpModule) versionResults() []commontypes.FlowResult {
+	info := buildinfo.Get()
+	var names []string
+	for _, mod := range m.registry() {
+		names = append(names, mod.Name())
+	}
+	commit := info.ShortCommit()
+	if info.Modified {
+		commit += "-dirty"
+	}
+	title := fmt.Sprintf("answerflow %s (%s)", info.Version, commit)
+	subTitle := fmt.Sprintf("Built %s with %s | modules: %s", info.Date, info.GoVersion, strings.Join(names, ", "))
+	res := m.result(title, subTitle, scoreHelp)
+	res.JsonRPCAction = commontypes.CopyToClipboard(title + " | " + subTitle)
+	return []commontypes.FlowResult{res}
+}
+
+func (m *HelpModule) result(title, subTitle string, score int) commontypes.FlowResult {
+	return commontypes.FlowResult{
+		Title:    title,
+		SubTitle: subTitle,
+		IcoPath:  m.iconPath,
+		Score:    score,
+	}
+}
+
+func changeQuery(query string) commontypes.JsonRPCAction {
+	return commontypes.ChangeQuery(query, false)
+}
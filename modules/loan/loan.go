@@ -0,0 +1,179 @@
+// Package loan answers loan and investment questions:
+//
+//	loan 300000 rub 12% 5y       monthly annuity payment, interest, total paid
+//	compound 1000 usd 7% 10y     value after annual compounding
+//
+// Amounts are read like calculator input ("300k", "1 500 000", "2*150k"),
+// terms in years ("5y") or months ("18m").
+package loan
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"answerflow/commontypes"
+	"answerflow/modules/currency"
+	"answerflow/numparse"
+	"answerflow/safeexpr"
+)
+
+// Keyword queries are unambiguous, so the headline figure goes on top.
+const scoreHeadline = 100
+
+// maxTermMonths bounds terms to a century; longer ones are typos.
+const maxTermMonths = 1200
+
+var (
+	regexLoanQuery = regexp.MustCompile(
+		`(?i)^\s*(loan|mortgage|compound)\s+([0-9][0-9.,\s*/+\-()kmb]*?)(?:\s+(\p{L}{2,5}))?\s+([0-9]+(?:[.,][0-9]+)?)\s*%\s*(?:for\s+)?([0-9]+(?:[.,][0-9]+)?)\s*(y|yr|yrs|years?|m|mo|months?)\s*$`)
+
+	// Amount suffixes: "300k", "1.5m", "2b"
+	regexAmountSuffix = regexp.MustCompile(`(?i)([0-9][0-9.,]*)\s*([kmb])\b`)
+	regexNumber       = regexp.MustCompile(`[0-9]+(?:[0-9\s ,.]*[0-9])?`)
+)
+
+var suffixMultipliers = map[string]string{"k": "*1000", "m": "*1000000", "b": "*1000000000"}
+
+// LoanModule answers "loan", "mortgage" and "compound" queries.
+type LoanModule struct {
+	iconPath string
+	commontypes.Scoring
+}
+
+func NewLoanModule(iconPath string) *LoanModule {
+	return &LoanModule{iconPath: iconPath}
+}
+
+func (m *LoanModule) Name() string            { return "Loan" }
+func (m *LoanModule) DefaultIconPath() string { return m.iconPath }
+func (m *LoanModule) ResultGroup() string     { return "Loans" }
+func (m *LoanModule) QueryHint() string       { return "loan 300000 rub 12% 5y" }
+
+func (m *LoanModule) ProcessQuery(ctx context.Context, query string, apiCache *currency.APICache) ([]commontypes.FlowResult, error) {
+	if len(query) > safeexpr.MaxLength {
+		return nil, nil
+	}
+	matches := regexLoanQuery.FindStringSubmatch(query)
+	if matches == nil {
+		return nil, nil
+	}
+
+	principal, err := evaluateAmount(matches[2])
+	if err != nil || principal <= 0 || math.IsInf(principal, 0) {
+		return nil, nil
+	}
+	code := strings.ToUpper(matches[3])
+	rate, err := strconv.ParseFloat(numparse.Normalize(matches[4]), 64)
+	if err != nil || rate < 0 || rate > 1000 {
+		return nil, nil
+	}
+	term, err := strconv.ParseFloat(numparse.Normalize(matches[5]), 64)
+	if err != nil {
+		return nil, nil
+	}
+	months := term
+	if strings.HasPrefix(strings.ToLower(matches[6]), "y") {
+		months = term * 12
+	}
+	months = math.Round(months)
+	if months < 1 || months > maxTermMonths {
+		return nil, nil
+	}
+
+	money := func(amount float64) string {
+		decimals := 2
+		if code != "" {
+			decimals = currency.GetCurrencyDecimalPlaces(code)
+		}
+		text := commontypes.GroupThousands(strconv.FormatFloat(amount, 'f', decimals, 64))
+		if code != "" {
+			text += " " + code
+		}
+		return text
+	}
+	terms := fmt.Sprintf("%s at %s%% over %s", money(principal), strconv.FormatFloat(rate, 'f', -1, 64), describeTerm(months))
+
+	var results []commontypes.FlowResult
+	if strings.EqualFold(matches[1], "compound") {
+		value := compoundValue(principal, rate, months)
+		growth := value - principal
+		results = []commontypes.FlowResult{
+			m.result("Future value: "+money(value), "Compounded yearly: "+terms, value, scoreHeadline),
+			m.result(fmt.Sprintf("Growth: %s (+%.1f%%)", money(growth), growth/principal*100), "Compounded yearly: "+terms, growth, scoreHeadline-1),
+		}
+	} else {
+		payment := annuityPayment(principal, rate, months)
+		total := payment * months
+		results = []commontypes.FlowResult{
+			m.result("Monthly payment: "+money(payment), "Annuity loan: "+terms, payment, scoreHeadline),
+			m.result("Total interest: "+money(total-principal), "Annuity loan: "+terms, total-principal, scoreHeadline-1),
+			m.result("Total paid: "+money(total), "Annuity loan: "+terms, total, scoreHeadline-2),
+		}
+	}
+	m.ApplyScoring(m.Name(), results)
+	return results, nil
+}
+
+func (m *LoanModule) result(title, subTitle string, value float64, score int) commontypes.FlowResult {
+	return commontypes.FlowResult{
+		Title:         title,
+		SubTitle:      subTitle,
+		IcoPath:       m.iconPath,
+		Score:         score,
+		JsonRPCAction: commontypes.CopyToClipboard(strconv.FormatFloat(value, 'f', 2, 64)),
+	}
+}
+
+// annuityPayment is the fixed monthly payment that repays principal with
+// interest at annualRate percent, compounded monthly, over months.
+func annuityPayment(principal, annualRate, months float64) float64 {
+	r := annualRate / 100 / 12
+	if r == 0 {
+		return principal / months
+	}
+	return principal * r / (1 - math.Pow(1+r, -months))
+}
+
+// compoundValue is principal grown at annualRate percent compounded yearly,
+// with a part year compounded pro rata.
+func compoundValue(principal, annualRate, months float64) float64 {
+	return principal * math.Pow(1+annualRate/100, months/12)
+}
+
+func describeTerm(months float64) string {
+	if math.Mod(months, 12) == 0 {
+		if months == 12 {
+			return "1 year"
+		}
+		return fmt.Sprintf("%.0f years", months/12)
+	}
+	return fmt.Sprintf("%.0f months", months)
+}
+
+// evaluateAmount reads an amount as the calculator does: locale-aware
+// numbers, k/m/b suffixes and plain arithmetic.
+func evaluateAmount(s string) (float64, error) {
+	expr := regexAmountSuffix.ReplaceAllStringFunc(strings.TrimSpace(s), func(match string) string {
+		m := regexAmountSuffix.FindStringSubmatch(match)
+		return m[1] + suffixMultipliers[strings.ToLower(m[2])]
+	})
+	expr = regexNumber.ReplaceAllStringFunc(expr, numparse.Normalize)
+
+	output, err := safeexpr.Eval(expr, nil)
+	if err != nil {
+		return 0, err
+	}
+	switch v := output.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	}
+	return 0, fmt.Errorf("not a number")
+}
@@ -17,3 +17,44 @@ type Module interface {
 	// UPDATED: ProcessQuery now uses currency.APICache and commontypes.FlowResult
 	ProcessQuery(ctx context.Context, query string, apiCache *currency.APICache) ([]commontypes.FlowResult, error)
 }
+
+// ConcurrencyLimiter is optionally implemented by modules that want a tighter
+// bound on concurrent ProcessQuery calls than the server default.
+type ConcurrencyLimiter interface {
+	MaxConcurrency() int
+}
+
+// ResultGrouper is optionally implemented by modules whose results belong
+// under a section label in launchers that group results. Results that set
+// their own Group keep it.
+type ResultGrouper interface {
+	ResultGroup() string
+}
+
+// QueryHinter is optionally implemented by modules that can show an example
+// query, used to point users at the right syntax when nothing matched.
+type QueryHinter interface {
+	QueryHint() string
+}
+
+// PrivateQuerier is optionally implemented by modules whose queries carry
+// personal data, such as card numbers. PrivateQuery reports whether query
+// is one of them; such queries are kept out of the autocomplete history.
+type PrivateQuerier interface {
+	PrivateQuery(query string) bool
+}
+
+// DependencyAware is optionally implemented by modules that read another
+// module's commontypes.Blackboard entries. DependsOn names those modules;
+// the module runs once they have finished. Only modules registered earlier
+// can be depended on, so the registration order is the run order.
+type DependencyAware interface {
+	DependsOn() []string
+}
+
+// ScoringAware is optionally implemented by modules that rescore their
+// results with the deployment's commontypes.ScoringStrategy, usually by
+// embedding commontypes.Scoring.
+type ScoringAware interface {
+	SetScoringStrategy(strategy commontypes.ScoringStrategy)
+}
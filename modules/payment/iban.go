@@ -0,0 +1,150 @@
+package payment
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ibanCountry is the IBAN format of one country: its total length and
+// where the bank code sits in the BBAN (the part after the check digits).
+type ibanCountry struct {
+	name      string
+	length    int
+	bankStart int
+	bankLen   int
+}
+
+var ibanCountries = map[string]ibanCountry{
+	"AD": {"Andorra", 24, 0, 4},
+	"AE": {"United Arab Emirates", 23, 0, 3},
+	"AL": {"Albania", 28, 0, 3},
+	"AT": {"Austria", 20, 0, 5},
+	"AZ": {"Azerbaijan", 28, 0, 4},
+	"BA": {"Bosnia and Herzegovina", 20, 0, 3},
+	"BE": {"Belgium", 16, 0, 3},
+	"BG": {"Bulgaria", 22, 0, 4},
+	"BH": {"Bahrain", 22, 0, 4},
+	"BR": {"Brazil", 29, 0, 8},
+	"CH": {"Switzerland", 21, 0, 5},
+	"CY": {"Cyprus", 28, 0, 3},
+	"CZ": {"Czechia", 24, 0, 4},
+	"DE": {"Germany", 22, 0, 8},
+	"DK": {"Denmark", 18, 0, 4},
+	"EE": {"Estonia", 20, 0, 2},
+	"EG": {"Egypt", 29, 0, 4},
+	"ES": {"Spain", 24, 0, 4},
+	"FI": {"Finland", 18, 0, 3},
+	"FO": {"Faroe Islands", 18, 0, 4},
+	"FR": {"France", 27, 0, 5},
+	"GB": {"United Kingdom", 22, 0, 4},
+	"GE": {"Georgia", 22, 0, 2},
+	"GI": {"Gibraltar", 23, 0, 4},
+	"GL": {"Greenland", 18, 0, 4},
+	"GR": {"Greece", 27, 0, 3},
+	"HR": {"Croatia", 21, 0, 7},
+	"HU": {"Hungary", 28, 0, 3},
+	"IE": {"Ireland", 22, 0, 4},
+	"IL": {"Israel", 23, 0, 3},
+	"IS": {"Iceland", 26, 0, 4},
+	"IT": {"Italy", 27, 1, 5},
+	"JO": {"Jordan", 30, 0, 4},
+	"KW": {"Kuwait", 30, 0, 4},
+	"KZ": {"Kazakhstan", 20, 0, 3},
+	"LB": {"Lebanon", 28, 0, 4},
+	"LI": {"Liechtenstein", 21, 0, 5},
+	"LT": {"Lithuania", 20, 0, 5},
+	"LU": {"Luxembourg", 20, 0, 3},
+	"LV": {"Latvia", 21, 0, 4},
+	"MC": {"Monaco", 27, 0, 5},
+	"MD": {"Moldova", 24, 0, 2},
+	"ME": {"Montenegro", 22, 0, 3},
+	"MK": {"North Macedonia", 19, 0, 3},
+	"MT": {"Malta", 31, 0, 4},
+	"MU": {"Mauritius", 30, 0, 6},
+	"NL": {"Netherlands", 18, 0, 4},
+	"NO": {"Norway", 15, 0, 4},
+	"PK": {"Pakistan", 24, 0, 4},
+	"PL": {"Poland", 28, 0, 8},
+	"PS": {"Palestine", 29, 0, 4},
+	"PT": {"Portugal", 25, 0, 4},
+	"QA": {"Qatar", 29, 0, 4},
+	"RO": {"Romania", 24, 0, 4},
+	"RS": {"Serbia", 22, 0, 3},
+	"SA": {"Saudi Arabia", 24, 0, 2},
+	"SE": {"Sweden", 24, 0, 3},
+	"SI": {"Slovenia", 19, 0, 5},
+	"SK": {"Slovakia", 24, 0, 4},
+	"SM": {"San Marino", 27, 1, 5},
+	"TN": {"Tunisia", 24, 0, 2},
+	"TR": {"Turkey", 26, 0, 5},
+	"UA": {"Ukraine", 29, 0, 6},
+	"VA": {"Vatican City", 22, 0, 3},
+	"VG": {"British Virgin Islands", 24, 0, 4},
+	"XK": {"Kosovo", 20, 0, 2},
+}
+
+// IBANInfo describes a valid IBAN.
+type IBANInfo struct {
+	IBAN        string // electronic format: upper case, no spaces
+	CountryCode string
+	Country     string
+	CheckDigits string
+	BankCode    string
+}
+
+// Formatted is the IBAN in print format, in groups of four.
+func (i IBANInfo) Formatted() string {
+	return group(i.IBAN, 4)
+}
+
+// ValidateIBAN checks an IBAN's country, length and ISO 7064 mod-97
+// checksum. Spaces are ignored and letters may be in either case.
+func ValidateIBAN(input string) (IBANInfo, error) {
+	iban := strings.ToUpper(strings.ReplaceAll(input, " ", ""))
+	if len(iban) < 5 {
+		return IBANInfo{}, fmt.Errorf("too short")
+	}
+	for _, r := range iban {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return IBANInfo{}, fmt.Errorf("unexpected character %q", r)
+		}
+	}
+
+	code := iban[:2]
+	country, ok := ibanCountries[code]
+	if !ok {
+		return IBANInfo{}, fmt.Errorf("unknown country code %s", code)
+	}
+	if len(iban) != country.length {
+		return IBANInfo{}, fmt.Errorf("%s IBANs have %d characters, not %d", country.name, country.length, len(iban))
+	}
+	if iban[2] < '0' || iban[2] > '9' || iban[3] < '0' || iban[3] > '9' {
+		return IBANInfo{}, fmt.Errorf("check digits must be numeric")
+	}
+	if ibanMod97(iban[4:]+iban[:4]) != 1 {
+		return IBANInfo{}, fmt.Errorf("checksum mismatch")
+	}
+
+	bban := iban[4:]
+	return IBANInfo{
+		IBAN:        iban,
+		CountryCode: code,
+		Country:     country.name,
+		CheckDigits: iban[2:4],
+		BankCode:    bban[country.bankStart : country.bankStart+country.bankLen],
+	}, nil
+}
+
+// ibanMod97 is s mod 97 with letters read as two-digit numbers (A=10).
+func ibanMod97(s string) int {
+	rem := 0
+	for _, r := range s {
+		if r >= 'A' && r <= 'Z' {
+			v := int(r-'A') + 10
+			rem = (rem*100 + v) % 97
+		} else {
+			rem = (rem*10 + int(r-'0')) % 97
+		}
+	}
+	return rem
+}
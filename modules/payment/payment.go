@@ -0,0 +1,113 @@
+// Package payment validates payment identifiers typed after a keyword:
+// IBANs ("iban DE89 3704 0044 0532 0130 00") by checksum and country
+// format, and card numbers or BINs ("bin 414720", "card 4111 1111 1111
+// 1111") by Luhn check and scheme prefix. Nothing leaves the process.
+package payment
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"answerflow/commontypes"
+	"answerflow/modules/currency"
+)
+
+// Keyword queries are unambiguous, so their answer goes on top.
+const scoreValidation = 100
+
+var (
+	regexIBANQuery = regexp.MustCompile(`(?i)^\s*iban\s+([a-z0-9 ]+?)\s*$`)
+	regexCardQuery = regexp.MustCompile(`(?i)^\s*(?:bin|card)\s+([0-9 -]+?)\s*$`)
+)
+
+// ValidatorModule answers "iban …", "bin …" and "card …" queries.
+type ValidatorModule struct {
+	iconPath string
+	commontypes.Scoring
+}
+
+func NewValidatorModule(iconPath string) *ValidatorModule {
+	return &ValidatorModule{iconPath: iconPath}
+}
+
+func (m *ValidatorModule) Name() string            { return "PaymentValidator" }
+func (m *ValidatorModule) DefaultIconPath() string { return m.iconPath }
+func (m *ValidatorModule) ResultGroup() string     { return "Validation" }
+func (m *ValidatorModule) QueryHint() string       { return "iban DE89 3704 0044 0532 0130 00" }
+
+// PrivateQuery reports whether query asks to validate an IBAN or a card
+// number, which are not to be remembered.
+func (m *ValidatorModule) PrivateQuery(query string) bool {
+	return regexIBANQuery.MatchString(query) || regexCardQuery.MatchString(query)
+}
+
+func (m *ValidatorModule) ProcessQuery(ctx context.Context, query string, apiCache *currency.APICache) ([]commontypes.FlowResult, error) {
+	var results []commontypes.FlowResult
+	if matches := regexIBANQuery.FindStringSubmatch(query); matches != nil {
+		results = append(results, m.ibanResult(matches[1]))
+	} else if matches := regexCardQuery.FindStringSubmatch(query); matches != nil {
+		results = append(results, m.cardResult(matches[1]))
+	}
+	m.ApplyScoring(m.Name(), results)
+	return results, nil
+}
+
+func (m *ValidatorModule) ibanResult(input string) commontypes.FlowResult {
+	info, err := ValidateIBAN(input)
+	if err != nil {
+		return m.invalidResult("IBAN", input, err)
+	}
+	details := []string{info.Country}
+	if info.BankCode != "" {
+		details = append(details, "bank code "+info.BankCode)
+	}
+	details = append(details, "check digits "+info.CheckDigits)
+	return commontypes.FlowResult{
+		Title:         "Valid IBAN: " + info.Formatted(),
+		SubTitle:      strings.Join(details, " | "),
+		IcoPath:       m.iconPath,
+		Score:         scoreValidation,
+		JsonRPCAction: commontypes.CopyToClipboard(info.IBAN),
+	}
+}
+
+func (m *ValidatorModule) cardResult(input string) commontypes.FlowResult {
+	info, err := ValidateCard(input)
+	if err != nil {
+		return m.invalidResult("card number", input, err)
+	}
+	kind := "BIN"
+	if info.Complete {
+		kind = "card number"
+	}
+	return commontypes.FlowResult{
+		Title:         fmt.Sprintf("%s %s: %s", info.Scheme, kind, info.Formatted()),
+		SubTitle:      fmt.Sprintf("BIN %s | %s", info.BIN(), info.status()),
+		IcoPath:       m.iconPath,
+		Score:         scoreValidation,
+		JsonRPCAction: commontypes.CopyToClipboard(info.Number),
+	}
+}
+
+func (m *ValidatorModule) invalidResult(kind, input string, err error) commontypes.FlowResult {
+	return commontypes.FlowResult{
+		Title:    fmt.Sprintf("%s Invalid %s", commontypes.GlyphWarning, kind),
+		SubTitle: fmt.Sprintf("%s: %v", strings.TrimSpace(input), err),
+		IcoPath:  m.iconPath,
+		Score:    scoreValidation,
+	}
+}
+
+// group splits s into space-separated runs of size characters.
+func group(s string, size int) string {
+	var b strings.Builder
+	for i, r := range s {
+		if i > 0 && i%size == 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
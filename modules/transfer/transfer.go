@@ -0,0 +1,183 @@
+// Package transfer compares fiat conversions against what money transfer
+// services (Wise, Revolut, banks) would deliver for the same amount. It runs
+// as its own module next to the currency converter, so a slow or failing
+// comparison source never holds up conversion results.
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"answerflow/commontypes"
+	"answerflow/modules/currency"
+)
+
+// Scores sit below the currency module's own results (80 and up), so the
+// comparison rows follow the conversion they compare against.
+const (
+	scoreBetter = 75
+	scoreWorse  = 74
+)
+
+// Quote is what one transfer service delivers for a given amount.
+type Quote struct {
+	Provider string  // display name, e.g. "Revolut"
+	Received float64 // in the target currency, after the service's fees
+	Fee      float64 // in the source currency
+}
+
+// Provider is a source of transfer quotes. A single provider may report
+// quotes for several services (a comparison site, say).
+type Provider interface {
+	Name() string
+	Quotes(ctx context.Context, amount float64, from, to string) ([]Quote, error)
+}
+
+// ComparisonModule adds comparison rows to fiat→fiat conversions: one per
+// quote, labelled with how it compares to the amount our route delivers.
+type ComparisonModule struct {
+	converter *currency.CurrencyConverterModule
+	providers []Provider
+	iconPath  string
+	cacheTTL  time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedQuotes
+
+	commontypes.Scoring
+}
+
+type cachedQuotes struct {
+	quotes  []Quote
+	expires time.Time
+}
+
+// NewComparisonModule compares converter's results against providers.
+func NewComparisonModule(converter *currency.CurrencyConverterModule, providers []Provider, iconPath string) *ComparisonModule {
+	return &ComparisonModule{
+		converter: converter,
+		providers: providers,
+		iconPath:  iconPath,
+		cacheTTL:  currency.EnvDuration("TRANSFER_QUOTE_TTL", 5*time.Minute),
+		cache:     make(map[string]cachedQuotes),
+	}
+}
+
+func (m *ComparisonModule) Name() string            { return "TransferComparison" }
+func (m *ComparisonModule) DefaultIconPath() string { return m.iconPath }
+func (m *ComparisonModule) ResultGroup() string     { return "Transfers" }
+func (m *ComparisonModule) MaxConcurrency() int     { return 2 }
+
+func (m *ComparisonModule) ProcessQuery(ctx context.Context, query string, apiCache *currency.APICache) ([]commontypes.FlowResult, error) {
+	req, err := m.converter.ParseConversion(query)
+	if err != nil || req.ToCurrency == "" || req.Inverse || req.Table || req.Provisional || len(req.Via) > 0 {
+		return nil, nil
+	}
+	if !apiCache.IsFiat(req.FromCurrency) || !apiCache.IsFiat(req.ToCurrency) || req.FromCurrency == req.ToCurrency {
+		return nil, nil
+	}
+
+	route, err := m.converter.Explain(req.Amount, req.FromCurrency, req.ToCurrency, apiCache)
+	if err != nil {
+		return nil, nil // the currency module reports conversion errors
+	}
+	ours := route.Result * (1 - req.PersonalFee/100)
+
+	quotes := m.quotes(ctx, req.Amount, req.FromCurrency, req.ToCurrency)
+	results := make([]commontypes.FlowResult, 0, len(quotes))
+	for _, q := range quotes {
+		results = append(results, formatQuote(q, ours, req.Amount, req.FromCurrency, req.ToCurrency))
+	}
+	m.ApplyScoring(m.Name(), results)
+	return results, nil
+}
+
+// quotes collects the quotes of every provider, best first, reusing answers
+// younger than the cache TTL since users type the same amount repeatedly.
+func (m *ComparisonModule) quotes(ctx context.Context, amount float64, from, to string) []Quote {
+	key := fmt.Sprintf("%s/%s/%g", from, to, amount)
+	m.mu.Lock()
+	if c, ok := m.cache[key]; ok && time.Now().Before(c.expires) {
+		m.mu.Unlock()
+		return c.quotes
+	}
+	m.mu.Unlock()
+
+	var quotes []Quote
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, p := range m.providers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q, err := p.Quotes(ctx, amount, from, to)
+			if err != nil {
+				log.Printf("Warning: transfer quotes from %s failed for %g %s→%s: %v", p.Name(), amount, from, to, err)
+				return
+			}
+			mu.Lock()
+			quotes = append(quotes, q...)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	sort.Slice(quotes, func(i, j int) bool { return quotes[i].Received > quotes[j].Received })
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for k, c := range m.cache {
+		if now.After(c.expires) {
+			delete(m.cache, k)
+		}
+	}
+	m.cache[key] = cachedQuotes{quotes: quotes, expires: now.Add(m.cacheTTL)}
+	return quotes
+}
+
+func formatQuote(q Quote, ours, amount float64, from, to string) commontypes.FlowResult {
+	received := formatAmount(q.Received, to)
+	diff := q.Received - ours
+	comparison := fmt.Sprintf("%s %s more than our route", formatAmount(diff, to), to)
+	score := scoreBetter
+	if diff < 0 {
+		comparison = fmt.Sprintf("%s %s less than our route", formatAmount(-diff, to), to)
+		score = scoreWorse
+	}
+	subTitle := fmt.Sprintf("Transfer comparison: %s %s sent via %s | %s", formatAmount(amount, from), from, q.Provider, comparison)
+	if q.Fee > 0 {
+		subTitle += fmt.Sprintf(" | fee %s %s", formatAmount(q.Fee, from), from)
+	}
+	return commontypes.FlowResult{
+		Title:         fmt.Sprintf("%s %s via %s", received, to, q.Provider),
+		SubTitle:      subTitle,
+		Score:         score,
+		JsonRPCAction: commontypes.CopyToClipboard(received + " " + to),
+	}
+}
+
+func formatAmount(amount float64, code string) string {
+	return strconv.FormatFloat(amount, 'f', currency.GetCurrencyDecimalPlaces(code), 64)
+}
+
+// ProvidersFromEnv builds the providers enabled in TRANSFER_PROVIDERS, a
+// comma-separated list (default "wise").
+func ProvidersFromEnv() []Provider {
+	var providers []Provider
+	for _, name := range strings.Split(currency.EnvString("TRANSFER_PROVIDERS", "wise"), ",") {
+		switch name = strings.TrimSpace(strings.ToLower(name)); name {
+		case "":
+		case "wise":
+			providers = append(providers, NewWiseProvider())
+		default:
+			log.Printf("Warning: unknown transfer provider %q", name)
+		}
+	}
+	return providers
+}
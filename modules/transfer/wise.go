@@ -0,0 +1,102 @@
+package transfer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"answerflow/modules/currency"
+)
+
+const maxWiseResponse = 1 << 20
+
+// WiseProvider reads Wise's public price comparison, which quotes Wise
+// itself next to other services (Revolut, banks, remittance companies) for
+// the same transfer. TRANSFER_WISE_SERVICES picks which of them to show by
+// alias, comma-separated (default "wise,revolut"); "*" shows all.
+type WiseProvider struct {
+	url      string
+	services map[string]bool
+	client   *http.Client
+	timeout  time.Duration
+}
+
+func NewWiseProvider() *WiseProvider {
+	p := &WiseProvider{
+		url:     currency.EnvString("TRANSFER_WISE_URL", "https://api.wise.com/v4/comparisons/"),
+		client:  currency.CreateHTTPClient(),
+		timeout: currency.EnvDuration("TRANSFER_WISE_TIMEOUT", 3*time.Second),
+	}
+	if services := currency.EnvString("TRANSFER_WISE_SERVICES", "wise,revolut"); services != "*" {
+		p.services = make(map[string]bool)
+		for _, s := range strings.Split(services, ",") {
+			p.services[strings.TrimSpace(strings.ToLower(s))] = true
+		}
+	}
+	return p
+}
+
+func (p *WiseProvider) Name() string { return "wise" }
+
+type wiseComparison struct {
+	Providers []struct {
+		Name   string `json:"name"`
+		Alias  string `json:"alias"`
+		Quotes []struct {
+			Fee            float64 `json:"fee"`
+			ReceivedAmount float64 `json:"receivedAmount"`
+		} `json:"quotes"`
+	} `json:"providers"`
+}
+
+func (p *WiseProvider) Quotes(ctx context.Context, amount float64, from, to string) ([]Quote, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	params := url.Values{
+		"sourceCurrency": {from},
+		"targetCurrency": {to},
+		"sendAmount":     {strconv.FormatFloat(amount, 'f', -1, 64)},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %s", resp.Status)
+	}
+
+	var comparison wiseComparison
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxWiseResponse)).Decode(&comparison); err != nil {
+		return nil, fmt.Errorf("failed to decode comparison: %w", err)
+	}
+	var quotes []Quote
+	for _, service := range comparison.Providers {
+		if p.services != nil && !p.services[strings.ToLower(service.Alias)] {
+			continue
+		}
+		// A service may list several quotes (delivery options); keep its best
+		var best *Quote
+		for _, q := range service.Quotes {
+			if q.ReceivedAmount > 0 && (best == nil || q.ReceivedAmount > best.Received) {
+				best = &Quote{Provider: service.Name, Received: q.ReceivedAmount, Fee: q.Fee}
+			}
+		}
+		if best != nil {
+			quotes = append(quotes, *best)
+		}
+	}
+	return quotes, nil
+}
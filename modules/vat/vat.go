@@ -0,0 +1,180 @@
+// Package vat splits amounts into net, tax and gross:
+//
+//	vat 20% on 150 eur        150 EUR net, 30 EUR VAT, 180 EUR gross
+//	vat de on 150 eur         Germany's standard rate
+//	150 eur incl vat 20       150 EUR gross, split back into net and VAT
+//	vat on 150 eur in usd     VAT_DEFAULT_COUNTRY's rate, amounts converted
+//
+// Amounts and currencies are read by the currency module's parser, and a
+// target currency is priced through its conversion routes.
+package vat
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"answerflow/commontypes"
+	"answerflow/modules/currency"
+	"answerflow/numparse"
+)
+
+//go:embed config/vat_rates.json
+var embeddedRatesJSON []byte
+
+// Keyword queries are unambiguous; their rows sit just below a conversion
+// the same query might also produce.
+const (
+	scoreNet   = 96
+	scoreTax   = 95
+	scoreGross = 94
+)
+
+const ratePart = `(\d+(?:[.,]\d+)?\s*%?|[a-z]{2})`
+
+var (
+	// "vat 20% on 150 eur", "vat on 150 eur to usd"
+	regexVATOn = regexp.MustCompile(`(?i)^\s*vat(?:\s+` + ratePart + `)?\s+on\s+(.+?)\s*$`)
+
+	// "150 eur incl vat 20", "150 eur including vat de in usd"
+	regexVATIncl = regexp.MustCompile(`(?i)^\s*(.+?)\s+(?:incl\.?|including)\s+vat(?:\s+` + ratePart + `)?(?:\s+((?:to|in)\s+\S+))?\s*$`)
+)
+
+// VATModule answers VAT queries, converting through converter when a
+// target currency is given.
+type VATModule struct {
+	converter      *currency.CurrencyConverterModule
+	iconPath       string
+	rates          map[string]float64 // country code -> standard rate in percent
+	defaultCountry string
+	commontypes.Scoring
+}
+
+func NewVATModule(converter *currency.CurrencyConverterModule, iconPath string) *VATModule {
+	var rates map[string]float64
+	if err := json.Unmarshal(embeddedRatesJSON, &rates); err != nil {
+		log.Printf("Warning: Failed to load VAT rates: %v", err)
+	}
+	m := &VATModule{
+		converter:      converter,
+		iconPath:       iconPath,
+		rates:          rates,
+		defaultCountry: strings.ToUpper(os.Getenv("VAT_DEFAULT_COUNTRY")),
+	}
+	if _, ok := rates[m.defaultCountry]; m.defaultCountry != "" && !ok {
+		log.Printf("Warning: VAT_DEFAULT_COUNTRY %q has no VAT rate, ignoring it", m.defaultCountry)
+		m.defaultCountry = ""
+	}
+	return m
+}
+
+func (m *VATModule) Name() string            { return "VAT" }
+func (m *VATModule) DefaultIconPath() string { return m.iconPath }
+func (m *VATModule) ResultGroup() string     { return "VAT" }
+func (m *VATModule) QueryHint() string       { return "vat 20% on 150 eur" }
+
+func (m *VATModule) ProcessQuery(ctx context.Context, query string, apiCache *currency.APICache) ([]commontypes.FlowResult, error) {
+	var amountQuery, rateStr string
+	inclusive := false
+	if matches := regexVATOn.FindStringSubmatch(query); matches != nil {
+		rateStr, amountQuery = matches[1], matches[2]
+	} else if matches := regexVATIncl.FindStringSubmatch(query); matches != nil {
+		amountQuery, rateStr = matches[1], matches[2]
+		if matches[3] != "" {
+			amountQuery += " " + matches[3]
+		}
+		inclusive = true
+	} else {
+		return nil, nil
+	}
+
+	rate, label, ok := m.rate(rateStr)
+	if !ok {
+		return nil, nil
+	}
+	req, err := m.converter.ParseConversion(amountQuery)
+	if err != nil || req.Table || req.Inverse || len(req.Via) > 0 {
+		return nil, nil
+	}
+
+	net, gross := req.Amount, req.Amount*(1+rate/100)
+	if inclusive {
+		net, gross = req.Amount/(1+rate/100), req.Amount
+	}
+	tax := gross - net
+
+	code, factor := req.FromCurrency, 1.0
+	if req.ToCurrency != "" && req.ToCurrency != req.FromCurrency {
+		route, err := m.converter.Explain(gross, req.FromCurrency, req.ToCurrency, apiCache)
+		if err != nil {
+			return nil, nil // the currency module reports conversion errors
+		}
+		code, factor = route.To, route.Result/gross
+	}
+
+	rows := []struct {
+		name   string
+		amount float64
+		score  int
+	}{
+		{"Net", net, scoreNet},
+		{"VAT " + label, tax, scoreTax},
+		{"Gross", gross, scoreGross},
+	}
+	results := make([]commontypes.FlowResult, 0, len(rows))
+	for _, row := range rows {
+		shown := formatAmount(row.amount*factor, code)
+		subTitle := fmt.Sprintf("%s of %s %s %s", row.name, formatAmount(req.Amount, req.FromCurrency), req.FromCurrency, basis(inclusive))
+		if code != req.FromCurrency {
+			subTitle += fmt.Sprintf(" | %s %s", formatAmount(row.amount, req.FromCurrency), req.FromCurrency)
+		}
+		results = append(results, commontypes.FlowResult{
+			Title:         fmt.Sprintf("%s: %s %s", row.name, shown, code),
+			SubTitle:      subTitle,
+			IcoPath:       m.iconPath,
+			Score:         row.score,
+			JsonRPCAction: commontypes.CopyToClipboard(shown),
+		})
+	}
+	m.ApplyScoring(m.Name(), results)
+	return results, nil
+}
+
+// rate reads a rate given as a percentage ("20", "7.7%") or a country code
+// ("de"), falling back to VAT_DEFAULT_COUNTRY when none is given. label
+// describes it for the tax row: "20%" or "19% DE".
+func (m *VATModule) rate(s string) (rate float64, label string, ok bool) {
+	s = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "%"))
+	if s == "" {
+		s = m.defaultCountry
+	}
+	if s == "" {
+		return 0, "", false
+	}
+	if country := strings.ToUpper(s); len(country) == 2 && country[0] >= 'A' && country[0] <= 'Z' {
+		rate, ok = m.rates[country]
+		return rate, fmt.Sprintf("%s%% %s", strconv.FormatFloat(rate, 'f', -1, 64), country), ok
+	}
+	rate, err := strconv.ParseFloat(numparse.Normalize(s), 64)
+	if err != nil || rate < 0 || rate >= 100 {
+		return 0, "", false
+	}
+	return rate, strconv.FormatFloat(rate, 'f', -1, 64) + "%", true
+}
+
+func basis(inclusive bool) string {
+	if inclusive {
+		return "incl. VAT"
+	}
+	return "excl. VAT"
+}
+
+func formatAmount(amount float64, code string) string {
+	return strconv.FormatFloat(amount, 'f', currency.GetCurrencyDecimalPlaces(code), 64)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// cp1252High maps the characters Windows-1252 puts at 0x80–0x9F back to their
+// bytes. The five undefined positions decode to C1 controls of the same
+// value, which the Latin-1 range below already covers.
+var cp1252High = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B, 'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+func cp1252Byte(r rune) (byte, bool) {
+	if r <= 0xFF {
+		return byte(r), true
+	}
+	b, ok := cp1252High[r]
+	return b, ok
+}
+
+// fixMojibake repairs UTF-8 that was decoded as Windows-1252 and encoded
+// again, so "âš ï¸" reads "⚠️" and "ðŸ›ï¸" reads "🛍️". Each run of characters
+// that fit in a single byte is re-read as UTF-8 and replaced only if that
+// yields valid UTF-8 with at least one multi-byte character, which genuine
+// Latin-1 text ("café") never does. Reports whether anything changed.
+func fixMojibake(s string) (string, bool) {
+	if !strings.ContainsFunc(s, func(r rune) bool { return r >= 0xC2 && r <= 0xF4 }) {
+		return s, false
+	}
+
+	var out strings.Builder
+	var run []byte
+	fixed := false
+	flush := func(original string) {
+		if len(run) > 0 && utf8.Valid(run) && utf8.RuneCount(run) < len(run) {
+			out.Write(run)
+			fixed = true
+		} else {
+			out.WriteString(original)
+		}
+		run = run[:0]
+	}
+
+	start := 0
+	for i, r := range s {
+		if b, ok := cp1252Byte(r); ok {
+			if len(run) == 0 {
+				start = i
+			}
+			run = append(run, b)
+			continue
+		}
+		flush(s[start:i])
+		start = i + utf8.RuneLen(r)
+		out.WriteRune(r)
+	}
+	flush(s[start:])
+	if !fixed {
+		return s, false
+	}
+	return out.String(), true
+}
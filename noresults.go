@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+
+	"answerflow/commontypes"
+	"answerflow/modules"
+)
+
+// Fallback shown when no module answered a non-empty query.
+//
+//	NO_RESULTS_MODE     item (default), none, or suggest
+//	NO_RESULTS_TITLE    title of the item
+//	NO_RESULTS_SUBTITLE subtitle of the item
+//	NO_RESULTS_ACTION   requery (default, re-submit the query) or none
+//
+// In suggest mode the item offers the example query of the module whose hint
+// best matches what was typed.
+var (
+	noResultsMode     = getEnv("NO_RESULTS_MODE", "item")
+	noResultsTitle    = getEnv("NO_RESULTS_TITLE", "No results found")
+	noResultsSubtitle = getEnv("NO_RESULTS_SUBTITLE", "Please try a different query.")
+	noResultsAction   = getEnv("NO_RESULTS_ACTION", "requery")
+)
+
+// noResultsItem builds the configured fallback item for query, if any.
+func noResultsItem(query string) (commontypes.FlowResult, bool) {
+	item := commontypes.FlowResult{
+		Title:    noResultsTitle,
+		SubTitle: noResultsSubtitle,
+		IcoPath:  defaultModuleIcon,
+		Score:    0,
+	}
+
+	switch noResultsMode {
+	case "none":
+		return commontypes.FlowResult{}, false
+	case "suggest":
+		if hint := closestQueryHint(query); hint != "" {
+			item.SubTitle = "Try: " + hint
+			item.JsonRPCAction = commontypes.ChangeQuery(hint, false)
+			return item, true
+		}
+	}
+
+	if noResultsAction == "requery" {
+		item.JsonRPCAction = commontypes.ChangeQuery(query, false)
+	}
+	return item, true
+}
+
+// closestQueryHint returns the example query of the registered module that
+// shares the longest case-insensitive prefix with query, falling back to the
+// first module that has a hint.
+func closestQueryHint(query string) string {
+	query = strings.ToLower(strings.TrimSpace(query))
+	best, bestLen := "", -1
+	for _, m := range registeredModules {
+		h, ok := m.(modules.QueryHinter)
+		if !ok {
+			continue
+		}
+		hint := h.QueryHint()
+		if n := commonPrefixLen(query, strings.ToLower(hint)); n > bestLen {
+			best, bestLen = hint, n
+		}
+	}
+	return best
+}
+
+func commonPrefixLen(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,165 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var httpClient = &http.Client{Timeout: sendTimeout}
+
+func post(ctx context.Context, req *http.Request) error {
+	resp, err := httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("status %s", resp.Status)
+	}
+	return nil
+}
+
+// Webhook posts {"subject", "text", "level"} as JSON. "text" alone is
+// enough for Slack- and Mattermost-style incoming webhooks.
+type Webhook struct {
+	URL string
+}
+
+func (w *Webhook) Name() string { return "webhook" }
+
+func (w *Webhook) Notify(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(map[string]string{
+		"subject": msg.Subject,
+		"text":    msg.Text,
+		"level":   string(msg.Level),
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return post(ctx, req)
+}
+
+// Telegram sends through the Bot API to one chat, text preformatted.
+type Telegram struct {
+	Token  string
+	ChatID string
+}
+
+func (t *Telegram) Name() string { return "telegram" }
+
+func (t *Telegram) Notify(ctx context.Context, msg Message) error {
+	text := "<pre>" + html.EscapeString(msg.Text) + "</pre>"
+	if msg.Subject != "" {
+		text = "<b>" + html.EscapeString(msg.Subject) + "</b>\n" + text
+	}
+	params := url.Values{}
+	params.Set("chat_id", t.ChatID)
+	params.Set("text", text)
+	params.Set("parse_mode", "HTML")
+
+	req, err := http.NewRequestWithContext(ctx, "POST",
+		"https://api.telegram.org/bot"+t.Token+"/sendMessage", strings.NewReader(params.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := post(ctx, req); err != nil {
+		// The bot URL carries the token; Send logs what we return
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = strings.ReplaceAll(urlErr.URL, t.Token, "<token>")
+		}
+		return err
+	}
+	return nil
+}
+
+// Email sends plain-text mail over SMTP, with PLAIN auth when a username
+// is set.
+type Email struct {
+	Addr     string
+	From     string
+	To       []string
+	Username string
+	Password string
+}
+
+func (e *Email) Name() string { return "email" }
+
+func (e *Email) Notify(ctx context.Context, msg Message) error {
+	if e.From == "" || len(e.To) == 0 {
+		return fmt.Errorf("NOTIFY_SMTP_FROM and NOTIFY_SMTP_TO are required")
+	}
+	var auth smtp.Auth
+	if e.Username != "" {
+		host := e.Addr
+		if i := strings.LastIndex(host, ":"); i >= 0 {
+			host = host[:i]
+		}
+		auth = smtp.PlainAuth("", e.Username, e.Password, host)
+	}
+
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "From: %s\r\n", e.From)
+	fmt.Fprintf(&body, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(&body, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&body, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	body.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	body.WriteString(strings.ReplaceAll(msg.Text, "\n", "\r\n"))
+
+	// net/smtp has no context support; run it so ctx can abandon the wait
+	done := make(chan error, 1)
+	go func() { done <- smtp.SendMail(e.Addr, auth, e.From, e.To, body.Bytes()) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Ntfy publishes to an ntfy topic, which reaches desktop and phone clients.
+type Ntfy struct {
+	URL   string
+	Token string
+}
+
+func (n *Ntfy) Name() string { return "ntfy" }
+
+var ntfyPriority = map[Level]string{
+	LevelInfo:     "default",
+	LevelWarning:  "high",
+	LevelCritical: "urgent",
+}
+
+func (n *Ntfy) Notify(ctx context.Context, msg Message) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", n.URL, strings.NewReader(msg.Text))
+	if err != nil {
+		return err
+	}
+	if msg.Subject != "" {
+		req.Header.Set("Title", msg.Subject)
+	}
+	if priority, ok := ntfyPriority[msg.Level]; ok {
+		req.Header.Set("Priority", priority)
+	}
+	if n.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.Token)
+	}
+	return post(ctx, req)
+}
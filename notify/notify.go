@@ -0,0 +1,134 @@
+// Package notify delivers operator notifications (daily summaries, provider
+// alerts) over the channels configured in the environment, so every
+// subsystem shares one delivery path:
+//
+//	NOTIFY_WEBHOOK_URL        POST {"subject", "text", "level"} as JSON
+//	NOTIFY_TELEGRAM_CHAT_ID   via the TELEGRAM_BOT_TOKEN bot
+//	NOTIFY_SMTP_ADDR          host:port, with NOTIFY_SMTP_FROM, NOTIFY_SMTP_TO
+//	                          (comma-separated) and optional NOTIFY_SMTP_USERNAME
+//	                          and NOTIFY_SMTP_PASSWORD
+//	NOTIFY_NTFY_URL           ntfy topic URL (https://ntfy.sh/<topic>), with
+//	                          optional NOTIFY_NTFY_TOKEN
+//
+// SUMMARY_WEBHOOK_URL and SUMMARY_TELEGRAM_CHAT_ID are still read as
+// aliases of the NOTIFY_ variables.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Level is the urgency of a message; channels that support priorities
+// (ntfy) map it onto theirs.
+type Level string
+
+const (
+	LevelInfo     Level = "info"
+	LevelWarning  Level = "warning"
+	LevelCritical Level = "critical"
+)
+
+type Message struct {
+	Subject string
+	Text    string
+	Level   Level
+}
+
+// Notifier delivers a message over one channel.
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, msg Message) error
+}
+
+// sendTimeout bounds a single delivery attempt on every channel.
+const sendTimeout = 10 * time.Second
+
+// Multi fans a message out to several notifiers. A nil or empty Multi
+// delivers nothing.
+type Multi []Notifier
+
+func (m Multi) Name() string {
+	names := make([]string, len(m))
+	for i, n := range m {
+		names[i] = n.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+// Notify sends msg to every channel and returns the joined errors of the
+// ones that failed.
+func (m Multi) Notify(ctx context.Context, msg Message) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(ctx, msg); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", n.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Send notifies in the background and logs failures, for callers that must
+// not block on delivery.
+func Send(n Notifier, msg Message) {
+	if n == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout*2)
+		defer cancel()
+		if err := n.Notify(ctx, msg); err != nil {
+			log.Printf("Warning: notification %q not delivered: %v", msg.Subject, err)
+		}
+	}()
+}
+
+// FromEnv builds the notifiers configured in the environment.
+func FromEnv() Multi {
+	var m Multi
+	if url := envAlias("NOTIFY_WEBHOOK_URL", "SUMMARY_WEBHOOK_URL"); url != "" {
+		m = append(m, &Webhook{URL: url})
+	}
+	if chatID := envAlias("NOTIFY_TELEGRAM_CHAT_ID", "SUMMARY_TELEGRAM_CHAT_ID"); chatID != "" {
+		if token := os.Getenv("TELEGRAM_BOT_TOKEN"); token != "" {
+			m = append(m, &Telegram{Token: token, ChatID: chatID})
+		} else {
+			log.Println("Warning: NOTIFY_TELEGRAM_CHAT_ID set without TELEGRAM_BOT_TOKEN, Telegram notifications disabled")
+		}
+	}
+	if addr := os.Getenv("NOTIFY_SMTP_ADDR"); addr != "" {
+		m = append(m, &Email{
+			Addr:     addr,
+			From:     os.Getenv("NOTIFY_SMTP_FROM"),
+			To:       splitList(os.Getenv("NOTIFY_SMTP_TO")),
+			Username: os.Getenv("NOTIFY_SMTP_USERNAME"),
+			Password: os.Getenv("NOTIFY_SMTP_PASSWORD"),
+		})
+	}
+	if url := os.Getenv("NOTIFY_NTFY_URL"); url != "" {
+		m = append(m, &Ntfy{URL: url, Token: os.Getenv("NOTIFY_NTFY_TOKEN")})
+	}
+	return m
+}
+
+func envAlias(key, legacy string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return os.Getenv(legacy)
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
@@ -0,0 +1,103 @@
+// Package numparse turns numbers as people type them ("1 234,5", "1,234.5",
+// "1.234,5") into the plain form strconv and expr accept. It is shared by
+// every module that reads amounts, so they agree on what a number means.
+//
+// NUMBER_DECIMAL_SEPARATOR declares the decimal separator: "dot" (1,234.5),
+// "comma" (1.234,5) or "auto" (the default), which guesses per number.
+package numparse
+
+import (
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	_ "answerflow/preset" // PRESET may choose the separator
+)
+
+// Style is how "." and "," are told apart.
+type Style int
+
+const (
+	StyleAuto         Style = iota // guess from the digits, see Normalize
+	StyleDecimalDot                // "," only ever separates thousands
+	StyleDecimalComma              // "." only ever separates thousands
+)
+
+// DefaultStyle is the style Normalize uses, from NUMBER_DECIMAL_SEPARATOR.
+var DefaultStyle = styleFromEnv()
+
+var (
+	digitsOnly     = regexp.MustCompile(`^\d+$`)
+	ambiguousShape = regexp.MustCompile(`^[1-9]\d{0,2}[.,]\d{3}$`)
+)
+
+func styleFromEnv() Style {
+	switch value := strings.ToLower(strings.TrimSpace(os.Getenv("NUMBER_DECIMAL_SEPARATOR"))); value {
+	case "", "auto":
+		return StyleAuto
+	case "dot", ".":
+		return StyleDecimalDot
+	case "comma", ",":
+		return StyleDecimalComma
+	default:
+		log.Printf("Warning: NUMBER_DECIMAL_SEPARATOR=%q not recognised, using auto", value)
+		return StyleAuto
+	}
+}
+
+// Normalize strips thousands separators and makes the decimal separator a
+// dot, in DefaultStyle.
+func Normalize(s string) string {
+	return NormalizeStyle(s, DefaultStyle)
+}
+
+// NormalizeStyle is Normalize in the given style. In StyleAuto, with both
+// "." and "," present the last one is the decimal separator; a lone ","
+// followed by one to three digits is read as a decimal comma ("1,5",
+// "1,234"); several commas are thousands separators.
+func NormalizeStyle(s string, style Style) string {
+	s = strings.ReplaceAll(s, " ", "")
+	s = strings.ReplaceAll(s, " ", "")
+
+	switch style {
+	case StyleDecimalDot:
+		return strings.ReplaceAll(s, ",", "")
+	case StyleDecimalComma:
+		return strings.ReplaceAll(strings.ReplaceAll(s, ".", ""), ",", ".")
+	}
+
+	dotIdx := strings.LastIndex(s, ".")
+	commaIdx := strings.LastIndex(s, ",")
+
+	if dotIdx != -1 && commaIdx != -1 {
+		if commaIdx > dotIdx {
+			s = strings.ReplaceAll(s, ".", "")
+			s = strings.Replace(s, ",", ".", 1)
+		} else {
+			s = strings.ReplaceAll(s, ",", "")
+		}
+	} else if commaIdx != -1 {
+		parts := strings.Split(s, ",")
+		if len(parts) > 1 {
+			lastPart := parts[len(parts)-1]
+			if len(lastPart) >= 1 && len(lastPart) <= 3 && digitsOnly.MatchString(lastPart) {
+				if strings.Count(s, ",") == 1 {
+					s = strings.Join(parts[:len(parts)-1], "") + "." + lastPart
+				} else {
+					s = strings.ReplaceAll(s, ",", "")
+				}
+			} else {
+				s = strings.ReplaceAll(s, ",", "")
+			}
+		}
+	}
+	return s
+}
+
+// Ambiguous reports whether s reads as a different number with a decimal
+// dot than with a decimal comma, like "1,234" or "1.234": one separator
+// followed by exactly three digits.
+func Ambiguous(s string) bool {
+	return ambiguousShape.MatchString(s)
+}
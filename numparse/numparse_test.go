@@ -0,0 +1,98 @@
+package numparse
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+func TestNormalizeStyle(t *testing.T) {
+	tests := []struct {
+		in    string
+		style Style
+		want  string
+	}{
+		{"1234.5", StyleAuto, "1234.5"},
+		{"1,234.5", StyleAuto, "1234.5"},
+		{"1.234,5", StyleAuto, "1234.5"},
+		{"1 234,5", StyleAuto, "1234.5"},
+		{"1 234,5", StyleAuto, "1234.5"},
+		{"1,5", StyleAuto, "1.5"},
+		{"1,234", StyleAuto, "1.234"},
+		{"1,234,567", StyleAuto, "1234567"},
+		{"1,2345", StyleAuto, "12345"},
+		{"1,234", StyleDecimalDot, "1234"},
+		{"1,234.5", StyleDecimalDot, "1234.5"},
+		{"1.234", StyleDecimalComma, "1234"},
+		{"1.234,5", StyleDecimalComma, "1234.5"},
+		{"1234", StyleDecimalComma, "1234"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeStyle(tt.in, tt.style); got != tt.want {
+			t.Errorf("NormalizeStyle(%q, %d) = %q, want %q", tt.in, tt.style, got, tt.want)
+		}
+	}
+}
+
+func TestAmbiguous(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"1,234", true},
+		{"1.234", true},
+		{"999,000", true},
+		{"1,5", false},
+		{"1,2345", false},
+		{"1,234.5", false},
+		{"0,123", false},
+		{"1234", false},
+	}
+	for _, tt := range tests {
+		if got := Ambiguous(tt.in); got != tt.want {
+			t.Errorf("Ambiguous(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+// group writes the integer part of n with sep between thousands and mark
+// before the two decimals.
+func group(n uint32, cents uint8, sep, mark string) string {
+	digits := strconv.FormatUint(uint64(n), 10)
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteString(sep)
+		}
+		b.WriteRune(d)
+	}
+	return b.String() + mark + strconv.Itoa(int(cents%90)+10)
+}
+
+// Formatting a number in either convention and normalizing it in that
+// convention's style, or with guessing, gives the number back.
+func TestNormalizeRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		sep, mark string
+		style     Style
+	}{
+		{"us", ",", ".", StyleDecimalDot},
+		{"eu", ".", ",", StyleDecimalComma},
+		{"fr", " ", ",", StyleDecimalComma},
+		{"ch", " ", ".", StyleDecimalDot},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			property := func(n uint32, cents uint8) bool {
+				formatted := group(n, cents, tt.sep, tt.mark)
+				want := strconv.FormatUint(uint64(n), 10) + "." + strconv.Itoa(int(cents%90)+10)
+				return NormalizeStyle(formatted, tt.style) == want && NormalizeStyle(formatted, StyleAuto) == want
+			}
+			if err := quick.Check(property, nil); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"sort"
+
+	"answerflow/commontypes"
+	"answerflow/modules/currency"
+)
+
+// sortResults orders results by score, breaking ties the same way on every
+// keystroke so equal-score rows don't swap places as modules finish in a
+// different order: by module registration order, then target currency
+// (home, RUB, USD, QUICK_TARGETS, favorites, then by code), then title.
+// origins[i] names the module that produced results[i]; it is reordered
+// along with results.
+func sortResults(ctx context.Context, results []commontypes.FlowResult, origins []string) {
+	moduleRank := make(map[string]int, len(registeredModules))
+	for i, m := range registeredModules {
+		moduleRank[m.Name()] = i
+	}
+	targetRank := make(map[string]int)
+	addTarget := func(code string) {
+		if _, ok := targetRank[code]; !ok && code != "" {
+			targetRank[code] = len(targetRank)
+		}
+	}
+	addTarget(commontypes.HomeCurrencyFromContext(ctx))
+	addTarget(currency.CurrencyRUB)
+	addTarget(currency.CurrencyUSD)
+	for _, code := range quickTargets {
+		addTarget(code)
+	}
+	for _, code := range commontypes.FavoriteCurrenciesFromContext(ctx) {
+		addTarget(code)
+	}
+
+	type sortKey struct {
+		module int
+		target string
+	}
+	keys := make([]sortKey, len(results))
+	for i := range results {
+		keys[i] = sortKey{module: moduleRank[origins[i]]}
+		if route, ok := results[i].ContextData.(*currency.Route); ok {
+			keys[i].target = route.To
+		}
+	}
+	less := func(a, b int) bool {
+		ra, rb := results[a], results[b]
+		if ra.Score != rb.Score {
+			return ra.Score > rb.Score
+		}
+		ka, kb := keys[a], keys[b]
+		if ka.module != kb.module {
+			return ka.module < kb.module
+		}
+		if ka.target != kb.target {
+			ta, aKnown := targetRank[ka.target]
+			tb, bKnown := targetRank[kb.target]
+			switch {
+			case aKnown && bKnown:
+				return ta < tb
+			case aKnown != bKnown:
+				return aKnown
+			default:
+				return ka.target < kb.target
+			}
+		}
+		return ra.Title < rb.Title
+	}
+	order := make([]int, len(results))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return less(order[i], order[j]) })
+
+	sorted := make([]commontypes.FlowResult, len(results))
+	sortedOrigins := make([]string, len(origins))
+	for i, from := range order {
+		sorted[i], sortedOrigins[i] = results[from], origins[from]
+	}
+	copy(results, sorted)
+	copy(origins, sortedOrigins)
+}
@@ -0,0 +1,60 @@
+package main
+
+import "net/http"
+
+// parseResult is what GET /parse reports: how the currency parser read a
+// query, or why it couldn't.
+type parseResult struct {
+	Query       string   `json:"query"`
+	OK          bool     `json:"ok"`
+	Error       string   `json:"error,omitempty"`
+	Pattern     string   `json:"pattern,omitempty"`
+	Amount      float64  `json:"amount,omitempty"`
+	From        string   `json:"from,omitempty"`
+	To          string   `json:"to,omitempty"`
+	Via         []string `json:"via,omitempty"`
+	Symbol      string   `json:"symbol,omitempty"`
+	Inverse     bool     `json:"inverse,omitempty"`
+	Table       bool     `json:"table,omitempty"`
+	Side        string   `json:"side,omitempty"`
+	Precision   *int     `json:"precision,omitempty"`
+	PersonalFee float64  `json:"personal_fee,omitempty"`
+	Words       bool     `json:"words,omitempty"`
+	EntryPrice  float64  `json:"entry_price,omitempty"`
+	AmountNote  string   `json:"amount_note,omitempty"`
+}
+
+// handleParse serves GET /parse?q= with the parsed conversion request,
+// without converting anything, so a query that isn't understood can be
+// reported with the exact parse and frontends can check input early. A
+// query that doesn't parse is still a 200 with ok=false.
+func handleParse(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	result := parseResult{Query: query}
+	req, err := currencyModule.ParseConversion(query)
+	if err != nil {
+		result.Error = err.Error()
+		writeJSON(w, result)
+		return
+	}
+
+	result.OK = true
+	result.Pattern = req.Pattern
+	result.Amount = req.Amount
+	result.From = req.FromCurrency
+	result.To = req.ToCurrency
+	result.Via = req.Via
+	result.Symbol = req.Symbol
+	result.Inverse = req.Inverse
+	result.Table = req.Table
+	result.Side = req.Side
+	if req.HasPrecision {
+		precision := req.Precision
+		result.Precision = &precision
+	}
+	result.PersonalFee = req.PersonalFee
+	result.Words = req.Words
+	result.EntryPrice = req.EntryPrice
+	result.AmountNote = req.AmountNote
+	writeJSON(w, result)
+}
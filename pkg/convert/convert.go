@@ -0,0 +1,155 @@
+// Package convert exposes the conversion engine (providers, router and fee
+// model) to other Go programs without the launcher frontends or the HTTP
+// server:
+//
+//	engine := convert.New(convert.Options{Background: true})
+//	if err := engine.Start(); err != nil {
+//		log.Fatal(err)
+//	}
+//	defer engine.Close()
+//	route, err := engine.Explain(100, "USD", "EUR")
+//
+// Providers are configured through the same environment variables as the
+// server (API URLs, credentials, criticality, refresh policies).
+package convert
+
+import (
+	"time"
+
+	"answerflow/modules/currency"
+)
+
+// Route describes one conversion leg by leg, with the providers used and
+// how long the quote stays valid.
+type Route struct {
+	Amount        float64   `json:"amount"`
+	From          string    `json:"from"`
+	To            string    `json:"to"`
+	Result        float64   `json:"result"`
+	EffectiveRate float64   `json:"effective_rate"`
+	Legs          []Step    `json:"legs"`
+	Providers     []string  `json:"providers"`
+	Approximate   bool      `json:"approximate,omitempty"` // priced from a modeled or top-of-book rate
+	QuotedAt      time.Time `json:"quoted_at"`             // timestamp of the oldest rate used
+	ValidUntil    time.Time `json:"valid_until"`           // first moment any rate used is due for refresh
+	Warnings      []string  `json:"warnings,omitempty"`
+}
+
+// Step is a single leg of a Route.
+type Step struct {
+	From          string    `json:"from"`
+	To            string    `json:"to"`
+	AmountIn      float64   `json:"amount_in"`
+	AmountOut     float64   `json:"amount_out"`
+	Rate          float64   `json:"rate"`
+	Fee           string    `json:"fee"`
+	Provider      string    `json:"provider"`
+	RateTimestamp time.Time `json:"rate_timestamp"`
+	Description   string    `json:"description"`
+}
+
+// newRoute copies the engine's route into the package's own types, so
+// changes to the engine's internals don't reach callers.
+func newRoute(r *currency.Route) *Route {
+	route := &Route{
+		Amount:        r.Amount,
+		From:          r.From,
+		To:            r.To,
+		Result:        r.Result,
+		EffectiveRate: r.EffectiveRate,
+		Legs:          make([]Step, len(r.Legs)),
+		Providers:     append([]string(nil), r.Providers...),
+		Approximate:   r.Approximate,
+		QuotedAt:      r.QuotedAt,
+		ValidUntil:    r.ValidUntil,
+		Warnings:      append([]string(nil), r.Warnings...),
+	}
+	for i, leg := range r.Legs {
+		route.Legs[i] = Step{
+			From:          leg.From,
+			To:            leg.To,
+			AmountIn:      leg.AmountIn,
+			AmountOut:     leg.AmountOut,
+			Rate:          leg.Rate,
+			Fee:           leg.Fee,
+			Provider:      leg.Provider,
+			RateTimestamp: leg.RateTimestamp,
+			Description:   leg.Description,
+		}
+	}
+	return route
+}
+
+type Options struct {
+	// Background keeps rates fresh with the provider update loops. Leave it
+	// off for one-shot tools that convert once and exit.
+	Background bool
+
+	// UseDiskCache seeds rates from the on-disk cache and skips the initial
+	// fetch when the cache covers every enabled provider.
+	UseDiskCache bool
+}
+
+// Engine converts amounts between currencies. It is safe for concurrent use
+// once Start has returned.
+type Engine struct {
+	opts   Options
+	cache  *currency.APICache
+	router *currency.CurrencyConverterModule
+}
+
+func New(opts Options) *Engine {
+	return &Engine{
+		opts:   opts,
+		cache:  currency.NewAPICache(),
+		router: currency.NewCurrencyConverterModule(nil, currency.CurrencyUSD, "", true),
+	}
+}
+
+// Start loads the initial rates and, with Options.Background, starts the
+// update loops. It fails only when a critical provider is unavailable.
+func (e *Engine) Start() error {
+	loaded := false
+	if e.opts.UseDiskCache {
+		loaded = e.cache.LoadFromFile() == nil && e.cache.HasCachedRates()
+	}
+	if !loaded {
+		if err := e.cache.InitialFetch(); err != nil {
+			return err
+		}
+	}
+	e.cache.InitializeTradeablePairs()
+	if e.opts.Background {
+		e.cache.StartBackgroundUpdaters()
+	}
+	return nil
+}
+
+// Explain converts amount from one currency to another and returns the full
+// route. Currencies may be codes, names or symbols ("usd", "dollar", "$").
+func (e *Engine) Explain(amount float64, from, to string) (*Route, error) {
+	route, err := e.router.Explain(amount, from, to, e.cache)
+	if err != nil {
+		return nil, err
+	}
+	return newRoute(route), nil
+}
+
+// Convert returns only the converted amount of Explain.
+func (e *Engine) Convert(amount float64, from, to string) (float64, error) {
+	route, err := e.Explain(amount, from, to)
+	if err != nil {
+		return 0, err
+	}
+	return route.Result, nil
+}
+
+// Refresh fetches fresh rates from every enabled provider now.
+func (e *Engine) Refresh() error {
+	return e.cache.ForceRefresh()
+}
+
+// Close stops the update loops and releases the engine's resources.
+func (e *Engine) Close() {
+	e.cache.Shutdown()
+}
@@ -0,0 +1,92 @@
+// Package preset bundles the settings of common deployments under one name,
+// picked with PRESET: quick targets, home currency, number and wording
+// locale, and the fee profile (card network, USDT network, RUB bridge).
+//
+// A preset only supplies defaults: a variable set in the environment wins
+// over it. Packages that read settings while initialising import preset for
+// its side effect, so the preset is in place before they look.
+package preset
+
+import (
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+var presets = map[string]map[string]string{
+	// Russian user paying by card abroad: RUB home, cash-out chain, decimal comma
+	"ru": {
+		"HOME_CURRENCY":            "RUB",
+		"QUICK_TARGETS":            "EUR",
+		"RUB_BRIDGE":               "true",
+		"CARD_NETWORK":             "mastercard",
+		"NUMBER_DECIMAL_SEPARATOR": "comma",
+		"AMOUNT_WORDS_LANG":        "ru",
+		"VAT_DEFAULT_COUNTRY":      "RU",
+	},
+	// Euro area: plain reference rates, either decimal separator
+	"eu": {
+		"HOME_CURRENCY":            "EUR",
+		"QUICK_TARGETS":            "GBP,CHF",
+		"RUB_BRIDGE":               "false",
+		"CARD_NETWORK":             "mastercard",
+		"NUMBER_DECIMAL_SEPARATOR": "auto",
+		"AMOUNT_WORDS_LANG":        "en",
+	},
+	"us": {
+		"HOME_CURRENCY":            "USD",
+		"QUICK_TARGETS":            "EUR,GBP,CAD",
+		"RUB_BRIDGE":               "false",
+		"CARD_NETWORK":             "mastercard",
+		"NUMBER_DECIMAL_SEPARATOR": "dot",
+		"AMOUNT_WORDS_LANG":        "en",
+	},
+	// Prices in USDT, exchange quotes next to the mid-market reference
+	"crypto-trader": {
+		"HOME_CURRENCY":            "USDT",
+		"QUICK_TARGETS":            "BTC,ETH",
+		"RUB_BRIDGE":               "false",
+		"USDT_NETWORK":             "trc20",
+		"REFERENCE_RESULTS":        "true",
+		"NUMBER_DECIMAL_SEPARATOR": "dot",
+		"AMOUNT_WORDS_LANG":        "en",
+	},
+}
+
+// Active is the preset in effect, "" when PRESET is unset or unknown.
+var Active = apply(os.Getenv("PRESET"))
+
+// Names lists the presets PRESET accepts.
+func Names() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Settings returns the variables name sets, nil for an unknown preset.
+func Settings(name string) map[string]string {
+	return presets[name]
+}
+
+func apply(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return ""
+	}
+	settings, ok := presets[name]
+	if !ok {
+		log.Printf("Warning: unknown PRESET %q, none applied (known: %s)", name, strings.Join(Names(), ", "))
+		return ""
+	}
+	for key, value := range settings {
+		if os.Getenv(key) == "" {
+			os.Setenv(key, value)
+		}
+	}
+	log.Printf("Preset %s applied", name)
+	return name
+}
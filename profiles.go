@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"answerflow/modules/currency"
+)
+
+// Preference profiles let one shared instance serve several people: each
+// client sends its ID (X-Client-ID header or ?client=) and gets its own
+// defaults. Profiles persist in PROFILES_FILE; at most PROFILES_MAX exist.
+var (
+	profilesPath = getEnv("PROFILES_FILE", "data/profiles.json")
+	profilesMax  = getEnvInt("PROFILES_MAX", 100)
+
+	profiles = &profileStore{Profiles: make(map[string]*profile)}
+
+	clientIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+)
+
+// profile is one client's defaults. Query parameters on a request still
+// override them.
+type profile struct {
+	Home      string   `json:"home,omitempty"`
+	Locale    string   `json:"locale,omitempty"`
+	Favorites []string `json:"favorites,omitempty"`
+	Fee       float64  `json:"fee,omitempty"`
+	Watchlist []string `json:"watchlist,omitempty"` // "USD/RUB" pairs shown for an empty query
+}
+
+func (p *profile) normalize() error {
+	p.Home = strings.ToUpper(strings.TrimSpace(p.Home))
+	p.Locale = strings.TrimSpace(p.Locale)
+	if p.Fee < 0 || p.Fee >= 100 {
+		return fmt.Errorf("fee must be a percentage in [0, 100)")
+	}
+	var favorites []string
+	for _, code := range p.Favorites {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if code != "" && !containsCode(favorites, code) {
+			favorites = append(favorites, code)
+		}
+	}
+	if len(favorites) > 10 {
+		return fmt.Errorf("at most 10 favorites")
+	}
+	p.Favorites = favorites
+
+	var pairs []string
+	for _, pair := range p.Watchlist {
+		pair = strings.ToUpper(strings.TrimSpace(pair))
+		if _, _, ok := currency.SplitWatchPair(pair); !ok {
+			return fmt.Errorf("watchlist entry %q is not a FROM/TO pair", pair)
+		}
+		if !containsCode(pairs, pair) {
+			pairs = append(pairs, pair)
+		}
+	}
+	if len(pairs) > 10 {
+		return fmt.Errorf("at most 10 watchlist pairs")
+	}
+	p.Watchlist = pairs
+	return nil
+}
+
+func containsCode(codes []string, code string) bool {
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+type profileStore struct {
+	mu       sync.RWMutex
+	Profiles map[string]*profile `json:"profiles"`
+}
+
+// Get returns a copy of the profile for client id, or nil.
+func (s *profileStore) Get(id string) *profile {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.Profiles[id]
+	if !ok {
+		return nil
+	}
+	cp := *p
+	cp.Favorites = append([]string(nil), p.Favorites...)
+	cp.Watchlist = append([]string(nil), p.Watchlist...)
+	return &cp
+}
+
+// Set stores p as the profile for client id and persists the store.
+func (s *profileStore) Set(id string, p *profile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.Profiles[id]; !ok && len(s.Profiles) >= profilesMax {
+		return errProfilesFull
+	}
+	s.Profiles[id] = p
+	return s.saveLocked()
+}
+
+// Delete removes the profile for client id and persists the store.
+func (s *profileStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.Profiles, id)
+	return s.saveLocked()
+}
+
+func (s *profileStore) snapshot() map[string]profile {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make(map[string]profile, len(s.Profiles))
+	for id, p := range s.Profiles {
+		out[id] = *p
+	}
+	return out
+}
+
+var errProfilesFull = fmt.Errorf("profile limit reached")
+
+func (s *profileStore) saveLocked() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(profilesPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	tempFile := profilesPath + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0600); err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := os.Rename(tempFile, profilesPath); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+	return nil
+}
+
+// loadProfiles restores the profiles saved by a previous run.
+func loadProfiles() {
+	data, err := os.ReadFile(profilesPath)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err == nil {
+		profiles.mu.Lock()
+		err = json.Unmarshal(data, profiles)
+		if profiles.Profiles == nil {
+			profiles.Profiles = make(map[string]*profile)
+		}
+		profiles.mu.Unlock()
+	}
+	if err != nil {
+		log.Printf("Warning: profiles not loaded from %s: %v", profilesPath, err)
+		return
+	}
+	log.Printf("Loaded %d preference profiles from %s", len(profiles.snapshot()), profilesPath)
+}
+
+// clientID returns the requester's client ID, or "" when none or an
+// invalid one is given.
+func clientID(r *http.Request) string {
+	id := r.Header.Get("X-Client-ID")
+	if id == "" {
+		id = r.URL.Query().Get("client")
+	}
+	if !clientIDPattern.MatchString(id) {
+		return ""
+	}
+	return id
+}
+
+type clientIDContextKey struct{}
+
+func withClientID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, clientIDContextKey{}, id)
+}
+
+// clientIDFromContext returns the client ID of the query in ctx, or "".
+func clientIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(clientIDContextKey{}).(string)
+	return id
+}
+
+// requestProfile returns the profile of the requesting client, or an empty
+// one.
+func requestProfile(r *http.Request) *profile {
+	if id := clientID(r); id != "" {
+		if p := profiles.Get(id); p != nil {
+			return p
+		}
+	}
+	return &profile{}
+}
+
+// handleProfile lets a client read (GET), replace (PUT, JSON body) or
+// remove (DELETE) its own profile.
+func handleProfile(w http.ResponseWriter, r *http.Request) {
+	id := clientID(r)
+	if id == "" {
+		http.Error(w, "X-Client-ID header or client parameter required", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		p := profiles.Get(id)
+		if p == nil {
+			http.Error(w, "no profile", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, p)
+
+	case http.MethodPut:
+		var p profile
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&p); err != nil {
+			http.Error(w, "invalid profile: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := p.normalize(); err != nil {
+			http.Error(w, "invalid profile: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := profiles.Set(id, &p); err == errProfilesFull {
+			http.Error(w, err.Error(), http.StatusInsufficientStorage)
+			return
+		} else if err != nil {
+			// The profile took effect; only persisting it failed
+			log.Printf("Warning: profile for %s not saved: %v", id, err)
+		}
+		writeJSON(w, p)
+
+	case http.MethodDelete:
+		if err := profiles.Delete(id); err != nil {
+			log.Printf("Warning: profile removal for %s not saved: %v", id, err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleProfiles lists every stored profile by client ID.
+func handleProfiles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, profiles.snapshot())
+}
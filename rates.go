@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"answerflow/modules/currency"
+)
+
+// /rates/rub lists effective RUB rates, full route and fees included, for
+// spreadsheets (=IMPORTDATA("https://.../rates/rub")) and dashboards.
+// RUB_RATES_CURRENCIES is the default list, ?currencies= overrides it; every
+// rate is priced at RUB_RATES_AMOUNT RUB so large-order slippage and fixed
+// fees weigh the same for all of them.
+var (
+	rubRatesCurrencies = loadQuickTargets(getEnv("RUB_RATES_CURRENCIES", "USD,EUR,USDT,TON,BTC"))
+	rubRatesAmount     = getEnvInt("RUB_RATES_AMOUNT", 100000)
+)
+
+// handleRUBRates serves the rates as CSV, or as JSON with ?format=json.
+func handleRUBRates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	codes := rubRatesCurrencies
+	if value := r.URL.Query().Get("currencies"); value != "" {
+		codes = loadQuickTargets(value)
+		if len(codes) > 50 {
+			http.Error(w, "at most 50 currencies", http.StatusBadRequest)
+			return
+		}
+	}
+	for _, code := range codes {
+		if !currency.KnownCurrency(code) {
+			http.Error(w, fmt.Sprintf("unknown currency %q", code), http.StatusBadRequest)
+			return
+		}
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "csv" && format != "json" {
+		http.Error(w, "format must be csv or json", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+	now := time.Now().UTC()
+	rates := currencyModule.EffectiveRUBRates(ctx, codes, float64(rubRatesAmount), globalAPICache)
+
+	if format == "json" {
+		writeJSON(w, map[string]interface{}{
+			"amount_rub": rubRatesAmount,
+			"as_of":      now,
+			"rates":      rates,
+		})
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	out := csv.NewWriter(w)
+	out.Write([]string{"currency", "buy_rub", "sell_rub", "amount_rub", "as_of", "error"})
+	for _, rate := range rates {
+		out.Write([]string{rate.Code, csvRate(rate.Buy), csvRate(rate.Sell), strconv.Itoa(rubRatesAmount), now.Format(time.RFC3339), rate.Error})
+	}
+	out.Flush()
+}
+
+// csvRate leaves a missing rate empty rather than 0, so a spreadsheet shows
+// a gap instead of a wrong price.
+func csvRate(rate float64) string {
+	if rate <= 0 {
+		return ""
+	}
+	return strings.TrimRight(strings.TrimRight(strconv.FormatFloat(rate, 'f', 6, 64), "0"), ".")
+}
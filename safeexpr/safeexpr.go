@@ -0,0 +1,71 @@
+// Package safeexpr evaluates user-supplied arithmetic with expr under cost
+// limits: bounded AST size, a whitelist of node types and operators, and no
+// builtins. Every node the whitelist allows evaluates in constant time (no
+// loops, predicates or collections), so MaxNodes caps the work of an
+// evaluation and it runs to completion on the caller's goroutine; functions
+// passed in env must be constant-time as well. Callers enforce MaxLength on
+// the raw query, before their own preprocessing expands it.
+package safeexpr
+
+import (
+	"fmt"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/conf"
+)
+
+const (
+	MaxLength = 200 // Characters of raw user input
+	MaxNodes  = 100 // AST nodes after parsing
+)
+
+var allowedOperators = map[string]bool{
+	"+": true, "-": true, "*": true, "/": true, "%": true, "^": true, "**": true,
+	"==": true, "!=": true, "<": true, ">": true, "<=": true, ">=": true,
+	"&&": true, "||": true, "!": true, "and": true, "or": true, "not": true,
+}
+
+// whitelist rejects everything but numbers, identifiers from the
+// environment, calls and arithmetic/comparison operators.
+type whitelist struct {
+	err error
+}
+
+func (w *whitelist) Visit(node *ast.Node) {
+	if w.err != nil {
+		return
+	}
+	switch n := (*node).(type) {
+	case *ast.IntegerNode, *ast.FloatNode, *ast.BoolNode, *ast.IdentifierNode, *ast.CallNode:
+	case *ast.UnaryNode:
+		if !allowedOperators[n.Operator] {
+			w.err = fmt.Errorf("operator %q not allowed", n.Operator)
+		}
+	case *ast.BinaryNode:
+		if !allowedOperators[n.Operator] {
+			w.err = fmt.Errorf("operator %q not allowed", n.Operator)
+		}
+	default:
+		w.err = fmt.Errorf("expression element %T not allowed", n)
+	}
+}
+
+// Eval compiles and runs input against env (which may be nil) within the
+// package limits.
+func Eval(input string, env map[string]interface{}) (interface{}, error) {
+	guard := &whitelist{}
+	program, err := expr.Compile(input,
+		expr.Env(env),
+		expr.DisableAllBuiltins(),
+		expr.Patch(guard),
+		func(c *conf.Config) { c.MaxNodes = MaxNodes },
+	)
+	if err == nil {
+		err = guard.err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return expr.Run(program, env)
+}
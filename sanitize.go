@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"answerflow/commontypes"
+)
+
+// Limits on result text, in characters. Launchers truncate long lines
+// anyway; these only stop a runaway module from shipping megabytes.
+var (
+	maxTitleLength    = getEnvInt("RESULT_MAX_TITLE_LENGTH", 200)
+	maxSubTitleLength = getEnvInt("RESULT_MAX_SUBTITLE_LENGTH", 500)
+)
+
+// ACTION_METHODS lists the launcher action methods results may carry,
+// comma-separated; by default commontypes.DefaultActionMethods, which
+// leaves out running shell commands. Actions with any other method, or
+// with parameters their method doesn't take, are dropped.
+var allowedActionMethods = loadAllowedActionMethods()
+
+func loadAllowedActionMethods() map[string]bool {
+	allowed := make(map[string]bool)
+	for _, method := range strings.Split(getEnv("ACTION_METHODS", strings.Join(commontypes.DefaultActionMethods, ",")), ",") {
+		if method = strings.TrimSpace(method); method != "" {
+			allowed[method] = true
+		}
+	}
+	return allowed
+}
+
+// sanitizeWarned remembers which module/problem pairs were already logged,
+// so a misbehaving module is reported once rather than on every keystroke.
+var sanitizeWarned sync.Map
+
+// sanitizeResult makes a module result safe to encode: invalid UTF-8 is
+// dropped, double-encoded UTF-8 is repaired, control characters are removed
+// (line breaks and tabs become spaces) and overlong text is cut. Actions
+// ACTION_METHODS doesn't allow are dropped. Offending modules are logged.
+func sanitizeResult(module string, res *commontypes.FlowResult) {
+	var problems []string
+	clean := func(s string, limit int) string {
+		out, problem := sanitizeText(s, limit)
+		if problem != "" {
+			problems = append(problems, problem)
+		}
+		return out
+	}
+
+	action := func(a commontypes.JsonRPCAction) (commontypes.JsonRPCAction, bool) {
+		problem := actionProblem(a)
+		if problem == "" {
+			return a, true
+		}
+		problems = append(problems, problem)
+		return commontypes.JsonRPCAction{}, false
+	}
+
+	res.Title = clean(res.Title, maxTitleLength)
+	res.SubTitle = clean(res.SubTitle, maxSubTitleLength)
+	res.JsonRPCAction, _ = action(res.JsonRPCAction)
+	// A fresh slice: results may share their menu with a cached copy
+	var items []commontypes.ContextMenuItem
+	for _, item := range res.ContextMenuItems {
+		var ok bool
+		if item.JsonRPCAction, ok = action(item.JsonRPCAction); !ok {
+			continue
+		}
+		item.Title = clean(item.Title, maxTitleLength)
+		item.SubTitle = clean(item.SubTitle, maxSubTitleLength)
+		items = append(items, item)
+	}
+	res.ContextMenuItems = items
+
+	for _, problem := range problems {
+		if _, seen := sanitizeWarned.LoadOrStore(module+"\x00"+problem, true); !seen {
+			log.Printf("Warning: module '%s' returned %s (query results are sanitized, logged once)", module, problem)
+		}
+	}
+}
+
+// actionProblem says what is wrong with an action, if anything: a method
+// not in ACTION_METHODS or parameters the method doesn't take.
+func actionProblem(a commontypes.JsonRPCAction) string {
+	if a.Method == "" {
+		return ""
+	}
+	if !allowedActionMethods[a.Method] {
+		return fmt.Sprintf("action method %q not in ACTION_METHODS", a.Method)
+	}
+	if err := commontypes.ValidateAction(a); err != nil {
+		return "invalid action: " + err.Error()
+	}
+	return ""
+}
+
+// sanitizeText returns s cleaned and cut to limit characters, and what was
+// wrong with it, if anything.
+func sanitizeText(s string, limit int) (string, string) {
+	problem := ""
+	if !utf8.ValidString(s) {
+		s = strings.ToValidUTF8(s, "")
+		problem = "invalid UTF-8"
+	}
+	if fixed, ok := fixMojibake(s); ok {
+		s = fixed
+		if problem == "" {
+			problem = "double-encoded UTF-8"
+		}
+	}
+	if strings.IndexFunc(s, unicode.IsControl) >= 0 {
+		s = strings.Map(func(r rune) rune {
+			switch {
+			case r == '\n' || r == '\r' || r == '\t':
+				return ' '
+			case unicode.IsControl(r):
+				return -1
+			}
+			return r
+		}, s)
+		if problem == "" {
+			problem = "control characters"
+		}
+	}
+	if limit > 0 && utf8.RuneCountInString(s) > limit {
+		s = string([]rune(s)[:limit-1]) + "…"
+		if problem == "" {
+			problem = "overlong text"
+		}
+	}
+	return s, problem
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"log"
+	"math"
+	"strings"
+	"sync"
+
+	"answerflow/commontypes"
+	"answerflow/modules/currency"
+)
+
+// SCORING_STRATEGY picks how module base scores are adjusted before
+// ranking: "static" keeps them, "usage" favours the modules this deployment
+// answers with most, "freshness" demotes results priced from stale rates.
+var scoringStrategy = loadScoringStrategy(getEnv("SCORING_STRATEGY", "static"))
+
+// Most any strategy moves a score, so a module's own ordering of its result
+// types (specific conversion above quick conversions, say) survives.
+var (
+	usageMaxBoost       = getEnvInt("SCORING_USAGE_MAX_BOOST", 5)
+	freshnessMaxPenalty = getEnvInt("SCORING_FRESHNESS_MAX_PENALTY", 10)
+	freshnessHalfLife   = getEnvDuration("SCORING_FRESHNESS_HALF_LIFE", 0)
+)
+
+func loadScoringStrategy(name string) commontypes.ScoringStrategy {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "static", "":
+		return staticScoring{}
+	case "usage":
+		return &usageScoring{counts: make(map[string]int)}
+	case "freshness":
+		return freshnessScoring{}
+	}
+	log.Printf("Warning: unknown SCORING_STRATEGY %q, using static", name)
+	return staticScoring{}
+}
+
+// staticScoring keeps every module's base score.
+type staticScoring struct{}
+
+func (staticScoring) Score(module string, res *commontypes.FlowResult) int {
+	return res.Score
+}
+
+// usageScoring raises a module's scores by up to usageMaxBoost in
+// proportion to how many results it has returned, relative to the busiest
+// module, so ties between modules go to the one the deployment relies on.
+type usageScoring struct {
+	mu     sync.Mutex
+	counts map[string]int
+	most   int
+}
+
+func (s *usageScoring) Score(module string, res *commontypes.FlowResult) int {
+	s.mu.Lock()
+	s.counts[module]++
+	count := s.counts[module]
+	if count > s.most {
+		s.most = count
+	}
+	most := s.most
+	s.mu.Unlock()
+
+	return res.Score + int(math.Round(float64(usageMaxBoost)*float64(count)/float64(most)))
+}
+
+// freshnessScoring lowers the score of results by the age of the rates
+// behind them: the full freshnessMaxPenalty for stale or approximate
+// results, and for others a penalty that reaches half of it at
+// SCORING_FRESHNESS_HALF_LIFE and nears all of it beyond (off when unset).
+type freshnessScoring struct{}
+
+func (freshnessScoring) Score(module string, res *commontypes.FlowResult) int {
+	for _, b := range res.Badges {
+		if b == commontypes.BadgeStale || b == commontypes.BadgeApproximate {
+			return res.Score - freshnessMaxPenalty
+		}
+	}
+	route, ok := res.ContextData.(*currency.Route)
+	if !ok || freshnessHalfLife <= 0 {
+		return res.Score
+	}
+	freshness := math.Exp2(-route.StalenessSeconds / freshnessHalfLife.Seconds())
+	return res.Score - int(math.Round(float64(freshnessMaxPenalty)*(1-freshness)))
+}
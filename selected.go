@@ -0,0 +1,25 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"answerflow/events"
+)
+
+// handleSelected serves POST /selected?q=&title=, sent by clients when the
+// user picks a result. Picks are counted in the metrics; anyone can send
+// them, so they never feed autocomplete.
+func handleSelected(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		http.Error(w, "q is required", http.StatusBadRequest)
+		return
+	}
+	events.Publish(events.Event{Kind: events.ResultSelected, Query: query, Title: r.URL.Query().Get("title")})
+	w.WriteHeader(http.StatusNoContent)
+}
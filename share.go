@@ -0,0 +1,189 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"answerflow/commontypes"
+)
+
+// Shared quotes live in memory for SHARE_TTL; at most SHARE_MAX_ENTRIES are
+// kept, the least recently used making room first.
+var (
+	shareTTL        = getEnvDuration("SHARE_TTL", 24*time.Hour)
+	shareMaxEntries = getEnvInt("SHARE_MAX_ENTRIES", 10000)
+	shareBaseURL    = strings.TrimRight(getEnv("SHARE_BASE_URL", ""), "/")
+
+	shares = newShareStore()
+)
+
+// sharedQuote is a result frozen at the time it was shared.
+type sharedQuote struct {
+	ID        string      `json:"id"`
+	Query     string      `json:"query"`
+	Title     string      `json:"title"`
+	SubTitle  string      `json:"subtitle"`
+	Route     interface{} `json:"route,omitempty"`
+	QuotedAt  time.Time   `json:"quoted_at"`
+	ExpiresAt time.Time   `json:"expires_at"`
+}
+
+type shareStore struct {
+	mu      sync.Mutex
+	order   *list.List // front is most recently used
+	entries map[string]*list.Element
+}
+
+func newShareStore() *shareStore {
+	return &shareStore{order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+func (s *shareStore) put(q *sharedQuote) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.entries[q.ID]; ok {
+		el.Value = q
+		s.order.MoveToFront(el)
+		return
+	}
+	s.entries[q.ID] = s.order.PushFront(q)
+	for s.order.Len() > max(shareMaxEntries, 1) {
+		s.removeLocked(s.order.Back())
+	}
+}
+
+func (s *shareStore) removeLocked(el *list.Element) {
+	s.order.Remove(el)
+	delete(s.entries, el.Value.(*sharedQuote).ID)
+}
+
+// compact drops the expired quotes; the cache's janitor calls it.
+func (s *shareStore) compact(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for el := s.order.Front(); el != nil; {
+		next := el.Next()
+		if now.After(el.Value.(*sharedQuote).ExpiresAt) {
+			s.removeLocked(el)
+		}
+		el = next
+	}
+}
+
+func (s *shareStore) get(id string) (*sharedQuote, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.entries[id]
+	if !ok {
+		return nil, false
+	}
+	q := el.Value.(*sharedQuote)
+	if time.Now().After(q.ExpiresAt) {
+		return nil, false
+	}
+	s.order.MoveToFront(el)
+	return q, true
+}
+
+func newShareID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// handleShare serves POST /share?q=: it runs the query, freezes its top
+// result and answers with the link to it.
+func handleShare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		http.Error(w, "missing q", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+	if home := homeCurrencyFor(r); home != "" {
+		ctx = commontypes.WithHomeCurrency(ctx, home)
+	}
+	results := runModulesCoalesced(ctx, query)
+	if len(results) == 0 {
+		http.Error(w, "nothing to share", http.StatusNotFound)
+		return
+	}
+
+	now := time.Now()
+	top := results[0]
+	quote := &sharedQuote{
+		ID:        newShareID(),
+		Query:     query,
+		Title:     top.Title,
+		SubTitle:  top.SubTitle,
+		Route:     top.ContextData,
+		QuotedAt:  now,
+		ExpiresAt: now.Add(shareTTL),
+	}
+	shares.put(quote)
+
+	writeJSON(w, map[string]interface{}{
+		"id":         quote.ID,
+		"url":        shareURL(r, quote.ID),
+		"expires_at": quote.ExpiresAt,
+	})
+}
+
+// shareURL is the public link to a shared quote, on SHARE_BASE_URL or else
+// the host the request came in on.
+func shareURL(r *http.Request, id string) string {
+	base := shareBaseURL
+	if base == "" {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		base = scheme + "://" + r.Host
+	}
+	return base + "/r/" + id
+}
+
+var sharedQuotePage = template.Must(template.New("share").Parse(`<!doctype html>
+<html><head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>body{font-family:system-ui,sans-serif;max-width:32rem;margin:3rem auto;padding:0 1rem}h1{font-size:1.6rem}p{color:#555}small{color:#888}</style>
+</head><body>
+<h1>{{.Title}}</h1>
+<p>{{.SubTitle}}</p>
+<small>{{.Query}} · quoted {{.QuotedAt.UTC.Format "2006-01-02 15:04 UTC"}} · link expires {{.ExpiresAt.UTC.Format "2006-01-02 15:04 UTC"}}</small>
+</body></html>
+`))
+
+// handleSharedQuote serves GET /r/{id} as a minimal HTML page, or as JSON
+// with format=json or an Accept header asking for it.
+func handleSharedQuote(w http.ResponseWriter, r *http.Request) {
+	quote, ok := shares.get(strings.TrimPrefix(r.URL.Path, "/r/"))
+	if !ok {
+		http.Error(w, "quote not found or expired", http.StatusNotFound)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		writeJSON(w, quote)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := sharedQuotePage.Execute(w, quote); err != nil {
+		log.Printf("Error rendering shared quote %s: %v", quote.ID, err)
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"answerflow/modules/currency"
+)
+
+// soakQueries are the query shapes the soak load is drawn from. %d is a
+// random amount, so conversions miss the parse cache as real traffic does;
+// every query is also sent as a run of keystroke prefixes.
+var soakQueries = []string{
+	"%d usd to eur",
+	"%d eur rub",
+	"%d rub to usdt",
+	"%d btc to usd",
+	"%d ton в рублях",
+	"how much eur for %d usd",
+	"%d gbp to jpy, chf",
+	"%d doge usd",
+	"%d+%d*3",
+	"help",
+	"currencies eu",
+	"%d xyz to usd",
+}
+
+// runSoakCommand implements `answerflow soak`: it serves the full pipeline
+// in-process against stub providers, sends it synthetic queries for
+// -duration and fails as soon as the goroutine count, live heap or a cache
+// grows past its bound. Bounds are relative to a baseline taken once the
+// first -interval of load has warmed the caches up.
+func runSoakCommand(args []string) int {
+	fs := flag.NewFlagSet("soak", flag.ContinueOnError)
+	duration := fs.Duration("duration", time.Hour, "how long to keep the load up")
+	interval := fs.Duration("interval", time.Minute, "how often to sample; the first sample is the baseline")
+	workers := fs.Int("workers", 8, "concurrent clients")
+	goroutineSlack := fs.Int("goroutine-slack", 64, "goroutines allowed above the baseline")
+	heapGrowth := fs.Float64("heap-growth", 2, "live heap allowed, as a multiple of the baseline")
+	cacheGrowth := fs.Float64("cache-growth", 2, "cache entries allowed, as a multiple of the baseline (at least baseline+64)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: answerflow soak [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	// The soak talks to stub providers and keeps its rates snapshot in a
+	// temporary directory: hours of synthetic load must reach neither the
+	// real APIs nor the deployment's snapshot
+	dir, err := os.MkdirTemp("", "answerflow-soak-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+	currency.SetPersistencePath(filepath.Join(dir, "exchange_rates.json"))
+	currency.UseStubProviders()
+
+	globalAPICache = currency.NewAPICache()
+	if err := globalAPICache.InitialFetch(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	globalAPICache.InitializeTradeablePairs()
+	// The background goroutines are what the soak is after
+	globalAPICache.StartBackgroundUpdaters()
+	globalAPICache.AddJanitorTask("shares", shares.compact)
+	defer globalAPICache.Shutdown()
+	registerModules()
+
+	mux := http.NewServeMux()
+	limit := newInFlightLimiter()
+	mux.HandleFunc("/", limit.wrap(handleQuery))
+	mux.HandleFunc("/explain", limit.wrap(handleExplain))
+	mux.HandleFunc("/suggest", handleSuggest)
+	server := httptest.NewServer(trackActivity(mux))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *duration)
+	defer cancel()
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	var sent, failed atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < *workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			soakClient(ctx, server.URL, &sent, &failed)
+		}()
+	}
+	log.Printf("Soak: %d clients for %v against %s", *workers, *duration, server.URL)
+
+	code := 0
+	ticker := time.NewTicker(*interval)
+	var baseline *soakSample
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-ticker.C:
+		}
+		sample := takeSoakSample()
+		log.Printf("Soak: %d queries (%d failed), %s", sent.Load(), failed.Load(), sample)
+		if baseline == nil {
+			baseline = &sample
+			continue
+		}
+		if problems := sample.exceeds(*baseline, *goroutineSlack, *heapGrowth, *cacheGrowth); len(problems) > 0 {
+			for _, p := range problems {
+				fmt.Fprintf(os.Stderr, "FAIL %s\n", p)
+			}
+			if sample.goroutines > baseline.goroutines+*goroutineSlack {
+				pprof.Lookup("goroutine").WriteTo(os.Stderr, 1)
+			}
+			code = 1
+			break
+		}
+	}
+	ticker.Stop()
+	cancel()
+	wg.Wait()
+
+	if code == 0 {
+		fmt.Printf("ok   %d queries (%d failed) in %v, no growth past the bounds\n", sent.Load(), failed.Load(), *duration)
+	}
+	return code
+}
+
+// soakClient sends random queries, each typed out a keystroke prefix at a
+// time, until ctx is done.
+func soakClient(ctx context.Context, base string, sent, failed *atomic.Int64) {
+	client := &http.Client{Timeout: requestTimeout + 5*time.Second}
+	for ctx.Err() == nil {
+		query := soakQueries[rand.Intn(len(soakQueries))]
+		for strings.Contains(query, "%d") {
+			query = strings.Replace(query, "%d", fmt.Sprint(1+rand.Intn(100000)), 1)
+		}
+		for end := 1 + rand.Intn(3); end <= len(query) && ctx.Err() == nil; end += 1 + rand.Intn(3) {
+			path := "/?q="
+			if rand.Intn(10) == 0 {
+				path = "/suggest?q="
+			}
+			req, err := http.NewRequestWithContext(ctx, "GET", base+path+url.QueryEscape(query[:end]), nil)
+			if err != nil {
+				return
+			}
+			sent.Add(1)
+			resp, err := client.Do(req)
+			if err != nil {
+				if ctx.Err() == nil {
+					failed.Add(1)
+				}
+				continue
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode >= 500 {
+				failed.Add(1)
+			}
+		}
+	}
+}
+
+// soakSample is the state the soak bounds after a garbage collection.
+type soakSample struct {
+	goroutines int
+	heapBytes  uint64
+	caches     map[string]int
+}
+
+func takeSoakSample() soakSample {
+	runtime.GC()
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	caches := globalAPICache.CacheSizes()
+	caches["parse_cache"] = currencyModule.ParseStats().CacheEntries
+	caches["shared_quotes"] = len(shares.export())
+	caches["history"] = len(recentQueries.export())
+	return soakSample{goroutines: runtime.NumGoroutine(), heapBytes: mem.HeapAlloc, caches: caches}
+}
+
+func (s soakSample) String() string {
+	names := make([]string, 0, len(s.caches))
+	for name := range s.caches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := []string{fmt.Sprintf("%d goroutines", s.goroutines), fmt.Sprintf("heap %.1f MB", float64(s.heapBytes)/(1<<20))}
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s=%d", name, s.caches[name]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// exceeds lists the bounds s breaks relative to baseline.
+func (s soakSample) exceeds(baseline soakSample, goroutineSlack int, heapGrowth, cacheGrowth float64) []string {
+	var problems []string
+	if limit := baseline.goroutines + goroutineSlack; s.goroutines > limit {
+		problems = append(problems, fmt.Sprintf("goroutines: %d, baseline %d, limit %d", s.goroutines, baseline.goroutines, limit))
+	}
+	if limit := uint64(float64(baseline.heapBytes) * heapGrowth); s.heapBytes > limit {
+		problems = append(problems, fmt.Sprintf("heap: %d bytes, baseline %d, limit %d", s.heapBytes, baseline.heapBytes, limit))
+	}
+	for name, n := range s.caches {
+		base := baseline.caches[name]
+		limit := max(int(float64(base)*cacheGrowth), base+64)
+		if n > limit {
+			problems = append(problems, fmt.Sprintf("%s: %d entries, baseline %d, limit %d", name, n, base, limit))
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
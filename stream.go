@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"answerflow/commontypes"
+	"answerflow/events"
+)
+
+// streamResults answers query in two phases over server-sent events, so a
+// launcher gets constant keystroke latency without giving up accuracy:
+//
+//	event: fast   results from cached rates only (best bid/ask, no provider calls)
+//	event: final  results with fees and slippage from fresh quotes
+//
+// The fast event is skipped when the cached answer is already exact, and
+// the final event is always the last one. A provider going down or
+// recovering meanwhile is announced with a notice event
+// ({"provider", "status"}), since the final results may be affected.
+func streamResults(w http.ResponseWriter, ctx context.Context, query string, serialize func([]commontypes.FlowResult) interface{}) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	// The refinement may take up to requestTimeout, longer than the server's write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(requestTimeout + 5*time.Second)); err != nil {
+		log.Printf("Warning: could not extend write deadline for stream: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	// Notices arrive on the publisher's goroutine; nothing follows the final event
+	var mu sync.Mutex
+	done := false
+	send := func(event string, v interface{}, last bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if done {
+			return
+		}
+		writeEvent(w, event, v)
+		flusher.Flush()
+		done = last
+	}
+	notice := func(status string) func(events.Event) {
+		return func(e events.Event) {
+			// A slow client must not hold up the update loop publishing
+			go send("notice", map[string]string{"provider": e.Provider, "status": status}, false)
+		}
+	}
+	defer events.Subscribe(events.ProviderDown, notice("down"))()
+	defer events.Subscribe(events.ProviderRecovered, notice("recovered"))()
+
+	// QueryServed goes out once per query, for whichever pass is final
+	fast, served := runQuery(commontypes.WithFastPath(ctx), query)
+	if !commontypes.HasApproximate(fast) {
+		events.Publish(served)
+		send("final", serialize(fast), true)
+		return
+	}
+	send("fast", serialize(fast), false)
+
+	send("final", serialize(queryResults(ctx, query)), true)
+}
+
+func writeEvent(w http.ResponseWriter, event string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Error encoding %s event: %v", event, err)
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"answerflow/events"
+	"answerflow/modules"
+)
+
+// Recent queries that produced results are kept for autocomplete, newest
+// first, at most SUGGEST_HISTORY_SIZE of them per client ID. Queries
+// without a client ID aren't kept, and neither are queries a module marks
+// private (card numbers, IBANs): one user's queries are never suggested to
+// another.
+var (
+	suggestHistorySize = getEnvInt("SUGGEST_HISTORY_SIZE", 200)
+
+	recentQueries = &clientHistories{byClient: make(map[string]*queryHistory)}
+)
+
+func init() {
+	events.Subscribe(events.QueryServed, func(e events.Event) {
+		if e.Results > 0 && e.Client != "" && !privateQuery(e.Query) {
+			recentQueries.add(e.Client, e.Query)
+		}
+	})
+}
+
+// privateQuery reports whether a registered module treats query as private.
+func privateQuery(query string) bool {
+	for _, m := range registeredModules {
+		if p, ok := m.(modules.PrivateQuerier); ok && p.PrivateQuery(query) {
+			return true
+		}
+	}
+	return false
+}
+
+// commonPhrasings are offered when the typed text is a prefix of them, so a
+// new user sees what the launcher understands.
+var commonPhrasings = []string{
+	"100 usd to eur",
+	"100 eur to rub",
+	"1000 rub to usd",
+	"1 btc to usd",
+	"100 usdt to rub",
+	"usd to eur",
+	"eur to usd",
+}
+
+// clientHistories holds the history of each client, for at most
+// PROFILES_MAX clients; the one that went longest without a query makes
+// room for a new one.
+type clientHistories struct {
+	mu       sync.Mutex
+	byClient map[string]*queryHistory
+}
+
+type queryHistory struct {
+	mu       sync.Mutex
+	queries  []string
+	lastUsed time.Time // guarded by clientHistories.mu
+}
+
+// add records query in the history of client.
+func (c *clientHistories) add(client, query string) {
+	c.mu.Lock()
+	h, ok := c.byClient[client]
+	if !ok {
+		if len(c.byClient) >= profilesMax {
+			c.evictLocked()
+		}
+		h = &queryHistory{}
+		c.byClient[client] = h
+	}
+	h.lastUsed = time.Now()
+	c.mu.Unlock()
+	h.add(query)
+}
+
+func (c *clientHistories) evictLocked() {
+	var oldest string
+	for id, h := range c.byClient {
+		if oldest == "" || h.lastUsed.Before(c.byClient[oldest].lastUsed) {
+			oldest = id
+		}
+	}
+	delete(c.byClient, oldest)
+}
+
+// matching returns up to limit recent queries of client that extend prefix.
+func (c *clientHistories) matching(client, prefix string, limit int) []string {
+	if client == "" {
+		return nil
+	}
+	c.mu.Lock()
+	h := c.byClient[client]
+	c.mu.Unlock()
+	if h == nil {
+		return nil
+	}
+	return h.matching(prefix, limit)
+}
+
+// add records query as the most recent one. Shorter entries it extends are
+// dropped, since they are usually the same query caught mid-typing.
+func (h *queryHistory) add(query string) {
+	query = strings.ToLower(strings.Join(strings.Fields(query), " "))
+	if query == "" || suggestHistorySize <= 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	kept := h.queries[:0]
+	for _, q := range h.queries {
+		if !strings.HasPrefix(query, q) {
+			kept = append(kept, q)
+		}
+	}
+	h.queries = append([]string{query}, kept...)
+	if len(h.queries) > suggestHistorySize {
+		h.queries = h.queries[:suggestHistorySize]
+	}
+}
+
+// matching returns up to limit recent queries that extend prefix.
+func (h *queryHistory) matching(prefix string, limit int) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var out []string
+	for _, q := range h.queries {
+		if len(out) == limit {
+			break
+		}
+		if q != prefix && strings.HasPrefix(q, prefix) {
+			out = append(out, q)
+		}
+	}
+	return out
+}
+
+// suggestions merges the client's recent queries, common phrasings and
+// currency completions for query, in that order and without duplicates.
+func suggestions(client, query string, limit int) []string {
+	prefix := strings.ToLower(strings.Join(strings.Fields(query), " "))
+	if prefix == "" {
+		return []string{}
+	}
+
+	out := []string{}
+	seen := map[string]bool{prefix: true}
+	add := func(list []string) {
+		for _, s := range list {
+			if len(out) == limit {
+				return
+			}
+			if !seen[s] {
+				seen[s] = true
+				out = append(out, s)
+			}
+		}
+	}
+
+	add(recentQueries.matching(client, prefix, limit))
+	for _, p := range commonPhrasings {
+		if strings.HasPrefix(p, prefix) {
+			add([]string{p})
+		}
+	}
+	if currencyModule != nil {
+		add(currencyModule.CompleteQuery(prefix, limit))
+	}
+	return out
+}
+
+// handleSuggest serves GET /suggest?q=&limit= for launchers with
+// autocomplete. Recent queries are offered to the client that made them.
+func handleSuggest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	limit := 8
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 50 {
+		limit = l
+	}
+	query := r.URL.Query().Get("q")
+	writeJSON(w, map[string]interface{}{
+		"query":       query,
+		"suggestions": suggestions(clientID(r), query, limit),
+	})
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"answerflow/cron"
+	"answerflow/notify"
+)
+
+// Daily summary of key rates and provider health, posted on SUMMARY_CRON
+// (default 09:00 daily, server time unless it starts with CRON_TZ=) to the
+// notification channels.
+var summaryCron = getEnv("SUMMARY_CRON", "0 9 * * *")
+
+const (
+	rateSampleInterval = time.Hour
+	rateHistoryWindow  = 48 * time.Hour
+)
+
+// summaryPairs are the rates reported in the summary, as effective rates
+// for the given amount (fees and order book depth included).
+var summaryPairs = []struct {
+	label    string
+	amount   float64
+	from, to string
+}{
+	{"USD/RUB", 100, "USD", "RUB"},
+	{"TON/USDT", 1, "TON", "USDT"},
+	{"BTC/USDT", 1, "BTC", "USDT"},
+}
+
+type rateSample struct {
+	at    time.Time
+	rates map[string]float64
+}
+
+// rateHistory keeps hourly samples of summaryPairs for 24h change figures.
+type rateHistory struct {
+	mu      sync.Mutex
+	samples []rateSample
+}
+
+func (h *rateHistory) Record(s rateSample) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.samples = append(h.samples, s)
+	cutoff := time.Now().Add(-rateHistoryWindow)
+	for len(h.samples) > 0 && h.samples[0].at.Before(cutoff) {
+		h.samples = h.samples[1:]
+	}
+}
+
+// Around returns the sample closest to t, if one is within an hour of it.
+func (h *rateHistory) Around(t time.Time) (rateSample, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	i := sort.Search(len(h.samples), func(i int) bool { return !h.samples[i].at.Before(t) })
+	best, found := rateSample{}, false
+	for _, j := range []int{i - 1, i} {
+		if j < 0 || j >= len(h.samples) {
+			continue
+		}
+		d := h.samples[j].at.Sub(t).Abs()
+		if d <= rateSampleInterval && (!found || d < best.at.Sub(t).Abs()) {
+			best, found = h.samples[j], true
+		}
+	}
+	return best, found
+}
+
+func sampleRates() rateSample {
+	s := rateSample{at: time.Now(), rates: make(map[string]float64)}
+	for _, p := range summaryPairs {
+		route, err := currencyModule.Explain(p.amount, p.from, p.to, globalAPICache)
+		if err != nil {
+			log.Printf("Warning: summary rate %s unavailable: %v", p.label, err)
+			continue
+		}
+		s.rates[p.label] = route.EffectiveRate
+	}
+	return s
+}
+
+// startSummaryScheduler samples rates hourly and posts the summary on
+// SUMMARY_CRON. It does nothing unless a notification channel is configured.
+func startSummaryScheduler(notifier notify.Multi) {
+	if len(notifier) == 0 {
+		return
+	}
+	schedule, err := cron.Parse(summaryCron)
+	if err != nil {
+		log.Printf("Warning: invalid SUMMARY_CRON, daily summary disabled: %v", err)
+		return
+	}
+
+	history := &rateHistory{}
+	go func() {
+		history.Record(sampleRates())
+		ticker := time.NewTicker(rateSampleInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			history.Record(sampleRates())
+		}
+	}()
+
+	go func() {
+		for {
+			next := schedule.Next(time.Now())
+			if next.IsZero() {
+				log.Printf("Warning: SUMMARY_CRON %q never fires, daily summary disabled", summaryCron)
+				return
+			}
+			time.Sleep(time.Until(next))
+			notify.Send(notifier, notify.Message{Subject: "Daily rates summary", Text: buildSummary(history), Level: notify.LevelInfo})
+		}
+	}()
+	log.Printf("Daily summary scheduled (%s)", summaryCron)
+}
+
+func buildSummary(history *rateHistory) string {
+	now := sampleRates()
+	past, havePast := history.Around(now.at.Add(-24 * time.Hour))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Rates summary %s\n", now.at.Format("2006-01-02 15:04"))
+	for _, p := range summaryPairs {
+		rate, ok := now.rates[p.label]
+		if !ok {
+			fmt.Fprintf(&sb, "%s: unavailable\n", p.label)
+			continue
+		}
+		line := fmt.Sprintf("%s: %s", p.label, strconv.FormatFloat(rate, 'g', 6, 64))
+		if prev, ok := past.rates[p.label]; havePast && ok && prev > 0 {
+			line += fmt.Sprintf(" (%+.2f%% 24h)", (rate/prev-1)*100)
+		}
+		sb.WriteString(line + "\n")
+	}
+
+	sb.WriteString("Providers:")
+	staleness := globalAPICache.GetCacheStaleness()
+	providers := make([]string, 0, len(staleness))
+	for name := range staleness {
+		providers = append(providers, name)
+	}
+	sort.Strings(providers)
+	for _, name := range providers {
+		fmt.Fprintf(&sb, " %s updated %v ago;", name, staleness[name].Round(time.Second))
+	}
+	sb.WriteString("\nModules:")
+	for _, m := range registeredModules {
+		fmt.Fprintf(&sb, " %s %s;", m.Name(), moduleHealthByName[m.Name()].Snapshot().State)
+	}
+	return sb.String()
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// moduleSwitchesPath persists runtime kill switches across restarts.
+var moduleSwitchesPath = getEnv("MODULE_SWITCHES_FILE", "data/module_switches.json")
+
+// moduleSwitches are the operator's on/off switches, set through the admin
+// API. Unlike moduleHealth they never expire: a module switched off stays
+// off until switched back on, restarts included.
+type moduleSwitches struct {
+	mu          sync.RWMutex
+	AllDisabled bool            `json:"all_disabled"`
+	Disabled    map[string]bool `json:"disabled"`
+}
+
+var switches = &moduleSwitches{Disabled: make(map[string]bool)}
+
+// Enabled reports whether module name may run.
+func (s *moduleSwitches) Enabled(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return !s.AllDisabled && !s.Disabled[name]
+}
+
+// Set switches module name, or every module for "*", on or off and
+// persists the result.
+func (s *moduleSwitches) Set(name string, enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if name == "*" {
+		s.AllDisabled = !enabled
+	} else if enabled {
+		delete(s.Disabled, name)
+	} else {
+		s.Disabled[name] = true
+	}
+	return s.saveLocked()
+}
+
+func (s *moduleSwitches) snapshot() map[string]interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	modules := make(map[string]bool, len(registeredModules))
+	for _, m := range registeredModules {
+		modules[m.Name()] = !s.Disabled[m.Name()]
+	}
+	return map[string]interface{}{
+		"all_disabled": s.AllDisabled,
+		"modules":      modules,
+	}
+}
+
+func (s *moduleSwitches) saveLocked() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(moduleSwitchesPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	tempFile := moduleSwitchesPath + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := os.Rename(tempFile, moduleSwitchesPath); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+	return nil
+}
+
+// loadModuleSwitches restores the switches saved by a previous run.
+func loadModuleSwitches() {
+	data, err := os.ReadFile(moduleSwitchesPath)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err == nil {
+		switches.mu.Lock()
+		err = json.Unmarshal(data, switches)
+		if switches.Disabled == nil {
+			switches.Disabled = make(map[string]bool)
+		}
+		switches.mu.Unlock()
+	}
+	if err != nil {
+		log.Printf("Warning: module switches not loaded from %s: %v", moduleSwitchesPath, err)
+		return
+	}
+	if !switches.Enabled("") {
+		log.Printf("Warning: all modules are switched off (%s)", moduleSwitchesPath)
+	}
+	for name := range switches.Disabled {
+		log.Printf("Module '%s' is switched off (%s)", name, moduleSwitchesPath)
+	}
+}
+
+// handleModules lists the module switches (GET) or flips one
+// (POST ?module=<name>|*&action=enable|disable).
+func handleModules(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, switches.snapshot())
+
+	case http.MethodPost:
+		name := r.URL.Query().Get("module")
+		if _, ok := moduleSemaphores[name]; !ok && name != "*" {
+			http.Error(w, "unknown module", http.StatusNotFound)
+			return
+		}
+		var enabled bool
+		switch r.URL.Query().Get("action") {
+		case "enable":
+			enabled = true
+		case "disable":
+		default:
+			http.Error(w, "action must be enable or disable", http.StatusBadRequest)
+			return
+		}
+		if err := switches.Set(name, enabled); err != nil {
+			// The switch took effect; only persisting it failed
+			log.Printf("Warning: module switches not saved: %v", err)
+		}
+		log.Printf("Module '%s' %sd via admin API", name, r.URL.Query().Get("action"))
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
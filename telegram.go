@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"answerflow/commontypes"
+)
+
+const (
+	telegramAPIBase     = "https://api.telegram.org/bot"
+	telegramPollTimeout = 30 // seconds, long polling
+	telegramMaxResults  = 10
+)
+
+type telegramUpdate struct {
+	UpdateID int `json:"update_id"`
+	Message  *struct {
+		Chat struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+		Text string `json:"text"`
+	} `json:"message"`
+}
+
+// telegramBot answers chat messages with the same results the launcher gets.
+// It runs alongside the HTTP server when TELEGRAM_BOT_TOKEN is set.
+type telegramBot struct {
+	token  string
+	client *http.Client
+	limit  *inFlightLimiter
+}
+
+func newTelegramBot(token string, limit *inFlightLimiter) *telegramBot {
+	return &telegramBot{
+		token:  token,
+		client: &http.Client{Timeout: (telegramPollTimeout + 10) * time.Second},
+		limit:  limit,
+	}
+}
+
+func (b *telegramBot) Run() {
+	log.Println("Telegram bot started (long polling)")
+	offset := 0
+	for {
+		updates, err := b.getUpdates(offset)
+		if err != nil {
+			log.Printf("Telegram getUpdates failed: %v", err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			if u.Message == nil || strings.TrimSpace(u.Message.Text) == "" {
+				continue
+			}
+			go b.answer(u.Message.Chat.ID, u.Message.Text)
+		}
+	}
+}
+
+func (b *telegramBot) getUpdates(offset int) ([]telegramUpdate, error) {
+	params := url.Values{}
+	params.Set("timeout", strconv.Itoa(telegramPollTimeout))
+	params.Set("offset", strconv.Itoa(offset))
+
+	resp, err := b.client.Get(telegramAPIBase + b.token + "/getUpdates?" + params.Encode())
+	if err != nil {
+		return nil, b.redact(err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK     bool             `json:"ok"`
+		Result []telegramUpdate `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if !result.OK {
+		return nil, fmt.Errorf("status %s", resp.Status)
+	}
+	return result.Result, nil
+}
+
+func (b *telegramBot) answer(chatID int64, text string) {
+	query := strings.TrimSpace(text)
+	if query == "/start" || query == "/help" {
+		b.sendMessage(chatID, "Send a query like <code>100 usd</code> or <code>50 eur to rub</code>.")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	// Chat queries count against the same budget as HTTP ones
+	if !b.limit.acquire(ctx) {
+		b.sendMessage(chatID, "Too many queries right now. Please try again in a moment.")
+		return
+	}
+	defer b.limit.release()
+
+	results := queryResults(ctx, query)
+	if len(results) == 0 {
+		b.sendMessage(chatID, "No results found. Please try a different query.")
+		return
+	}
+	b.sendMessage(chatID, formatTelegramResults(results))
+}
+
+// redact removes the bot token from the request URL a client error quotes,
+// so failures can be logged.
+func (b *telegramBot) redact(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = strings.ReplaceAll(urlErr.URL, b.token, "<token>")
+	}
+	return err
+}
+
+// formatTelegramResults renders results as HTML; the clipboard value of each
+// result goes on its own <code> line so it can be copied with a tap.
+func formatTelegramResults(results []commontypes.FlowResult) string {
+	if len(results) > telegramMaxResults {
+		results = results[:telegramMaxResults]
+	}
+
+	var sb strings.Builder
+	for i, res := range results {
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+		sb.WriteString("<b>" + html.EscapeString(res.Title) + "</b>")
+		if res.SubTitle != "" {
+			sb.WriteString("\n" + html.EscapeString(res.SubTitle))
+		}
+		if clip, ok := clipboardText(res); ok {
+			sb.WriteString("\n<code>" + html.EscapeString(clip) + "</code>")
+		}
+	}
+	return sb.String()
+}
+
+func (b *telegramBot) sendMessage(chatID int64, text string) {
+	params := url.Values{}
+	params.Set("chat_id", strconv.FormatInt(chatID, 10))
+	params.Set("text", text)
+	params.Set("parse_mode", "HTML")
+
+	resp, err := b.client.PostForm(telegramAPIBase+b.token+"/sendMessage", params)
+	if err != nil {
+		log.Printf("Telegram sendMessage failed: %v", b.redact(err))
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("Telegram sendMessage failed: status %s", resp.Status)
+	}
+}
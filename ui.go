@@ -0,0 +1,20 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed ui
+var uiAssets embed.FS
+
+// uiHandler serves the embedded single-page UI under /ui/. It queries the
+// Raycast format of the query endpoint, which carries copy actions.
+func uiHandler() http.Handler {
+	assets, err := fs.Sub(uiAssets, "ui")
+	if err != nil {
+		panic(err)
+	}
+	return http.StripPrefix("/ui/", http.FileServer(http.FS(assets)))
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// userDataVersion is bumped when userDataBundle changes incompatibly.
+// Version 2 keeps the autocomplete history per client ID.
+const userDataVersion = 2
+
+// userDataBundle is the user-facing state of an instance, for moving it to
+// another machine: preference profiles (favorites included), the
+// autocomplete history and unexpired shared quotes. Rate caches are left
+// out; the new instance fetches its own.
+type userDataBundle struct {
+	Version    int                 `json:"version"`
+	ExportedAt time.Time           `json:"exported_at"`
+	Profiles   map[string]*profile `json:"profiles"`
+	History    map[string][]string `json:"history"` // by client ID, newest first
+	Shares     []*sharedQuote      `json:"shares"`
+}
+
+// handleUserData exports the bundle (GET) or imports one (POST, JSON body).
+// An import merges into the current state unless ?mode=replace; either way
+// nothing changes when any part of the bundle is invalid.
+func handleUserData(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Disposition", `attachment; filename="answerflow-userdata.json"`)
+		writeJSON(w, userDataBundle{
+			Version:    userDataVersion,
+			ExportedAt: time.Now().UTC(),
+			Profiles:   profiles.export(),
+			History:    recentQueries.export(),
+			Shares:     shares.export(),
+		})
+
+	case http.MethodPost:
+		replace := false
+		switch mode := r.URL.Query().Get("mode"); mode {
+		case "", "merge":
+		case "replace":
+			replace = true
+		default:
+			http.Error(w, "mode must be merge or replace", http.StatusBadRequest)
+			return
+		}
+		var bundle userDataBundle
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 16<<20)).Decode(&bundle); err != nil {
+			http.Error(w, "invalid bundle: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := bundle.validate(); err != nil {
+			http.Error(w, "invalid bundle: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := profiles.importAll(bundle.Profiles, replace); err == errProfilesFull {
+			http.Error(w, fmt.Sprintf("%v: the bundle needs more than PROFILES_MAX=%d", err, profilesMax), http.StatusInsufficientStorage)
+			return
+		} else if err != nil {
+			// The profiles took effect; only persisting them failed
+			log.Printf("Warning: imported profiles not saved: %v", err)
+		}
+		recentQueries.importAll(bundle.History, replace)
+		shares.importAll(bundle.Shares, replace)
+		log.Printf("Imported user data from %s: %d profiles, %d client histories, %d shared quotes",
+			bundle.ExportedAt.Format(time.RFC3339), len(bundle.Profiles), len(bundle.History), len(bundle.Shares))
+
+		writeJSON(w, map[string]interface{}{
+			"mode":     map[bool]string{false: "merge", true: "replace"}[replace],
+			"profiles": len(bundle.Profiles),
+			"history":  len(bundle.History),
+			"shares":   len(bundle.Shares),
+		})
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// validate checks and normalizes the bundle the way the endpoints that
+// created its parts would have, dropping expired shared quotes.
+func (b *userDataBundle) validate() error {
+	if b.Version != userDataVersion {
+		return fmt.Errorf("version %d, this instance reads version %d", b.Version, userDataVersion)
+	}
+	for id, p := range b.Profiles {
+		if !clientIDPattern.MatchString(id) {
+			return fmt.Errorf("profile %q: invalid client ID", id)
+		}
+		if p == nil {
+			return fmt.Errorf("profile %q: empty", id)
+		}
+		if err := p.normalize(); err != nil {
+			return fmt.Errorf("profile %q: %w", id, err)
+		}
+	}
+
+	for id, queries := range b.History {
+		if !clientIDPattern.MatchString(id) {
+			return fmt.Errorf("history %q: invalid client ID", id)
+		}
+		history := queries[:0]
+		for _, q := range queries {
+			if q = strings.ToLower(strings.Join(strings.Fields(q), " ")); q != "" {
+				history = append(history, q)
+			}
+		}
+		b.History[id] = history
+	}
+
+	now := time.Now()
+	var live []*sharedQuote
+	for _, q := range b.Shares {
+		if q == nil || q.ID == "" {
+			return fmt.Errorf("shared quote without an ID")
+		}
+		if now.Before(q.ExpiresAt) {
+			live = append(live, q)
+		}
+	}
+	b.Shares = live
+	return nil
+}
+
+// export returns a copy of every profile.
+func (s *profileStore) export() map[string]*profile {
+	out := make(map[string]*profile)
+	for id, p := range s.snapshot() {
+		p := p
+		p.Favorites = append([]string(nil), p.Favorites...)
+		p.Watchlist = append([]string(nil), p.Watchlist...)
+		out[id] = &p
+	}
+	return out
+}
+
+// importAll adds in to the store, or makes it the whole store when replace
+// is set, and persists the result. It changes nothing when the result
+// would exceed PROFILES_MAX.
+func (s *profileStore) importAll(in map[string]*profile, replace bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	merged := make(map[string]*profile, len(s.Profiles)+len(in))
+	if !replace {
+		for id, p := range s.Profiles {
+			merged[id] = p
+		}
+	}
+	for id, p := range in {
+		merged[id] = p
+	}
+	if len(merged) > profilesMax {
+		return errProfilesFull
+	}
+	s.Profiles = merged
+	return s.saveLocked()
+}
+
+// export returns the recent queries of every client, newest first.
+func (c *clientHistories) export() map[string][]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[string][]string, len(c.byClient))
+	for id, h := range c.byClient {
+		out[id] = h.export()
+	}
+	return out
+}
+
+// importAll imports the history of each client in histories, dropping
+// every other client's when replace is set.
+func (c *clientHistories) importAll(histories map[string][]string, replace bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if replace {
+		c.byClient = make(map[string]*queryHistory)
+	}
+	for id, queries := range histories {
+		h, ok := c.byClient[id]
+		if !ok {
+			if len(c.byClient) >= profilesMax {
+				c.evictLocked()
+			}
+			h = &queryHistory{lastUsed: time.Now()}
+			c.byClient[id] = h
+		}
+		h.importAll(queries, replace)
+	}
+}
+
+// export returns the recent queries, newest first.
+func (h *queryHistory) export() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string{}, h.queries...)
+}
+
+// importAll puts queries (newest first) ahead of the current history, or in
+// its place when replace is set, keeping SUGGEST_HISTORY_SIZE entries.
+func (h *queryHistory) importAll(queries []string, replace bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	merged := append([]string(nil), queries...)
+	if !replace {
+		seen := make(map[string]bool, len(queries))
+		for _, q := range queries {
+			seen[q] = true
+		}
+		for _, q := range h.queries {
+			if !seen[q] {
+				merged = append(merged, q)
+			}
+		}
+	}
+	if len(merged) > suggestHistorySize {
+		merged = merged[:max(suggestHistorySize, 0)]
+	}
+	h.queries = merged
+}
+
+// export returns the unexpired shared quotes, oldest first.
+func (s *shareStore) export() []*sharedQuote {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	out := []*sharedQuote{}
+	for el := s.order.Front(); el != nil; el = el.Next() {
+		if q := el.Value.(*sharedQuote); now.Before(q.ExpiresAt) {
+			cp := *q
+			out = append(out, &cp)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].QuotedAt.Before(out[j].QuotedAt) })
+	return out
+}
+
+// importAll adds quotes under their original IDs, so links handed out by
+// the old instance keep working, or replaces all quotes when replace is set.
+func (s *shareStore) importAll(quotes []*sharedQuote, replace bool) {
+	if replace {
+		s.mu.Lock()
+		s.order.Init()
+		clear(s.entries)
+		s.mu.Unlock()
+	}
+	for _, q := range quotes {
+		s.put(q)
+	}
+}
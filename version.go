@@ -0,0 +1,20 @@
+package main
+
+import (
+	"net/http"
+
+	"answerflow/buildinfo"
+)
+
+// handleVersion serves /version: the build's identity and the modules it
+// runs, for bug reports.
+func handleVersion(w http.ResponseWriter, r *http.Request) {
+	names := []string{}
+	for _, m := range enabledModules() {
+		names = append(names, m.Name())
+	}
+	writeJSON(w, struct {
+		buildinfo.Info
+		Modules []string `json:"modules"`
+	}{buildinfo.Get(), names})
+}